@@ -1,6 +1,8 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,12 +11,16 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 )
 
 // Register a test backend for PrefixDB as well, with some unrelated junk data
 func init() {
 	// nolint: errcheck
-	registerDBCreator("prefixdb", func(name, dir string) (DB, error) {
+	registerDBCreator("prefixdb", func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
 		mdb := NewMemDB()
 		mdb.Set([]byte("a"), []byte{1})
 		mdb.Set([]byte("b"), []byte{2})
@@ -80,16 +86,16 @@ func testBackendGetSetDelete(t *testing.T, backend BackendType) {
 	err = db.Delete([]byte("a"))
 	require.NoError(t, err)
 
-	value, err = db.Get([]byte("a"))
+	ok, err = db.Has([]byte("a"))
 	require.NoError(t, err)
-	require.Nil(t, value)
+	require.False(t, ok)
 
 	err = db.DeleteSync([]byte("b"))
 	require.NoError(t, err)
 
-	value, err = db.Get([]byte("b"))
+	ok, err = db.Has([]byte("b"))
 	require.NoError(t, err)
-	require.Nil(t, value)
+	require.False(t, ok)
 
 	// Setting, getting, and deleting an empty key should error.
 	_, err = db.Get([]byte{})
@@ -133,6 +139,9 @@ func testBackendGetSetDelete(t *testing.T, backend BackendType) {
 	value, err = db.Get([]byte("x"))
 	require.NoError(t, err)
 	require.Equal(t, []byte{}, value)
+	ok, err = db.Has([]byte("x"))
+	require.NoError(t, err)
+	require.True(t, ok)
 }
 
 func TestBackendsGetSetDelete(t *testing.T) {
@@ -143,6 +152,994 @@ func TestBackendsGetSetDelete(t *testing.T) {
 	}
 }
 
+func TestOpen(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "test_open_")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			db, err := Open(dbType, "testdb", dir, Options{
+				MaxOpenFiles:   100,
+				BlockCacheSize: 1 << 20,
+				Logger:         &bufferLogger{},
+			})
+			require.NoError(t, err)
+			require.NoError(t, db.Set([]byte("a"), []byte{1}))
+			checkValue(t, db, []byte("a"), []byte{1})
+			require.NoError(t, db.Close())
+		})
+	}
+
+	_, err := Open("unknown", "testdb", os.TempDir(), Options{})
+	require.Error(t, err)
+	_, err = Open(MemDBBackend, "testdb", os.TempDir(), Options{ReadOnly: true})
+	require.ErrorIs(t, err, errReadOnlyUnsupported)
+}
+
+func TestOpenExistence(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, RocksDBBackend, MemDBBackend} {
+		if _, ok := backends[dbType]; !ok {
+			continue
+		}
+		t.Run(string(dbType), func(t *testing.T) {
+			dir := t.TempDir()
+
+			_, err := Open(dbType, "testdb", dir, Options{ErrorIfMissing: true})
+			require.ErrorIs(t, err, ErrDBNotFound)
+
+			db, err := Open(dbType, "testdb", dir, Options{ErrorIfExists: true})
+			require.NoError(t, err)
+			require.NoError(t, db.Close())
+			if dbType == MemDBBackend {
+				// Simulate the database existing, memdb is not persisted.
+				require.NoError(t, os.Mkdir(filepath.Join(dir, "testdb.db"), 0o700))
+			}
+
+			_, err = Open(dbType, "testdb", dir, Options{ErrorIfExists: true})
+			require.ErrorIs(t, err, ErrDBAlreadyExists)
+			db, err = Open(dbType, "testdb", dir, Options{ErrorIfMissing: true})
+			require.NoError(t, err)
+			require.NoError(t, db.Close())
+		})
+	}
+}
+
+func TestDBReadOnly(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBReadOnly(t, dbType)
+		})
+	}
+}
+
+func testDBReadOnly(t *testing.T, backend BackendType) {
+	dir, err := ioutil.TempDir("", "test_read_only_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := Open(backend, "testdb", dir, Options{})
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Close())
+
+	db, err = Open(backend, "testdb", dir, Options{ReadOnly: true})
+	if errors.Is(err, errReadOnlyUnsupported) {
+		t.Skip("read-only mode not supported")
+	}
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Reads work as usual.
+	checkValue(t, db, []byte("a"), []byte{1})
+	ok, err := db.Has([]byte("b"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	require.NoError(t, itr.Close())
+
+	// Writes fail.
+	require.ErrorIs(t, db.Set([]byte("c"), []byte{3}), ErrReadOnly)
+	require.ErrorIs(t, db.SetSync([]byte("c"), []byte{3}), ErrReadOnly)
+	require.ErrorIs(t, db.Delete([]byte("a")), ErrReadOnly)
+	require.ErrorIs(t, db.DeleteSync([]byte("a")), ErrReadOnly)
+	require.ErrorIs(t, db.DeleteRange(nil, nil), ErrReadOnly)
+	require.ErrorIs(t, db.Compact(nil, nil), ErrReadOnly)
+	_, err = db.CompareAndSet([]byte("a"), []byte{1}, []byte{3})
+	require.ErrorIs(t, err, ErrReadOnly)
+
+	batch := db.NewBatch()
+	require.ErrorIs(t, batch.Set([]byte("c"), []byte{3}), ErrReadOnly)
+	require.ErrorIs(t, batch.Write(), ErrReadOnly)
+	require.NoError(t, batch.Close())
+
+	checkValue(t, db, []byte("a"), []byte{1})
+	checkValue(t, db, []byte("c"), nil)
+}
+
+func TestDBStats(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+
+			require.NoError(t, db.Set([]byte("a"), []byte{1}))
+			require.NotNil(t, db.Stats())
+		})
+	}
+}
+
+func TestDBCompareAndSet(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBCompareAndSet(t, dbType)
+		})
+	}
+}
+
+func testDBCompareAndSet(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	// A nil expected value requires the key to be missing.
+	swapped, err := db.CompareAndSet([]byte("a"), nil, []byte{1})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	swapped, err = db.CompareAndSet([]byte("a"), nil, []byte{2})
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	// An empty expected value does not match a missing key.
+	swapped, err = db.CompareAndSet([]byte("b"), []byte{}, []byte{1})
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	// A mismatching value is left as is, a matching one is replaced.
+	swapped, err = db.CompareAndSet([]byte("a"), []byte{2}, []byte{3})
+	require.NoError(t, err)
+	require.False(t, swapped)
+	swapped, err = db.CompareAndSet([]byte("a"), []byte{1}, []byte{3})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	assertKeyValues(t, db, map[string][]byte{"a": {3}})
+
+	// Empty keys and nil values are errors.
+	_, err = db.CompareAndSet([]byte{}, nil, []byte{1})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = db.CompareAndSet([]byte("c"), nil, nil)
+	require.Equal(t, errValueNil, err)
+
+	// Exactly one of many concurrent swaps from the same value should succeed.
+	var (
+		g         errgroup.Group
+		successes = make(chan int, 100)
+	)
+	for i := 0; i < 100; i++ {
+		i := i
+		g.Go(func() error {
+			swapped, err := db.CompareAndSet([]byte("a"), []byte{3}, int642Bytes(int64(i)))
+			if swapped {
+				successes <- i
+			}
+			return err
+		})
+	}
+	require.NoError(t, g.Wait())
+	close(successes)
+	require.Len(t, successes, 1)
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, int642Bytes(int64(<-successes)), value)
+}
+
+func TestDBCompareAndSetWithWrites(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			// A swap racing with a Set followed by a Delete of the same key can only succeed
+			// between the two, so the key is always gone once both are done. A swap which is not
+			// atomic with respect to the Delete could write the key after it.
+			for i := 0; i < 200; i++ {
+				key := int642Bytes(int64(i))
+				var g errgroup.Group
+				g.Go(func() error {
+					if err := db.Set(key, []byte{1}); err != nil {
+						return err
+					}
+					return db.Delete(key)
+				})
+				g.Go(func() error {
+					_, err := db.CompareAndSet(key, []byte{1}, []byte{2})
+					return err
+				})
+				g.Go(func() error {
+					batch := db.NewBatch()
+					defer batch.Close()
+					if err := batch.Set([]byte("other"), key); err != nil {
+						return err
+					}
+					return batch.Write()
+				})
+				require.NoError(t, g.Wait())
+				checkValue(t, db, key, nil)
+			}
+		})
+	}
+}
+
+func TestDBMultiGet(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBMultiGet(t, dbType)
+		})
+	}
+}
+
+func testDBMultiGet(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+
+	// Missing keys have nil values in the same position, and keys may be repeated.
+	values, err := db.MultiGet([][]byte{[]byte("x"), []byte("a"), []byte("b"), []byte("c"), []byte("a")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{nil, {1}, nil, {3}, {1}}, values)
+
+	values, err = db.MultiGet(nil)
+	require.NoError(t, err)
+	require.Empty(t, values)
+
+	_, err = db.MultiGet([][]byte{[]byte("a"), {}})
+	require.Equal(t, errKeyEmpty, err)
+}
+
+func TestDBForEach(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBForEach(t, dbType)
+		})
+	}
+}
+
+func testDBForEach(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	// An empty database never calls fn.
+	err := db.ForEach(func(key, value []byte) error {
+		t.Fatalf("unexpected key %q", key)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, db.Set([]byte(key), []byte(key)))
+	}
+
+	// A full pass visits every pair in order.
+	visited := []string{}
+	err = db.ForEach(func(key, value []byte) error {
+		require.Equal(t, key, value)
+		visited = append(visited, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d"}, visited)
+
+	// Returning an error stops the iteration and forwards the error.
+	errStop := fmt.Errorf("stop")
+	calls := 0
+	err = db.ForEach(func(key, value []byte) error {
+		calls++
+		if string(key) == "b" {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 2, calls)
+
+	// The iterator must have been closed, so writes are possible again.
+	require.NoError(t, db.Set([]byte("e"), []byte("e")))
+}
+
+func TestDBForEachReverse(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			require.NoError(t, ForEachReverse(db, func(key, value []byte) error {
+				t.Fatalf("unexpected key %q", key)
+				return nil
+			}))
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			var forward, reverse []int64
+			require.NoError(t, db.ForEach(func(key, value []byte) error {
+				forward = append(forward, bytes2Int64(key))
+				return nil
+			}))
+			require.NoError(t, ForEachReverse(db, func(key, value []byte) error {
+				require.Equal(t, key, value)
+				reverse = append(reverse, bytes2Int64(key))
+				return nil
+			}))
+			require.Len(t, reverse, 100)
+			for i := range forward {
+				require.Equal(t, forward[i], reverse[len(reverse)-1-i])
+			}
+
+			// Returning an error stops the iteration and forwards the error.
+			errStop := fmt.Errorf("stop")
+			calls := 0
+			err := ForEachReverse(db, func(key, value []byte) error {
+				calls++
+				if bytes2Int64(key) == 98 {
+					return errStop
+				}
+				return nil
+			})
+			require.Equal(t, errStop, err)
+			require.Equal(t, 2, calls)
+
+			// The iterator must have been closed, so writes are possible again.
+			require.NoError(t, db.Set(int642Bytes(100), []byte{1}))
+		})
+	}
+}
+
+func TestDBDeleteRange(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBDeleteRange(t, dbType)
+		})
+	}
+}
+
+func testDBDeleteRange(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	keys := []string{"a", "b", "c", "d", "e", "f", "g"}
+	for _, key := range keys {
+		require.NoError(t, db.Set([]byte(key), []byte{1}))
+	}
+	expect := func(keys ...string) map[string][]byte {
+		kvs := map[string][]byte{}
+		for _, key := range keys {
+			kvs[key] = []byte{1}
+		}
+		return kvs
+	}
+
+	// Empty keys are errors.
+	require.Equal(t, errKeyEmpty, db.DeleteRange([]byte{}, nil))
+	require.Equal(t, errKeyEmpty, db.DeleteRange(nil, []byte{}))
+
+	// Empty domains are no-ops.
+	require.NoError(t, db.DeleteRange([]byte("b"), []byte("b")))
+	require.NoError(t, db.DeleteRange([]byte("c"), []byte("b")))
+	require.NoError(t, db.DeleteRange([]byte("bb"), []byte("bc")))
+	assertKeyValues(t, db, expect(keys...))
+
+	// The end is exclusive, and keys outside the domain survive.
+	require.NoError(t, db.DeleteRange([]byte("c"), []byte("e")))
+	assertKeyValues(t, db, expect("a", "b", "e", "f", "g"))
+
+	// Nil bounds delete from the first or to the last key.
+	require.NoError(t, db.DeleteRange(nil, []byte("b")))
+	assertKeyValues(t, db, expect("b", "e", "f", "g"))
+	require.NoError(t, db.DeleteRange([]byte("f"), nil))
+	assertKeyValues(t, db, expect("b", "e"))
+	require.NoError(t, db.DeleteRange(nil, nil))
+	assertKeyValues(t, db, expect())
+}
+
+func TestDBIteratorSeek(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorSeek(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorSeek(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	for _, k := range []byte{1, 3, 5, 7} {
+		require.NoError(t, db.Set([]byte{k}, []byte{k}))
+	}
+
+	testCases := []struct {
+		name    string
+		reverse bool
+		seek    byte
+		valid   bool
+		key     byte
+	}{
+		{"existing", false, 5, true, 5},
+		{"gap", false, 4, true, 5},
+		{"before start", false, 0, true, 3},
+		{"at end", false, 7, false, 0},
+		{"after end", false, 9, false, 0},
+		{"reverse existing", true, 5, true, 5},
+		{"reverse gap", true, 4, true, 3},
+		{"reverse before start", true, 1, false, 0},
+		{"reverse at end", true, 7, true, 5},
+		{"reverse after end", true, 9, true, 5},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				itr Iterator
+				err error
+			)
+			if tc.reverse {
+				itr, err = db.ReverseIterator([]byte{2}, []byte{7})
+			} else {
+				itr, err = db.Iterator([]byte{2}, []byte{7})
+			}
+			require.NoError(t, err)
+			defer itr.Close()
+
+			require.Equal(t, tc.valid, itr.Seek([]byte{tc.seek}))
+			require.Equal(t, tc.valid, itr.Valid())
+			if tc.valid {
+				checkItem(t, itr, []byte{tc.key}, []byte{tc.key})
+			}
+			require.NoError(t, itr.Error())
+		})
+	}
+
+	// Exhausted iterators can be repositioned, and then iterated as usual.
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.False(t, itr.Seek([]byte{8}))
+	require.True(t, itr.Seek([]byte{2}))
+	checkItem(t, itr, []byte{3}, []byte{3})
+	itr.Next()
+	checkItem(t, itr, []byte{5}, []byte{5})
+	require.NoError(t, itr.Close())
+
+	// As can context iterators.
+	itr, err = db.IteratorWithContext(context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Seek([]byte{6}))
+	checkItem(t, itr, []byte{7}, []byte{7})
+	require.NoError(t, itr.Close())
+}
+
+func TestDBIteratorPosition(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorPosition(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorPosition(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	for i := int64(0); i < 10; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{byte(i)}))
+	}
+
+	// Iterate halfway, and save the position of the next item.
+	itr, err := db.Iterator(nil, int642Bytes(9))
+	require.NoError(t, err)
+	var visited []int64
+	for ; itr.Valid() && len(visited) < 5; itr.Next() {
+		visited = append(visited, bytes2Int64(itr.Key()))
+	}
+	pos := itr.Position()
+	require.NoError(t, itr.Close())
+	require.Equal(t, int642Bytes(5), pos)
+
+	// Resume from the saved position.
+	itr, err = IteratorFrom(db, pos, int642Bytes(9))
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+		visited = append(visited, bytes2Int64(itr.Key()))
+	}
+	assert.Nil(t, itr.Position(), "an invalid iterator has no position")
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8}, visited)
+}
+
+func TestDBIteratorWithContext(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBIteratorWithContext(t, dbType)
+		})
+	}
+}
+
+func testDBIteratorWithContext(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, db.Set([]byte(key), []byte{1}))
+	}
+
+	// Without cancellation, it behaves like a regular iterator.
+	itr, err := db.IteratorWithContext(context.Background(), []byte("b"), nil)
+	require.NoError(t, err)
+	keys := []string{}
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	require.NoError(t, itr.Error())
+	require.Equal(t, []string{"b", "c", "d"}, keys)
+	require.NoError(t, itr.Close())
+
+	// Cancelling mid-iteration invalidates the iterator.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	itr, err = db.IteratorWithContext(ctx, nil, nil)
+	require.NoError(t, err)
+	checkValid(t, itr, true)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	itr.Next()
+	checkItem(t, itr, []byte("b"), []byte{1})
+
+	cancel()
+	itr.Next()
+	require.False(t, itr.Valid())
+	require.Equal(t, context.Canceled, itr.Error())
+	require.NoError(t, itr.Close())
+
+	// An iterator created with a cancelled context is invalid right away.
+	itr, err = db.IteratorWithContext(ctx, nil, nil)
+	require.NoError(t, err)
+	require.False(t, itr.Valid())
+	require.Equal(t, context.Canceled, itr.Error())
+	require.NoError(t, itr.Close())
+}
+
+func TestDBCompact(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			// Compacting an empty database is fine.
+			require.NoError(t, db.Compact(nil, nil))
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+			}
+			for i := int64(0); i < 100; i += 2 {
+				require.NoError(t, db.Delete(int642Bytes(i)))
+			}
+			require.NoError(t, db.Compact(int642Bytes(10), int642Bytes(20)))
+			require.NoError(t, db.Compact(nil, int642Bytes(50)))
+			require.NoError(t, db.Compact(int642Bytes(50), nil))
+			require.NoError(t, db.Compact(nil, nil))
+
+			// Compaction must not affect the contents.
+			expect := map[string][]byte{}
+			for i := int64(1); i < 100; i += 2 {
+				expect[string(int642Bytes(i))] = []byte{1}
+			}
+			assertKeyValues(t, db, expect)
+		})
+	}
+}
+
+func TestDBSizeOf(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			size, err := SizeOf(db)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, size, int64(0))
+
+			value := make([]byte, 1000)
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), value))
+			}
+			require.NoError(t, db.Compact(nil, nil))
+			size, err = SizeOf(db)
+			require.NoError(t, err)
+			if dbType != BadgerDBBackend { // badger only computes its size periodically
+				assert.Positive(t, size)
+			}
+
+			// Wrappers without their own size are scanned.
+			pdb := NewPrefixDB(db, int642Bytes(10))
+			size, err = SizeOf(pdb)
+			require.NoError(t, err)
+			assert.EqualValues(t, 0, size)
+			require.NoError(t, pdb.Set([]byte{1, 2}, []byte{3, 4, 5}))
+			size, err = SizeOf(pdb)
+			require.NoError(t, err)
+			assert.EqualValues(t, 5, size)
+		})
+	}
+}
+
+func TestMemDBSizeOf(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("a"), []byte("value")))
+	require.NoError(t, db.Set([]byte("key"), []byte{}))
+	size, err := SizeOf(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 9, size)
+}
+
+func TestDBCount(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			// Count natively where supported, and by iterating through a wrapper.
+			for name, countDB := range map[string]DB{"native": db, "iterated": NewSyncDB(db)} {
+				count, err := Count(countDB, nil, nil)
+				require.NoError(t, err, name)
+				assert.EqualValues(t, 0, count, name)
+			}
+
+			for i := int64(0); i < 10; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+			}
+			testcases := map[string]struct {
+				start, end []byte
+				expect     int64
+			}{
+				"full":             {nil, nil, 10},
+				"from start":       {int642Bytes(3), nil, 7},
+				"until end":        {nil, int642Bytes(3), 3},
+				"partial":          {int642Bytes(2), int642Bytes(5), 3},
+				"single":           {int642Bytes(9), int642Bytes(10), 1},
+				"empty":            {int642Bytes(20), int642Bytes(30), 0},
+				"start equals end": {int642Bytes(5), int642Bytes(5), 0},
+			}
+			for name, tc := range testcases {
+				for _, countDB := range []DB{db, NewSyncDB(db)} {
+					count, err := Count(countDB, tc.start, tc.end)
+					require.NoError(t, err, name)
+					assert.Equal(t, tc.expect, count, name)
+				}
+			}
+
+			_, err := Count(db, []byte{}, nil)
+			require.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestDBKeysOnly(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			keys, err := KeysOnly(db, nil, nil)
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+
+			// Write the keys in reverse, so that the result must be sorted by the database.
+			batch := db.NewBatch()
+			for i := int64(499); i >= 0; i-- {
+				require.NoError(t, batch.Set(int642Bytes(i), []byte{byte(i)}))
+			}
+			require.NoError(t, batch.Write())
+			require.NoError(t, batch.Close())
+
+			// Use the native keys-only iterator where supported, and a regular one through a wrapper.
+			for _, keysDB := range []DB{db, NewSyncDB(db)} {
+				keys, err = KeysOnly(keysDB, nil, nil)
+				require.NoError(t, err)
+				require.Len(t, keys, 500)
+				for i, key := range keys {
+					require.Equal(t, int642Bytes(int64(i)), key)
+				}
+
+				keys, err = KeysOnly(keysDB, int642Bytes(100), int642Bytes(103))
+				require.NoError(t, err)
+				assert.Equal(t, [][]byte{int642Bytes(100), int642Bytes(101), int642Bytes(102)}, keys)
+			}
+
+			_, err = KeysOnly(db, []byte{}, nil)
+			require.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestDBValues(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+
+			values, err := Values(db, nil, nil)
+			require.NoError(t, err)
+			assert.Empty(t, values)
+
+			require.NoError(t, db.Set([]byte("c"), []byte("3")))
+			require.NoError(t, db.Set([]byte("a"), []byte("1")))
+			require.NoError(t, db.Set([]byte("b"), []byte{}))
+			require.NoError(t, db.Set([]byte("d"), []byte("4")))
+
+			values, err = Values(db, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, [][]byte{[]byte("1"), {}, []byte("3"), []byte("4")}, values)
+
+			values, err = Values(db, []byte("b"), []byte("d"))
+			require.NoError(t, err)
+			assert.Equal(t, [][]byte{{}, []byte("3")}, values)
+
+			_, err = Values(db, []byte{}, nil)
+			require.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestDBWriteBatch(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBWriteBatch(t, dbType)
+		})
+	}
+}
+
+func testDBWriteBatch(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	// Operations are applied in order.
+	require.NoError(t, db.WriteBatch([]BatchOp{
+		{Key: []byte("a"), Delete: true},
+		{Key: []byte("c"), Value: []byte{3}},
+		{Key: []byte("c"), Value: []byte{4}},
+		{Key: []byte("d"), Value: []byte{5}},
+		{Key: []byte("d"), Delete: true},
+	}))
+	checkValue(t, db, []byte("a"), nil)
+	checkValue(t, db, []byte("b"), []byte{2})
+	checkValue(t, db, []byte("c"), []byte{4})
+	checkValue(t, db, []byte("d"), nil)
+
+	require.NoError(t, db.WriteBatchSync([]BatchOp{{Key: []byte("e"), Value: []byte{6}}}))
+	checkValue(t, db, []byte("e"), []byte{6})
+	require.NoError(t, db.WriteBatch(nil))
+
+	// Invalid operations abort the whole batch.
+	require.Error(t, db.WriteBatch([]BatchOp{
+		{Key: []byte("f"), Value: []byte{7}},
+		{Key: nil, Value: []byte{8}},
+	}))
+	checkValue(t, db, []byte("f"), nil)
+
+	// As do panics while applying them.
+	pdb := &panicBatchDB{DB: db, panicAfter: 2}
+	require.Panics(t, func() {
+		_ = writeBatch(pdb, []BatchOp{
+			{Key: []byte("b"), Delete: true},
+			{Key: []byte("g"), Value: []byte{9}},
+			{Key: []byte("h"), Value: []byte{10}},
+		}, false)
+	})
+	checkValue(t, db, []byte("b"), []byte{2})
+	checkValue(t, db, []byte("g"), nil)
+	checkValue(t, db, []byte("h"), nil)
+}
+
+// panicBatchDB wraps a DB, and returns batches which panic after a number of operations.
+type panicBatchDB struct {
+	DB
+	panicAfter int
+}
+
+func (db *panicBatchDB) NewBatchWithSize(expectedOps int) Batch {
+	return &panicBatch{Batch: db.DB.NewBatchWithSize(expectedOps), left: db.panicAfter}
+}
+
+type panicBatch struct {
+	Batch
+	left int
+}
+
+func (b *panicBatch) Set(key, value []byte) error {
+	b.tick()
+	return b.Batch.Set(key, value)
+}
+
+func (b *panicBatch) Delete(key []byte) error {
+	b.tick()
+	return b.Batch.Delete(key)
+}
+
+func (b *panicBatch) tick() {
+	if b.left == 0 {
+		panic("injected panic")
+	}
+	b.left--
+}
+
+func TestDBSnapshot(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBSnapshot(t, dbType)
+		})
+	}
+}
+
+func testDBSnapshot(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	sdb, ok := db.(Snapshotable)
+	if !ok {
+		t.Skip("snapshots not supported")
+	}
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	snap, err := sdb.Snapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+
+	// Modify the live database, both directly and with a batch.
+	require.NoError(t, db.Set([]byte("a"), []byte{10}))
+	require.NoError(t, db.Delete([]byte("b")))
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, db, []byte("a"), []byte{10})
+
+	// The snapshot is unaffected.
+	value, err := snap.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	value, err = snap.Get([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, value)
+	value, err = snap.Get([]byte("c"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+	_, err = snap.Get(nil)
+	require.Error(t, err)
+
+	itr, err := snap.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	itr.Next()
+	checkItem(t, itr, []byte("b"), []byte{2})
+	itr.Next()
+	checkValid(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	snap.Release()
+	snap.Release()
+	_, err = snap.Get([]byte("a"))
+	require.ErrorIs(t, err, errSnapshotReleased)
+	_, err = snap.Iterator(nil, nil)
+	require.ErrorIs(t, err, errSnapshotReleased)
+}
+
+func TestDBCheckpoint(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBCheckpoint(t, dbType)
+		})
+	}
+}
+
+func testDBCheckpoint(t *testing.T, backend BackendType) {
+	db, dir := newTempDB(t, backend)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)}))
+	}
+
+	cpDir, err := ioutil.TempDir("", "test_checkpoint_")
+	require.NoError(t, err)
+	defer os.RemoveAll(cpDir)
+	cpPath := filepath.Join(cpDir, "checkpoint.db")
+	require.NoError(t, CheckpointIfSupported(db, cpPath))
+	require.Error(t, CheckpointIfSupported(db, cpPath), "checkpoint directory must not exist")
+
+	// Writes after the checkpoint must not be visible in it.
+	require.NoError(t, db.Set([]byte("key00"), []byte{0xff}))
+	require.NoError(t, db.Set([]byte("new"), []byte{1}))
+	require.NoError(t, db.Delete([]byte("key01")))
+
+	// Checkpoints use the same backend if supported natively, and goleveldb otherwise.
+	restoreBackend := GoLevelDBBackend
+	if _, ok := db.(Checkpointable); ok {
+		restoreBackend = backend
+	}
+	restored, err := Open(restoreBackend, "checkpoint", cpDir, Options{})
+	require.NoError(t, err)
+	defer restored.Close()
+
+	for i := 0; i < 100; i++ {
+		checkValue(t, restored, []byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)})
+	}
+	checkValue(t, restored, []byte("new"), nil)
+}
+
+func TestDBLogger(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBLogger(t, dbType)
+		})
+	}
+}
+
+func testDBLogger(t *testing.T, backend BackendType) {
+	switch backend {
+	case GoLevelDBBackend, CLevelDBBackend, RocksDBBackend, PebbleDBBackend:
+	default:
+		t.Skip("backend does not compact")
+	}
+	dir, err := ioutil.TempDir("", "test_logger_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logger := &bufferLogger{}
+	db, err := Open(backend, "testdb", dir, Options{Logger: logger})
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(int64(i)), []byte{byte(i)}))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	assert.Contains(t, logger.String(), "compaction completed")
+}
+
+func TestMemDBStats(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	stats := db.Stats()
+	assert.Equal(t, "memDB", stats["database.type"])
+	assert.Equal(t, "2", stats["database.size"])
+}
+
 func TestGoLevelDBBackend(t *testing.T) {
 	name := fmt.Sprintf("test_%x", randStr(12))
 	db, err := NewDB(name, GoLevelDBBackend, "")
@@ -344,13 +1341,16 @@ func testDBBatch(t *testing.T, backend BackendType) {
 
 	// create a new batch, and some items - they should not be visible until we write
 	batch := db.NewBatch()
+	require.Equal(t, 0, batch.Len())
 	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
 	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
 	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.Equal(t, 3, batch.Len())
 	assertKeyValues(t, db, map[string][]byte{})
 
 	err = batch.Write()
 	require.NoError(t, err)
+	require.Equal(t, 0, batch.Len())
 	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}, "c": {3}})
 
 	// trying to modify or rewrite a written batch should error, but closing it should work
@@ -368,7 +1368,9 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
 	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
 	require.NoError(t, batch.Delete([]byte("c")))
-	require.NoError(t, batch.Write())
+	require.Equal(t, 6, batch.Len())
+	require.NoError(t, batch.WriteSync())
+	require.Equal(t, 0, batch.Len())
 	require.NoError(t, batch.Close())
 	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}})
 
@@ -385,6 +1387,7 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	require.Equal(t, errKeyEmpty, err)
 	err = batch.Delete(nil)
 	require.Equal(t, errKeyEmpty, err)
+	require.Equal(t, 0, batch.Len())
 
 	err = batch.Close()
 	require.NoError(t, err)
@@ -395,6 +1398,15 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	require.NoError(t, err)
 	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}})
 
+	// closing a batch without writing it should discard its changes
+	batch = db.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.Equal(t, 2, batch.Len())
+	require.NoError(t, batch.Close())
+	require.Equal(t, 0, batch.Len())
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}})
+
 	// it should be possible to close an empty batch, and to re-close a closed batch
 	batch = db.NewBatch()
 	batch.Close()
@@ -405,6 +1417,18 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	require.Error(t, batch.Delete([]byte("a")))
 	require.Error(t, batch.Write())
 	require.Error(t, batch.WriteSync())
+
+	// batches created with a size hint should behave the same, regardless of the hint
+	for _, size := range []int{-1, 0, 1, 100} {
+		batch = db.NewBatchWithSize(size)
+		require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+		require.NoError(t, batch.Set([]byte("d"), []byte{4}))
+		require.NoError(t, batch.Delete([]byte("c")))
+		require.NoError(t, batch.WriteSync())
+		require.Error(t, batch.Set([]byte("a"), []byte{9}))
+		require.NoError(t, batch.Close())
+		assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}, "d": {4}})
+	}
 }
 
 func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
@@ -420,3 +1444,175 @@ func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
 
 	assert.Equal(t, expect, actual)
 }
+
+// mockBatch wraps a Batch and records the calls made to it.
+type mockBatch struct {
+	Batch
+	calls map[string]int
+	// lens records the value of Len after every call.
+	lens []int
+}
+
+func newMockBatch(batch Batch) *mockBatch {
+	return &mockBatch{Batch: batch, calls: make(map[string]int)}
+}
+
+func (b *mockBatch) recordLen() {
+	b.lens = append(b.lens, b.Batch.Len())
+}
+
+func (b *mockBatch) Set(key, value []byte) error {
+	b.calls["Set"]++
+	defer b.recordLen()
+	return b.Batch.Set(key, value)
+}
+
+func (b *mockBatch) Delete(key []byte) error {
+	b.calls["Delete"]++
+	defer b.recordLen()
+	return b.Batch.Delete(key)
+}
+
+func (b *mockBatch) Write() error {
+	b.calls["Write"]++
+	defer b.recordLen()
+	return b.Batch.Write()
+}
+
+func (b *mockBatch) WriteSync() error {
+	b.calls["WriteSync"]++
+	defer b.recordLen()
+	return b.Batch.WriteSync()
+}
+
+func (b *mockBatch) Close() error {
+	b.calls["Close"]++
+	defer b.recordLen()
+	return b.Batch.Close()
+}
+
+// mockBatchDB is a MemDB whose batches are mockBatches, so tests can inspect how wrapping
+// databases use the underlying batches.
+type mockBatchDB struct {
+	*MemDB
+	batches []*mockBatch
+}
+
+func (db *mockBatchDB) NewBatch() Batch {
+	batch := newMockBatch(db.MemDB.NewBatch())
+	db.batches = append(db.batches, batch)
+	return batch
+}
+
+func TestDBBatchClose(t *testing.T) {
+	mdb := &mockBatchDB{MemDB: NewMemDB()}
+	pdb := NewPrefixDB(mdb, []byte("p/"))
+
+	// an abandoned batch must release the underlying batch without writing it
+	batch := pdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Close())
+	require.Len(t, mdb.batches, 1)
+	assert.Equal(t, map[string]int{"Set": 1, "Close": 1}, mdb.batches[0].calls)
+	assert.Equal(t, []int{1, 0}, mdb.batches[0].lens)
+	assertKeyValues(t, mdb, map[string][]byte{})
+
+	// a written batch must still be closed by the caller
+	batch = pdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.Equal(t, 2, batch.Len())
+	require.NoError(t, batch.Write())
+	require.Equal(t, 0, batch.Len())
+	require.NoError(t, batch.Close())
+	require.Len(t, mdb.batches, 2)
+	assert.Equal(t, map[string]int{"Set": 1, "Delete": 1, "Write": 1, "Close": 1}, mdb.batches[1].calls)
+	assert.Equal(t, []int{1, 2, 0, 0}, mdb.batches[1].lens)
+	assertKeyValues(t, mdb, map[string][]byte{"p/a": {1}})
+}
+
+func BenchmarkBatchWriteLarge(b *testing.B) {
+	const numOps = 10000
+
+	for dbType := range backends {
+		for _, hint := range []bool{false, true} {
+			name := fmt.Sprintf("%s/hint=%v", dbType, hint)
+			b.Run(name, func(b *testing.B) {
+				dir, err := ioutil.TempDir("", "tm-db-bench")
+				require.NoError(b, err)
+				defer os.RemoveAll(dir)
+				db, err := NewDB("benchdb", dbType, dir)
+				require.NoError(b, err)
+				defer db.Close()
+
+				value := make([]byte, 32)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					var batch Batch
+					if hint {
+						batch = db.NewBatchWithSize(numOps)
+					} else {
+						batch = db.NewBatch()
+					}
+					for j := 0; j < numOps; j++ {
+						if err := batch.Set(int642Bytes(int64(j)), value); err != nil {
+							b.Fatal(err)
+						}
+					}
+					if err := batch.Write(); err != nil {
+						b.Fatal(err)
+					}
+					batch.Close()
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkCompact writes and deletes keys, compacts the whole database, and reports the
+// resulting on-disk size.
+func BenchmarkCompact(b *testing.B) {
+	const numKeys = 100000
+
+	for dbType := range backends {
+		b.Run(string(dbType), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				dir, err := ioutil.TempDir("", "tm-db-bench")
+				require.NoError(b, err)
+				db, err := NewDB("benchdb", dbType, dir)
+				require.NoError(b, err)
+
+				value := make([]byte, 32)
+				for j := 0; j < numKeys; j++ {
+					require.NoError(b, db.Set(int642Bytes(int64(j)), value))
+				}
+				require.NoError(b, db.DeleteRange(nil, nil))
+
+				b.StartTimer()
+				require.NoError(b, db.Compact(nil, nil))
+				b.StopTimer()
+
+				b.ReportMetric(float64(dirSize(b, dir)), "bytes-on-disk")
+				db.Close()
+				os.RemoveAll(dir)
+			}
+		})
+	}
+}
+
+// dirSize returns the total size of the files in a directory.
+func dirSize(tb testing.TB, dir string) int64 {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	require.NoError(tb, err)
+	return size
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A backup written by BackupDB starts with a header, followed by one record per item in
+// ascending key order, and ends with a trailer:
+//
+//	header:  magic "TMDBBAK" (7 bytes) | version (1 byte, currently 1)
+//	record:  key length (4 bytes) | key | value length (4 bytes) | value
+//	trailer: key length 0 (4 bytes)
+//
+// Lengths are big-endian. Keys are never empty, so the zero-length trailer distinguishes a
+// complete backup from one truncated between records.
+const (
+	backupMagic   = "TMDBBAK"
+	backupVersion = 1
+)
+
+// errBackupInvalid is returned when restoring data which is not a valid backup.
+var errBackupInvalid = errors.New("invalid database backup")
+
+// BackupDB writes all items of a database to w, in a portable format which can be restored into
+// any backend with RestoreDB or RestoreDBTo. Items are streamed from an iterator, so the database
+// does not need to fit in memory; writes made during the backup may or may not be included,
+// unless the database is a snapshot.
+func BackupDB(db DB, w io.Writer) error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	bw := bufio.NewWriter(w)
+	var lenBuf [4]byte
+	writeBytes := func(bz []byte) error {
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(bz)))
+		if _, err := bw.Write(lenBuf[:]); err != nil {
+			return err
+		}
+		_, err := bw.Write(bz)
+		return err
+	}
+
+	if _, err := bw.WriteString(backupMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(backupVersion); err != nil {
+		return err
+	}
+	for ; itr.Valid(); itr.Next() {
+		if err := writeBytes(itr.Key()); err != nil {
+			return err
+		}
+		if err := writeBytes(itr.Value()); err != nil {
+			return err
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	if err := writeBytes(nil); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// RestoreDB reads a backup written by BackupDB into a new MemDB.
+func RestoreDB(r io.Reader) (DB, error) {
+	db := NewMemDB()
+	if err := RestoreDBTo(r, db, CopyOptions{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// RestoreDBTo reads a backup written by BackupDB into dst, writing it in batches of
+// opts.BatchSize items, so that large backups can be restored into an on-disk database without
+// loading them into memory. Existing keys in dst are overwritten. If the backup is invalid or
+// truncated, an error is returned and dst contains a partial restore.
+func RestoreDBTo(r io.Reader, dst DB, opts CopyOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCopyBatchSize
+	}
+
+	br, err := newBackupReader(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+
+	batch := dst.NewBatchWithSize(batchSize)
+	defer func() {
+		batch.Close()
+	}()
+	pending := 0
+	for {
+		key, value, err := br.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", errBackupInvalid, err)
+		}
+		if err := batch.Set(key, value); err != nil {
+			return err
+		}
+		pending++
+		if pending < batchSize {
+			continue
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+		batch = dst.NewBatchWithSize(batchSize)
+		pending = 0
+	}
+	if pending > 0 {
+		return batch.Write()
+	}
+	return nil
+}
+
+// backupReader reads the records of a backup written by BackupDB.
+type backupReader struct {
+	r      *bufio.Reader
+	lenBuf [4]byte
+}
+
+// newBackupReader reads and validates the header of a backup.
+func newBackupReader(r *bufio.Reader) (*backupReader, error) {
+	header := make([]byte, len(backupMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", errBackupInvalid, err)
+	}
+	if string(header[:len(backupMagic)]) != backupMagic {
+		return nil, fmt.Errorf("%w: bad magic bytes", errBackupInvalid)
+	}
+	if version := header[len(backupMagic)]; version != backupVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errBackupInvalid, version)
+	}
+	return &backupReader{r: r}, nil
+}
+
+// next returns the next record, or io.EOF once the trailer has been read. Errors wrap
+// io.ErrUnexpectedEOF if the backup is truncated.
+func (br *backupReader) next() (key, value []byte, err error) {
+	key, err = br.readBytes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, nil, io.EOF
+	}
+	value, err = br.readBytes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read value of key %X: %w", key, err)
+	}
+	return key, value, nil
+}
+
+// readBytes reads a length-prefixed byte slice. The length is not trusted to allocate a buffer
+// up front, since a corrupt backup could claim up to 4 GiB per record; the buffer only grows as
+// data is actually read.
+func (br *backupReader) readBytes() ([]byte, error) {
+	if _, err := io.ReadFull(br.r, br.lenBuf[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, br.r, int64(binary.BigEndian.Uint32(br.lenBuf[:]))); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if buf.Len() == 0 {
+		// Empty values are restored as empty, not nil, slices.
+		return []byte{}, nil
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupDB(t *testing.T) {
+	src := NewMemDB()
+	expect := map[string][]byte{}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key%05d", i)
+		value := []byte(fmt.Sprintf("value%d", i))
+		if i%100 == 0 {
+			value = []byte{}
+		}
+		require.NoError(t, src.Set([]byte(key), value))
+		expect[key] = value
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, BackupDB(src, &buf))
+	data := buf.Bytes()
+	assert.Equal(t, []byte("TMDBBAK\x01"), data[:8])
+
+	restored, err := RestoreDB(bytes.NewReader(data))
+	require.NoError(t, err)
+	assertKeyValues(t, restored, expect)
+
+	// Backups can be restored into any backend, in batches.
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+			require.NoError(t, RestoreDBTo(bytes.NewReader(data), db, CopyOptions{BatchSize: 3000}))
+			require.NoError(t, verifyBackupRestore(db, expect))
+		})
+	}
+}
+
+// verifyBackupRestore checks that a restored database contains the expected items. Some backends
+// return empty values as nil, so values are compared with bytes.Equal.
+func verifyBackupRestore(db DB, expect map[string][]byte) error {
+	count := 0
+	err := db.ForEach(func(key, value []byte) error {
+		count++
+		if v, ok := expect[string(key)]; !ok || !bytes.Equal(v, value) {
+			return fmt.Errorf("unexpected item %q = %q", key, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if count != len(expect) {
+		return fmt.Errorf("expected %d items, got %d", len(expect), count)
+	}
+	return nil
+}
+
+func TestRestoreDBInvalid(t *testing.T) {
+	src := NewMemDB()
+	require.NoError(t, src.Set([]byte("a"), []byte{1}))
+	require.NoError(t, src.Set([]byte("b"), []byte{2}))
+	var buf bytes.Buffer
+	require.NoError(t, BackupDB(src, &buf))
+	data := cp(buf.Bytes())
+
+	empty := NewMemDB()
+	buf.Reset()
+	require.NoError(t, BackupDB(empty, &buf))
+	restored, err := RestoreDB(&buf)
+	require.NoError(t, err)
+	assertKeyValues(t, restored, map[string][]byte{})
+
+	// Truncation anywhere, including between records, is detected.
+	for i := 0; i < len(data); i++ {
+		_, err := RestoreDB(bytes.NewReader(data[:i]))
+		require.ErrorIs(t, err, errBackupInvalid, "truncated to %d bytes", i)
+	}
+
+	bad := cp(data)
+	bad[0] = 'X'
+	_, err = RestoreDB(bytes.NewReader(bad))
+	require.ErrorIs(t, err, errBackupInvalid)
+	bad = cp(data)
+	bad[7] = 2
+	_, err = RestoreDB(bytes.NewReader(bad))
+	require.ErrorIs(t, err, errBackupInvalid)
+
+	// A corrupt length must not be trusted to allocate memory up front.
+	bad = append([]byte(backupMagic), backupVersion, 0xff, 0xff, 0xff, 0xff, 'a')
+	_, err = RestoreDB(bytes.NewReader(bad))
+	require.ErrorIs(t, err, errBackupInvalid)
+}
@@ -5,6 +5,7 @@ package db
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,13 +15,18 @@ import (
 
 func init() { registerDBCreator(BadgerDBBackend, badgerDBCreator, true) }
 
-func badgerDBCreator(dbName, dir string) (DB, error) {
-	return NewBadgerDB(dbName, dir)
+func badgerDBCreator(dbName, dir string, opts Options) (DB, error) {
+	return newBadgerDB(dbName, dir, opts)
 }
 
 // NewBadgerDB creates a Badger key-value store backed to the
 // directory dir supplied. If dir does not exist, it will be created.
 func NewBadgerDB(dbName, dir string) (*BadgerDB, error) {
+	return newBadgerDB(dbName, dir, Options{})
+}
+
+// newBadgerDB creates a BadgerDB, mapping Options to Badger options.
+func newBadgerDB(dbName, dir string, o Options) (*BadgerDB, error) {
 	// Since Badger doesn't support database names, we join both to obtain
 	// the final directory to use for the database.
 	path := filepath.Join(dir, dbName)
@@ -31,9 +37,41 @@ func NewBadgerDB(dbName, dir string) (*BadgerDB, error) {
 	opts := badger.DefaultOptions(path)
 	opts.SyncWrites = false // note that we have Sync methods
 	opts.Logger = nil       // badger is too chatty by default
+	opts.ReadOnly = o.ReadOnly
+	if o.BlockCacheSize > 0 {
+		opts.BlockCacheSize = o.BlockCacheSize
+	}
+	if o.Logger != nil {
+		opts.Logger = badgerLogger{o.Logger}
+	}
 	return NewBadgerDBWithOptions(opts)
 }
 
+// badgerLogger adapts a Logger to the Badger logging interface.
+type badgerLogger struct {
+	Logger
+}
+
+// Errorf implements badger.Logger.
+func (l badgerLogger) Errorf(format string, args ...interface{}) {
+	l.Error(fmt.Sprintf(format, args...))
+}
+
+// Warningf implements badger.Logger.
+func (l badgerLogger) Warningf(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+// Infof implements badger.Logger.
+func (l badgerLogger) Infof(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+// Debugf implements badger.Logger.
+func (l badgerLogger) Debugf(format string, args ...interface{}) {
+	l.Debug(fmt.Sprintf(format, args...))
+}
+
 // NewBadgerDBWithOptions creates a BadgerDB key value store
 // gives the flexibility of initializing a database with the
 // respective options.
@@ -42,15 +80,19 @@ func NewBadgerDBWithOptions(opts badger.Options) (*BadgerDB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &BadgerDB{db: db}, nil
+	return &BadgerDB{db: db, readOnly: opts.ReadOnly}, nil
 }
 
+// BadgerDB is a connection to a BadgerDB key-value database.
 type BadgerDB struct {
-	db *badger.DB
+	db       *badger.DB
+	readOnly bool
 }
 
 var _ DB = (*BadgerDB)(nil)
+var _ Sizable = (*BadgerDB)(nil)
 
+// Get implements DB.
 func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
@@ -72,6 +114,7 @@ func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 	return val, err
 }
 
+// Has implements DB.
 func (b *BadgerDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
 		return false, errKeyEmpty
@@ -88,7 +131,16 @@ func (b *BadgerDB) Has(key []byte) (bool, error) {
 	return found, err
 }
 
+// MultiGet implements DB.
+func (b *BadgerDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(b, keys)
+}
+
+// Set implements DB.
 func (b *BadgerDB) Set(key, value []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -100,6 +152,52 @@ func (b *BadgerDB) Set(key, value []byte) error {
 	})
 }
 
+// CompareAndSet implements DB. The comparison and write happen in a single transaction, which
+// is retried if it conflicts with a concurrent write.
+func (b *BadgerDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if b.readOnly {
+		return false, ErrReadOnly
+	}
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	for {
+		var swapped bool
+		err := b.db.Update(func(txn *badger.Txn) error {
+			var current []byte
+			item, err := txn.Get(key)
+			switch {
+			case err == badger.ErrKeyNotFound:
+			case err != nil:
+				return err
+			default:
+				current, err = item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				if current == nil {
+					current = []byte{}
+				}
+			}
+			if !valueMatches(current, expected) {
+				return nil
+			}
+			swapped = true
+			return txn.Set(key, newVal)
+		})
+		if err == badger.ErrConflict {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return swapped, nil
+	}
+}
+
 func withSync(db *badger.DB, err error) error {
 	if err != nil {
 		return err
@@ -107,11 +205,19 @@ func withSync(db *badger.DB, err error) error {
 	return db.Sync()
 }
 
+// SetSync implements DB.
 func (b *BadgerDB) SetSync(key, value []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
 	return withSync(b.db, b.Set(key, value))
 }
 
+// Delete implements DB.
 func (b *BadgerDB) Delete(key []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -120,15 +226,32 @@ func (b *BadgerDB) Delete(key []byte) error {
 	})
 }
 
+// DeleteSync implements DB.
 func (b *BadgerDB) DeleteSync(key []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
 	return withSync(b.db, b.Delete(key))
 }
 
+// Close implements DB.
 func (b *BadgerDB) Close() error {
+	GlobalRegistry.unregister(b)
 	return b.db.Close()
 }
 
+// Print implements DB.
 func (b *BadgerDB) Print() error {
+	itr, err := b.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
 	return nil
 }
 
@@ -139,11 +262,15 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 	txn := b.db.NewTransaction(false)
 	iter := txn.NewIterator(opts)
 	iter.Rewind()
-	iter.Seek(start)
-	if opts.Reverse && iter.Valid() && bytes.Equal(iter.Item().Key(), start) {
-		// If we're going in reverse, our starting point was "end",
-		// which is exclusive.
-		iter.Next()
+	if opts.Reverse {
+		iter.Seek(end)
+		if iter.Valid() && bytes.Equal(iter.Item().Key(), end) {
+			// If we're going in reverse, our starting point is "end",
+			// which is exclusive.
+			iter.Next()
+		}
+	} else {
+		iter.Seek(start)
 	}
 	return &badgerDBIterator{
 		reverse: opts.Reverse,
@@ -155,22 +282,91 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 	}, nil
 }
 
+// Iterator implements DB.
 func (b *BadgerDB) Iterator(start, end []byte) (Iterator, error) {
 	opts := badger.DefaultIteratorOptions
 	return b.iteratorOpts(start, end, opts)
 }
 
+// KeysOnlyIterator implements KeysOnlyIterator. Values are not prefetched, and are only read from
+// the value log if Value is called.
+func (b *BadgerDB) KeysOnlyIterator(start, end []byte) (Iterator, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	return b.iteratorOpts(start, end, opts)
+}
+
+// ReverseIterator implements DB.
 func (b *BadgerDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	opts := badger.DefaultIteratorOptions
 	opts.Reverse = true
-	return b.iteratorOpts(end, start, opts)
+	return b.iteratorOpts(start, end, opts)
 }
 
+// Stats implements DB.
 func (b *BadgerDB) Stats() map[string]string {
+	lsm, vlog := b.db.Size()
+	stats := make(map[string]string)
+	stats["database.type"] = "badgerDB"
+	stats["badger.lsm.size"] = fmt.Sprintf("%d", lsm)
+	stats["badger.vlog.size"] = fmt.Sprintf("%d", vlog)
+	stats["badger.tables"] = fmt.Sprintf("%d", len(b.db.Tables()))
+	return stats
+}
+
+// ApproxSize implements Sizable. It is the size of the LSM tree and value log, as last computed
+// by Badger, which does so periodically.
+func (b *BadgerDB) ApproxSize() (int64, error) {
+	lsm, vlog := b.db.Size()
+	return lsm + vlog, nil
+}
+
+// DeleteRange implements DB. The domain is not deleted atomically.
+func (b *BadgerDB) DeleteRange(start, end []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
+	return deleteRange(b, start, end)
+}
+
+// IteratorWithContext implements DB.
+func (b *BadgerDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, b, start, end)
+}
+
+// Compact implements DB. It is a no-op.
+func (b *BadgerDB) Compact(start, end []byte) error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
 	return nil
 }
 
+// WriteBatch implements DB.
+func (b *BadgerDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(b, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (b *BadgerDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(b, ops, true)
+}
+
+// ApplyLog implements DB.
+func (b *BadgerDB) ApplyLog(ops BatchOpList) error {
+	return b.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (b *BadgerDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(b, fn)
+}
+
+// NewBatch implements DB.
 func (b *BadgerDB) NewBatch() Batch {
+	if b.readOnly {
+		return readOnlyBatch{}
+	}
 	wb := &badgerDBBatch{
 		db:         b.db,
 		wb:         b.db.NewWriteBatch(),
@@ -180,11 +376,22 @@ func (b *BadgerDB) NewBatch() Batch {
 	return wb
 }
 
+// NewBatchWithSize implements DB. Badger sizes its write batches itself, so the size hint is
+// ignored.
+func (b *BadgerDB) NewBatchWithSize(_ int) Batch {
+	if b.readOnly {
+		return readOnlyBatch{}
+	}
+	return b.NewBatch()
+}
+
 var _ Batch = (*badgerDBBatch)(nil)
 
 type badgerDBBatch struct {
 	db *badger.DB
 	wb *badger.WriteBatch
+	// ops is the number of pending operations, since badger does not expose it.
+	ops int
 
 	// Calling db.Flush twice panics, so we must keep track of whether we've
 	// flushed already on our own. If Write can receive from the firstFlush
@@ -195,6 +402,7 @@ type badgerDBBatch struct {
 	firstFlush chan struct{}
 }
 
+// Set implements Batch.
 func (b *badgerDBBatch) Set(key, value []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
@@ -202,38 +410,59 @@ func (b *badgerDBBatch) Set(key, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	return b.wb.Set(key, value)
+	if err := b.wb.Set(key, value); err != nil {
+		return err
+	}
+	b.ops++
+	return nil
 }
 
+// Delete implements Batch.
 func (b *badgerDBBatch) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	return b.wb.Delete(key)
+	if err := b.wb.Delete(key); err != nil {
+		return err
+	}
+	b.ops++
+	return nil
+}
+
+// Len implements Batch.
+func (b *badgerDBBatch) Len() int {
+	return b.ops
 }
 
+// Write implements Batch.
 func (b *badgerDBBatch) Write() error {
 	select {
 	case <-b.firstFlush:
+		b.ops = 0
 		return b.wb.Flush()
 	default:
 		return fmt.Errorf("batch already flushed")
 	}
 }
 
+// WriteSync implements Batch.
 func (b *badgerDBBatch) WriteSync() error {
 	return withSync(b.db, b.Write())
 }
 
+// Close implements Batch.
 func (b *badgerDBBatch) Close() error {
 	select {
 	case <-b.firstFlush: // a Flush after Cancel panics too
 	default:
 	}
 	b.wb.Cancel()
+	b.ops = 0
 	return nil
 }
 
+var _ Iterator = (*badgerDBIterator)(nil)
+
 type badgerDBIterator struct {
 	reverse    bool
 	start, end []byte
@@ -244,6 +473,7 @@ type badgerDBIterator struct {
 	lastErr error
 }
 
+// Close implements Iterator.
 func (i *badgerDBIterator) Close() error {
 	i.iter.Close()
 	i.txn.Discard()
@@ -253,6 +483,7 @@ func (i *badgerDBIterator) Close() error {
 func (i *badgerDBIterator) Domain() (start, end []byte) { return i.start, i.end }
 func (i *badgerDBIterator) Error() error                { return i.lastErr }
 
+// Next implements Iterator.
 func (i *badgerDBIterator) Next() {
 	if !i.Valid() {
 		panic("iterator is invalid")
@@ -260,13 +491,19 @@ func (i *badgerDBIterator) Next() {
 	i.iter.Next()
 }
 
+// Valid implements Iterator.
 func (i *badgerDBIterator) Valid() bool {
-	if !i.iter.Valid() {
+	if i.lastErr != nil || !i.iter.Valid() {
 		return false
 	}
-	if len(i.end) > 0 {
+	// In reverse, the limit we must not cross is the (inclusive) start key.
+	limit := i.end
+	if i.reverse {
+		limit = i.start
+	}
+	if len(limit) > 0 {
 		key := i.iter.Item().Key()
-		if c := bytes.Compare(key, i.end); (!i.reverse && c >= 0) || (i.reverse && c < 0) {
+		if c := bytes.Compare(key, limit); (!i.reverse && c >= 0) || (i.reverse && c < 0) {
 			// We're at the end key, or past the end.
 			return false
 		}
@@ -274,6 +511,31 @@ func (i *badgerDBIterator) Valid() bool {
 	return true
 }
 
+// Seek implements Iterator.
+func (i *badgerDBIterator) Seek(key []byte) bool {
+	if i.lastErr != nil {
+		return false
+	}
+	switch {
+	case !i.reverse:
+		if i.start != nil && bytes.Compare(key, i.start) < 0 {
+			key = i.start
+		}
+		i.iter.Seek(key)
+	case i.end != nil && bytes.Compare(key, i.end) >= 0:
+		// The end is exclusive, so move to the last key before it.
+		i.iter.Seek(i.end)
+		if i.iter.Valid() && bytes.Equal(i.iter.Item().Key(), i.end) {
+			i.iter.Next()
+		}
+	default:
+		// In reverse, badger seeks to the last key at or before the given key.
+		i.iter.Seek(key)
+	}
+	return i.Valid()
+}
+
+// Key implements Iterator.
 func (i *badgerDBIterator) Key() []byte {
 	if !i.Valid() {
 		panic("iterator is invalid")
@@ -283,6 +545,7 @@ func (i *badgerDBIterator) Key() []byte {
 	return i.iter.Item().KeyCopy(nil)
 }
 
+// Value implements Iterator.
 func (i *badgerDBIterator) Value() []byte {
 	if !i.Valid() {
 		panic("iterator is invalid")
@@ -293,3 +556,8 @@ func (i *badgerDBIterator) Value() []byte {
 	}
 	return val
 }
+
+// Position implements Iterator.
+func (i *badgerDBIterator) Position() []byte {
+	return iteratorPosition(i)
+}
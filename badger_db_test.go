@@ -0,0 +1,47 @@
+//go:build badgerdb
+// +build badgerdb
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadgerDBBackend(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, BadgerDBBackend, dir)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Join(dir, name))
+
+	_, ok := db.(*BadgerDB)
+	assert.True(t, ok)
+	require.NoError(t, db.Close())
+}
+
+func TestWithBadgerDB(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "badgerdb")
+
+	db, err := NewBadgerDB(path, "")
+	require.NoError(t, err)
+
+	t.Run("BadgerDB", func(t *testing.T) { Run(t, db) })
+}
+
+func BenchmarkBadgerDBRandomReadsWrites(b *testing.B) {
+	dir := b.TempDir()
+	db, err := NewBadgerDB(fmt.Sprintf("test_%x", randStr(12)), dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	benchmarkRandomReadsWrites(b, db)
+}
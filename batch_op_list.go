@@ -0,0 +1,96 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/tendermint/tm-db/batchpb"
+)
+
+// BatchOpList is a list of operations, such as a write-ahead log, which can be serialized as JSON
+// or protobuf and applied to a database with DB.ApplyLog.
+//
+// In JSON, it is an array of objects with base64-encoded "key" and "value" fields, and a boolean
+// "delete" field. Value is omitted for deletes and empty values.
+type BatchOpList []BatchOp
+
+// batchOpJSON is the JSON form of a BatchOp. Byte slices are base64-encoded by encoding/json.
+type batchOpJSON struct {
+	Key    []byte `json:"key"`
+	Value  []byte `json:"value,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l BatchOpList) MarshalJSON() ([]byte, error) {
+	ops := make([]batchOpJSON, 0, len(l))
+	for _, op := range l {
+		jop := batchOpJSON{Key: op.Key, Delete: op.Delete}
+		if !op.Delete {
+			jop.Value = op.Value
+		}
+		ops = append(ops, jop)
+	}
+	return json.Marshal(ops)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *BatchOpList) UnmarshalJSON(data []byte) error {
+	var ops []batchOpJSON
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return err
+	}
+	list := make(BatchOpList, 0, len(ops))
+	for _, op := range ops {
+		list = append(list, newDecodedBatchOp(op.Key, op.Value, op.Delete))
+	}
+	*l = list
+	return nil
+}
+
+// ToProto converts the list to its protobuf form.
+func (l BatchOpList) ToProto() *batchpb.BatchOpList {
+	pb := &batchpb.BatchOpList{Ops: make([]*batchpb.BatchOp, 0, len(l))}
+	for _, op := range l {
+		pop := &batchpb.BatchOp{Key: op.Key, Delete: op.Delete}
+		if !op.Delete {
+			pop.Value = op.Value
+		}
+		pb.Ops = append(pb.Ops, pop)
+	}
+	return pb
+}
+
+// BatchOpListFromProto converts a list from its protobuf form.
+func BatchOpListFromProto(pb *batchpb.BatchOpList) BatchOpList {
+	list := make(BatchOpList, 0, len(pb.GetOps()))
+	for _, op := range pb.GetOps() {
+		list = append(list, newDecodedBatchOp(op.Key, op.Value, op.Delete))
+	}
+	return list
+}
+
+// newDecodedBatchOp creates a BatchOp from its serialized fields. Neither encoding distinguishes
+// between nil and empty values, so values of sets are always non-nil.
+func newDecodedBatchOp(key, value []byte, del bool) BatchOp {
+	if value == nil && !del {
+		value = []byte{}
+	}
+	return BatchOp{Key: key, Value: value, Delete: del}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, using the protobuf encoding.
+func (l BatchOpList) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(l.ToProto())
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, using the protobuf encoding.
+func (l *BatchOpList) UnmarshalBinary(data []byte) error {
+	pb := &batchpb.BatchOpList{}
+	if err := proto.Unmarshal(data, pb); err != nil {
+		return err
+	}
+	*l = BatchOpListFromProto(pb)
+	return nil
+}
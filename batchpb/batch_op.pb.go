@@ -0,0 +1,133 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: batchpb/batch_op.proto
+
+package batchpb
+
+import (
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type BatchOp struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Delete               bool     `protobuf:"varint,3,opt,name=delete,proto3" json:"delete,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchOp) Reset()         { *m = BatchOp{} }
+func (m *BatchOp) String() string { return proto.CompactTextString(m) }
+func (*BatchOp) ProtoMessage()    {}
+func (*BatchOp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_11876e1f2b934d6a, []int{0}
+}
+func (m *BatchOp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchOp.Unmarshal(m, b)
+}
+func (m *BatchOp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchOp.Marshal(b, m, deterministic)
+}
+func (m *BatchOp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchOp.Merge(m, src)
+}
+func (m *BatchOp) XXX_Size() int {
+	return xxx_messageInfo_BatchOp.Size(m)
+}
+func (m *BatchOp) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchOp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchOp proto.InternalMessageInfo
+
+func (m *BatchOp) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *BatchOp) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *BatchOp) GetDelete() bool {
+	if m != nil {
+		return m.Delete
+	}
+	return false
+}
+
+type BatchOpList struct {
+	Ops                  []*BatchOp `protobuf:"bytes,1,rep,name=ops,proto3" json:"ops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *BatchOpList) Reset()         { *m = BatchOpList{} }
+func (m *BatchOpList) String() string { return proto.CompactTextString(m) }
+func (*BatchOpList) ProtoMessage()    {}
+func (*BatchOpList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_11876e1f2b934d6a, []int{1}
+}
+func (m *BatchOpList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchOpList.Unmarshal(m, b)
+}
+func (m *BatchOpList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchOpList.Marshal(b, m, deterministic)
+}
+func (m *BatchOpList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchOpList.Merge(m, src)
+}
+func (m *BatchOpList) XXX_Size() int {
+	return xxx_messageInfo_BatchOpList.Size(m)
+}
+func (m *BatchOpList) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchOpList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchOpList proto.InternalMessageInfo
+
+func (m *BatchOpList) GetOps() []*BatchOp {
+	if m != nil {
+		return m.Ops
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*BatchOp)(nil), "batchpb.BatchOp")
+	proto.RegisterType((*BatchOpList)(nil), "batchpb.BatchOpList")
+}
+
+func init() { proto.RegisterFile("batchpb/batch_op.proto", fileDescriptor_11876e1f2b934d6a) }
+
+var fileDescriptor_11876e1f2b934d6a = []byte{
+	// 143 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x4b, 0x4a, 0x2c, 0x49,
+	0xce, 0x28, 0x48, 0xd2, 0x07, 0xd3, 0xf1, 0xf9, 0x05, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42,
+	0xec, 0x50, 0x71, 0x25, 0x4f, 0x2e, 0x76, 0x27, 0x10, 0xd3, 0xbf, 0x40, 0x48, 0x80, 0x8b, 0x39,
+	0x3b, 0xb5, 0x52, 0x82, 0x51, 0x81, 0x51, 0x83, 0x27, 0x08, 0xc4, 0x14, 0x12, 0xe1, 0x62, 0x2d,
+	0x4b, 0xcc, 0x29, 0x4d, 0x95, 0x60, 0x02, 0x8b, 0x41, 0x38, 0x42, 0x62, 0x5c, 0x6c, 0x29, 0xa9,
+	0x39, 0xa9, 0x25, 0xa9, 0x12, 0xcc, 0x0a, 0x8c, 0x1a, 0x1c, 0x41, 0x50, 0x9e, 0x92, 0x21, 0x17,
+	0x37, 0xd4, 0x28, 0x9f, 0xcc, 0xe2, 0x12, 0x21, 0x25, 0x2e, 0xe6, 0xfc, 0x82, 0x62, 0x09, 0x46,
+	0x05, 0x66, 0x0d, 0x6e, 0x23, 0x01, 0x3d, 0xa8, 0x85, 0x7a, 0x50, 0x25, 0x41, 0x20, 0xc9, 0x24,
+	0x36, 0xb0, 0x6b, 0x8c, 0x01, 0x03, 0x00, 0x4d, 0xf5, 0xe5, 0xcd, 0xa7, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,36 @@
+package db_test
+
+import (
+	"testing"
+
+	db "github.com/tendermint/tm-db"
+	"github.com/tendermint/tm-db/dbtest"
+)
+
+// BenchmarkBackendSuite runs dbtest.BenchmarkSuite against every backend compiled in, e.g.
+// go test -tags boltdb,badgerdb,pebbledb -run - -bench BackendSuite
+func BenchmarkBackendSuite(b *testing.B) {
+	for _, backend := range []db.BackendType{
+		db.MemDBBackend,
+		db.GoLevelDBBackend,
+		db.CLevelDBBackend,
+		db.RocksDBBackend,
+		db.BoltDBBackend,
+		db.BadgerDBBackend,
+		db.PebbleDBBackend,
+	} {
+		backend := backend
+		b.Run(string(backend), func(b *testing.B) {
+			if !db.IsBackendRegistered(backend) {
+				b.Skipf("backend %s is not compiled in", backend)
+			}
+			database, err := db.NewDB("benchmark_suite", backend, b.TempDir())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer database.Close()
+
+			dbtest.BenchmarkSuite(b, database)
+		})
+	}
+}
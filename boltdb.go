@@ -4,6 +4,8 @@
 package db
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -15,7 +17,12 @@ import (
 var bucket = []byte("tm")
 
 func init() {
-	registerDBCreator(BoltDBBackend, NewBoltDB, false)
+	registerDBCreator(BoltDBBackend, func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
+		return NewBoltDB(name, dir)
+	}, false)
 }
 
 // BoltDB is a wrapper around etcd's fork of bolt (https://github.com/etcd-io/bbolt).
@@ -25,11 +32,17 @@ func init() {
 //
 // A single bucket ([]byte("tm")) is used per a database instance. This could
 // lead to performance issues when/if there will be lots of keys.
+//
+// NOTE: bbolt allows only one read-write transaction at a time, so all writes
+// (including batches) are serialized behind an exclusive lock, and a writer
+// may also wait for open iterators, since those hold read transactions.
 type BoltDB struct {
 	db *bbolt.DB
 }
 
 var _ DB = (*BoltDB)(nil)
+var _ Sizable = (*BoltDB)(nil)
+var _ Transactional = (*BoltDB)(nil)
 
 // NewBoltDB returns a BoltDB with default options.
 func NewBoltDB(name, dir string) (DB, error) {
@@ -80,12 +93,23 @@ func (bdb *BoltDB) Get(key []byte) (value []byte, err error) {
 }
 
 // Has implements DB.
-func (bdb *BoltDB) Has(key []byte) (bool, error) {
-	bytes, err := bdb.Get(key)
+func (bdb *BoltDB) Has(key []byte) (ok bool, err error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	err = bdb.db.View(func(tx *bbolt.Tx) error {
+		ok = tx.Bucket(bucket).Get(key) != nil
+		return nil
+	})
 	if err != nil {
 		return false, err
 	}
-	return bytes != nil, nil
+	return ok, nil
+}
+
+// MultiGet implements DB.
+func (bdb *BoltDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(bdb, keys)
 }
 
 // Set implements DB.
@@ -111,6 +135,28 @@ func (bdb *BoltDB) SetSync(key, value []byte) error {
 	return bdb.Set(key, value)
 }
 
+// CompareAndSet implements DB. The comparison and write happen in a single transaction.
+func (bdb *BoltDB) CompareAndSet(key, expected, newVal []byte) (swapped bool, err error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	err = bdb.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if !valueMatches(b.Get(key), expected) {
+			return nil
+		}
+		swapped = true
+		return b.Put(key, newVal)
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
 // Delete implements DB.
 func (bdb *BoltDB) Delete(key []byte) error {
 	if len(key) == 0 {
@@ -130,8 +176,21 @@ func (bdb *BoltDB) DeleteSync(key []byte) error {
 	return bdb.Delete(key)
 }
 
+// Begin implements Transactional. A read-write transaction blocks all other writes to the
+// database until it ends, so calling e.g. db.Set from the goroutine holding it deadlocks.
+// Committing a read-write transaction which grows the database file waits for all open
+// read-only transactions (and iterators) to end, since bbolt has to remap the file.
+func (bdb *BoltDB) Begin(readOnly bool) (Transaction, error) {
+	tx, err := bdb.db.Begin(!readOnly)
+	if err != nil {
+		return nil, err
+	}
+	return newBoltDBTx(tx), nil
+}
+
 // Close implements DB.
 func (bdb *BoltDB) Close() error {
+	GlobalRegistry.unregister(bdb)
 	return bdb.db.Close()
 }
 
@@ -172,11 +231,89 @@ func (bdb *BoltDB) Stats() map[string]string {
 	return m
 }
 
+// ApproxSize implements Sizable. It is the size of the database file.
+func (bdb *BoltDB) ApproxSize() (int64, error) {
+	var size int64
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	return size, err
+}
+
+// DeleteRange implements DB. The whole domain is deleted in a single transaction.
+func (bdb *BoltDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	return bdb.db.Update(func(tx *bbolt.Tx) error {
+		return boltDeleteRange(tx.Bucket(bucket), start, end)
+	})
+}
+
+// boltDeleteRange deletes all keys in a domain from a bucket.
+func boltDeleteRange(b *bbolt.Bucket, start, end []byte) error {
+	// Deleting through a cursor while iterating can skip keys, so collect them first.
+	var keys [][]byte
+	c := b.Cursor()
+	k, _ := c.First()
+	if start != nil {
+		k, _ = c.Seek(start)
+	}
+	for ; k != nil && (end == nil || bytes.Compare(k, end) < 0); k, _ = c.Next() {
+		keys = append(keys, cp(k))
+	}
+	for _, key := range keys {
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IteratorWithContext implements DB.
+func (bdb *BoltDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, bdb, start, end)
+}
+
+// Compact implements DB. It is a no-op.
+func (bdb *BoltDB) Compact(start, end []byte) error {
+	return nil
+}
+
+// WriteBatch implements DB.
+func (bdb *BoltDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(bdb, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (bdb *BoltDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(bdb, ops, true)
+}
+
+// ApplyLog implements DB.
+func (bdb *BoltDB) ApplyLog(ops BatchOpList) error {
+	return bdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (bdb *BoltDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(bdb, fn)
+}
+
 // NewBatch implements DB.
 func (bdb *BoltDB) NewBatch() Batch {
 	return newBoltDBBatch(bdb)
 }
 
+// NewBatchWithSize implements DB.
+func (bdb *BoltDB) NewBatchWithSize(expectedOps int) Batch {
+	return newBoltDBBatchWithSize(bdb, expectedOps)
+}
+
 // WARNING: Any concurrent writes or reads will block until the iterator is
 // closed.
 func (bdb *BoltDB) Iterator(start, end []byte) (Iterator, error) {
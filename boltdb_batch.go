@@ -14,9 +14,16 @@ type boltDBBatch struct {
 var _ Batch = (*boltDBBatch)(nil)
 
 func newBoltDBBatch(db *BoltDB) *boltDBBatch {
+	return newBoltDBBatchWithSize(db, 0)
+}
+
+func newBoltDBBatchWithSize(db *BoltDB, size int) *boltDBBatch {
+	if size < 0 {
+		size = 0
+	}
 	return &boltDBBatch{
 		db:  db,
-		ops: []operation{},
+		ops: make([]operation, 0, size),
 	}
 }
 
@@ -47,6 +54,11 @@ func (b *boltDBBatch) Delete(key []byte) error {
 	return nil
 }
 
+// Len implements Batch.
+func (b *boltDBBatch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *boltDBBatch) Write() error {
 	if b.ops == nil {
@@ -13,6 +13,8 @@ import (
 // start / end keys (nil & nil will result in doing full scan).
 type boltDBIterator struct {
 	tx *bbolt.Tx
+	// inTx is set for iterators of a Transaction, which must not end the transaction when closed.
+	inTx bool
 
 	itr   *bbolt.Cursor
 	start []byte
@@ -109,6 +111,35 @@ func (itr *boltDBIterator) Next() {
 	}
 }
 
+// Seek implements Iterator.
+func (itr *boltDBIterator) Seek(key []byte) bool {
+	itr.isInvalid = false
+	switch {
+	case !itr.isReverse:
+		if itr.start != nil && bytes.Compare(key, itr.start) < 0 {
+			key = itr.start
+		}
+		itr.currentKey, itr.currentValue = itr.itr.Seek(key)
+	case itr.end != nil && bytes.Compare(key, itr.end) >= 0:
+		// The end is exclusive, so move to the last key before it.
+		if k, _ := itr.itr.Seek(itr.end); k != nil {
+			itr.currentKey, itr.currentValue = itr.itr.Prev()
+		} else {
+			itr.currentKey, itr.currentValue = itr.itr.Last()
+		}
+	default:
+		k, v := itr.itr.Seek(key)
+		switch {
+		case k == nil:
+			k, v = itr.itr.Last()
+		case !bytes.Equal(k, key):
+			k, v = itr.itr.Prev()
+		}
+		itr.currentKey, itr.currentValue = k, v
+	}
+	return itr.Valid()
+}
+
 // Key implements Iterator.
 func (itr *boltDBIterator) Key() []byte {
 	itr.assertIsValid()
@@ -125,6 +156,11 @@ func (itr *boltDBIterator) Value() []byte {
 	return value
 }
 
+// Position implements Iterator.
+func (itr *boltDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
 // Error implements Iterator.
 func (itr *boltDBIterator) Error() error {
 	return nil
@@ -132,6 +168,9 @@ func (itr *boltDBIterator) Error() error {
 
 // Close implements Iterator.
 func (itr *boltDBIterator) Close() error {
+	if itr.inTx {
+		return nil
+	}
 	return itr.tx.Rollback()
 }
 
@@ -32,6 +32,67 @@ func TestWithBoltDB(t *testing.T) {
 	t.Run("BoltDB", func(t *testing.T) { Run(t, db) })
 }
 
+func newTransactionalBoltDB(t *testing.T) (DB, Transactional) {
+	db, err := NewBoltDB("testdb", t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	tdb, ok := db.(Transactional)
+	require.True(t, ok)
+	return db, tdb
+}
+
+func TestBoltDBTransactionRollback(t *testing.T) {
+	db, tdb := newTransactionalBoltDB(t)
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+
+	tx, err := tdb.Begin(false)
+	require.NoError(t, err)
+	require.NoError(t, tx.Set([]byte("b"), []byte{2}))
+	require.NoError(t, tx.Delete([]byte("a")))
+	batch := tx.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	// The transaction sees its own writes.
+	assertKeyValues(t, tx, map[string][]byte{"b": {2}, "c": {3}})
+
+	require.NoError(t, tx.Rollback())
+	require.Equal(t, errTxDone, tx.Rollback())
+	_, err = tx.Get([]byte("a"))
+	require.Equal(t, errTxDone, err)
+	assertKeyValues(t, db, map[string][]byte{"a": {1}})
+}
+
+func TestBoltDBTransactionCommit(t *testing.T) {
+	db, tdb := newTransactionalBoltDB(t)
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+
+	// A read-only transaction can not write, and does not see uncommitted writes. It must be
+	// closed before the read-write transaction commits, since bbolt may need to remap the file.
+	ro, err := tdb.Begin(true)
+	require.NoError(t, err)
+	require.Equal(t, ErrReadOnly, ro.Set([]byte("x"), []byte{0}))
+
+	tx, err := tdb.Begin(false)
+	require.NoError(t, err)
+	require.NoError(t, tx.Set([]byte("b"), []byte{2}))
+	swapped, err := tx.CompareAndSet([]byte("a"), []byte{1}, []byte{9})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	require.NoError(t, tx.WriteBatch([]BatchOp{{Key: []byte("c"), Value: []byte{3}}}))
+	require.NoError(t, tx.DeleteRange([]byte("c"), nil))
+
+	assertKeyValues(t, ro, map[string][]byte{"a": {1}})
+	require.NoError(t, ro.Close())
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t, errTxDone, tx.Commit())
+	require.NoError(t, tx.Close())
+
+	assertKeyValues(t, db, map[string][]byte{"a": {9}, "b": {2}})
+}
+
 func BenchmarkBoltDBRandomReadsWrites(b *testing.B) {
 	name := fmt.Sprintf("test_%x", randStr(12))
 	db, err := NewBoltDB(name, "")
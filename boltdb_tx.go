@@ -0,0 +1,353 @@
+//go:build boltdb
+// +build boltdb
+
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// boltDBTx is a Transaction of a BoltDB, wrapping a bbolt transaction.
+type boltDBTx struct {
+	tx   *bbolt.Tx
+	done bool
+}
+
+var _ Transaction = (*boltDBTx)(nil)
+
+func newBoltDBTx(tx *bbolt.Tx) *boltDBTx {
+	return &boltDBTx{tx: tx}
+}
+
+// bucket returns the bucket of the database, or an error if the transaction has ended.
+func (btx *boltDBTx) bucket() (*bbolt.Bucket, error) {
+	if btx.done {
+		return nil, errTxDone
+	}
+	return btx.tx.Bucket(bucket), nil
+}
+
+// writableBucket returns the bucket of the database, or an error if the transaction can not
+// write.
+func (btx *boltDBTx) writableBucket() (*bbolt.Bucket, error) {
+	b, err := btx.bucket()
+	if err != nil {
+		return nil, err
+	}
+	if !btx.tx.Writable() {
+		return nil, ErrReadOnly
+	}
+	return b, nil
+}
+
+// Commit implements Transaction.
+func (btx *boltDBTx) Commit() error {
+	if btx.done {
+		return errTxDone
+	}
+	btx.done = true
+	if !btx.tx.Writable() {
+		return btx.tx.Rollback() // read-only transactions have nothing to commit
+	}
+	return btx.tx.Commit()
+}
+
+// Rollback implements Transaction.
+func (btx *boltDBTx) Rollback() error {
+	if btx.done {
+		return errTxDone
+	}
+	btx.done = true
+	return btx.tx.Rollback()
+}
+
+// Get implements DB.
+func (btx *boltDBTx) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	b, err := btx.bucket()
+	if err != nil {
+		return nil, err
+	}
+	if v := b.Get(key); v != nil {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+// Has implements DB.
+func (btx *boltDBTx) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	b, err := btx.bucket()
+	if err != nil {
+		return false, err
+	}
+	return b.Get(key) != nil, nil
+}
+
+// MultiGet implements DB.
+func (btx *boltDBTx) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(btx, keys)
+}
+
+// Set implements DB.
+func (btx *boltDBTx) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	b, err := btx.writableBucket()
+	if err != nil {
+		return err
+	}
+	return b.Put(key, value)
+}
+
+// SetSync implements DB. Writes are synced when the transaction is committed.
+func (btx *boltDBTx) SetSync(key, value []byte) error {
+	return btx.Set(key, value)
+}
+
+// CompareAndSet implements DB.
+func (btx *boltDBTx) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	b, err := btx.writableBucket()
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(b.Get(key), expected) {
+		return false, nil
+	}
+	return true, b.Put(key, newVal)
+}
+
+// Delete implements DB.
+func (btx *boltDBTx) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	b, err := btx.writableBucket()
+	if err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+// DeleteSync implements DB. Writes are synced when the transaction is committed.
+func (btx *boltDBTx) DeleteSync(key []byte) error {
+	return btx.Delete(key)
+}
+
+// DeleteRange implements DB.
+func (btx *boltDBTx) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	b, err := btx.writableBucket()
+	if err != nil {
+		return err
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	return boltDeleteRange(b, start, end)
+}
+
+// Iterator implements DB. The iterator is only valid until the transaction ends.
+func (btx *boltDBTx) Iterator(start, end []byte) (Iterator, error) {
+	return btx.iterator(start, end, false)
+}
+
+// ReverseIterator implements DB. The iterator is only valid until the transaction ends.
+func (btx *boltDBTx) ReverseIterator(start, end []byte) (Iterator, error) {
+	return btx.iterator(start, end, true)
+}
+
+func (btx *boltDBTx) iterator(start, end []byte, isReverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if btx.done {
+		return nil, errTxDone
+	}
+	itr := newBoltDBIterator(btx.tx, start, end, isReverse)
+	itr.inTx = true
+	return itr, nil
+}
+
+// IteratorWithContext implements DB.
+func (btx *boltDBTx) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, btx, start, end)
+}
+
+// Compact implements DB. It is a no-op.
+func (btx *boltDBTx) Compact(start, end []byte) error {
+	return nil
+}
+
+// WriteBatch implements DB.
+func (btx *boltDBTx) WriteBatch(ops []BatchOp) error {
+	for _, op := range ops {
+		if len(op.Key) == 0 {
+			return errKeyEmpty
+		}
+		if !op.Delete && op.Value == nil {
+			return errValueNil
+		}
+	}
+	b, err := btx.writableBucket()
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if op.Delete {
+			err = b.Delete(op.Key)
+		} else {
+			err = b.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBatchSync implements DB. Writes are synced when the transaction is committed.
+func (btx *boltDBTx) WriteBatchSync(ops []BatchOp) error {
+	return btx.WriteBatch(ops)
+}
+
+// ApplyLog implements DB.
+func (btx *boltDBTx) ApplyLog(ops BatchOpList) error {
+	return btx.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (btx *boltDBTx) ForEach(fn func(key, value []byte) error) error {
+	return forEach(btx, fn)
+}
+
+// Close implements DB. It rolls back the transaction, unless it has already ended.
+func (btx *boltDBTx) Close() error {
+	if btx.done {
+		return nil
+	}
+	return btx.Rollback()
+}
+
+// NewBatch implements DB. Writing the batch writes to the transaction.
+func (btx *boltDBTx) NewBatch() Batch {
+	return newBoltDBTxBatch(btx, 0)
+}
+
+// NewBatchWithSize implements DB. Writing the batch writes to the transaction.
+func (btx *boltDBTx) NewBatchWithSize(expectedOps int) Batch {
+	return newBoltDBTxBatch(btx, expectedOps)
+}
+
+// Print implements DB.
+func (btx *boltDBTx) Print() error {
+	itr, err := btx.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (btx *boltDBTx) Stats() map[string]string {
+	return map[string]string{
+		"database.type": "boltDBTx",
+		"Writable":      fmt.Sprintf("%v", btx.tx.Writable()),
+	}
+}
+
+// boltDBTxBatch buffers operations, and writes them to a transaction on Write.
+type boltDBTxBatch struct {
+	tx  *boltDBTx
+	ops []BatchOp
+}
+
+var _ Batch = (*boltDBTxBatch)(nil)
+
+func newBoltDBTxBatch(tx *boltDBTx, size int) *boltDBTxBatch {
+	if size < 0 {
+		size = 0
+	}
+	return &boltDBTxBatch{
+		tx:  tx,
+		ops: make([]BatchOp, 0, size),
+	}
+}
+
+// Set implements Batch.
+func (b *boltDBTxBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, BatchOp{Key: key, Value: value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *boltDBTxBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, BatchOp{Key: key, Delete: true})
+	return nil
+}
+
+// Len implements Batch.
+func (b *boltDBTxBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *boltDBTxBatch) Write() error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	if err := b.tx.WriteBatch(b.ops); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *boltDBTxBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *boltDBTxBatch) Close() error {
+	b.ops = nil
+	return nil
+}
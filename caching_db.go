@@ -0,0 +1,382 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// CachingDB wraps a database with an in-memory LRU cache of values. Get and Has are served from
+// the cache when possible, and misses populate it. Set, Delete and CompareAndSet update both the
+// wrapped database and the cache, while batches and range deletions invalidate the cache entries
+// they touch. Iterators bypass the cache entirely.
+//
+// Writes to a key hold its lock in a LockManager while updating both the wrapped database and the
+// cache, so concurrent writes update the cache in the order they were applied. Batches and range
+// deletions hold every lock.
+//
+// Only writes going through the CachingDB (including its batches) are visible to the cache, so
+// the wrapped database must not be written to directly while it is in use.
+type CachingDB struct {
+	db    DB
+	locks LockManager
+
+	mtx      sync.Mutex
+	capacity int
+	order    *list.List // of *cacheEntry, most recently used first
+	entries  map[string]*list.Element
+	// gen is incremented on every write, so that a Get racing with a write does not populate
+	// the cache with a stale value.
+	gen uint64
+}
+
+var _ DB = (*CachingDB)(nil)
+
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewCachingDB creates a CachingDB wrapping the given database, caching at most cacheEntries
+// values. A cacheEntries value below 1 disables caching.
+func NewCachingDB(inner DB, cacheEntries int) *CachingDB {
+	return &CachingDB{
+		db:       inner,
+		capacity: cacheEntries,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// lookup returns the cached value for a key, if any, along with the current generation.
+func (cdb *CachingDB) lookup(key []byte) (value []byte, ok bool, gen uint64) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	if e, ok := cdb.entries[string(key)]; ok {
+		cdb.order.MoveToFront(e)
+		return e.Value.(*cacheEntry).value, true, cdb.gen
+	}
+	return nil, false, cdb.gen
+}
+
+// populate caches a value read from the wrapped database, unless a write has happened since gen.
+func (cdb *CachingDB) populate(key, value []byte, gen uint64) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	if gen == cdb.gen {
+		cdb.put(key, value)
+	}
+}
+
+// put caches a value. It requires holding mtx.
+func (cdb *CachingDB) put(key, value []byte) {
+	if cdb.capacity < 1 {
+		return
+	}
+	if e, ok := cdb.entries[string(key)]; ok {
+		e.Value.(*cacheEntry).value = value
+		cdb.order.MoveToFront(e)
+		return
+	}
+	cdb.entries[string(key)] = cdb.order.PushFront(&cacheEntry{key: string(key), value: value})
+	for cdb.order.Len() > cdb.capacity {
+		e := cdb.order.Back()
+		cdb.order.Remove(e)
+		delete(cdb.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// update caches the value just written for a key, or removes the key if the value is nil, and
+// bumps the generation so that a racing Get can not overwrite it with the value it read before
+// the write. It requires holding the key's lock.
+func (cdb *CachingDB) update(key, value []byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	cdb.gen++
+	if value == nil {
+		cdb.remove(key)
+	} else {
+		cdb.put(key, value)
+	}
+}
+
+// remove removes a key from the cache. It requires holding mtx.
+func (cdb *CachingDB) remove(key []byte) {
+	if e, ok := cdb.entries[string(key)]; ok {
+		cdb.order.Remove(e)
+		delete(cdb.entries, string(key))
+	}
+}
+
+// invalidate removes keys from the cache, and bumps the generation. It must be called after a
+// write to the wrapped database has completed, so that a racing Get can not repopulate the cache
+// with the old value.
+func (cdb *CachingDB) invalidate(keys ...[]byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	cdb.gen++
+	for _, key := range keys {
+		cdb.remove(key)
+	}
+}
+
+// invalidateRange is like invalidate, but removes all keys in the given domain.
+func (cdb *CachingDB) invalidateRange(start, end []byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+
+	cdb.gen++
+	for key, e := range cdb.entries {
+		if IsKeyInDomain([]byte(key), start, end) {
+			cdb.order.Remove(e)
+			delete(cdb.entries, key)
+		}
+	}
+}
+
+// Get implements DB.
+func (cdb *CachingDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, ok, gen := cdb.lookup(key)
+	if ok {
+		return value, nil
+	}
+	value, err := cdb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		cdb.populate(key, value, gen)
+	}
+	return value, nil
+}
+
+// Has implements DB.
+func (cdb *CachingDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if _, ok, _ := cdb.lookup(key); ok {
+		return true, nil
+	}
+	return cdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (cdb *CachingDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(cdb, keys)
+}
+
+// Set implements DB.
+func (cdb *CachingDB) Set(key []byte, value []byte) error {
+	return cdb.set(key, value, cdb.db.Set)
+}
+
+// SetSync implements DB.
+func (cdb *CachingDB) SetSync(key []byte, value []byte) error {
+	return cdb.set(key, value, cdb.db.SetSync)
+}
+
+// set writes a value, and caches it. If the write fails the cached value is invalidated instead,
+// since the wrapped database may or may not hold the new value.
+func (cdb *CachingDB) set(key []byte, value []byte, setFn func([]byte, []byte) error) error {
+	cdb.locks.Lock(key)
+	defer cdb.locks.Unlock(key)
+
+	if err := setFn(key, value); err != nil {
+		cdb.invalidate(key)
+		return err
+	}
+	cdb.update(key, cp(value))
+	return nil
+}
+
+// Delete implements DB.
+func (cdb *CachingDB) Delete(key []byte) error {
+	return cdb.delete(key, cdb.db.Delete)
+}
+
+// DeleteSync implements DB.
+func (cdb *CachingDB) DeleteSync(key []byte) error {
+	return cdb.delete(key, cdb.db.DeleteSync)
+}
+
+// delete deletes a key, and removes it from the cache.
+func (cdb *CachingDB) delete(key []byte, deleteFn func([]byte) error) error {
+	cdb.locks.Lock(key)
+	defer cdb.locks.Unlock(key)
+
+	err := deleteFn(key)
+	cdb.invalidate(key)
+	return err
+}
+
+// CompareAndSet implements DB.
+func (cdb *CachingDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	cdb.locks.Lock(key)
+	defer cdb.locks.Unlock(key)
+
+	swapped, err := cdb.db.CompareAndSet(key, expected, newVal)
+	switch {
+	case err != nil:
+		cdb.invalidate(key)
+	case swapped:
+		cdb.update(key, cp(newVal))
+	}
+	return swapped, err
+}
+
+// DeleteRange implements DB.
+func (cdb *CachingDB) DeleteRange(start, end []byte) error {
+	cdb.locks.LockRange(start, end)
+	defer cdb.locks.UnlockRange(start, end)
+
+	err := cdb.db.DeleteRange(start, end)
+	cdb.invalidateRange(start, end)
+	return err
+}
+
+// Iterator implements DB.
+func (cdb *CachingDB) Iterator(start, end []byte) (Iterator, error) {
+	return cdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (cdb *CachingDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return cdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (cdb *CachingDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return cdb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (cdb *CachingDB) Compact(start, end []byte) error {
+	return cdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (cdb *CachingDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(cdb, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (cdb *CachingDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(cdb, ops, true)
+}
+
+// ApplyLog implements DB.
+func (cdb *CachingDB) ApplyLog(ops BatchOpList) error {
+	return cdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (cdb *CachingDB) ForEach(fn func(key, value []byte) error) error {
+	return cdb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (cdb *CachingDB) Close() error {
+	cdb.mtx.Lock()
+	cdb.order.Init()
+	cdb.entries = make(map[string]*list.Element)
+	cdb.mtx.Unlock()
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CachingDB) NewBatch() Batch {
+	return newCachingDBBatch(cdb, cdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (cdb *CachingDB) NewBatchWithSize(expectedOps int) Batch {
+	return newCachingDBBatch(cdb, cdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (cdb *CachingDB) Print() error {
+	return cdb.db.Print()
+}
+
+// Stats implements DB.
+func (cdb *CachingDB) Stats() map[string]string {
+	return cdb.db.Stats()
+}
+
+// cachingDBBatch wraps a batch, and invalidates the cached values of all the keys it touches
+// when written. Writes hold every key lock of the CachingDB, so that they are ordered with the
+// other writes to the keys they touch.
+type cachingDBBatch struct {
+	db    *CachingDB
+	batch Batch
+	keys  [][]byte
+}
+
+var _ Batch = (*cachingDBBatch)(nil)
+
+func newCachingDBBatch(db *CachingDB, batch Batch) *cachingDBBatch {
+	return &cachingDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *cachingDBBatch) Set(key, value []byte) error {
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, cp(key))
+	return nil
+}
+
+// Delete implements Batch.
+func (b *cachingDBBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, cp(key))
+	return nil
+}
+
+// Len implements Batch.
+func (b *cachingDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *cachingDBBatch) Write() error {
+	return b.write(b.batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *cachingDBBatch) WriteSync() error {
+	return b.write(b.batch.WriteSync)
+}
+
+func (b *cachingDBBatch) write(writeFn func() error) error {
+	b.db.locks.LockRange(nil, nil)
+	defer b.db.locks.UnlockRange(nil, nil)
+
+	err := writeFn()
+	b.db.invalidate(b.keys...)
+	if err != nil {
+		return err
+	}
+	b.keys = nil
+	return nil
+}
+
+// Close implements Batch.
+func (b *cachingDBBatch) Close() error {
+	b.keys = nil
+	return b.batch.Close()
+}
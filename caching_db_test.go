@@ -0,0 +1,201 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingDB(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCachingDB(inner, 50)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, cdb.Set([]byte(fmt.Sprintf("key%03d", i)), []byte{byte(i)}))
+	}
+	// Read everything, so the most recently read keys are cached.
+	for i := 0; i < 100; i++ {
+		checkValue(t, cdb, []byte(fmt.Sprintf("key%03d", i)), []byte{byte(i)})
+	}
+
+	// Delete cached and uncached keys, through both the DB and a batch.
+	batch := cdb.NewBatch()
+	for i := 0; i < 100; i += 10 {
+		key := []byte(fmt.Sprintf("key%03d", i))
+		if i < 50 {
+			require.NoError(t, cdb.Delete(key))
+		} else {
+			require.NoError(t, batch.Delete(key))
+		}
+	}
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key%03d", i))
+		ok, err := cdb.Has(key)
+		require.NoError(t, err)
+		if i%10 == 0 {
+			checkValue(t, cdb, key, nil)
+			require.False(t, ok)
+		} else {
+			checkValue(t, cdb, key, []byte{byte(i)})
+			require.True(t, ok)
+		}
+	}
+
+	// Iterators bypass the cache, and see the same data.
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	count := 0
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Close())
+	require.Equal(t, 90, count)
+}
+
+func TestCachingDBWrites(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCachingDB(inner, 10)
+
+	// Sets and deletes update both the cache and the wrapped database.
+	value := []byte{1}
+	require.NoError(t, cdb.Set([]byte("a"), value))
+	require.Contains(t, cdb.entries, "a")
+	value[0] = 9 // the cache must hold a copy
+	checkValue(t, cdb, []byte("a"), []byte{1})
+	require.NoError(t, cdb.SetSync([]byte("a"), []byte{2}))
+	checkValue(t, cdb, []byte("a"), []byte{2})
+	checkValue(t, inner, []byte("a"), []byte{2})
+
+	require.NoError(t, cdb.Set([]byte("b"), []byte{3}))
+	checkValue(t, cdb, []byte("b"), []byte{3})
+
+	require.NoError(t, cdb.Set([]byte("c"), []byte{6}))
+	require.NoError(t, cdb.DeleteSync([]byte("c")))
+	require.NotContains(t, cdb.entries, "c")
+	checkValue(t, cdb, []byte("c"), nil)
+	checkValue(t, inner, []byte("c"), nil)
+
+	// Batch writes must invalidate cached values.
+	batch := cdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{4}))
+	require.Equal(t, 1, batch.Len())
+	checkValue(t, cdb, []byte("a"), []byte{2})
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	checkValue(t, cdb, []byte("a"), []byte{4})
+
+	// As must compare-and-set and range deletions.
+	swapped, err := cdb.CompareAndSet([]byte("a"), []byte{4}, []byte{5})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	require.Contains(t, cdb.entries, "a")
+	checkValue(t, cdb, []byte("a"), []byte{5})
+
+	require.NoError(t, cdb.DeleteRange([]byte("a"), []byte("b")))
+	checkValue(t, cdb, []byte("a"), nil)
+	checkValue(t, cdb, []byte("b"), []byte{3})
+}
+
+func TestCachingDBConcurrentSets(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCachingDB(inner, 10)
+	key := []byte("key")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				switch {
+				case j%10 == 9:
+					assert.NoError(t, cdb.Delete(key))
+				case j%10 == 8:
+					assert.NoError(t, cdb.WriteBatch([]BatchOp{{Key: key, Value: []byte{byte(i), byte(j)}}}))
+				default:
+					assert.NoError(t, cdb.Set(key, []byte{byte(i), byte(j)}))
+				}
+				_, err := cdb.Get(key)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The cache must agree with the wrapped database.
+	value, err := inner.Get(key)
+	require.NoError(t, err)
+	checkValue(t, cdb, key, value)
+}
+
+// pausingSetDB pauses a Set after writing to the wrapped database, while paused is being received
+// from, until resume is sent to.
+type pausingSetDB struct {
+	*MemDB
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (db *pausingSetDB) Set(key, value []byte) error {
+	err := db.MemDB.Set(key, value)
+	select {
+	case db.paused <- struct{}{}:
+		<-db.resume
+	default:
+	}
+	return err
+}
+
+func TestCachingDBReorderedSets(t *testing.T) {
+	// A Set which wrote to the wrapped database before another must also update the cache before
+	// it, or the cache would keep the older value.
+	inner := &pausingSetDB{MemDB: NewMemDB(), paused: make(chan struct{}), resume: make(chan struct{})}
+	cdb := NewCachingDB(inner, 10)
+	key := []byte("key")
+
+	first := make(chan error)
+	go func() { first <- cdb.Set(key, []byte{1}) }()
+	<-inner.paused
+
+	second := make(chan error)
+	go func() { second <- cdb.Set(key, []byte{2}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	close(inner.resume)
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+	checkValue(t, inner, key, []byte{2})
+	checkValue(t, cdb, key, []byte{2})
+}
+
+func TestCachingDBFailedWrite(t *testing.T) {
+	errMock := errors.New("mock error")
+	mock := NewMockDBWrapping(NewMemDB())
+	cdb := NewCachingDB(mock, 10)
+
+	require.NoError(t, cdb.Set([]byte("a"), []byte{1}))
+	require.Contains(t, cdb.entries, "a")
+
+	// A failed write must not leave the new value in the cache, nor keep the old one.
+	mock.SetError("Set", errMock)
+	require.ErrorIs(t, cdb.Set([]byte("a"), []byte{2}), errMock)
+	require.NotContains(t, cdb.entries, "a")
+	checkValue(t, cdb, []byte("a"), []byte{1})
+}
+
+func TestCachingDBDisabled(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCachingDB(inner, 0)
+
+	require.NoError(t, cdb.Set([]byte("a"), []byte{1}))
+	checkValue(t, cdb, []byte("a"), []byte{1})
+	require.Empty(t, cdb.entries)
+}
@@ -0,0 +1,31 @@
+package db
+
+// Checkpointable is implemented by databases which can create checkpoints: consistent,
+// point-in-time copies of the database on disk, taken while it remains in use.
+type Checkpointable interface {
+	// Checkpoint creates a checkpoint in destDir, which must not exist. destDir becomes the
+	// database directory of the checkpoint, which can be opened with the same backend, e.g. with
+	// Open(backend, name, dir, opts) if destDir is filepath.Join(dir, name+".db").
+	Checkpoint(destDir string) error
+}
+
+// CheckpointIfSupported creates a checkpoint of the database in dir, which must not exist. It
+// uses Checkpoint for Checkpointable databases. Other databases are first copied into a MemDB,
+// which is then written to a new GoLevelDB database in dir, so the whole database must fit in
+// memory, and writes made during the copy may or may not be included.
+func CheckpointIfSupported(db DB, dir string) error {
+	if cdb, ok := db.(Checkpointable); ok {
+		return cdb.Checkpoint(dir)
+	}
+
+	mem := NewMemDB()
+	if err := CopyTo(db, mem, CopyOptions{}); err != nil {
+		return err
+	}
+	itr, err := mem.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	return writeGoLevelDBCheckpoint(itr, dir)
+}
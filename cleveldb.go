@@ -4,15 +4,17 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/jmhodges/levigo"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewCLevelDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newCLevelDBWithOptions(name, dir, opts)
 	}
 	registerDBCreator(CLevelDBBackend, dbCreator, false)
 }
@@ -23,17 +25,41 @@ type CLevelDB struct {
 	ro     *levigo.ReadOptions
 	wo     *levigo.WriteOptions
 	woSync *levigo.WriteOptions
+
+	// readOnly rejects writes. LevelDB can not be opened read-only, so reads use a snapshot instead
+	// to get a consistent view of the database.
+	readOnly bool
+	snapshot *levigo.Snapshot
+
+	logger Logger
+
+	// casMtx makes CompareAndSet atomic, since LevelDB has no native support for it: writes hold
+	// a read lock, so they still run in parallel, and CompareAndSet holds the write lock.
+	casMtx sync.RWMutex
 }
 
 var _ DB = (*CLevelDB)(nil)
+var _ Sizable = (*CLevelDB)(nil)
 
 // NewCLevelDB creates a new CLevelDB.
 func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
+	return newCLevelDBWithOptions(name, dir, Options{})
+}
+
+// newCLevelDBWithOptions creates a CLevelDB, mapping Options to LevelDB options.
+func newCLevelDBWithOptions(name string, dir string, o Options) (*CLevelDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
 
+	cacheSize := o.BlockCacheSize
+	if cacheSize <= 0 {
+		cacheSize = 1 << 30
+	}
 	opts := levigo.NewOptions()
-	opts.SetCache(levigo.NewLRUCache(1 << 30))
-	opts.SetCreateIfMissing(true)
+	opts.SetCache(levigo.NewLRUCache(int(cacheSize)))
+	if o.MaxOpenFiles > 0 {
+		opts.SetMaxOpenFiles(o.MaxOpenFiles)
+	}
+	opts.SetCreateIfMissing(!o.ReadOnly)
 	db, err := levigo.Open(dbPath, opts)
 	if err != nil {
 		return nil, err
@@ -43,10 +69,16 @@ func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
 	woSync := levigo.NewWriteOptions()
 	woSync.SetSync(true)
 	database := &CLevelDB{
-		db:     db,
-		ro:     ro,
-		wo:     wo,
-		woSync: woSync,
+		db:       db,
+		ro:       ro,
+		wo:       wo,
+		woSync:   woSync,
+		readOnly: o.ReadOnly,
+		logger:   loggerOrNop(o.Logger),
+	}
+	if o.ReadOnly {
+		database.snapshot = db.NewSnapshot()
+		ro.SetSnapshot(database.snapshot)
 	}
 	return database, nil
 }
@@ -72,36 +104,66 @@ func (db *CLevelDB) Has(key []byte) (bool, error) {
 	return bytes != nil, nil
 }
 
+// MultiGet implements DB.
+func (db *CLevelDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(db, keys)
+}
+
 // Set implements DB.
 func (db *CLevelDB) Set(key []byte, value []byte) error {
-	if len(key) == 0 {
-		return errKeyEmpty
-	}
-	if value == nil {
-		return errValueNil
-	}
-	if err := db.db.Put(db.wo, key, value); err != nil {
-		return err
-	}
-	return nil
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, db.wo)
 }
 
 // SetSync implements DB.
 func (db *CLevelDB) SetSync(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, db.woSync)
+}
+
+// syncWriteDurable implements syncWriteDurable. LevelDB journals every write to a single log, so
+// a sync write persists all earlier ones.
+func (db *CLevelDB) syncWriteDurable() {}
+
+// set sets a value without locking casMtx.
+func (db *CLevelDB) set(key []byte, value []byte, wo *levigo.WriteOptions) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
-	if err := db.db.Put(db.woSync, key, value); err != nil {
+	if err := db.db.Put(wo, key, value); err != nil {
 		return err
 	}
 	return nil
 }
 
+// CompareAndSet implements DB.
+func (db *CLevelDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if db.readOnly {
+		return false, ErrReadOnly
+	}
+	db.casMtx.Lock()
+	defer db.casMtx.Unlock()
+
+	return compareAndSet(db, key, expected, newVal, func(key, value []byte) error {
+		return db.set(key, value, db.wo)
+	})
+}
+
 // Delete implements DB.
 func (db *CLevelDB) Delete(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -113,6 +175,11 @@ func (db *CLevelDB) Delete(key []byte) error {
 
 // DeleteSync implements DB.
 func (db *CLevelDB) DeleteSync(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -129,6 +196,10 @@ func (db *CLevelDB) DB() *levigo.DB {
 
 // Close implements DB.
 func (db *CLevelDB) Close() error {
+	GlobalRegistry.unregister(db)
+	if db.snapshot != nil {
+		db.db.ReleaseSnapshot(db.snapshot)
+	}
 	db.db.Close()
 	db.ro.Close()
 	db.wo.Close()
@@ -168,11 +239,96 @@ func (db *CLevelDB) Stats() map[string]string {
 	return stats
 }
 
+// ApproxSize implements Sizable. It is the approximate size of the table files, which does not
+// include writes still held in the memtable.
+func (db *CLevelDB) ApproxSize() (int64, error) {
+	itr := db.db.NewIterator(db.ro)
+	itr.SeekToLast()
+	valid := itr.Valid()
+	var limit []byte
+	if valid {
+		limit = append(cp(itr.Key()), 0x00)
+	}
+	err := itr.GetError()
+	itr.Close()
+	if err != nil || !valid {
+		return 0, err
+	}
+	var size int64
+	for _, s := range db.db.GetApproximateSizes([]levigo.Range{{Start: []byte{}, Limit: limit}}) {
+		size += int64(s)
+	}
+	return size, nil
+}
+
+// DeleteRange implements DB. The domain is not deleted atomically, and is compacted afterwards
+// to reclaim the space of the deleted keys.
+func (db *CLevelDB) DeleteRange(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if err := deleteRange(db, start, end); err != nil {
+		return err
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	return db.Compact(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (db *CLevelDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB.
+func (db *CLevelDB) Compact(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	return logCompaction(db.logger, start, end, func() error {
+		db.db.CompactRange(levigo.Range{Start: start, Limit: end})
+		return nil
+	})
+}
+
+// WriteBatch implements DB.
+func (db *CLevelDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *CLevelDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *CLevelDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *CLevelDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
 // NewBatch implements DB.
 func (db *CLevelDB) NewBatch() Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
 	return newCLevelDBBatch(db)
 }
 
+// NewBatchWithSize implements DB. levigo has no way to preallocate a batch, so the size hint is
+// ignored.
+func (db *CLevelDB) NewBatchWithSize(_ int) Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
+	return db.NewBatch()
+}
+
 // Iterator implements DB.
 func (db *CLevelDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
@@ -9,6 +9,8 @@ import "github.com/jmhodges/levigo"
 type cLevelDBBatch struct {
 	db    *CLevelDB
 	batch *levigo.WriteBatch
+	// levigo does not expose the number of operations in a batch, so count them ourselves.
+	ops int
 }
 
 func newCLevelDBBatch(db *CLevelDB) *cLevelDBBatch {
@@ -30,6 +32,7 @@ func (b *cLevelDBBatch) Set(key, value []byte) error {
 		return errBatchClosed
 	}
 	b.batch.Put(key, value)
+	b.ops++
 	return nil
 }
 
@@ -42,15 +45,23 @@ func (b *cLevelDBBatch) Delete(key []byte) error {
 		return errBatchClosed
 	}
 	b.batch.Delete(key)
+	b.ops++
 	return nil
 }
 
+// Len implements Batch.
+func (b *cLevelDBBatch) Len() int {
+	return b.ops
+}
+
 // Write implements Batch.
 func (b *cLevelDBBatch) Write() error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.db.casMtx.RLock()
 	err := b.db.db.Write(b.db.wo, b.batch)
+	b.db.casMtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -63,7 +74,9 @@ func (b *cLevelDBBatch) WriteSync() error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.db.casMtx.RLock()
 	err := b.db.db.Write(b.db.woSync, b.batch)
+	b.db.casMtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -77,6 +90,7 @@ func (b *cLevelDBBatch) Close() error {
 	if b.batch != nil {
 		b.batch.Close()
 		b.batch = nil
+		b.ops = 0
 	}
 	return nil
 }
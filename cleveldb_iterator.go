@@ -90,6 +90,36 @@ func (itr cLevelDBIterator) Valid() bool {
 	return true
 }
 
+// Seek implements Iterator.
+func (itr cLevelDBIterator) Seek(key []byte) bool {
+	if itr.source.GetError() != nil {
+		return false
+	}
+	switch {
+	case !itr.isReverse:
+		if itr.start != nil && bytes.Compare(key, itr.start) < 0 {
+			key = itr.start
+		}
+		itr.source.Seek(key)
+	case itr.end != nil && bytes.Compare(key, itr.end) >= 0:
+		// The end is exclusive, so move to the last key before it.
+		itr.source.Seek(itr.end)
+		if itr.source.Valid() {
+			itr.source.Prev()
+		} else {
+			itr.source.SeekToLast()
+		}
+	default:
+		itr.source.Seek(key)
+		if !itr.source.Valid() {
+			itr.source.SeekToLast()
+		} else if !bytes.Equal(itr.source.Key(), key) {
+			itr.source.Prev()
+		}
+	}
+	return itr.Valid()
+}
+
 // Key implements Iterator.
 func (itr cLevelDBIterator) Key() []byte {
 	itr.assertIsValid()
@@ -102,6 +132,11 @@ func (itr cLevelDBIterator) Value() []byte {
 	return itr.source.Value()
 }
 
+// Position implements Iterator.
+func (itr cLevelDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
 // Next implements Iterator.
 func (itr cLevelDBIterator) Next() {
 	itr.assertIsValid()
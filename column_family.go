@@ -0,0 +1,22 @@
+package db
+
+// MultiCFDB is implemented by databases with several column families: namespaces which share a
+// database, but are stored separately, so that each can have its own compaction, compression and
+// caching settings. The DB methods operate on the default column family, and column families are
+// referred to by the names they were opened with.
+type MultiCFDB interface {
+	DB
+
+	// GetCF fetches the value of the given key in a column family, or nil if it does not exist.
+	GetCF(cf string, key []byte) ([]byte, error)
+
+	// SetCF sets the value for the given key in a column family.
+	SetCF(cf string, key, value []byte) error
+
+	// DeleteCF deletes the given key from a column family.
+	DeleteCF(cf string, key []byte) error
+
+	// IteratorCF iterates over a domain of keys of a column family in ascending order, like
+	// DB.Iterator.
+	IteratorCF(cf string, start, end []byte) (Iterator, error)
+}
@@ -23,11 +23,12 @@ func checkValue(t *testing.T, db DB, key []byte, valueWanted []byte) {
 func checkValid(t *testing.T, itr Iterator, expected bool) {
 	valid := itr.Valid()
 	require.Equal(t, expected, valid)
+	require.NoError(t, itr.Error())
 }
 
 func checkNext(t *testing.T, itr Iterator, expected bool) {
 	itr.Next()
-	// assert.NoError(t, err) TODO: look at fixing this
+	require.NoError(t, itr.Error())
 	valid := itr.Valid()
 	require.Equal(t, expected, valid)
 }
@@ -0,0 +1,490 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses and decompresses values for CompressedDB. It must be safe for concurrent use.
+type Codec interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// Codec identifiers, stored as the first byte of each CompressedDB value.
+const (
+	codecIDNone   byte = 0x00
+	codecIDSnappy byte = 0x01
+	codecIDZstd   byte = 0x02
+	codecIDCustom byte = 0xff
+)
+
+// errCompressedValueInvalid is returned when a value stored by CompressedDB has no known codec
+// identifier, e.g. because it was written directly to the wrapped database.
+var errCompressedValueInvalid = errors.New("value stored in compressed database has an unknown codec")
+
+// SnappyCodec compresses values with Snappy, which is fast but compresses less than zstd.
+type SnappyCodec struct{}
+
+var _ Codec = SnappyCodec{}
+
+// Compress implements Codec.
+func (SnappyCodec) Compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+// Decompress implements Codec.
+func (SnappyCodec) Decompress(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
+
+// zstdEncoder and zstdDecoder are shared by all ZstdCodecs, since they are expensive to create and
+// safe for concurrent use with EncodeAll and DecodeAll.
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// ZstdCodec compresses values with zstd, at the default compression level.
+type ZstdCodec struct{}
+
+var _ Codec = ZstdCodec{}
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr == nil {
+			zstdDecoder, zstdErr = zstd.NewReader(nil)
+		}
+	})
+	return zstdErr
+}
+
+// Compress implements Codec.
+func (ZstdCodec) Compress(src []byte) ([]byte, error) {
+	if err := initZstd(); err != nil {
+		return nil, err
+	}
+	return zstdEncoder.EncodeAll(src, nil), nil
+}
+
+// Decompress implements Codec.
+func (ZstdCodec) Decompress(src []byte) ([]byte, error) {
+	if err := initZstd(); err != nil {
+		return nil, err
+	}
+	return zstdDecoder.DecodeAll(src, nil)
+}
+
+// CompressedDB wraps a database, and compresses values with a Codec. Each value is stored in the
+// wrapped database prefixed by a byte identifying its codec, so values written with the built-in
+// codecs can be read regardless of the codec the CompressedDB was created with. Values which do
+// not shrink when compressed are stored uncompressed.
+//
+// Values written with custom codecs can only be read by a CompressedDB using the same codec. The
+// wrapped database must only be written through the CompressedDB.
+type CompressedDB struct {
+	db      DB
+	codec   Codec
+	codecID byte
+}
+
+var _ DB = (*CompressedDB)(nil)
+
+// NewCompressedDB creates a CompressedDB wrapping the given database, compressing values with the
+// given codec.
+func NewCompressedDB(inner DB, codec Codec) *CompressedDB {
+	var id byte
+	switch codec.(type) {
+	case SnappyCodec, *SnappyCodec:
+		id = codecIDSnappy
+	case ZstdCodec, *ZstdCodec:
+		id = codecIDZstd
+	default:
+		id = codecIDCustom
+	}
+	return &CompressedDB{
+		db:      inner,
+		codec:   codec,
+		codecID: id,
+	}
+}
+
+// compress compresses a value, prefixed by its codec identifier.
+func (cdb *CompressedDB) compress(value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil // let the wrapped database reject it
+	}
+	compressed, err := cdb.codec.Compress(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	id := cdb.codecID
+	if len(compressed) >= len(value) {
+		compressed, id = value, codecIDNone
+	}
+	bz := make([]byte, 1+len(compressed))
+	bz[0] = id
+	copy(bz[1:], compressed)
+	return bz, nil
+}
+
+// decompress decompresses a stored value for the given key. Missing values are returned as nil.
+func (cdb *CompressedDB) decompress(key, stored []byte) ([]byte, error) {
+	if stored == nil {
+		return nil, nil
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("key %X: %w", key, errCompressedValueInvalid)
+	}
+	var codec Codec
+	switch id := stored[0]; {
+	case id == codecIDNone:
+		return stored[1:], nil
+	case id == codecIDSnappy:
+		codec = SnappyCodec{}
+	case id == codecIDZstd:
+		codec = ZstdCodec{}
+	case id == codecIDCustom && cdb.codecID == codecIDCustom:
+		codec = cdb.codec
+	default:
+		return nil, fmt.Errorf("key %X: %w", key, errCompressedValueInvalid)
+	}
+	value, err := codec.Decompress(stored[1:])
+	if err != nil {
+		return nil, fmt.Errorf("key %X: failed to decompress value: %w", key, err)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Get implements DB.
+func (cdb *CompressedDB) Get(key []byte) ([]byte, error) {
+	stored, err := cdb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return cdb.decompress(key, stored)
+}
+
+// Has implements DB.
+func (cdb *CompressedDB) Has(key []byte) (bool, error) {
+	return cdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (cdb *CompressedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(cdb, keys)
+}
+
+// Set implements DB.
+func (cdb *CompressedDB) Set(key []byte, value []byte) error {
+	stored, err := cdb.compress(value)
+	if err != nil {
+		return err
+	}
+	return cdb.db.Set(key, stored)
+}
+
+// SetSync implements DB.
+func (cdb *CompressedDB) SetSync(key []byte, value []byte) error {
+	stored, err := cdb.compress(value)
+	if err != nil {
+		return err
+	}
+	return cdb.db.SetSync(key, stored)
+}
+
+// Delete implements DB.
+func (cdb *CompressedDB) Delete(key []byte) error {
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *CompressedDB) DeleteSync(key []byte) error {
+	return cdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB. It is atomic if the wrapped database's CompareAndSet is.
+func (cdb *CompressedDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if newVal == nil {
+		return false, errValueNil
+	}
+	stored, err := cdb.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	current, err := cdb.decompress(key, stored)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	compressed, err := cdb.compress(newVal)
+	if err != nil {
+		return false, err
+	}
+	// Compare against the stored value, so that concurrent writes make the swap fail.
+	return cdb.db.CompareAndSet(key, stored, compressed)
+}
+
+// DeleteRange implements DB.
+func (cdb *CompressedDB) DeleteRange(start, end []byte) error {
+	return cdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (cdb *CompressedDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedDBIterator(cdb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (cdb *CompressedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedDBIterator(cdb, itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (cdb *CompressedDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, cdb, start, end)
+}
+
+// Compact implements DB.
+func (cdb *CompressedDB) Compact(start, end []byte) error {
+	return cdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (cdb *CompressedDB) WriteBatch(ops []BatchOp) error {
+	compressed, err := cdb.compressOps(ops)
+	if err != nil {
+		return err
+	}
+	return cdb.db.WriteBatch(compressed)
+}
+
+// WriteBatchSync implements DB.
+func (cdb *CompressedDB) WriteBatchSync(ops []BatchOp) error {
+	compressed, err := cdb.compressOps(ops)
+	if err != nil {
+		return err
+	}
+	return cdb.db.WriteBatchSync(compressed)
+}
+
+// compressOps compresses the values of set operations.
+func (cdb *CompressedDB) compressOps(ops []BatchOp) ([]BatchOp, error) {
+	compressed := make([]BatchOp, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			var err error
+			op.Value, err = cdb.compress(op.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		compressed = append(compressed, op)
+	}
+	return compressed, nil
+}
+
+// ApplyLog implements DB.
+func (cdb *CompressedDB) ApplyLog(ops BatchOpList) error {
+	return cdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (cdb *CompressedDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(cdb, fn)
+}
+
+// Close implements DB.
+func (cdb *CompressedDB) Close() error {
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CompressedDB) NewBatch() Batch {
+	return newCompressedDBBatch(cdb, cdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (cdb *CompressedDB) NewBatchWithSize(expectedOps int) Batch {
+	return newCompressedDBBatch(cdb, cdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (cdb *CompressedDB) Print() error {
+	itr, err := cdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (cdb *CompressedDB) Stats() map[string]string {
+	return cdb.db.Stats()
+}
+
+// compressedDBBatch wraps a batch, compressing values.
+type compressedDBBatch struct {
+	db    *CompressedDB
+	batch Batch
+}
+
+var _ Batch = (*compressedDBBatch)(nil)
+
+func newCompressedDBBatch(db *CompressedDB, batch Batch) *compressedDBBatch {
+	return &compressedDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *compressedDBBatch) Set(key, value []byte) error {
+	stored, err := b.db.compress(value)
+	if err != nil {
+		return err
+	}
+	return b.batch.Set(key, stored)
+}
+
+// Delete implements Batch.
+func (b *compressedDBBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *compressedDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *compressedDBBatch) Write() error {
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *compressedDBBatch) WriteSync() error {
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *compressedDBBatch) Close() error {
+	return b.batch.Close()
+}
+
+// compressedDBIterator wraps an iterator, decompressing values. A value which fails to
+// decompress invalidates the iterator, and is reported by Error.
+type compressedDBIterator struct {
+	db     *CompressedDB
+	source Iterator
+	value  []byte
+	err    error
+}
+
+var _ Iterator = (*compressedDBIterator)(nil)
+
+func newCompressedDBIterator(db *CompressedDB, source Iterator) *compressedDBIterator {
+	itr := &compressedDBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.decompressValue()
+	return itr
+}
+
+// decompressValue decompresses the value at the current position of the source.
+func (itr *compressedDBIterator) decompressValue() {
+	itr.value = nil
+	if itr.err != nil || !itr.source.Valid() {
+		return
+	}
+	itr.value, itr.err = itr.db.decompress(itr.source.Key(), itr.source.Value())
+}
+
+// Domain implements Iterator.
+func (itr *compressedDBIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *compressedDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *compressedDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.decompressValue()
+}
+
+// Seek implements Iterator.
+func (itr *compressedDBIterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	itr.source.Seek(key)
+	itr.decompressValue()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *compressedDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *compressedDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Position implements Iterator.
+func (itr *compressedDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *compressedDBIterator) Error() error {
+	if err := itr.source.Error(); err != nil {
+		return err
+	}
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *compressedDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *compressedDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressibleValue returns a value of the given size made of random words, which compresses
+// roughly like encoded application state.
+func compressibleValue(rnd *rand.Rand, size int) []byte {
+	words := []string{"validator", "address", "power", "height", "hash", "block", "commit", "0x"}
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[rnd.Intn(len(words))])
+		buf.WriteByte(byte(rnd.Intn(256)))
+	}
+	return buf.Bytes()[:size]
+}
+
+// truncatingCodec is a custom codec which "compresses" values by dropping their last byte.
+type truncatingCodec struct{}
+
+func (truncatingCodec) Compress(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return src[:len(src)-1], nil
+}
+
+func (truncatingCodec) Decompress(src []byte) ([]byte, error) {
+	return append(cp(src), 'x'), nil
+}
+
+func TestCompressedDB(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	large := compressibleValue(rnd, 10000)
+
+	for _, codec := range []Codec{SnappyCodec{}, ZstdCodec{}} {
+		codec := codec
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			inner := NewMemDB()
+			cdb := NewCompressedDB(inner, codec)
+			defer cdb.Close()
+
+			require.NoError(t, cdb.Set([]byte("large"), large))
+			require.NoError(t, cdb.Set([]byte("small"), []byte{1}))
+			require.NoError(t, cdb.Set([]byte("empty"), []byte{}))
+			batch := cdb.NewBatch()
+			require.NoError(t, batch.Set([]byte("batched"), large))
+			require.NoError(t, batch.Write())
+			require.NoError(t, batch.Close())
+
+			assertKeyValues(t, cdb, map[string][]byte{
+				"large":   large,
+				"small":   {1},
+				"empty":   {},
+				"batched": large,
+			})
+			checkValue(t, cdb, []byte("empty"), []byte{})
+
+			// Compressible values shrink, and incompressible ones are stored as is.
+			stored, err := inner.Get([]byte("large"))
+			require.NoError(t, err)
+			assert.Less(t, len(stored), len(large)*3/4)
+			stored, err = inner.Get([]byte("small"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte{codecIDNone, 1}, stored)
+
+			swapped, err := cdb.CompareAndSet([]byte("large"), large, []byte{2})
+			require.NoError(t, err)
+			assert.True(t, swapped)
+			checkValue(t, cdb, []byte("large"), []byte{2})
+		})
+	}
+}
+
+func TestCompressedDBSelfDescribing(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	value := compressibleValue(rnd, 1000)
+	inner := NewMemDB()
+
+	require.NoError(t, NewCompressedDB(inner, SnappyCodec{}).Set([]byte("snappy"), value))
+	require.NoError(t, NewCompressedDB(inner, ZstdCodec{}).Set([]byte("zstd"), value))
+	require.NoError(t, NewCompressedDB(inner, truncatingCodec{}).Set([]byte("custom"), value))
+
+	// Any codec reads values written by the built-in codecs.
+	for _, codec := range []Codec{SnappyCodec{}, ZstdCodec{}, truncatingCodec{}} {
+		cdb := NewCompressedDB(inner, codec)
+		checkValue(t, cdb, []byte("snappy"), value)
+		checkValue(t, cdb, []byte("zstd"), value)
+	}
+
+	// Custom codecs can only be read with a custom codec.
+	checkValue(t, NewCompressedDB(inner, truncatingCodec{}), []byte("custom"), append(cp(value[:len(value)-1]), 'x'))
+	_, err := NewCompressedDB(inner, SnappyCodec{}).Get([]byte("custom"))
+	require.ErrorIs(t, err, errCompressedValueInvalid)
+
+	// Codec errors are returned.
+	require.Error(t, NewCompressedDB(inner, truncatingCodec{}).Set([]byte("a"), []byte{}))
+
+	// Corrupt values are detected, also while iterating.
+	require.NoError(t, inner.Set([]byte("corrupt"), []byte{codecIDSnappy, 0xff}))
+	cdb := NewCompressedDB(inner, ZstdCodec{})
+	_, err = cdb.Get([]byte("corrupt"))
+	require.Error(t, err)
+	itr, err := cdb.Iterator([]byte("corrupt"), nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	require.Error(t, itr.Error())
+	require.NoError(t, itr.Close())
+}
+
+func BenchmarkCompressedDB(b *testing.B) {
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{
+		{"snappy", SnappyCodec{}},
+		{"zstd", ZstdCodec{}},
+	}
+	for _, size := range []int{1 << 10, 100 << 10} {
+		value := compressibleValue(rand.New(rand.NewSource(1)), size)
+		key := []byte("key")
+		b.Run(fmt.Sprintf("%dKB/none", size>>10), func(b *testing.B) {
+			db := NewMemDB()
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if err := db.Set(key, value); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := db.Get(key); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, c := range codecs {
+			c := c
+			b.Run(fmt.Sprintf("%dKB/%s", size>>10, c.name), func(b *testing.B) {
+				inner := NewMemDB()
+				cdb := NewCompressedDB(inner, c.codec)
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					if err := cdb.Set(key, value); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := cdb.Get(key); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StopTimer()
+				stored, err := inner.Get(key)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(len(stored))/float64(size), "stored/raw")
+			})
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+)
+
+// contextIterator wraps an Iterator, and invalidates it once its context is done.
+type contextIterator struct {
+	ctx    context.Context
+	source Iterator
+	err    error
+}
+
+var _ Iterator = (*contextIterator)(nil)
+
+// iteratorWithContext implements DB.IteratorWithContext on top of DB.Iterator.
+func iteratorWithContext(ctx context.Context, db DB, start, end []byte) (Iterator, error) {
+	source, err := db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newContextIterator(ctx, source), nil
+}
+
+func newContextIterator(ctx context.Context, source Iterator) *contextIterator {
+	itr := &contextIterator{ctx: ctx, source: source}
+	itr.checkContext()
+	return itr
+}
+
+// checkContext invalidates the iterator if its context is done.
+func (itr *contextIterator) checkContext() {
+	select {
+	case <-itr.ctx.Done():
+		itr.err = itr.ctx.Err()
+	default:
+	}
+}
+
+// Domain implements Iterator.
+func (itr *contextIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *contextIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *contextIterator) Next() {
+	itr.assertIsValid()
+	itr.checkContext()
+	if itr.err != nil {
+		return
+	}
+	itr.source.Next()
+}
+
+// Seek implements Iterator.
+func (itr *contextIterator) Seek(key []byte) bool {
+	itr.checkContext()
+	if itr.err != nil {
+		return false
+	}
+	return itr.source.Seek(key)
+}
+
+// Key implements Iterator.
+func (itr *contextIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *contextIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()
+}
+
+// Position implements Iterator.
+func (itr *contextIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *contextIterator) Error() error {
+	if err := itr.source.Error(); err != nil {
+		return err
+	}
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *contextIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *contextIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errCounterOverflow is returned when an increment would overflow a counter.
+var errCounterOverflow = errors.New("counter overflow")
+
+// CounterDB maintains integer counters in a database, stored as 8-byte big-endian int64 values.
+// Increments are atomic with respect to other increments through the same CounterDB, by locking
+// the key with a LockManager while its value is read and written, but not with respect to writes
+// made directly to the database.
+type CounterDB struct {
+	db    DB
+	locks LockManager
+}
+
+// NewCounterDB creates a CounterDB storing counters in the given database.
+func NewCounterDB(db DB) *CounterDB {
+	return &CounterDB{db: db}
+}
+
+// Increment adds delta, which may be negative, to the counter of the given key, returning its new
+// value. Missing counters start at 0. An error is returned if the counter would overflow.
+func (cdb *CounterDB) Increment(key []byte, delta int64) (int64, error) {
+	cdb.locks.Lock(key)
+	defer cdb.locks.Unlock(key)
+
+	value, err := cdb.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if (delta > 0 && value > math.MaxInt64-delta) || (delta < 0 && value < math.MinInt64-delta) {
+		return 0, fmt.Errorf("%w: counter %X = %d, delta %d", errCounterOverflow, key, value, delta)
+	}
+	value += delta
+	var bz [8]byte
+	binary.BigEndian.PutUint64(bz[:], uint64(value))
+	if err := cdb.db.Set(key, bz[:]); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// Get returns the value of the counter of the given key, or 0 if it does not exist.
+func (cdb *CounterDB) Get(key []byte) (int64, error) {
+	bz, err := cdb.db.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if bz == nil {
+		return 0, nil
+	}
+	if len(bz) != 8 {
+		return 0, fmt.Errorf("invalid counter %X: expected 8 bytes, got %d", key, len(bz))
+	}
+	return int64(binary.BigEndian.Uint64(bz)), nil
+}
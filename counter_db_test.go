@@ -0,0 +1,91 @@
+package db
+
+import (
+	"math"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterDB(t *testing.T) {
+	db := NewMemDB()
+	cdb := NewCounterDB(db)
+
+	value, err := cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Zero(t, value)
+	value, err = cdb.Increment([]byte("a"), 5)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, value)
+	value, err = cdb.Increment([]byte("a"), -7)
+	require.NoError(t, err)
+	assert.EqualValues(t, -2, value)
+	value, err = cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.EqualValues(t, -2, value)
+	checkValue(t, db, []byte("a"), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe})
+
+	// Overflowing increments fail, and leave the counter unchanged.
+	_, err = cdb.Increment([]byte("b"), math.MaxInt64)
+	require.NoError(t, err)
+	_, err = cdb.Increment([]byte("b"), 1)
+	require.ErrorIs(t, err, errCounterOverflow)
+	_, err = cdb.Increment([]byte("a"), math.MinInt64)
+	require.ErrorIs(t, err, errCounterOverflow)
+	value, err = cdb.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.EqualValues(t, -2, value)
+
+	// Values which are not counters are rejected.
+	require.NoError(t, db.Set([]byte("c"), []byte{1}))
+	_, err = cdb.Get([]byte("c"))
+	require.Error(t, err)
+	_, err = cdb.Increment([]byte("c"), 1)
+	require.Error(t, err)
+	_, err = cdb.Increment(nil, 1)
+	require.Error(t, err)
+}
+
+func TestCounterDBConcurrent(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+			cdb := NewCounterDB(db)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 1000)
+			for i := 0; i < 1000; i++ {
+				wg.Add(1)
+				key := []byte("even")
+				if i%2 == 1 {
+					key = []byte("odd")
+				}
+				go func() {
+					defer wg.Done()
+					if _, err := cdb.Increment([]byte("total"), 1); err != nil {
+						errs <- err
+					}
+					if _, err := cdb.Increment(key, 1); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+
+			for key, expect := range map[string]int64{"total": 1000, "even": 500, "odd": 500} {
+				value, err := cdb.Get([]byte(key))
+				require.NoError(t, err)
+				assert.Equal(t, expect, value, key)
+			}
+		})
+	}
+}
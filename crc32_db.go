@@ -0,0 +1,368 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// crc32Size is the size of the checksum appended to each value stored by CRC32DB.
+const crc32Size = crc32.Size
+
+// crc32Table is the Castagnoli (CRC32C) table used by CRC32DB.
+var crc32Table = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksumMismatch is returned when a value stored by CRC32DB does not match its checksum,
+// because it has been corrupted or was not written through the CRC32DB.
+var ErrChecksumMismatch = errors.New("value checksum mismatch")
+
+// CRC32DB wraps a database, and appends a CRC32C checksum to every stored value, to detect values
+// silently corrupted by the storage layer. Values are verified when read with Get, MultiGet or an
+// iterator; Has only checks for the presence of a key, and does not verify its value.
+//
+// The wrapped database must only be written through the CRC32DB.
+type CRC32DB struct {
+	db DB
+}
+
+var _ DB = (*CRC32DB)(nil)
+
+// NewCRC32DB creates a CRC32DB wrapping the given database.
+func NewCRC32DB(inner DB) *CRC32DB {
+	return &CRC32DB{db: inner}
+}
+
+// checksum appends the checksum of a value to it.
+func (cdb *CRC32DB) checksum(value []byte) []byte {
+	if value == nil {
+		return nil // let the wrapped database reject it
+	}
+	stored := make([]byte, len(value)+crc32Size)
+	copy(stored, value)
+	binary.BigEndian.PutUint32(stored[len(value):], crc32.Checksum(value, crc32Table))
+	return stored
+}
+
+// verify verifies the checksum of a stored value for the given key, and returns the value without
+// it. Missing values are returned as nil.
+func (cdb *CRC32DB) verify(key, stored []byte) ([]byte, error) {
+	if stored == nil {
+		return nil, nil
+	}
+	if len(stored) < crc32Size {
+		return nil, fmt.Errorf("key %X: %w", key, ErrChecksumMismatch)
+	}
+	value, sum := stored[:len(stored)-crc32Size], stored[len(stored)-crc32Size:]
+	if crc32.Checksum(value, crc32Table) != binary.BigEndian.Uint32(sum) {
+		return nil, fmt.Errorf("key %X: %w", key, ErrChecksumMismatch)
+	}
+	return value, nil
+}
+
+// Get implements DB.
+func (cdb *CRC32DB) Get(key []byte) ([]byte, error) {
+	stored, err := cdb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return cdb.verify(key, stored)
+}
+
+// Has implements DB. The value is not verified.
+func (cdb *CRC32DB) Has(key []byte) (bool, error) {
+	return cdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (cdb *CRC32DB) MultiGet(keys [][]byte) ([][]byte, error) {
+	stored, err := cdb.db.MultiGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(stored))
+	for i, bz := range stored {
+		values[i], err = cdb.verify(keys[i], bz)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Set implements DB.
+func (cdb *CRC32DB) Set(key []byte, value []byte) error {
+	return cdb.db.Set(key, cdb.checksum(value))
+}
+
+// SetSync implements DB.
+func (cdb *CRC32DB) SetSync(key []byte, value []byte) error {
+	return cdb.db.SetSync(key, cdb.checksum(value))
+}
+
+// Delete implements DB.
+func (cdb *CRC32DB) Delete(key []byte) error {
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *CRC32DB) DeleteSync(key []byte) error {
+	return cdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB. It is atomic if the wrapped database's CompareAndSet is.
+func (cdb *CRC32DB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if newVal == nil {
+		return false, errValueNil
+	}
+	stored, err := cdb.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	current, err := cdb.verify(key, stored)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	// Compare against the stored value, so that concurrent writes make the swap fail.
+	return cdb.db.CompareAndSet(key, stored, cdb.checksum(newVal))
+}
+
+// DeleteRange implements DB.
+func (cdb *CRC32DB) DeleteRange(start, end []byte) error {
+	return cdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (cdb *CRC32DB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCRC32DBIterator(cdb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (cdb *CRC32DB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCRC32DBIterator(cdb, itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (cdb *CRC32DB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, cdb, start, end)
+}
+
+// Compact implements DB.
+func (cdb *CRC32DB) Compact(start, end []byte) error {
+	return cdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (cdb *CRC32DB) WriteBatch(ops []BatchOp) error {
+	return cdb.db.WriteBatch(cdb.checksumOps(ops))
+}
+
+// WriteBatchSync implements DB.
+func (cdb *CRC32DB) WriteBatchSync(ops []BatchOp) error {
+	return cdb.db.WriteBatchSync(cdb.checksumOps(ops))
+}
+
+// checksumOps appends checksums to the values of set operations.
+func (cdb *CRC32DB) checksumOps(ops []BatchOp) []BatchOp {
+	checksummed := make([]BatchOp, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			op.Value = cdb.checksum(op.Value)
+		}
+		checksummed = append(checksummed, op)
+	}
+	return checksummed
+}
+
+// ApplyLog implements DB.
+func (cdb *CRC32DB) ApplyLog(ops BatchOpList) error {
+	return cdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (cdb *CRC32DB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(cdb, fn)
+}
+
+// Close implements DB.
+func (cdb *CRC32DB) Close() error {
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CRC32DB) NewBatch() Batch {
+	return newCRC32DBBatch(cdb, cdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (cdb *CRC32DB) NewBatchWithSize(expectedOps int) Batch {
+	return newCRC32DBBatch(cdb, cdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (cdb *CRC32DB) Print() error {
+	itr, err := cdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (cdb *CRC32DB) Stats() map[string]string {
+	return cdb.db.Stats()
+}
+
+// crc32DBBatch wraps a batch, appending checksums to values.
+type crc32DBBatch struct {
+	db    *CRC32DB
+	batch Batch
+}
+
+var _ Batch = (*crc32DBBatch)(nil)
+
+func newCRC32DBBatch(db *CRC32DB, batch Batch) *crc32DBBatch {
+	return &crc32DBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *crc32DBBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, b.db.checksum(value))
+}
+
+// Delete implements Batch.
+func (b *crc32DBBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *crc32DBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *crc32DBBatch) Write() error {
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *crc32DBBatch) WriteSync() error {
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *crc32DBBatch) Close() error {
+	return b.batch.Close()
+}
+
+// crc32DBIterator wraps an iterator, verifying values. A value which fails verification
+// invalidates the iterator, and is reported by Error.
+type crc32DBIterator struct {
+	db     *CRC32DB
+	source Iterator
+	value  []byte
+	err    error
+}
+
+var _ Iterator = (*crc32DBIterator)(nil)
+
+func newCRC32DBIterator(db *CRC32DB, source Iterator) *crc32DBIterator {
+	itr := &crc32DBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.verifyValue()
+	return itr
+}
+
+// verifyValue verifies the value at the current position of the source, so that a corrupted value
+// is never returned by Value.
+func (itr *crc32DBIterator) verifyValue() {
+	itr.value = nil
+	if itr.err != nil || !itr.source.Valid() {
+		return
+	}
+	itr.value, itr.err = itr.db.verify(itr.source.Key(), itr.source.Value())
+}
+
+// Domain implements Iterator.
+func (itr *crc32DBIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *crc32DBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *crc32DBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.verifyValue()
+}
+
+// Seek implements Iterator.
+func (itr *crc32DBIterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	itr.source.Seek(key)
+	itr.verifyValue()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *crc32DBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *crc32DBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Position implements Iterator.
+func (itr *crc32DBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *crc32DBIterator) Error() error {
+	if err := itr.source.Error(); err != nil {
+		return err
+	}
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *crc32DBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *crc32DBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
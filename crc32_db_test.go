@@ -0,0 +1,84 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC32DBRoundtrip(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCRC32DB(inner)
+	defer cdb.Close()
+
+	require.NoError(t, cdb.Set([]byte("a"), []byte("value")))
+	require.NoError(t, cdb.SetSync([]byte("b"), []byte{}))
+	batch := cdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte("batched")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.NoError(t, cdb.WriteBatch([]BatchOp{{Key: []byte("d"), Value: []byte("written")}}))
+
+	assertKeyValues(t, cdb, map[string][]byte{
+		"a": []byte("value"),
+		"b": {},
+		"c": []byte("batched"),
+		"d": []byte("written"),
+	})
+	checkValue(t, cdb, []byte("b"), []byte{})
+	values, err := cdb.MultiGet([][]byte{[]byte("a"), []byte("x")})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("value"), nil}, values)
+
+	stored, err := inner.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Len(t, stored, len("value")+crc32Size)
+	assert.Equal(t, []byte("value"), stored[:len("value")])
+
+	swapped, err := cdb.CompareAndSet([]byte("a"), []byte("value"), []byte("changed"))
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = cdb.CompareAndSet([]byte("a"), []byte("value"), []byte("again"))
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	checkValue(t, cdb, []byte("a"), []byte("changed"))
+}
+
+func TestCRC32DBCorrupted(t *testing.T) {
+	inner := NewMemDB()
+	cdb := NewCRC32DB(inner)
+	require.NoError(t, cdb.Set([]byte("a"), []byte("value")))
+
+	// Corrupt the checksum stored in the last 4 bytes of the value.
+	stored, err := inner.Get([]byte("a"))
+	require.NoError(t, err)
+	corrupted := cp(stored)
+	for i := len(corrupted) - crc32Size; i < len(corrupted); i++ {
+		corrupted[i] ^= 0xff
+	}
+	require.NoError(t, inner.Set([]byte("a"), corrupted))
+
+	_, err = cdb.Get([]byte("a"))
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = cdb.MultiGet([][]byte{[]byte("a")})
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = cdb.CompareAndSet([]byte("a"), []byte("value"), []byte("changed"))
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// Has does not verify the value.
+	ok, err := cdb.Has([]byte("a"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	require.ErrorIs(t, itr.Error(), ErrChecksumMismatch)
+	require.NoError(t, itr.Close())
+
+	// So is a value too short to hold a checksum.
+	require.NoError(t, inner.Set([]byte("a"), []byte("ab")))
+	_, err = cdb.Get([]byte("a"))
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+}
@@ -1,8 +1,12 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type BackendType string
@@ -33,11 +37,84 @@ const (
 	//   - requires gcc
 	//   - use rocksdb build tag (go build -tags rocksdb)
 	RocksDBBackend BackendType = "rocksdb"
-
+	// BadgerDBBackend represents badger (uses github.com/dgraph-io/badger)
+	//   - EXPERIMENTAL
+	//   - pure go
+	//   - use badgerdb build tag (go build -tags badgerdb)
 	BadgerDBBackend BackendType = "badgerdb"
+	// PebbleDBBackend represents pebble (uses github.com/cockroachdb/pebble)
+	//   - EXPERIMENTAL
+	//   - pure go
+	//   - use pebbledb build tag (go build -tags pebbledb)
+	PebbleDBBackend BackendType = "pebbledb"
+	// SQLiteDBBackend represents sqlite (uses modernc.org/sqlite)
+	//   - EXPERIMENTAL
+	//   - pure go
+	//   - use sqlite build tag (go build -tags sqlite)
+	SQLiteDBBackend BackendType = "sqlite"
+	// RedisDBBackend represents redis (uses github.com/redis/go-redis)
+	//   - EXPERIMENTAL
+	//   - stores data on a Redis server, whose address is given as the directory
+	//   - use redis build tag (go build -tags redis)
+	RedisDBBackend BackendType = "redis"
+)
+
+// errReadOnlyUnsupported is returned when opening a database in read-only mode with a backend that
+// does not support it.
+var errReadOnlyUnsupported = errors.New("read-only mode is not supported by this backend")
+
+var (
+	// ErrDBAlreadyExists is returned by Open when Options.ErrorIfExists is set, and the database
+	// already exists.
+	ErrDBAlreadyExists = errors.New("database already exists")
+
+	// ErrDBNotFound is returned by Open when Options.ErrorIfMissing is set, and the database does
+	// not exist.
+	ErrDBNotFound = errors.New("database not found")
 )
 
-type dbCreator func(name string, dir string) (DB, error)
+// Options configures a database opened with Open. Backends silently ignore options they do not
+// support, except for ReadOnly.
+type Options struct {
+	// ReadOnly opens an existing database in read-only mode: writes, batches and compactions fail
+	// with ErrReadOnly. Supported by goleveldb, cleveldb, rocksdb, badgerdb and pebbledb; other
+	// backends fail to open.
+	ReadOnly bool
+	// MaxOpenFiles is the maximum number of files the database may keep open. Zero means the
+	// backend default.
+	MaxOpenFiles int
+	// BlockCacheSize is the size of the block cache in bytes. Zero means the backend default.
+	BlockCacheSize int64
+	// Logger receives log messages from the backend, such as compaction events. Nil discards
+	// them, except for badgerdb and pebbledb's own messages, which use the backend default.
+	Logger Logger
+	// ErrorIfMissing makes opening fail with ErrDBNotFound if the database does not exist, rather
+	// than creating it. Supported by goleveldb, rocksdb and memdb.
+	ErrorIfMissing bool
+	// ErrorIfExists makes opening fail with ErrDBAlreadyExists if the database already exists.
+	// Supported by goleveldb, rocksdb and memdb.
+	ErrorIfExists bool
+}
+
+// checkExistence returns the error for opening a database with the given existence, if any.
+func (opts Options) checkExistence(dbPath string, exists bool) error {
+	switch {
+	case exists && opts.ErrorIfExists:
+		return fmt.Errorf("%s: %w", dbPath, ErrDBAlreadyExists)
+	case !exists && opts.ErrorIfMissing:
+		return fmt.Errorf("%s: %w", dbPath, ErrDBNotFound)
+	default:
+		return nil
+	}
+}
+
+// fileExists returns whether a file or directory exists at the given path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+type dbCreator func(name string, dir string, opts Options) (DB, error)
 
 var backends = map[BackendType]dbCreator{}
 
@@ -49,8 +126,14 @@ func registerDBCreator(backend BackendType, creator dbCreator, force bool) {
 	backends[backend] = creator
 }
 
-// NewDB creates a new database of type backend with the given name.
+// NewDB creates a new database of type backend with the given name, using default options.
 func NewDB(name string, backend BackendType, dir string) (DB, error) {
+	return Open(backend, name, dir, Options{})
+}
+
+// Open opens a database of type backend with the given name and options. The database is listed
+// in GlobalRegistry until it is closed.
+func Open(backend BackendType, name, dir string, opts Options) (DB, error) {
 	dbCreator, ok := backends[backend]
 	if !ok {
 		keys := make([]string, 0, len(backends))
@@ -61,9 +144,65 @@ func NewDB(name string, backend BackendType, dir string) (DB, error) {
 			backend, strings.Join(keys, ","))
 	}
 
-	db, err := dbCreator(name, dir)
+	db, err := dbCreator(name, dir, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	GlobalRegistry.register(db, name, backend, dir)
 	return db, nil
 }
+
+// openRetryBaseDelay is the delay before the first retry of OpenWithRetry, doubled for every
+// further retry.
+const openRetryBaseDelay = 100 * time.Millisecond
+
+// OpenWithRetry opens a database like NewDB, making up to retries attempts with exponential
+// backoff, e.g. while another process still holds the database lock. If all but the last attempt
+// fail, the lock file is removed before the last attempt, to recover from a process which crashed
+// without releasing it.
+//
+// Removing the lock of a database which is still open in another process risks corrupting it, so
+// this should only be used when no other process can be using the database.
+func OpenWithRetry(backend BackendType, name, dir string, retries int) (DB, error) {
+	return OpenWithRetryOptions(backend, name, dir, retries, Options{})
+}
+
+// OpenWithRetryOptions is like OpenWithRetry, but opens the database with the given options like
+// Open. Each retry is logged to opts.Logger.
+func OpenWithRetryOptions(backend BackendType, name, dir string, retries int, opts Options) (DB, error) {
+	return openWithRetry(backend, name, dir, retries, opts, time.Sleep)
+}
+
+func openWithRetry(backend BackendType, name, dir string, retries int, opts Options,
+	sleep func(time.Duration)) (DB, error) {
+	logger := loggerOrNop(opts.Logger)
+	delay := openRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		db, err := Open(backend, name, dir, opts)
+		if err == nil || attempt >= retries {
+			return db, err
+		}
+		logger.Info("failed to open database, retrying", "backend", backend, "name", name,
+			"attempt", attempt, "delay", delay, "err", err)
+		sleep(delay)
+		delay *= 2
+
+		if attempt == retries-1 {
+			lock := lockFilePath(backend, name, dir)
+			logger.Error("removing database lock file before last attempt", "backend", backend,
+				"name", name, "path", lock)
+			if err := os.Remove(lock); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove lock file: %w", err)
+			}
+		}
+	}
+}
+
+// lockFilePath returns the path of the lock file of a database. Backends storing the database in
+// a single file, such as boltdb, have no lock file, and the returned path does not exist.
+func lockFilePath(backend BackendType, name, dir string) string {
+	if backend == BadgerDBBackend {
+		return filepath.Join(dir, name, "LOCK")
+	}
+	return filepath.Join(dir, name+".db", "LOCK")
+}
@@ -137,3 +137,127 @@ func TestDBIteratorNonemptyBeginAfter(t *testing.T) {
 		})
 	}
 }
+
+func TestDBReverseIteratorEmpty(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			itr, err := db.ReverseIterator(nil, nil)
+			assert.NoError(t, err)
+
+			checkInvalid(t, itr)
+		})
+	}
+}
+
+func TestDBReverseIteratorSingleKey(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			err := db.SetSync(bz("1"), bz("value_1"))
+			assert.NoError(t, err)
+			itr, err := db.ReverseIterator(nil, nil)
+			assert.NoError(t, err)
+
+			checkValid(t, itr, true)
+			checkItem(t, itr, bz("1"), bz("value_1"))
+			checkNext(t, itr, false)
+			checkValid(t, itr, false)
+			checkNextPanics(t, itr)
+
+			// Once invalid...
+			checkInvalid(t, itr)
+		})
+	}
+}
+
+func TestDBReverseIteratorTwoKeys(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			err := db.SetSync(bz("1"), bz("value_1"))
+			assert.NoError(t, err)
+
+			err = db.SetSync(bz("2"), bz("value_2"))
+			assert.NoError(t, err)
+
+			{ // Fail by calling Next too much
+				itr, err := db.ReverseIterator(nil, nil)
+				assert.NoError(t, err)
+				checkValid(t, itr, true)
+				checkItem(t, itr, bz("2"), bz("value_2"))
+
+				checkNext(t, itr, true)
+				checkValid(t, itr, true)
+				checkItem(t, itr, bz("1"), bz("value_1"))
+
+				checkNext(t, itr, false)
+				checkValid(t, itr, false)
+
+				checkNextPanics(t, itr)
+
+				// Once invalid...
+				checkInvalid(t, itr)
+			}
+		})
+	}
+}
+
+func TestDBReverseIteratorRange(t *testing.T) {
+	testcases := map[string]struct {
+		start  []byte
+		end    []byte
+		expect []string
+	}{
+		"full range":             {nil, nil, []string{"4", "3", "2", "1"}},
+		"nil start":              {nil, bz("3"), []string{"2", "1"}},
+		"nil end":                {bz("2"), nil, []string{"4", "3", "2"}},
+		"start and end":          {bz("2"), bz("4"), []string{"3", "2"}},
+		"end is exclusive":       {bz("3"), bz("4"), []string{"3"}},
+		"start equals end":       {bz("3"), bz("3"), nil},
+		"gap between keys":       {bz("22"), bz("25"), nil},
+		"start after last key":   {bz("5"), nil, nil},
+		"end before first key":   {nil, bz("0"), nil},
+		"bounds outside of keys": {bz("0"), bz("5"), []string{"4", "3", "2", "1"}},
+	}
+
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			for _, k := range []string{"1", "2", "3", "4"} {
+				err := db.SetSync(bz(k), bz("value_"+k))
+				assert.NoError(t, err)
+			}
+
+			for name, tc := range testcases {
+				tc := tc
+				t.Run(name, func(t *testing.T) {
+					itr, err := db.ReverseIterator(tc.start, tc.end)
+					assert.NoError(t, err)
+					defer itr.Close()
+					checkDomain(t, itr, tc.start, tc.end)
+
+					for i, k := range tc.expect {
+						if i > 0 {
+							checkNext(t, itr, true)
+						}
+						checkValid(t, itr, true)
+						checkItem(t, itr, bz(k), bz("value_"+k))
+					}
+					if len(tc.expect) > 0 {
+						checkNext(t, itr, false)
+					}
+					checkInvalid(t, itr)
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package dbtest contains helpers for testing and benchmarking tm-db backends. It is a separate
+// package so that importers of tm-db do not link the testing package.
+package dbtest
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	db "github.com/tendermint/tm-db"
+)
+
+const (
+	// benchmarkSuiteKeys is the number of keys loaded for the read and scan benchmarks.
+	benchmarkSuiteKeys = 100000
+	// benchmarkSuitePrefixes is the number of prefixes the loaded keys are spread across.
+	benchmarkSuitePrefixes = 100
+	// benchmarkSuiteValueSize is the size of every value written.
+	benchmarkSuiteValueSize = 100
+)
+
+// BenchmarkSuite runs a standard set of benchmarks against a database, giving a baseline for
+// comparing backends: sequential writes, random reads, iterator and prefix scans, and batch
+// writes of 1000, 10000 and 100000 keys. Each benchmark reports ops/s and B/s metrics, where an
+// op is a key read or written.
+//
+// The database should be empty. The benchmarks write to it, and do not clean up afterwards.
+// Backend benchmarks call it as e.g. dbtest.BenchmarkSuite(b, newBackend()).
+func BenchmarkSuite(b *testing.B, database db.DB) {
+	value := make([]byte, benchmarkSuiteValueSize)
+	rand.Read(value) // nolint:gosec // G404: Use of weak random number generator
+
+	// Load the keys used by the read and scan benchmarks, as prefix/index.
+	batch := database.NewBatch()
+	keys := make([][]byte, 0, benchmarkSuiteKeys)
+	for i := 0; i < benchmarkSuiteKeys; i++ {
+		key := benchmarkKey(benchmarkPrefix(i%benchmarkSuitePrefixes), uint64(i))
+		keys = append(keys, key)
+		if err := batch.Set(key, value); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		b.Fatal(err)
+	}
+	if err := batch.Close(); err != nil {
+		b.Fatal(err)
+	}
+	keySize := len(keys[0])
+
+	b.Run("SequentialWrites", func(b *testing.B) {
+		prefix := []byte("seq/")
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if err := database.Set(benchmarkKey(prefix, uint64(i)), value); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportThroughput(b, int64(b.N), int64(b.N)*int64(len(prefix)+8+len(value)), time.Since(start))
+	})
+
+	b.Run("RandomReads", func(b *testing.B) {
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			v, err := database.Get(keys[rand.Intn(len(keys))]) // nolint:gosec // G404
+			if err != nil {
+				b.Fatal(err)
+			} else if v == nil {
+				b.Fatal("value not found")
+			}
+		}
+		reportThroughput(b, int64(b.N), int64(b.N)*int64(keySize+len(value)), time.Since(start))
+	})
+
+	b.Run("IteratorScan", func(b *testing.B) {
+		var ops, size int64
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			// All loaded keys start with "p", unlike the keys written by other benchmarks.
+			itr, err := database.Iterator([]byte("p"), []byte("q"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			n, s, err := benchmarkScan(itr)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ops += n
+			size += s
+		}
+		reportThroughput(b, ops, size, time.Since(start))
+	})
+
+	b.Run("PrefixScan", func(b *testing.B) {
+		var ops, size int64
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			itr, err := db.IteratePrefix(database, benchmarkPrefix(rand.Intn(benchmarkSuitePrefixes))) // nolint:gosec // G404
+			if err != nil {
+				b.Fatal(err)
+			}
+			n, s, err := benchmarkScan(itr)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ops += n
+			size += s
+		}
+		reportThroughput(b, ops, size, time.Since(start))
+	})
+
+	for _, size := range []int{1000, 10000, 100000} {
+		size := size
+		b.Run("BatchWrite/"+strconv.Itoa(size), func(b *testing.B) {
+			prefix := []byte("batch/")
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				batch := database.NewBatchWithSize(size)
+				for j := 0; j < size; j++ {
+					if err := batch.Set(benchmarkKey(prefix, uint64(j)), value); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := batch.Write(); err != nil {
+					b.Fatal(err)
+				}
+				if err := batch.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			ops := int64(b.N) * int64(size)
+			reportThroughput(b, ops, ops*int64(len(prefix)+8+len(value)), time.Since(start))
+		})
+	}
+}
+
+// benchmarkPrefix returns the key prefix with the given index.
+func benchmarkPrefix(i int) []byte {
+	return []byte{'p', byte('0' + i/10), byte('0' + i%10), '/'}
+}
+
+// benchmarkKey returns a new key made of a prefix followed by a big-endian index.
+func benchmarkKey(prefix []byte, i uint64) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], i)
+	return key
+}
+
+// benchmarkScan reads and closes an iterator, returning the number of items and their size.
+func benchmarkScan(itr db.Iterator) (n int64, size int64, err error) {
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		n++
+		size += int64(len(itr.Key()) + len(itr.Value()))
+	}
+	return n, size, itr.Error()
+}
+
+// reportThroughput reports the ops/s and B/s metrics of a benchmark.
+func reportThroughput(b *testing.B, ops, size int64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	b.ReportMetric(float64(ops)/elapsed.Seconds(), "ops/s")
+	b.ReportMetric(float64(size)/elapsed.Seconds(), "B/s")
+}
@@ -0,0 +1,119 @@
+package db
+
+import "fmt"
+
+// DedupBatch wraps a batch, and buffers its operations so that only the last operation for each
+// key is passed on to the wrapped batch when written, reducing write amplification for callers
+// which set the same key repeatedly. Operations are passed on in the order of their last
+// occurrence.
+//
+// Until written, operations are only buffered, so Len returns the number of distinct keys which
+// will be passed on.
+type DedupBatch struct {
+	batch Batch
+	ops   []operation
+	index map[string]int // index of the last operation for each key in ops
+}
+
+var _ Batch = (*DedupBatch)(nil)
+
+// NewDedupBatch creates a DedupBatch wrapping the given batch.
+func NewDedupBatch(batch Batch) *DedupBatch {
+	return &DedupBatch{
+		batch: batch,
+		ops:   []operation{},
+		index: make(map[string]int),
+	}
+}
+
+// add buffers an operation, superseding any earlier operation on the same key.
+func (b *DedupBatch) add(op operation) {
+	if i, ok := b.index[string(op.key)]; ok {
+		b.ops[i].opType = 0 // superseded
+	}
+	b.index[string(op.key)] = len(b.ops)
+	b.ops = append(b.ops, op)
+}
+
+// Set implements Batch.
+func (b *DedupBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.add(operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *DedupBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.add(operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Len implements Batch.
+func (b *DedupBatch) Len() int {
+	return len(b.index)
+}
+
+// Write implements Batch.
+func (b *DedupBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *DedupBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *DedupBatch) write(sync bool) error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case 0:
+			continue
+		case opTypeSet:
+			err = b.batch.Set(op.key, op.value)
+		case opTypeDelete:
+			err = b.batch.Delete(op.key)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if sync {
+		err = b.batch.WriteSync()
+	} else {
+		err = b.batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *DedupBatch) Close() error {
+	b.ops = nil
+	b.index = nil
+	return b.batch.Close()
+}
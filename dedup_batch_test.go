@@ -0,0 +1,51 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupBatch(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("a"), []byte{0}))
+	require.NoError(t, db.Set([]byte("b"), []byte{0}))
+	inner := newMockBatch(db.NewBatch())
+	batch := NewDedupBatch(inner)
+
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("a"), []byte{2}))
+	require.NoError(t, batch.Set([]byte("a"), []byte{3}))
+	// A set after a delete only submits the set.
+	require.NoError(t, batch.Delete([]byte("c")))
+	require.NoError(t, batch.Set([]byte("c"), []byte{4}))
+	// A delete after a set only submits the delete.
+	require.NoError(t, batch.Set([]byte("b"), []byte{5}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	assert.Equal(t, 3, batch.Len())
+	assert.Empty(t, inner.calls, "operations must be buffered until written")
+
+	require.NoError(t, batch.Write())
+	assert.Equal(t, map[string]int{"Set": 2, "Delete": 1, "Write": 1, "Close": 1}, inner.calls)
+	assert.Equal(t, 0, batch.Len())
+	assertKeyValues(t, db, map[string][]byte{"a": {3}, "c": {4}})
+
+	assert.Equal(t, errBatchClosed, batch.Set([]byte("d"), []byte{1}))
+	assert.Equal(t, errBatchClosed, batch.Delete([]byte("d")))
+	require.NoError(t, batch.Close())
+	require.NoError(t, batch.Close())
+}
+
+func TestDedupBatchOrder(t *testing.T) {
+	db := NewMemDB()
+	inner := &recordingBatch{Batch: db.NewBatch()}
+	batch := NewDedupBatch(inner)
+
+	require.NoError(t, batch.Set([]byte("b"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	assert.Equal(t, []string{"set:a", "set:b"}, inner.keys)
+}
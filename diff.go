@@ -0,0 +1,105 @@
+package db
+
+import (
+	"bytes"
+)
+
+// DiffOp is the kind of change made to a key, as reported by Diff.
+type DiffOp uint8
+
+const (
+	// DiffAdded is a key which only exists in the new database.
+	DiffAdded DiffOp = iota + 1
+	// DiffModified is a key which exists in both databases, with different values.
+	DiffModified
+	// DiffDeleted is a key which only exists in the old database.
+	DiffDeleted
+)
+
+// String implements fmt.Stringer.
+func (op DiffOp) String() string {
+	switch op {
+	case DiffAdded:
+		return "added"
+	case DiffModified:
+		return "modified"
+	case DiffDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// DiffEntry is a key which differs between two databases. OldValue is nil for added keys, and
+// NewValue is nil for deleted keys.
+type DiffEntry struct {
+	Key      []byte
+	OldValue []byte
+	NewValue []byte
+	Op       DiffOp
+}
+
+// Diff returns the keys which differ between an old database a and a new database b, in
+// ascending key order. Both databases are iterated together, so it takes linear time in their
+// total size. Neither database must be written to while the diff is computed; use snapshots of
+// live databases.
+func Diff(a, b DB) ([]DiffEntry, error) {
+	itrA, err := a.Iterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer itrA.Close()
+	itrB, err := b.Iterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer itrB.Close()
+
+	var entries []DiffEntry
+	for itrA.Valid() || itrB.Valid() {
+		cmp := 0
+		switch {
+		case !itrA.Valid():
+			cmp = 1
+		case !itrB.Valid():
+			cmp = -1
+		default:
+			cmp = bytes.Compare(itrA.Key(), itrB.Key())
+		}
+
+		switch {
+		case cmp < 0:
+			entries = append(entries, DiffEntry{
+				Key:      cp(itrA.Key()),
+				OldValue: cp(itrA.Value()),
+				Op:       DiffDeleted,
+			})
+			itrA.Next()
+		case cmp > 0:
+			entries = append(entries, DiffEntry{
+				Key:      cp(itrB.Key()),
+				NewValue: cp(itrB.Value()),
+				Op:       DiffAdded,
+			})
+			itrB.Next()
+		default:
+			if !bytes.Equal(itrA.Value(), itrB.Value()) {
+				entries = append(entries, DiffEntry{
+					Key:      cp(itrA.Key()),
+					OldValue: cp(itrA.Value()),
+					NewValue: cp(itrB.Value()),
+					Op:       DiffModified,
+				})
+			}
+			itrA.Next()
+			itrB.Next()
+		}
+	}
+	if err := itrA.Error(); err != nil {
+		return nil, err
+	}
+	if err := itrB.Error(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
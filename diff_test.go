@@ -0,0 +1,71 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	a := NewMemDB()
+	b := NewMemDB()
+	for _, kv := range []struct{ key, value string }{
+		{"a", "1"}, {"b", "2"}, {"c", "3"}, {"e", "5"}, {"g", "7"},
+	} {
+		require.NoError(t, a.Set([]byte(kv.key), []byte(kv.value)))
+	}
+	for _, kv := range []struct{ key, value string }{
+		{"b", "2"}, {"c", "30"}, {"d", "4"}, {"e", "5"}, {"h", "8"},
+	} {
+		require.NoError(t, b.Set([]byte(kv.key), []byte(kv.value)))
+	}
+
+	entries, err := Diff(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []DiffEntry{
+		{Key: []byte("a"), OldValue: []byte("1"), Op: DiffDeleted},
+		{Key: []byte("c"), OldValue: []byte("3"), NewValue: []byte("30"), Op: DiffModified},
+		{Key: []byte("d"), NewValue: []byte("4"), Op: DiffAdded},
+		{Key: []byte("g"), OldValue: []byte("7"), Op: DiffDeleted},
+		{Key: []byte("h"), NewValue: []byte("8"), Op: DiffAdded},
+	}, entries)
+	for i := 1; i < len(entries); i++ {
+		assert.Equal(t, -1, bytes.Compare(entries[i-1].Key, entries[i].Key))
+	}
+
+	// The reverse diff swaps additions and deletions.
+	entries, err = Diff(b, a)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	assert.Equal(t, DiffAdded, entries[0].Op)
+	assert.Equal(t, DiffModified, entries[1].Op)
+	assert.Equal(t, DiffDeleted, entries[2].Op)
+
+	// Identical and empty databases have no diff.
+	entries, err = Diff(a, a)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = Diff(NewMemDB(), NewMemDB())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Everything is added to an empty database.
+	entries, err = Diff(NewMemDB(), b)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	for _, entry := range entries {
+		assert.Equal(t, DiffAdded, entry.Op)
+		assert.Nil(t, entry.OldValue)
+	}
+}
+
+func TestDiffError(t *testing.T) {
+	errIter := errors.New("iterator failed")
+	db := NewMockDB()
+	db.SetError("Iterator", errIter)
+	_, err := Diff(NewMemDB(), db)
+	require.ErrorIs(t, err, errIter)
+}
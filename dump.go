@@ -0,0 +1,142 @@
+package db
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errDumpInvalid is returned when loading data which is not a valid dump.
+var errDumpInvalid = errors.New("invalid database dump")
+
+// DBDumpRecord is a record of a dump written by DBDump: a single line of JSON per item, with the
+// key and value hex-encoded, e.g. {"k":"6b6579","v":"76616c7565"}. Unlike backups written by
+// BackupDB, dumps are meant to be inspected and processed by external tools.
+type DBDumpRecord struct {
+	Key   string `json:"k"`
+	Value string `json:"v"`
+}
+
+// DBDump writes all items of a database to w as line-delimited JSON DBDumpRecords, in ascending
+// key order. Items are streamed from an iterator, so the database does not need to fit in memory.
+func DBDump(db DB, w io.Writer) error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for ; itr.Valid(); itr.Next() {
+		record := DBDumpRecord{
+			Key:   hex.EncodeToString(itr.Key()),
+			Value: hex.EncodeToString(itr.Value()),
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DBLoad reads a dump written by DBDump into dst, writing it in batches of opts.BatchSize items,
+// so that large dumps can be loaded without reading them into memory. Existing keys in dst are
+// overwritten. If the dump is invalid, an error is returned and dst contains a partial load.
+func DBLoad(r io.Reader, dst DB, opts CopyOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCopyBatchSize
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	batch := dst.NewBatchWithSize(batchSize)
+	defer func() {
+		batch.Close()
+	}()
+	pending := 0
+	for line := 1; ; line++ {
+		var record DBDumpRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: record %d: %v", errDumpInvalid, line, err)
+		}
+		key, err := hex.DecodeString(record.Key)
+		if err != nil || len(key) == 0 {
+			return fmt.Errorf("%w: record %d: invalid key %q", errDumpInvalid, line, record.Key)
+		}
+		value, err := hex.DecodeString(record.Value)
+		if err != nil {
+			return fmt.Errorf("%w: record %d: invalid value %q", errDumpInvalid, line, record.Value)
+		}
+		if err := batch.Set(key, value); err != nil {
+			return err
+		}
+		pending++
+		if pending < batchSize {
+			continue
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+		batch = dst.NewBatchWithSize(batchSize)
+		pending = 0
+	}
+	if pending > 0 {
+		return batch.Write()
+	}
+	return nil
+}
+
+// ExportToFile writes a gzip-compressed dump of the database to the file at path, replacing any
+// existing file.
+func ExportToFile(db DB, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := DBDump(db, zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ImportFromFile reads a gzip-compressed dump written by ExportToFile into a new MemDB. The file
+// is streamed, so only the MemDB itself is held in memory.
+func ImportFromFile(path string) (*MemDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDumpInvalid, err)
+	}
+	defer zr.Close()
+
+	db := NewMemDB()
+	if err := DBLoad(zr, db, CopyOptions{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
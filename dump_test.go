@@ -0,0 +1,72 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportFile(t *testing.T) {
+	src := NewMemDB()
+	expect := map[string][]byte{}
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key%05d", i)
+		value := []byte(fmt.Sprintf("value%d", i))
+		if i%100 == 0 {
+			value = []byte{}
+		}
+		require.NoError(t, src.Set([]byte(key), value))
+		expect[key] = value
+	}
+
+	path := filepath.Join(t.TempDir(), "dump.jsonl.gz")
+	require.NoError(t, ExportToFile(src, path))
+	imported, err := ImportFromFile(path)
+	require.NoError(t, err)
+	assertKeyValues(t, imported, expect)
+
+	// The file is gzip-compressed line-delimited JSON, with hex-encoded keys and values.
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 5000)
+	assert.Equal(t, `{"k":"6b65793030303030","v":""}`, lines[0])
+	assert.Equal(t, `{"k":"6b65793030303031","v":"76616c756531"}`, lines[1])
+}
+
+func TestDBLoadInvalid(t *testing.T) {
+	dst := NewMemDB()
+	require.NoError(t, DBLoad(strings.NewReader(""), dst, CopyOptions{}))
+
+	for name, data := range map[string]string{
+		"not json":    "key value\n",
+		"bad key":     `{"k":"xyz","v":""}`,
+		"empty key":   `{"k":"","v":"01"}`,
+		"bad value":   `{"k":"01","v":"0"}`,
+		"truncated":   `{"k":"01","v":"01"}` + "\n" + `{"k":"02",`,
+		"wrong types": `{"k":1,"v":2}`,
+	} {
+		err := DBLoad(strings.NewReader(data), dst, CopyOptions{})
+		require.ErrorIs(t, err, errDumpInvalid, name)
+	}
+
+	path := filepath.Join(t.TempDir(), "plain.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"k":"01","v":"01"}`), 0o600))
+	_, err := ImportFromFile(path)
+	require.ErrorIs(t, err, errDumpInvalid)
+	_, err = ImportFromFile(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
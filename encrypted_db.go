@@ -0,0 +1,406 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	// EncryptionKeySize is the size of the key used by EncryptedDB, for AES-256.
+	EncryptionKeySize = 32
+
+	// encryptionNonceSize is the size of the random nonce stored before each ciphertext.
+	encryptionNonceSize = 12
+)
+
+// errDecryptionFailed is returned when a value stored by EncryptedDB can not be decrypted,
+// because it was encrypted with a different key, or has been tampered with.
+var errDecryptionFailed = errors.New("failed to decrypt value: wrong key or corrupted data")
+
+// EncryptedDB wraps a database, and encrypts values with AES-256-GCM. Each value is stored in the
+// wrapped database as a random nonce followed by the ciphertext. The key of each entry is used as
+// additional authenticated data, so values can not be moved between keys without being detected.
+//
+// Keys are stored in plaintext, and are iterated in their usual order; callers which need to
+// hide them must encode them some other way. The wrapped database must only be written through
+// the EncryptedDB.
+type EncryptedDB struct {
+	db   DB
+	aead cipher.AEAD
+}
+
+var _ DB = (*EncryptedDB)(nil)
+
+// NewEncryptedDB creates an EncryptedDB wrapping the given database, using the given key, which
+// must be EncryptionKeySize bytes.
+func NewEncryptedDB(inner DB, key []byte) (*EncryptedDB, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, encryptionNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedDB{
+		db:   inner,
+		aead: aead,
+	}, nil
+}
+
+// encrypt encrypts a value for the given key, prefixed by a random nonce.
+func (edb *EncryptedDB) encrypt(key, value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil // let the wrapped database reject it
+	}
+	bz := make([]byte, encryptionNonceSize, encryptionNonceSize+len(value)+edb.aead.Overhead())
+	if _, err := rand.Read(bz); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return edb.aead.Seal(bz, bz, value, key), nil
+}
+
+// decrypt decrypts a stored value for the given key. Missing values are returned as nil.
+func (edb *EncryptedDB) decrypt(key, stored []byte) ([]byte, error) {
+	if stored == nil {
+		return nil, nil
+	}
+	if len(stored) < encryptionNonceSize {
+		return nil, fmt.Errorf("key %X: %w", key, errDecryptionFailed)
+	}
+	nonce, ciphertext := stored[:encryptionNonceSize], stored[encryptionNonceSize:]
+	value, err := edb.aead.Open(make([]byte, 0, len(ciphertext)), nonce, ciphertext, key)
+	if err != nil {
+		return nil, fmt.Errorf("key %X: %w", key, errDecryptionFailed)
+	}
+	return value, nil
+}
+
+// Get implements DB.
+func (edb *EncryptedDB) Get(key []byte) ([]byte, error) {
+	stored, err := edb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return edb.decrypt(key, stored)
+}
+
+// Has implements DB.
+func (edb *EncryptedDB) Has(key []byte) (bool, error) {
+	return edb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (edb *EncryptedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(edb, keys)
+}
+
+// Set implements DB.
+func (edb *EncryptedDB) Set(key []byte, value []byte) error {
+	stored, err := edb.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return edb.db.Set(key, stored)
+}
+
+// SetSync implements DB.
+func (edb *EncryptedDB) SetSync(key []byte, value []byte) error {
+	stored, err := edb.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return edb.db.SetSync(key, stored)
+}
+
+// Delete implements DB.
+func (edb *EncryptedDB) Delete(key []byte) error {
+	return edb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (edb *EncryptedDB) DeleteSync(key []byte) error {
+	return edb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB. It is atomic if the wrapped database's CompareAndSet is.
+func (edb *EncryptedDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if newVal == nil {
+		return false, errValueNil
+	}
+	stored, err := edb.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	current, err := edb.decrypt(key, stored)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	encrypted, err := edb.encrypt(key, newVal)
+	if err != nil {
+		return false, err
+	}
+	// Compare against the stored ciphertext, so that concurrent writes make the swap fail.
+	return edb.db.CompareAndSet(key, stored, encrypted)
+}
+
+// DeleteRange implements DB.
+func (edb *EncryptedDB) DeleteRange(start, end []byte) error {
+	return edb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (edb *EncryptedDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedDBIterator(edb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (edb *EncryptedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedDBIterator(edb, itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (edb *EncryptedDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, edb, start, end)
+}
+
+// Compact implements DB.
+func (edb *EncryptedDB) Compact(start, end []byte) error {
+	return edb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (edb *EncryptedDB) WriteBatch(ops []BatchOp) error {
+	encrypted, err := edb.encryptOps(ops)
+	if err != nil {
+		return err
+	}
+	return edb.db.WriteBatch(encrypted)
+}
+
+// WriteBatchSync implements DB.
+func (edb *EncryptedDB) WriteBatchSync(ops []BatchOp) error {
+	encrypted, err := edb.encryptOps(ops)
+	if err != nil {
+		return err
+	}
+	return edb.db.WriteBatchSync(encrypted)
+}
+
+// encryptOps encrypts the values of set operations.
+func (edb *EncryptedDB) encryptOps(ops []BatchOp) ([]BatchOp, error) {
+	encrypted := make([]BatchOp, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			var err error
+			op.Value, err = edb.encrypt(op.Key, op.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		encrypted = append(encrypted, op)
+	}
+	return encrypted, nil
+}
+
+// ApplyLog implements DB.
+func (edb *EncryptedDB) ApplyLog(ops BatchOpList) error {
+	return edb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (edb *EncryptedDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(edb, fn)
+}
+
+// Close implements DB.
+func (edb *EncryptedDB) Close() error {
+	return edb.db.Close()
+}
+
+// NewBatch implements DB.
+func (edb *EncryptedDB) NewBatch() Batch {
+	return newEncryptedDBBatch(edb, edb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (edb *EncryptedDB) NewBatchWithSize(expectedOps int) Batch {
+	return newEncryptedDBBatch(edb, edb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (edb *EncryptedDB) Print() error {
+	itr, err := edb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (edb *EncryptedDB) Stats() map[string]string {
+	return edb.db.Stats()
+}
+
+// encryptedDBBatch wraps a batch, encrypting values.
+type encryptedDBBatch struct {
+	db    *EncryptedDB
+	batch Batch
+}
+
+var _ Batch = (*encryptedDBBatch)(nil)
+
+func newEncryptedDBBatch(db *EncryptedDB, batch Batch) *encryptedDBBatch {
+	return &encryptedDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *encryptedDBBatch) Set(key, value []byte) error {
+	stored, err := b.db.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return b.batch.Set(key, stored)
+}
+
+// Delete implements Batch.
+func (b *encryptedDBBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *encryptedDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *encryptedDBBatch) Write() error {
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *encryptedDBBatch) WriteSync() error {
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *encryptedDBBatch) Close() error {
+	return b.batch.Close()
+}
+
+// encryptedDBIterator wraps an iterator, decrypting values. A value which fails to decrypt
+// invalidates the iterator, and is reported by Error.
+type encryptedDBIterator struct {
+	db     *EncryptedDB
+	source Iterator
+	value  []byte
+	err    error
+}
+
+var _ Iterator = (*encryptedDBIterator)(nil)
+
+func newEncryptedDBIterator(db *EncryptedDB, source Iterator) *encryptedDBIterator {
+	itr := &encryptedDBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.decryptValue()
+	return itr
+}
+
+// decryptValue decrypts the value at the current position of the source.
+func (itr *encryptedDBIterator) decryptValue() {
+	itr.value = nil
+	if itr.err != nil || !itr.source.Valid() {
+		return
+	}
+	itr.value, itr.err = itr.db.decrypt(itr.source.Key(), itr.source.Value())
+}
+
+// Domain implements Iterator.
+func (itr *encryptedDBIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *encryptedDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *encryptedDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.decryptValue()
+}
+
+// Seek implements Iterator.
+func (itr *encryptedDBIterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	itr.source.Seek(key)
+	itr.decryptValue()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *encryptedDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *encryptedDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Position implements Iterator.
+func (itr *encryptedDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *encryptedDBIterator) Error() error {
+	if err := itr.source.Error(); err != nil {
+		return err
+	}
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *encryptedDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *encryptedDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
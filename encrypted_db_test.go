@@ -0,0 +1,101 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEncryptionKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, EncryptionKeySize)
+}
+
+func TestEncryptedDBRoundtrip(t *testing.T) {
+	inner := NewMemDB()
+	edb, err := NewEncryptedDB(inner, testEncryptionKey(1))
+	require.NoError(t, err)
+	defer edb.Close()
+
+	require.NoError(t, edb.Set([]byte("a"), []byte("secret")))
+	require.NoError(t, edb.SetSync([]byte("b"), []byte{}))
+	batch := edb.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte("batched")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.NoError(t, edb.WriteBatch([]BatchOp{{Key: []byte("d"), Value: []byte("written")}}))
+
+	assertKeyValues(t, edb, map[string][]byte{
+		"a": []byte("secret"),
+		"b": {},
+		"c": []byte("batched"),
+		"d": []byte("written"),
+	})
+	checkValue(t, edb, []byte("b"), []byte{})
+
+	// Values are stored as a nonce and ciphertext, which differ between writes of the same value.
+	stored, err := inner.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Len(t, stored, encryptionNonceSize+len("secret")+16)
+	assert.NotContains(t, string(stored), "secret")
+	require.NoError(t, edb.Set([]byte("a"), []byte("secret")))
+	restored, err := inner.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.NotEqual(t, stored, restored)
+
+	swapped, err := edb.CompareAndSet([]byte("a"), []byte("secret"), []byte("changed"))
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = edb.CompareAndSet([]byte("a"), []byte("secret"), []byte("again"))
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	checkValue(t, edb, []byte("a"), []byte("changed"))
+
+	_, err = NewEncryptedDB(inner, []byte("short"))
+	require.Error(t, err)
+}
+
+func TestEncryptedDBWrongKey(t *testing.T) {
+	inner := NewMemDB()
+	edb, err := NewEncryptedDB(inner, testEncryptionKey(1))
+	require.NoError(t, err)
+	require.NoError(t, edb.Set([]byte("a"), []byte("secret")))
+
+	other, err := NewEncryptedDB(inner, testEncryptionKey(2))
+	require.NoError(t, err)
+	_, err = other.Get([]byte("a"))
+	require.ErrorIs(t, err, errDecryptionFailed)
+
+	itr, err := other.Iterator(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	require.ErrorIs(t, itr.Error(), errDecryptionFailed)
+	require.NoError(t, itr.Close())
+}
+
+func TestEncryptedDBTampered(t *testing.T) {
+	inner := NewMemDB()
+	edb, err := NewEncryptedDB(inner, testEncryptionKey(1))
+	require.NoError(t, err)
+	require.NoError(t, edb.Set([]byte("a"), []byte("secret")))
+	stored, err := inner.Get([]byte("a"))
+	require.NoError(t, err)
+
+	// Flipping any bit of the nonce or ciphertext is detected.
+	for _, i := range []int{0, encryptionNonceSize, len(stored) - 1} {
+		tampered := cp(stored)
+		tampered[i] ^= 0x01
+		require.NoError(t, inner.Set([]byte("a"), tampered))
+		_, err = edb.Get([]byte("a"))
+		require.ErrorIs(t, err, errDecryptionFailed)
+	}
+
+	// So is a truncated value, or a value moved to another key.
+	require.NoError(t, inner.Set([]byte("a"), stored[:encryptionNonceSize-1]))
+	_, err = edb.Get([]byte("a"))
+	require.ErrorIs(t, err, errDecryptionFailed)
+	require.NoError(t, inner.Set([]byte("b"), stored))
+	_, err = edb.Get([]byte("b"))
+	require.ErrorIs(t, err, errDecryptionFailed)
+}
@@ -0,0 +1,504 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// expiryHeaderSize is the size of the expiry timestamp stored before each ExpiringDB value.
+const expiryHeaderSize = 8
+
+// errExpiringValueInvalid is returned when a value stored by ExpiringDB is too short to hold an
+// expiry timestamp, e.g. because it was written directly to the wrapped database.
+var errExpiringValueInvalid = errors.New("value stored in expiring database has no expiry timestamp")
+
+// ExpiringDB wraps a database, and expires entries after a time to live (TTL). Each value is
+// stored in the wrapped database prefixed by its expiry time, as big-endian Unix nanoseconds,
+// where zero means it never expires. Expired entries are hidden from reads and iterators, and are
+// removed from the wrapped database by garbage collection, which runs periodically in the
+// background and can also be run with GC.
+//
+// The wrapped database must only be written through the ExpiringDB.
+type ExpiringDB struct {
+	db         DB
+	defaultTTL time.Duration
+	now        func() time.Time // the clock, replaced in tests
+
+	// mtx is held for writing during garbage collection, and for reading by writes, so that
+	// garbage collection does not delete entries written while it runs.
+	mtx  sync.RWMutex
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+var _ DB = (*ExpiringDB)(nil)
+
+// NewExpiringDB creates an ExpiringDB wrapping the given database. Set and batches use
+// defaultTTL, where zero or less means entries never expire. Garbage collection runs every
+// gcInterval until the database is closed; zero or less disables it.
+func NewExpiringDB(inner DB, defaultTTL time.Duration, gcInterval time.Duration) *ExpiringDB {
+	return newExpiringDB(inner, defaultTTL, gcInterval, time.Now)
+}
+
+// newExpiringDB creates an ExpiringDB using the given clock.
+func newExpiringDB(inner DB, defaultTTL, gcInterval time.Duration, now func() time.Time) *ExpiringDB {
+	edb := &ExpiringDB{
+		db:         inner,
+		defaultTTL: defaultTTL,
+		now:        now,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if gcInterval > 0 {
+		go edb.gcRoutine(gcInterval)
+	} else {
+		close(edb.done)
+	}
+	return edb
+}
+
+// gcRoutine runs garbage collection every interval until the database is closed. Errors are
+// ignored, since the next run will retry; call GC directly to handle them.
+func (edb *ExpiringDB) gcRoutine(interval time.Duration) {
+	defer close(edb.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-edb.stop:
+			return
+		case <-ticker.C:
+			_ = edb.GC()
+		}
+	}
+}
+
+// GC removes all expired entries from the wrapped database. Each run of consecutive expired keys
+// is removed with a single DeleteRange. Writes block while it runs.
+func (edb *ExpiringDB) GC() error {
+	edb.mtx.Lock()
+	defer edb.mtx.Unlock()
+
+	type keyRange struct{ start, end []byte }
+	var (
+		ranges []keyRange
+		run    *keyRange
+	)
+	itr, err := edb.db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	now := edb.now()
+	for ; itr.Valid(); itr.Next() {
+		expired, err := isExpired(itr.Value(), now)
+		if err != nil {
+			itr.Close()
+			return fmt.Errorf("key %X: %w", itr.Key(), err)
+		}
+		switch {
+		case expired && run == nil:
+			ranges = append(ranges, keyRange{start: cp(itr.Key())})
+			run = &ranges[len(ranges)-1]
+		case !expired && run != nil:
+			run.end = cp(itr.Key())
+			run = nil
+		}
+	}
+	if err := itr.Error(); err != nil {
+		itr.Close()
+		return err
+	}
+	if err := itr.Close(); err != nil {
+		return err
+	}
+
+	// A run reaching the last key has a nil end, deleting to the end of the database.
+	for _, r := range ranges {
+		if err := edb.db.DeleteRange(r.start, r.end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiry returns the expiry time for an entry written now with the given TTL, or zero if it
+// never expires.
+func (edb *ExpiringDB) expiry(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return edb.now().Add(ttl).UnixNano()
+}
+
+// encodeExpiring prefixes a value with its expiry time.
+func encodeExpiring(value []byte, expiry int64) []byte {
+	if value == nil {
+		return nil // let the wrapped database reject it
+	}
+	bz := make([]byte, expiryHeaderSize+len(value))
+	binary.BigEndian.PutUint64(bz, uint64(expiry))
+	copy(bz[expiryHeaderSize:], value)
+	return bz
+}
+
+// isExpired returns whether a stored value has expired at the given time.
+func isExpired(stored []byte, now time.Time) (bool, error) {
+	if len(stored) < expiryHeaderSize {
+		return false, errExpiringValueInvalid
+	}
+	expiry := int64(binary.BigEndian.Uint64(stored))
+	return expiry != 0 && expiry <= now.UnixNano(), nil
+}
+
+// decode returns the value of a stored entry, or nil if it does not exist or has expired.
+func (edb *ExpiringDB) decode(stored []byte) ([]byte, error) {
+	if stored == nil {
+		return nil, nil
+	}
+	expired, err := isExpired(stored, edb.now())
+	if err != nil || expired {
+		return nil, err
+	}
+	return stored[expiryHeaderSize:], nil
+}
+
+// Get implements DB. Expired entries are not returned.
+func (edb *ExpiringDB) Get(key []byte) ([]byte, error) {
+	stored, err := edb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return edb.decode(stored)
+}
+
+// Has implements DB. Expired entries do not exist.
+func (edb *ExpiringDB) Has(key []byte) (bool, error) {
+	value, err := edb.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// MultiGet implements DB.
+func (edb *ExpiringDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(edb, keys)
+}
+
+// Set implements DB. The entry expires after the default TTL.
+func (edb *ExpiringDB) Set(key []byte, value []byte) error {
+	return edb.SetWithTTL(key, value, edb.defaultTTL)
+}
+
+// SetSync implements DB. The entry expires after the default TTL.
+func (edb *ExpiringDB) SetSync(key []byte, value []byte) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.SetSync(key, encodeExpiring(value, edb.expiry(edb.defaultTTL)))
+}
+
+// SetWithTTL sets the value for the given key, expiring after the given TTL. Zero or less means
+// the entry never expires.
+func (edb *ExpiringDB) SetWithTTL(key, value []byte, ttl time.Duration) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.Set(key, encodeExpiring(value, edb.expiry(ttl)))
+}
+
+// Delete implements DB.
+func (edb *ExpiringDB) Delete(key []byte) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (edb *ExpiringDB) DeleteSync(key []byte) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB. Expired entries compare as missing, and the new value expires
+// after the default TTL. It is atomic if the wrapped database's CompareAndSet is.
+func (edb *ExpiringDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if newVal == nil {
+		return false, errValueNil
+	}
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+
+	stored, err := edb.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	current, err := edb.decode(stored)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	// Compare against the stored entry, so that concurrent writes make the swap fail.
+	return edb.db.CompareAndSet(key, stored, encodeExpiring(newVal, edb.expiry(edb.defaultTTL)))
+}
+
+// DeleteRange implements DB.
+func (edb *ExpiringDB) DeleteRange(start, end []byte) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB. Expired entries are skipped.
+func (edb *ExpiringDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newExpiringDBIterator(edb, itr), nil
+}
+
+// ReverseIterator implements DB. Expired entries are skipped.
+func (edb *ExpiringDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newExpiringDBIterator(edb, itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (edb *ExpiringDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, edb, start, end)
+}
+
+// Compact implements DB.
+func (edb *ExpiringDB) Compact(start, end []byte) error {
+	return edb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB. Set entries expire after the default TTL.
+func (edb *ExpiringDB) WriteBatch(ops []BatchOp) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.WriteBatch(edb.encodeOps(ops))
+}
+
+// WriteBatchSync implements DB. Set entries expire after the default TTL.
+func (edb *ExpiringDB) WriteBatchSync(ops []BatchOp) error {
+	edb.mtx.RLock()
+	defer edb.mtx.RUnlock()
+	return edb.db.WriteBatchSync(edb.encodeOps(ops))
+}
+
+// encodeOps prefixes the values of set operations with their expiry time.
+func (edb *ExpiringDB) encodeOps(ops []BatchOp) []BatchOp {
+	expiry := edb.expiry(edb.defaultTTL)
+	encoded := make([]BatchOp, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			op.Value = encodeExpiring(op.Value, expiry)
+		}
+		encoded = append(encoded, op)
+	}
+	return encoded
+}
+
+// ApplyLog implements DB.
+func (edb *ExpiringDB) ApplyLog(ops BatchOpList) error {
+	return edb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (edb *ExpiringDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(edb, fn)
+}
+
+// Close implements DB. It stops garbage collection, and closes the wrapped database.
+func (edb *ExpiringDB) Close() error {
+	edb.once.Do(func() { close(edb.stop) })
+	<-edb.done
+	return edb.db.Close()
+}
+
+// NewBatch implements DB.
+func (edb *ExpiringDB) NewBatch() Batch {
+	return newExpiringDBBatch(edb, edb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (edb *ExpiringDB) NewBatchWithSize(expectedOps int) Batch {
+	return newExpiringDBBatch(edb, edb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (edb *ExpiringDB) Print() error {
+	itr, err := edb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (edb *ExpiringDB) Stats() map[string]string {
+	return edb.db.Stats()
+}
+
+// expiringDBBatch wraps a batch, prefixing values with their expiry time. Entries expire after
+// the default TTL from when they are added to the batch.
+type expiringDBBatch struct {
+	db    *ExpiringDB
+	batch Batch
+}
+
+var _ Batch = (*expiringDBBatch)(nil)
+
+func newExpiringDBBatch(db *ExpiringDB, batch Batch) *expiringDBBatch {
+	return &expiringDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *expiringDBBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, encodeExpiring(value, b.db.expiry(b.db.defaultTTL)))
+}
+
+// Delete implements Batch.
+func (b *expiringDBBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *expiringDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *expiringDBBatch) Write() error {
+	b.db.mtx.RLock()
+	defer b.db.mtx.RUnlock()
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *expiringDBBatch) WriteSync() error {
+	b.db.mtx.RLock()
+	defer b.db.mtx.RUnlock()
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *expiringDBBatch) Close() error {
+	return b.batch.Close()
+}
+
+// expiringDBIterator wraps an iterator, skipping expired entries and stripping expiry times
+// from values.
+type expiringDBIterator struct {
+	db     *ExpiringDB
+	source Iterator
+	err    error
+}
+
+var _ Iterator = (*expiringDBIterator)(nil)
+
+func newExpiringDBIterator(db *ExpiringDB, source Iterator) *expiringDBIterator {
+	itr := &expiringDBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.skipExpired()
+	return itr
+}
+
+// skipExpired advances the source past expired entries.
+func (itr *expiringDBIterator) skipExpired() {
+	now := itr.db.now()
+	for itr.err == nil && itr.source.Valid() {
+		expired, err := isExpired(itr.source.Value(), now)
+		if err != nil {
+			itr.err = fmt.Errorf("key %X: %w", itr.source.Key(), err)
+			return
+		}
+		if !expired {
+			return
+		}
+		itr.source.Next()
+	}
+}
+
+// Domain implements Iterator.
+func (itr *expiringDBIterator) Domain() (start []byte, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *expiringDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *expiringDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.skipExpired()
+}
+
+// Seek implements Iterator.
+func (itr *expiringDBIterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	itr.source.Seek(key)
+	itr.skipExpired()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *expiringDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *expiringDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()[expiryHeaderSize:]
+}
+
+// Position implements Iterator.
+func (itr *expiringDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *expiringDBIterator) Error() error {
+	if err := itr.source.Error(); err != nil {
+		return err
+	}
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *expiringDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *expiringDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
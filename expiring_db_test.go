@@ -0,0 +1,118 @@
+package db
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockClock is a clock which only moves when advanced.
+type mockClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func newMockClock() *mockClock {
+	return &mockClock{now: time.Unix(1000000, 0)}
+}
+
+func (c *mockClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *mockClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestExpiringDB(inner DB, defaultTTL, gcInterval time.Duration) (*ExpiringDB, *mockClock) {
+	clock := newMockClock()
+	return newExpiringDB(inner, defaultTTL, gcInterval, clock.Now), clock
+}
+
+func TestExpiringDBExpiry(t *testing.T) {
+	edb, clock := newTestExpiringDB(NewMemDB(), time.Minute, 0)
+	defer edb.Close()
+
+	require.NoError(t, edb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, edb.SetWithTTL([]byte("b"), []byte{2}, time.Hour))
+	require.NoError(t, edb.SetWithTTL([]byte("c"), []byte{3}, 0)) // never expires
+	batch := edb.NewBatch()
+	require.NoError(t, batch.Set([]byte("d"), []byte{4}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	assertKeyValues(t, edb, map[string][]byte{"a": {1}, "b": {2}, "c": {3}, "d": {4}})
+
+	clock.Advance(time.Minute)
+	checkValue(t, edb, []byte("a"), nil)
+	checkValue(t, edb, []byte("d"), nil)
+	checkValue(t, edb, []byte("b"), []byte{2})
+	ok, err := edb.Has([]byte("a"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assertKeyValues(t, edb, map[string][]byte{"b": {2}, "c": {3}})
+
+	// Expired entries compare as missing.
+	swapped, err := edb.CompareAndSet([]byte("a"), []byte{1}, []byte{9})
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = edb.CompareAndSet([]byte("a"), nil, []byte{9})
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	checkValue(t, edb, []byte("a"), []byte{9})
+
+	clock.Advance(time.Hour)
+	assertKeyValues(t, edb, map[string][]byte{"c": {3}})
+
+	// Setting an expired key gives it a new TTL.
+	require.NoError(t, edb.Set([]byte("b"), []byte{5}))
+	checkValue(t, edb, []byte("b"), []byte{5})
+}
+
+func TestExpiringDBGC(t *testing.T) {
+	inner := NewMemDB()
+	edb, clock := newTestExpiringDB(inner, time.Minute, 0)
+	defer edb.Close()
+
+	for i, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		ttl := time.Minute
+		if i == 2 || i == 3 {
+			ttl = time.Hour
+		}
+		require.NoError(t, edb.SetWithTTL([]byte(key), []byte{byte(i)}, ttl))
+	}
+
+	// Nothing has expired yet.
+	require.NoError(t, edb.GC())
+	require.Equal(t, "6", inner.Stats()["database.size"])
+
+	// The expired runs a-b and e-f are removed from the wrapped database.
+	clock.Advance(time.Minute)
+	require.NoError(t, edb.GC())
+	require.Equal(t, "2", inner.Stats()["database.size"])
+	assertKeyValues(t, edb, map[string][]byte{"c": {2}, "d": {3}})
+
+	clock.Advance(time.Hour)
+	require.NoError(t, edb.GC())
+	require.Equal(t, "0", inner.Stats()["database.size"])
+}
+
+func TestExpiringDBBackgroundGC(t *testing.T) {
+	inner := NewMemDB()
+	edb, clock := newTestExpiringDB(inner, time.Minute, time.Millisecond)
+
+	require.NoError(t, edb.Set([]byte("a"), []byte{1}))
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool {
+		return inner.Stats()["database.size"] == "0"
+	}, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, edb.Close())
+}
@@ -0,0 +1,7 @@
+package db
+
+// IsBackendRegistered reports whether a backend is compiled in, for external tests.
+func IsBackendRegistered(backend BackendType) bool {
+	_, ok := backends[backend]
+	return ok
+}
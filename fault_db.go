@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInjected is returned by FaultInjectingDB for operations it fails on purpose.
+var ErrInjected = errors.New("injected fault")
+
+// FaultConfig configures the probability, in [0, 1], that FaultInjectingDB fails each kind of
+// operation.
+type FaultConfig struct {
+	// GetFailRate applies to Get, Has and MultiGet.
+	GetFailRate float64
+	// SetFailRate applies to Set, SetSync and CompareAndSet.
+	SetFailRate float64
+	// DeleteFailRate applies to Delete, DeleteSync and DeleteRange.
+	DeleteFailRate float64
+	// IteratorFailRate applies to the creation of iterators, including by ForEach.
+	IteratorFailRate float64
+}
+
+// FaultInjectingDB wraps a database, and randomly fails operations with ErrInjected as configured
+// by FaultConfig, for chaos testing of consumers. A failed operation is not passed on to the wrapped
+// database. Other operations, such as batches, are always passed on.
+type FaultInjectingDB struct {
+	db     DB
+	config FaultConfig
+
+	mtx  sync.Mutex // rand.Rand is not safe for concurrent use
+	rand *rand.Rand
+}
+
+var _ DB = (*FaultInjectingDB)(nil)
+
+// NewFaultInjectingDB creates a FaultInjectingDB wrapping the given database. Faults are drawn
+// from src, or from a time-seeded source if nil.
+func NewFaultInjectingDB(db DB, config FaultConfig, src rand.Source) *FaultInjectingDB {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return &FaultInjectingDB{
+		db:     db,
+		config: config,
+		rand:   rand.New(src), // nolint:gosec // G404: fault injection does not need a secure source
+	}
+}
+
+// fault returns ErrInjected with the given probability, and nil otherwise.
+func (fdb *FaultInjectingDB) fault(rate float64) error {
+	fdb.mtx.Lock()
+	failed := fdb.rand.Float64() < rate
+	fdb.mtx.Unlock()
+	if failed {
+		return ErrInjected
+	}
+	return nil
+}
+
+// Get implements DB.
+func (fdb *FaultInjectingDB) Get(key []byte) ([]byte, error) {
+	if err := fdb.fault(fdb.config.GetFailRate); err != nil {
+		return nil, err
+	}
+	return fdb.db.Get(key)
+}
+
+// Has implements DB.
+func (fdb *FaultInjectingDB) Has(key []byte) (bool, error) {
+	if err := fdb.fault(fdb.config.GetFailRate); err != nil {
+		return false, err
+	}
+	return fdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (fdb *FaultInjectingDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	if err := fdb.fault(fdb.config.GetFailRate); err != nil {
+		return nil, err
+	}
+	return fdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (fdb *FaultInjectingDB) Set(key []byte, value []byte) error {
+	if err := fdb.fault(fdb.config.SetFailRate); err != nil {
+		return err
+	}
+	return fdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (fdb *FaultInjectingDB) SetSync(key []byte, value []byte) error {
+	if err := fdb.fault(fdb.config.SetFailRate); err != nil {
+		return err
+	}
+	return fdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (fdb *FaultInjectingDB) Delete(key []byte) error {
+	if err := fdb.fault(fdb.config.DeleteFailRate); err != nil {
+		return err
+	}
+	return fdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (fdb *FaultInjectingDB) DeleteSync(key []byte) error {
+	if err := fdb.fault(fdb.config.DeleteFailRate); err != nil {
+		return err
+	}
+	return fdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (fdb *FaultInjectingDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if err := fdb.fault(fdb.config.SetFailRate); err != nil {
+		return false, err
+	}
+	return fdb.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (fdb *FaultInjectingDB) DeleteRange(start, end []byte) error {
+	if err := fdb.fault(fdb.config.DeleteFailRate); err != nil {
+		return err
+	}
+	return fdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (fdb *FaultInjectingDB) Iterator(start, end []byte) (Iterator, error) {
+	if err := fdb.fault(fdb.config.IteratorFailRate); err != nil {
+		return nil, err
+	}
+	return fdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (fdb *FaultInjectingDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if err := fdb.fault(fdb.config.IteratorFailRate); err != nil {
+		return nil, err
+	}
+	return fdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (fdb *FaultInjectingDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	if err := fdb.fault(fdb.config.IteratorFailRate); err != nil {
+		return nil, err
+	}
+	return fdb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (fdb *FaultInjectingDB) Compact(start, end []byte) error {
+	return fdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (fdb *FaultInjectingDB) WriteBatch(ops []BatchOp) error {
+	return fdb.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (fdb *FaultInjectingDB) WriteBatchSync(ops []BatchOp) error {
+	return fdb.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (fdb *FaultInjectingDB) ApplyLog(ops BatchOpList) error {
+	return fdb.db.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (fdb *FaultInjectingDB) ForEach(fn func(key, value []byte) error) error {
+	if err := fdb.fault(fdb.config.IteratorFailRate); err != nil {
+		return err
+	}
+	return fdb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (fdb *FaultInjectingDB) Close() error {
+	return fdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (fdb *FaultInjectingDB) NewBatch() Batch {
+	return fdb.db.NewBatch()
+}
+
+// NewBatchWithSize implements DB.
+func (fdb *FaultInjectingDB) NewBatchWithSize(expectedOps int) Batch {
+	return fdb.db.NewBatchWithSize(expectedOps)
+}
+
+// Print implements DB.
+func (fdb *FaultInjectingDB) Print() error {
+	return fdb.db.Print()
+}
+
+// Stats implements DB.
+func (fdb *FaultInjectingDB) Stats() map[string]string {
+	return fdb.db.Stats()
+}
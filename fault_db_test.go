@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultOps returns every operation covered by FaultConfig, by name.
+func faultOps(db DB, key []byte) map[string]func() error {
+	return map[string]func() error{
+		"Get": func() error {
+			_, err := db.Get(key)
+			return err
+		},
+		"Has": func() error {
+			_, err := db.Has(key)
+			return err
+		},
+		"Set":     func() error { return db.Set(key, []byte{1}) },
+		"SetSync": func() error { return db.SetSync(key, []byte{1}) },
+		"CompareAndSet": func() error {
+			_, err := db.CompareAndSet(key, nil, []byte{1})
+			return err
+		},
+		"Delete":      func() error { return db.Delete(key) },
+		"DeleteSync":  func() error { return db.DeleteSync(key) },
+		"DeleteRange": func() error { return db.DeleteRange(nil, nil) },
+		"Iterator": func() error {
+			itr, err := db.Iterator(nil, nil)
+			if err == nil {
+				err = itr.Close()
+			}
+			return err
+		},
+		"ReverseIterator": func() error {
+			itr, err := db.ReverseIterator(nil, nil)
+			if err == nil {
+				err = itr.Close()
+			}
+			return err
+		},
+		"IteratorWithContext": func() error {
+			itr, err := db.IteratorWithContext(context.Background(), nil, nil)
+			if err == nil {
+				err = itr.Close()
+			}
+			return err
+		},
+		"ForEach": func() error {
+			return db.ForEach(func(key, value []byte) error { return nil })
+		},
+	}
+}
+
+func TestFaultInjectingDB(t *testing.T) {
+	testcases := map[string]struct {
+		rate   float64
+		expect error
+	}{
+		"always": {1, ErrInjected},
+		"never":  {0, nil},
+	}
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			mock := NewMockDBWrapping(NewMemDB())
+			fdb := NewFaultInjectingDB(mock, FaultConfig{
+				GetFailRate:      tc.rate,
+				SetFailRate:      tc.rate,
+				DeleteFailRate:   tc.rate,
+				IteratorFailRate: tc.rate,
+			}, rand.NewSource(1))
+
+			for i := 0; i < 100; i++ {
+				for op, fn := range faultOps(fdb, []byte(fmt.Sprintf("key%v", i))) {
+					assert.Equal(t, tc.expect, fn(), op)
+				}
+			}
+			if tc.expect != nil {
+				assert.Empty(t, mock.Calls, "failed calls must not reach the wrapped database")
+			}
+
+			// Batches are never failed.
+			batch := fdb.NewBatch()
+			require.NoError(t, batch.Set([]byte("key"), []byte{1}))
+			require.NoError(t, batch.Write())
+			require.NoError(t, batch.Close())
+		})
+	}
+}
+
+func TestFaultInjectingDBRates(t *testing.T) {
+	fdb := NewFaultInjectingDB(NewMemDB(), FaultConfig{GetFailRate: 0.5}, rand.NewSource(1))
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, fdb.Set([]byte("key"), []byte{1}))
+		if _, err := fdb.Get([]byte("key")); err != nil {
+			require.Equal(t, ErrInjected, err)
+			failed++
+		}
+	}
+	assert.InDelta(t, 500, failed, 100)
+}
@@ -1,30 +1,66 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewGoLevelDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newGoLevelDBWithOptions(name, dir, opts)
 	}
 	registerDBCreator(GoLevelDBBackend, dbCreator, false)
 }
 
 type GoLevelDB struct {
-	db *leveldb.DB
+	db       *leveldb.DB
+	readOnly bool
+	logger   Logger
+
+	// blockCacheCapacity is the configured block cache size, reported by Stats.
+	blockCacheCapacity int
+
+	// casMtx makes CompareAndSet atomic, since goleveldb has no native support for it: writes
+	// hold a read lock, so they still run in parallel, and CompareAndSet holds the write lock.
+	casMtx sync.RWMutex
 }
 
 var _ DB = (*GoLevelDB)(nil)
+var _ Sizable = (*GoLevelDB)(nil)
 
 func NewGoLevelDB(name string, dir string) (*GoLevelDB, error) {
-	return NewGoLevelDBWithOpts(name, dir, nil)
+	return newGoLevelDBWithOptions(name, dir, Options{})
+}
+
+// newGoLevelDBWithOptions creates a GoLevelDB, mapping Options to goleveldb options.
+func newGoLevelDBWithOptions(name string, dir string, opts Options) (*GoLevelDB, error) {
+	// Check for the CURRENT file of the database, since goleveldb reports missing or existing
+	// databases with plain os errors.
+	dbPath := filepath.Join(dir, name+".db")
+	if err := opts.checkExistence(dbPath, fileExists(filepath.Join(dbPath, "CURRENT"))); err != nil {
+		return nil, err
+	}
+	o := &opt.Options{
+		ReadOnly:               opts.ReadOnly,
+		OpenFilesCacheCapacity: opts.MaxOpenFiles,
+		BlockCacheCapacity:     int(opts.BlockCacheSize),
+		ErrorIfMissing:         opts.ErrorIfMissing,
+		ErrorIfExist:           opts.ErrorIfExists,
+	}
+	db, err := NewGoLevelDBWithOpts(name, dir, o)
+	if err != nil {
+		return nil, err
+	}
+	db.logger = loggerOrNop(opts.Logger)
+	return db, nil
 }
 
 func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB, error) {
@@ -34,11 +70,72 @@ func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB,
 		return nil, err
 	}
 	database := &GoLevelDB{
-		db: db,
+		db:                 db,
+		readOnly:           o != nil && o.ReadOnly,
+		logger:             nopLogger{},
+		blockCacheCapacity: o.GetBlockCacheCapacity(),
 	}
 	return database, nil
 }
 
+// LevelDBCompression is the block compression used by GoLevelDB.
+type LevelDBCompression int
+
+const (
+	// LevelDBDefaultCompression uses the goleveldb default, which is Snappy.
+	LevelDBDefaultCompression LevelDBCompression = iota
+	// LevelDBNoCompression disables compression.
+	LevelDBNoCompression
+	// LevelDBSnappyCompression compresses blocks with Snappy.
+	LevelDBSnappyCompression
+)
+
+// LevelDBOptions are the tuning options of GoLevelDB. Zero values mean the goleveldb defaults.
+// goleveldb does not support zstd compression.
+type LevelDBOptions struct {
+	// BlockSize is the approximate size of uncompressed data per block, in bytes.
+	BlockSize int
+	// BlockCacheCapacity is the size of the block cache, in bytes.
+	BlockCacheCapacity int
+	// WriteBufferSize is the size of the memtable, in bytes, which is flushed to disk when full.
+	WriteBufferSize int
+	// MaxOpenFiles is the maximum number of table files kept open.
+	MaxOpenFiles int
+	// CompactionTableSize is the size of the table files created by compactions, in bytes.
+	CompactionTableSize int
+	// BloomFilterBitsPerKey enables a bloom filter with the given number of bits per key, which
+	// speeds up reads of missing keys. Zero disables it; 10 is a common choice.
+	BloomFilterBitsPerKey int
+	// CompressionType is the block compression. goleveldb uses the same compression for every
+	// level, and it can only be chosen when opening the database.
+	CompressionType LevelDBCompression
+}
+
+// NewGoLevelDBWithOptions creates a GoLevelDB with the given tuning options.
+func NewGoLevelDBWithOptions(name string, dir string, opts LevelDBOptions) (*GoLevelDB, error) {
+	o := &opt.Options{
+		BlockSize:              opts.BlockSize,
+		BlockCacheCapacity:     opts.BlockCacheCapacity,
+		WriteBuffer:            opts.WriteBufferSize,
+		OpenFilesCacheCapacity: opts.MaxOpenFiles,
+		CompactionTableSize:    opts.CompactionTableSize,
+	}
+	if opts.BloomFilterBitsPerKey > 0 {
+		o.Filter = filter.NewBloomFilter(opts.BloomFilterBitsPerKey)
+	}
+	switch opts.CompressionType {
+	case LevelDBDefaultCompression:
+		o.Compression = opt.DefaultCompression
+	case LevelDBNoCompression:
+		o.Compression = opt.NoCompression
+	case LevelDBSnappyCompression:
+		o.Compression = opt.SnappyCompression
+	default:
+		return nil, fmt.Errorf("unknown LevelDB compression type %d", opts.CompressionType)
+	}
+	return NewGoLevelDBWithOpts(name, dir, o)
+}
+
 // Get implements DB.
 func (db *GoLevelDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
@@ -56,43 +153,72 @@ func (db *GoLevelDB) Get(key []byte) ([]byte, error) {
 
 // Has implements DB.
 func (db *GoLevelDB) Has(key []byte) (bool, error) {
-	bytes, err := db.Get(key)
-	if err != nil {
-		return false, err
+	if len(key) == 0 {
+		return false, errKeyEmpty
 	}
-	return bytes != nil, nil
+	return db.db.Has(key, nil)
+}
+
+// MultiGet implements DB.
+func (db *GoLevelDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(db, keys)
 }
 
 // Set implements DB.
 func (db *GoLevelDB) Set(key []byte, value []byte) error {
-	if len(key) == 0 {
-		return errKeyEmpty
-	}
-	if value == nil {
-		return errValueNil
-	}
-	if err := db.db.Put(key, value, nil); err != nil {
-		return err
-	}
-	return nil
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, nil)
 }
 
 // SetSync implements DB.
 func (db *GoLevelDB) SetSync(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, &opt.WriteOptions{Sync: true})
+}
+
+// syncWriteDurable implements syncWriteDurable. goleveldb journals every write to a single log, so
+// a sync write persists all earlier ones.
+func (db *GoLevelDB) syncWriteDurable() {}
+
+// set sets a value without locking casMtx.
+func (db *GoLevelDB) set(key []byte, value []byte, wo *opt.WriteOptions) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
-	if err := db.db.Put(key, value, &opt.WriteOptions{Sync: true}); err != nil {
+	if err := db.db.Put(key, value, wo); err != nil {
 		return err
 	}
 	return nil
 }
 
+// CompareAndSet implements DB.
+func (db *GoLevelDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if db.readOnly {
+		return false, ErrReadOnly
+	}
+	db.casMtx.Lock()
+	defer db.casMtx.Unlock()
+
+	return compareAndSet(db, key, expected, newVal, func(key, value []byte) error {
+		return db.set(key, value, nil)
+	})
+}
+
 // Delete implements DB.
 func (db *GoLevelDB) Delete(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -104,6 +230,11 @@ func (db *GoLevelDB) Delete(key []byte) error {
 
 // DeleteSync implements DB.
 func (db *GoLevelDB) DeleteSync(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -120,6 +251,7 @@ func (db *GoLevelDB) DB() *leveldb.DB {
 
 // Close implements DB.
 func (db *GoLevelDB) Close() error {
+	GlobalRegistry.unregister(db)
 	if err := db.db.Close(); err != nil {
 		return err
 	}
@@ -163,18 +295,98 @@ func (db *GoLevelDB) Stats() map[string]string {
 			stats[key] = str
 		}
 	}
+	stats["leveldb.block-cache-capacity"] = fmt.Sprintf("%d", db.blockCacheCapacity)
 	return stats
 }
 
+// ApproxSize implements Sizable. It is the approximate size of the table files, which does not
+// include writes still held in the memtable.
+func (db *GoLevelDB) ApproxSize() (int64, error) {
+	itr := db.db.NewIterator(nil, nil)
+	last := itr.Last()
+	limit := append(cp(itr.Key()), 0x00)
+	itr.Release()
+	if err := itr.Error(); err != nil || !last {
+		return 0, err
+	}
+	sizes, err := db.db.SizeOf([]util.Range{{Start: nil, Limit: limit}})
+	if err != nil {
+		return 0, err
+	}
+	return sizes.Sum(), nil
+}
+
+// ForceCompact compacts the given range. It is equivalent to Compact.
 func (db *GoLevelDB) ForceCompact(start, limit []byte) error {
-	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
+	return db.Compact(start, limit)
+}
+
+// Compact implements DB.
+func (db *GoLevelDB) Compact(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	return logCompaction(db.logger, start, end, func() error {
+		return db.db.CompactRange(util.Range{Start: start, Limit: end})
+	})
+}
+
+// DeleteRange implements DB. The domain is not deleted atomically, and is compacted afterwards
+// to reclaim the space of the deleted keys.
+func (db *GoLevelDB) DeleteRange(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if err := deleteRange(db, start, end); err != nil {
+		return err
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	return db.Compact(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (db *GoLevelDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// WriteBatch implements DB.
+func (db *GoLevelDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *GoLevelDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *GoLevelDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *GoLevelDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
 }
 
 // NewBatch implements DB.
 func (db *GoLevelDB) NewBatch() Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
 	return newGoLevelDBBatch(db)
 }
 
+// NewBatchWithSize implements DB.
+func (db *GoLevelDB) NewBatchWithSize(expectedOps int) Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
+	return newGoLevelDBBatchWithSize(db, expectedOps)
+}
+
 // Iterator implements DB.
 func (db *GoLevelDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
@@ -12,6 +12,10 @@ type goLevelDBBatch struct {
 
 var _ Batch = (*goLevelDBBatch)(nil)
 
+// goLevelDBBatchOpSize is the assumed encoded size in bytes of a single batch operation, used to
+// turn an operation count into a buffer size for leveldb.MakeBatch.
+const goLevelDBBatchOpSize = 64
+
 func newGoLevelDBBatch(db *GoLevelDB) *goLevelDBBatch {
 	return &goLevelDBBatch{
 		db:    db,
@@ -19,6 +23,16 @@ func newGoLevelDBBatch(db *GoLevelDB) *goLevelDBBatch {
 	}
 }
 
+func newGoLevelDBBatchWithSize(db *GoLevelDB, size int) *goLevelDBBatch {
+	if size < 0 {
+		size = 0
+	}
+	return &goLevelDBBatch{
+		db:    db,
+		batch: leveldb.MakeBatch(size * goLevelDBBatchOpSize),
+	}
+}
+
 // Set implements Batch.
 func (b *goLevelDBBatch) Set(key, value []byte) error {
 	if len(key) == 0 {
@@ -46,6 +60,14 @@ func (b *goLevelDBBatch) Delete(key []byte) error {
 	return nil
 }
 
+// Len implements Batch.
+func (b *goLevelDBBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Len()
+}
+
 // Write implements Batch.
 func (b *goLevelDBBatch) Write() error {
 	return b.write(false)
@@ -60,7 +82,9 @@ func (b *goLevelDBBatch) write(sync bool) error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.db.casMtx.RLock()
 	err := b.db.db.Write(b.batch, &opt.WriteOptions{Sync: sync})
+	b.db.casMtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+var _ Checkpointable = (*GoLevelDB)(nil)
+
+// Checkpoint implements Checkpointable. goleveldb can not pause compactions, which may remove
+// table files at any time, so rather than hard-linking them the checkpoint is written to a new
+// database from a snapshot.
+func (db *GoLevelDB) Checkpoint(destDir string) error {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	itr := newGoLevelDBIterator(snap.NewIterator(nil, nil), nil, nil, false)
+	defer itr.Close()
+	return writeGoLevelDBCheckpoint(itr, destDir)
+}
+
+// writeGoLevelDBCheckpoint writes all remaining key/value pairs of itr to a new GoLevelDB
+// database in destDir, which must not exist. destDir is removed again on failure.
+func writeGoLevelDBCheckpoint(itr Iterator, destDir string) (err error) {
+	if _, err := os.Stat(destDir); !os.IsNotExist(err) {
+		if err == nil {
+			err = fmt.Errorf("checkpoint directory %v already exists", destDir)
+		}
+		return err
+	}
+	ldb, err := leveldb.OpenFile(destDir, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := ldb.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.RemoveAll(destDir)
+		}
+	}()
+	return copyIterator(itr, &GoLevelDB{db: ldb}, defaultCopyBatchSize, nil)
+}
@@ -92,6 +92,35 @@ func (itr *goLevelDBIterator) Valid() bool {
 	return true
 }
 
+// Seek implements Iterator.
+func (itr *goLevelDBIterator) Seek(key []byte) bool {
+	if itr.Error() != nil {
+		return false
+	}
+	itr.isInvalid = false
+	switch {
+	case !itr.isReverse:
+		if itr.start != nil && bytes.Compare(key, itr.start) < 0 {
+			key = itr.start
+		}
+		itr.source.Seek(key)
+	case itr.end != nil && bytes.Compare(key, itr.end) >= 0:
+		// The end is exclusive, so move to the last key before it.
+		if itr.source.Seek(itr.end) {
+			itr.source.Prev()
+		} else {
+			itr.source.Last()
+		}
+	default:
+		if !itr.source.Seek(key) {
+			itr.source.Last()
+		} else if !bytes.Equal(itr.source.Key(), key) {
+			itr.source.Prev()
+		}
+	}
+	return itr.Valid()
+}
+
 // Key implements Iterator.
 func (itr *goLevelDBIterator) Key() []byte {
 	// Key returns a copy of the current key.
@@ -108,6 +137,11 @@ func (itr *goLevelDBIterator) Value() []byte {
 	return cp(itr.source.Value())
 }
 
+// Position implements Iterator.
+func (itr *goLevelDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
 // Next implements Iterator.
 func (itr *goLevelDBIterator) Next() {
 	itr.assertIsValid()
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// goLevelDBSnapshot is a GoLevelDB snapshot, using LevelDB's native snapshots.
+type goLevelDBSnapshot struct {
+	snap     *leveldb.Snapshot
+	released bool
+}
+
+var (
+	_ Snapshotable = (*GoLevelDB)(nil)
+	_ Snapshot     = (*goLevelDBSnapshot)(nil)
+)
+
+// Snapshot implements Snapshotable.
+func (db *GoLevelDB) Snapshot() (Snapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelDBSnapshot{snap: snap}, nil
+}
+
+// Get implements Snapshot.
+func (s *goLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if s.released {
+		return nil, errSnapshotReleased
+	}
+	res, err := s.snap.Get(key, nil)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// Iterator implements Snapshot.
+func (s *goLevelDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if s.released {
+		return nil, errSnapshotReleased
+	}
+	itr := s.snap.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return newGoLevelDBIterator(itr, start, end, false), nil
+}
+
+// Release implements Snapshot.
+func (s *goLevelDBSnapshot) Release() {
+	if !s.released {
+		s.snap.Release()
+		s.released = true
+	}
+}
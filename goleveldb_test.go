@@ -2,8 +2,12 @@ package db
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 )
@@ -28,6 +32,36 @@ func TestGoLevelDBNewGoLevelDB(t *testing.T) {
 	defer ro2.Close()
 }
 
+func TestGoLevelDBWithOptions(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewGoLevelDBWithOptions("testdb", dir, LevelDBOptions{
+		BlockSize:             8 << 10,
+		BlockCacheCapacity:    16 << 20,
+		WriteBufferSize:       1 << 20,
+		MaxOpenFiles:          100,
+		CompactionTableSize:   4 << 20,
+		BloomFilterBitsPerKey: 10,
+		CompressionType:       LevelDBNoCompression,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "16777216", db.Stats()["leveldb.block-cache-capacity"])
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	checkValue(t, db, []byte("a"), []byte{1})
+	checkValue(t, db, []byte("b"), nil)
+	require.NoError(t, db.Close())
+
+	// Options may differ when reopening the database.
+	db, err = NewGoLevelDBWithOptions("testdb", dir, LevelDBOptions{BloomFilterBitsPerKey: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "8388608", db.Stats()["leveldb.block-cache-capacity"])
+	checkValue(t, db, []byte("a"), []byte{1})
+	require.NoError(t, db.Close())
+
+	_, err = NewGoLevelDBWithOptions("testdb", dir, LevelDBOptions{CompressionType: 99})
+	require.Error(t, err)
+}
+
 func BenchmarkGoLevelDBRandomReadsWrites(b *testing.B) {
 	name := fmt.Sprintf("test_%x", randStr(12))
 	db, err := NewGoLevelDB(name, "")
@@ -41,3 +75,40 @@ func BenchmarkGoLevelDBRandomReadsWrites(b *testing.B) {
 
 	benchmarkRandomReadsWrites(b, db)
 }
+
+func TestOpenWithRetry(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate a lock left behind by a crashed process by holding it open, since goleveldb locks
+	// the LOCK file rather than checking for its existence.
+	locked, err := NewGoLevelDB("testdb", dir)
+	require.NoError(t, err)
+	defer locked.Close()
+	require.FileExists(t, filepath.Join(dir, "testdb.db", "LOCK"))
+
+	// A single attempt fails without touching the lock.
+	_, err = OpenWithRetry(GoLevelDBBackend, "testdb", dir, 1)
+	require.Error(t, err)
+
+	logger := &bufferLogger{}
+	var delays []time.Duration
+	db, err := openWithRetry(GoLevelDBBackend, "testdb", dir, 3, Options{Logger: logger},
+		func(d time.Duration) { delays = append(delays, d) })
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, []time.Duration{openRetryBaseDelay, 2 * openRetryBaseDelay}, delays)
+	var retries []string
+	for _, line := range logger.lines() {
+		if strings.Contains(line, "retrying") || strings.Contains(line, "lock file") {
+			retries = append(retries, line[:strings.Index(line, " backend=")])
+		}
+	}
+	assert.Equal(t, []string{
+		"I failed to open database, retrying",
+		"I failed to open database, retrying",
+		"E removing database lock file before last attempt",
+	}, retries)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	checkValue(t, db, []byte("a"), []byte{1})
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// healthCheckKeyPrefix prefixes the test keys written by HealthCheck.
+const healthCheckKeyPrefix = "__health_check__/"
+
+// HealthCheck checks that a database is functioning, e.g. for a readiness probe, by writing a
+// test key, reading it back and deleting it. The test key is healthCheckKeyPrefix followed by the
+// current time in nanoseconds, so that it does not conflict with application data or concurrent
+// health checks. It fails for read-only databases, and leaves the test key behind if deleting it
+// fails.
+func HealthCheck(db DB) error {
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	key := []byte(healthCheckKeyPrefix + now)
+	value := []byte(now)
+
+	if err := db.Set(key, value); err != nil {
+		return fmt.Errorf("health check failed to write test key: %w", err)
+	}
+	got, err := db.Get(key)
+	if err != nil {
+		return fmt.Errorf("health check failed to read test key: %w", err)
+	}
+	if !bytes.Equal(got, value) {
+		return fmt.Errorf("health check read %X for test key, expected %X", got, value)
+	}
+	if err := db.Delete(key); err != nil {
+		return fmt.Errorf("health check failed to delete test key: %w", err)
+	}
+	return nil
+}
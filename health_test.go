@@ -0,0 +1,37 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+			require.NoError(t, db.Set([]byte("a"), []byte{1}))
+			require.NoError(t, HealthCheck(db))
+			// The test key is removed, and application data is untouched.
+			assertKeyValues(t, db, map[string][]byte{"a": {1}})
+		})
+	}
+}
+
+func TestHealthCheckFaulty(t *testing.T) {
+	for name, config := range map[string]FaultConfig{
+		"Set":    {SetFailRate: 1},
+		"Get":    {GetFailRate: 1},
+		"Delete": {DeleteFailRate: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := HealthCheck(NewFaultInjectingDB(NewMemDB(), config, nil))
+			require.ErrorIs(t, err, ErrInjected)
+			assert.Contains(t, err.Error(), "health check")
+		})
+	}
+}
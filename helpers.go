@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"io"
+)
+
+// hexDumpMaxValue is the maximum number of value bytes written by HexDump for each entry.
+const hexDumpMaxValue = 256
+
+// MustGet fetches the value of the given key like DB.Get, or nil if it does not exist, and panics
+// on errors. It is meant for startup code such as loading configuration, and should not be used
+// in production hot paths, where errors must be handled.
+func MustGet(db DB, key []byte) []byte {
+	value, err := db.Get(key)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get key %X: %v", key, err))
+	}
+	return value
+}
+
+// MustSet sets the value of the given key like DB.Set, and panics on errors. It is meant for
+// startup code, and should not be used in production hot paths, where errors must be handled.
+func MustSet(db DB, key, value []byte) {
+	if err := db.Set(key, value); err != nil {
+		panic(fmt.Sprintf("failed to set key %X: %v", key, err))
+	}
+}
+
+// HexDump writes all key/value pairs of the database to w in key order, for debugging. Each pair
+// is written as a "key:" line and a "value:" line holding the hex-encoded bytes; values longer than
+// 256 bytes are truncated, with a "... (N more bytes)" suffix. Writers implementing
+// io.StringWriter are written to directly, without converting lines to byte slices.
+func HexDump(db DB, w io.Writer) error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		value := itr.Value()
+		var suffix string
+		if len(value) > hexDumpMaxValue {
+			suffix = fmt.Sprintf("... (%d more bytes)", len(value)-hexDumpMaxValue)
+			value = value[:hexDumpMaxValue]
+		}
+		if _, err := io.WriteString(w, fmt.Sprintf("key: %X\nvalue: %X%s\n", itr.Key(), value, suffix)); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
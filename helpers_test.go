@@ -0,0 +1,51 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustGetSet(t *testing.T) {
+	db := NewMemDB()
+	MustSet(db, []byte("key"), []byte{1})
+	assert.Equal(t, []byte{1}, MustGet(db, []byte("key")))
+	assert.Nil(t, MustGet(db, []byte("missing")))
+
+	mock := NewMockDBWrapping(db)
+	mock.SetError("Get", errors.New("boom"))
+	mock.SetError("Set", errors.New("boom"))
+	assert.PanicsWithValue(t, "failed to get key 0A0B: boom", func() {
+		MustGet(mock, []byte{0x0a, 0x0b})
+	})
+	assert.PanicsWithValue(t, "failed to set key 0A0B: boom", func() {
+		MustSet(mock, []byte{0x0a, 0x0b}, []byte{1})
+	})
+}
+
+func TestHexDump(t *testing.T) {
+	db := NewMemDB()
+	MustSet(db, []byte{0x01, 0xab}, []byte{0xde, 0xad, 0xbe, 0xef})
+	MustSet(db, []byte{0x02}, []byte{})
+	MustSet(db, []byte{0x03}, bytes.Repeat([]byte{0xff}, 300))
+
+	// strings.Builder implements io.StringWriter, a plain io.Writer is written to with Write.
+	var sb strings.Builder
+	require.NoError(t, HexDump(db, &sb))
+	assert.Equal(t, "key: 01AB\nvalue: DEADBEEF\n"+
+		"key: 02\nvalue: \n"+
+		"key: 03\nvalue: "+strings.Repeat("FF", 256)+"... (44 more bytes)\n", sb.String())
+
+	var buf bytes.Buffer
+	require.NoError(t, HexDump(db, struct{ io.Writer }{&buf}))
+	assert.Equal(t, sb.String(), buf.String())
+
+	mock := NewMockDBWrapping(db)
+	mock.SetError("Iterator", errors.New("boom"))
+	require.EqualError(t, HexDump(mock, &buf), "boom")
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeySizeExceeded is returned by LimitDB when writing a key larger than its limit.
+	ErrKeySizeExceeded = errors.New("key size exceeds limit")
+
+	// ErrValueSizeExceeded is returned by LimitDB when writing a value larger than its limit.
+	ErrValueSizeExceeded = errors.New("value size exceeds limit")
+)
+
+// LimitDB wraps a database, and rejects writes of keys or values larger than the given limits,
+// since large entries degrade the performance of LSM-based backends such as LevelDB. Reads and
+// deletions are passed on unchecked.
+type LimitDB struct {
+	db           DB
+	maxKeySize   int
+	maxValueSize int
+}
+
+var _ DB = (*LimitDB)(nil)
+
+// NewLimitDB creates a LimitDB wrapping the given database. A limit of -1 disables that limit.
+func NewLimitDB(inner DB, maxKeySize, maxValueSize int) *LimitDB {
+	return &LimitDB{
+		db:           inner,
+		maxKeySize:   maxKeySize,
+		maxValueSize: maxValueSize,
+	}
+}
+
+// check returns an error if a key or value written to the database exceeds the limits.
+func (ldb *LimitDB) check(key, value []byte) error {
+	if ldb.maxKeySize >= 0 && len(key) > ldb.maxKeySize {
+		return fmt.Errorf("key of %d bytes, limit %d: %w", len(key), ldb.maxKeySize, ErrKeySizeExceeded)
+	}
+	if ldb.maxValueSize >= 0 && len(value) > ldb.maxValueSize {
+		return fmt.Errorf("value of %d bytes for key %X, limit %d: %w",
+			len(value), key, ldb.maxValueSize, ErrValueSizeExceeded)
+	}
+	return nil
+}
+
+// checkOps checks the set operations of a batch, so that it is rejected as a whole if any of
+// them exceeds the limits.
+func (ldb *LimitDB) checkOps(ops []BatchOp) error {
+	for _, op := range ops {
+		if op.Delete {
+			continue
+		}
+		if err := ldb.check(op.Key, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get implements DB.
+func (ldb *LimitDB) Get(key []byte) ([]byte, error) {
+	return ldb.db.Get(key)
+}
+
+// Has implements DB.
+func (ldb *LimitDB) Has(key []byte) (bool, error) {
+	return ldb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (ldb *LimitDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return ldb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (ldb *LimitDB) Set(key []byte, value []byte) error {
+	if err := ldb.check(key, value); err != nil {
+		return err
+	}
+	return ldb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (ldb *LimitDB) SetSync(key []byte, value []byte) error {
+	if err := ldb.check(key, value); err != nil {
+		return err
+	}
+	return ldb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (ldb *LimitDB) Delete(key []byte) error {
+	return ldb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (ldb *LimitDB) DeleteSync(key []byte) error {
+	return ldb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (ldb *LimitDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if err := ldb.check(key, newVal); err != nil {
+		return false, err
+	}
+	return ldb.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (ldb *LimitDB) DeleteRange(start, end []byte) error {
+	return ldb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (ldb *LimitDB) Iterator(start, end []byte) (Iterator, error) {
+	return ldb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (ldb *LimitDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return ldb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (ldb *LimitDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return ldb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (ldb *LimitDB) Compact(start, end []byte) error {
+	return ldb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (ldb *LimitDB) WriteBatch(ops []BatchOp) error {
+	if err := ldb.checkOps(ops); err != nil {
+		return err
+	}
+	return ldb.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (ldb *LimitDB) WriteBatchSync(ops []BatchOp) error {
+	if err := ldb.checkOps(ops); err != nil {
+		return err
+	}
+	return ldb.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (ldb *LimitDB) ApplyLog(ops BatchOpList) error {
+	if err := ldb.checkOps(ops); err != nil {
+		return err
+	}
+	return ldb.db.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (ldb *LimitDB) ForEach(fn func(key, value []byte) error) error {
+	return ldb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (ldb *LimitDB) Close() error {
+	return ldb.db.Close()
+}
+
+// NewBatch implements DB.
+func (ldb *LimitDB) NewBatch() Batch {
+	return newLimitDBBatch(ldb, ldb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (ldb *LimitDB) NewBatchWithSize(expectedOps int) Batch {
+	return newLimitDBBatch(ldb, ldb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (ldb *LimitDB) Print() error {
+	return ldb.db.Print()
+}
+
+// Stats implements DB.
+func (ldb *LimitDB) Stats() map[string]string {
+	return ldb.db.Stats()
+}
+
+// limitDBBatch wraps a batch, rejecting sets which exceed the database's limits. A rejected set
+// is not added to the batch, while the others may still be written.
+type limitDBBatch struct {
+	db    *LimitDB
+	batch Batch
+}
+
+var _ Batch = (*limitDBBatch)(nil)
+
+func newLimitDBBatch(db *LimitDB, batch Batch) *limitDBBatch {
+	return &limitDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *limitDBBatch) Set(key, value []byte) error {
+	if err := b.db.check(key, value); err != nil {
+		return err
+	}
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *limitDBBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *limitDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *limitDBBatch) Write() error {
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *limitDBBatch) WriteSync() error {
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *limitDBBatch) Close() error {
+	return b.batch.Close()
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitDBBoundaries(t *testing.T) {
+	ldb := NewLimitDB(NewMemDB(), 4, 8)
+	defer ldb.Close()
+
+	// Entries exactly at the limits are accepted.
+	require.NoError(t, ldb.Set(bytes.Repeat([]byte{'k'}, 4), bytes.Repeat([]byte{'v'}, 8)))
+	require.NoError(t, ldb.SetSync([]byte("a"), bytes.Repeat([]byte{'v'}, 8)))
+	checkValue(t, ldb, []byte("kkkk"), []byte("vvvvvvvv"))
+
+	// One byte over is rejected, and nothing is written.
+	err := ldb.Set(bytes.Repeat([]byte{'k'}, 5), []byte("v"))
+	require.ErrorIs(t, err, ErrKeySizeExceeded)
+	err = ldb.SetSync([]byte("b"), bytes.Repeat([]byte{'v'}, 9))
+	require.ErrorIs(t, err, ErrValueSizeExceeded)
+	_, err = ldb.CompareAndSet([]byte("a"), nil, bytes.Repeat([]byte{'v'}, 9))
+	require.ErrorIs(t, err, ErrValueSizeExceeded)
+	checkValue(t, ldb, []byte("kkkkk"), nil)
+	checkValue(t, ldb, []byte("b"), nil)
+	checkValue(t, ldb, []byte("a"), []byte("vvvvvvvv"))
+
+	// Deleting is never limited.
+	require.NoError(t, ldb.Delete(bytes.Repeat([]byte{'k'}, 5)))
+
+	// A limit of -1 disables it.
+	unlimited := NewLimitDB(NewMemDB(), -1, -1)
+	require.NoError(t, unlimited.Set(bytes.Repeat([]byte{'k'}, 1024), bytes.Repeat([]byte{'v'}, 1<<20)))
+	keyOnly := NewLimitDB(NewMemDB(), 4, -1)
+	require.NoError(t, keyOnly.Set([]byte("k"), bytes.Repeat([]byte{'v'}, 1<<20)))
+	require.ErrorIs(t, keyOnly.Set([]byte("kkkkk"), nil), ErrKeySizeExceeded)
+}
+
+func TestLimitDBBatch(t *testing.T) {
+	ldb := NewLimitDB(NewMemDB(), 4, 8)
+	defer ldb.Close()
+
+	// Only the oversized sets of a batch are rejected, the rest are written.
+	batch := ldb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte("1")))
+	require.ErrorIs(t, batch.Set([]byte("toolong"), []byte("2")), ErrKeySizeExceeded)
+	require.ErrorIs(t, batch.Set([]byte("b"), bytes.Repeat([]byte{'v'}, 9)), ErrValueSizeExceeded)
+	require.NoError(t, batch.Set([]byte("c"), bytes.Repeat([]byte{'v'}, 8)))
+	assert.Equal(t, 2, batch.Len())
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assertKeyValues(t, ldb, map[string][]byte{
+		"a": []byte("1"),
+		"c": []byte("vvvvvvvv"),
+	})
+
+	// WriteBatch rejects the batch as a whole if any set exceeds the limits.
+	err := ldb.WriteBatch([]BatchOp{
+		{Key: []byte("d"), Value: []byte("4")},
+		{Key: []byte("e"), Value: bytes.Repeat([]byte{'v'}, 9)},
+	})
+	require.ErrorIs(t, err, ErrValueSizeExceeded)
+	err = ldb.WriteBatchSync([]BatchOp{
+		{Key: []byte("toolong"), Delete: true},
+		{Key: []byte("toolong"), Value: []byte("5")},
+	})
+	require.ErrorIs(t, err, ErrKeySizeExceeded)
+	checkValue(t, ldb, []byte("d"), nil)
+
+	require.NoError(t, ldb.WriteBatch([]BatchOp{
+		{Key: []byte("d"), Value: []byte("4")},
+		{Key: []byte("toolong"), Delete: true},
+	}))
+	checkValue(t, ldb, []byte("d"), []byte("4"))
+}
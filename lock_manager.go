@@ -0,0 +1,60 @@
+package db
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// lockStripes is the number of mutexes keys are spread over by LockManager.
+const lockStripes = 256
+
+// LockManager locks individual keys, for read-modify-write workflows spanning several database
+// operations which must not lock the entire database. Keys are hashed onto a fixed array of
+// mutexes, so unrelated keys may occasionally share a lock, and a goroutine must not lock a key
+// while holding the lock of another, since both may hash to the same stripe and deadlock. The
+// zero value is ready for use.
+//
+// Locks are advisory: they only exclude other callers of the same LockManager, not writes made
+// directly to the database.
+type LockManager struct {
+	stripes [lockStripes]sync.Mutex
+}
+
+// NewLockManager creates a LockManager.
+func NewLockManager() *LockManager {
+	return &LockManager{}
+}
+
+// stripe returns the mutex of the given key.
+func (lm *LockManager) stripe(key []byte) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write(key) // nolint:errcheck // never fails
+	return &lm.stripes[h.Sum32()%lockStripes]
+}
+
+// Lock locks the given key, blocking until it is available.
+func (lm *LockManager) Lock(key []byte) {
+	lm.stripe(key).Lock()
+}
+
+// Unlock unlocks the given key, which must be locked.
+func (lm *LockManager) Unlock(key []byte) {
+	lm.stripe(key).Unlock()
+}
+
+// LockRange locks every key in the domain [start, end). Since keys are spread over the stripes by
+// their hash, any stripe may hold a key in the domain, so all of them are locked, in order, which
+// excludes every other Lock and LockRange call until UnlockRange. It must not be called while
+// holding the lock of a key.
+func (lm *LockManager) LockRange(start, end []byte) {
+	for i := range lm.stripes {
+		lm.stripes[i].Lock()
+	}
+}
+
+// UnlockRange unlocks a domain locked with LockRange.
+func (lm *LockManager) UnlockRange(start, end []byte) {
+	for i := len(lm.stripes) - 1; i >= 0; i-- {
+		lm.stripes[i].Unlock()
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockManager(t *testing.T) {
+	db := NewMemDB()
+	lm := NewLockManager()
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, key := range keys {
+		require.NoError(t, db.Set(key, int642Bytes(0)))
+	}
+
+	// Read-modify-write updates under the key lock, yielding between the read and the write so
+	// that unlocked updates would be lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := keys[i%len(keys)]
+			for j := 0; j < 10; j++ {
+				lm.Lock(key)
+				value, err := db.Get(key)
+				assert.NoError(t, err)
+				time.Sleep(time.Microsecond)
+				assert.NoError(t, db.Set(key, int642Bytes(bytes2Int64(value)+1)))
+				lm.Unlock(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var total int64
+	for i, key := range keys {
+		value, err := db.Get(key)
+		require.NoError(t, err)
+		expect := int64(34 * 10)
+		if i > 0 {
+			expect = 33 * 10
+		}
+		assert.Equal(t, expect, bytes2Int64(value), "key %s", key)
+		total += bytes2Int64(value)
+	}
+	assert.EqualValues(t, 1000, total)
+}
+
+func TestLockManagerRange(t *testing.T) {
+	var lm LockManager
+	lm.LockRange([]byte("a"), []byte("z"))
+
+	// Keys wait for the range lock to be released.
+	var locked int32
+	done := make(chan struct{})
+	go func() {
+		lm.Lock([]byte("key"))
+		atomic.StoreInt32(&locked, 1)
+		lm.Unlock([]byte("key"))
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&locked))
+
+	lm.UnlockRange([]byte("a"), []byte("z"))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("key lock was not acquired after unlocking the range")
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&locked))
+}
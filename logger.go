@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger is the logging interface used by database wrappers. It is a subset of the Tendermint
+// log.Logger interface, so any Tendermint logger can be used. Keyvals are alternating keys and
+// values. Implementations must be safe for concurrent use, since backends log from their own
+// goroutines.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// nopLogger is a Logger which discards all messages. Backends use it when no logger is given.
+type nopLogger struct{}
+
+var _ Logger = nopLogger{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// loggerOrNop returns the given logger, or a nopLogger if it is nil.
+func loggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+	return logger
+}
+
+// logCompaction runs a backend compaction of the given domain, logging its start and outcome.
+func logCompaction(logger Logger, start, end []byte, compact func() error) error {
+	logger.Debug("starting compaction", "start", fmt.Sprintf("%X", start), "end", fmt.Sprintf("%X", end))
+	began := time.Now()
+	if err := compact(); err != nil {
+		logger.Error("compaction failed", "start", fmt.Sprintf("%X", start),
+			"end", fmt.Sprintf("%X", end), "err", err)
+		return err
+	}
+	logger.Info("compaction completed", "start", fmt.Sprintf("%X", start),
+		"end", fmt.Sprintf("%X", end), "duration", time.Since(began))
+	return nil
+}
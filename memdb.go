@@ -2,7 +2,11 @@ package db
 
 import (
 	"bytes"
+	"container/list"
+	"context"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/google/btree"
@@ -14,7 +18,15 @@ const (
 )
 
 func init() {
-	registerDBCreator(MemDBBackend, func(name, dir string) (DB, error) {
+	registerDBCreator(MemDBBackend, func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
+		// MemDBs are not persisted, so simulate existence with the path other backends would use.
+		dbPath := filepath.Join(dir, name+".db")
+		if err := opts.checkExistence(dbPath, fileExists(dbPath)); err != nil {
+			return nil, err
+		}
 		return NewMemDB(), nil
 	}, false)
 }
@@ -42,6 +54,29 @@ func newPair(key, value []byte) item {
 	return item{key: key, value: value}
 }
 
+// EvictionPolicy determines which entries a size-bounded MemDB evicts when it is full.
+type EvictionPolicy int
+
+const (
+	// EvictionFIFO evicts the entry that was inserted first. Replacing the value of an existing
+	// key does not change its position.
+	EvictionFIFO EvictionPolicy = iota
+	// EvictionLRU evicts the entry that was least recently read or written through Get, Has,
+	// Set or batches. Iterators do not count as accesses.
+	EvictionLRU
+)
+
+// MemDBOptions configures a MemDB.
+type MemDBOptions struct {
+	// MaxBytes is the maximum number of key and value bytes stored. Once a write makes the
+	// database exceed it, other entries are evicted according to Policy. The entry being written
+	// is never evicted by its own write, so a single entry larger than MaxBytes is retained.
+	// Zero or a negative value means the database is unbounded.
+	MaxBytes int64
+	// Policy is the eviction policy used when MaxBytes is exceeded.
+	Policy EvictionPolicy
+}
+
 // MemDB is an in-memory database backend using a B-tree for storage.
 //
 // For performance reasons, all given and returned keys and values are pointers to the in-memory
@@ -51,18 +86,75 @@ func newPair(key, value []byte) item {
 type MemDB struct {
 	mtx   sync.RWMutex
 	btree *btree.BTree
+	opts  MemDBOptions
+
+	// The fields below are only used by size-bounded databases. orderMtx protects order, so that
+	// LRU reads can update the order while only holding a read lock on mtx.
+	size     int64
+	orderMtx sync.Mutex
+	order    *list.List // of []byte keys, front is evicted first
+	elements map[string]*list.Element
 }
 
 var _ DB = (*MemDB)(nil)
+var _ Sizable = (*MemDB)(nil)
 
 // NewMemDB creates a new in-memory database.
 func NewMemDB() *MemDB {
+	return NewMemDBWithOptions(MemDBOptions{})
+}
+
+// NewMemDBWithCap creates a new in-memory database which stores at most maxBytes of keys and
+// values, evicting the oldest inserted entries when full.
+func NewMemDBWithCap(maxBytes int64) *MemDB {
+	return NewMemDBWithOptions(MemDBOptions{MaxBytes: maxBytes, Policy: EvictionFIFO})
+}
+
+// NewMemDBWithOptions creates a new in-memory database with the given options.
+func NewMemDBWithOptions(opts MemDBOptions) *MemDB {
 	database := &MemDB{
 		btree: btree.New(bTreeDegree),
+		opts:  opts,
+	}
+	if database.bounded() {
+		database.order = list.New()
+		database.elements = make(map[string]*list.Element)
 	}
 	return database
 }
 
+// NewMemDBFromMap creates a new in-memory database populated with the given entries, which is
+// mostly useful in tests. Keys are inserted in sorted order. It panics on empty keys or nil values.
+func NewMemDBFromMap(m map[string][]byte) *MemDB {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	database := NewMemDB()
+	for _, key := range keys {
+		if err := database.Set([]byte(key), m[key]); err != nil {
+			panic(fmt.Sprintf("invalid entry %q: %v", key, err))
+		}
+	}
+	return database
+}
+
+// NewMemDBFromIterator creates a new in-memory database populated with the remaining items of the
+// given iterator, e.g. to snapshot a domain of another database. Keys and values are copied. The
+// iterator is not closed, which is left to the caller.
+func NewMemDBFromIterator(itr Iterator) (*MemDB, error) {
+	database := NewMemDB()
+	for ; itr.Valid(); itr.Next() {
+		database.set(cp(itr.Key()), cp(itr.Value()))
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
 // Get implements DB.
 func (db *MemDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
@@ -73,6 +165,7 @@ func (db *MemDB) Get(key []byte) ([]byte, error) {
 
 	i := db.btree.Get(newKey(key))
 	if i != nil {
+		db.touch(key)
 		return i.(item).value, nil
 	}
 	return nil, nil
@@ -86,7 +179,16 @@ func (db *MemDB) Has(key []byte) (bool, error) {
 	db.mtx.RLock()
 	defer db.mtx.RUnlock()
 
-	return db.btree.Has(newKey(key)), nil
+	has := db.btree.Has(newKey(key))
+	if has {
+		db.touch(key)
+	}
+	return has, nil
+}
+
+// MultiGet implements DB.
+func (db *MemDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(db, keys)
 }
 
 // Set implements DB.
@@ -106,7 +208,73 @@ func (db *MemDB) Set(key []byte, value []byte) error {
 
 // set sets a value without locking the mutex.
 func (db *MemDB) set(key []byte, value []byte) {
-	db.btree.ReplaceOrInsert(newPair(key, value))
+	old := db.btree.ReplaceOrInsert(newPair(key, value))
+	if !db.bounded() {
+		return
+	}
+
+	db.orderMtx.Lock()
+	defer db.orderMtx.Unlock()
+	if old != nil {
+		db.size -= int64(len(old.(item).key) + len(old.(item).value))
+		if db.opts.Policy == EvictionLRU {
+			db.order.MoveToBack(db.elements[string(key)])
+		}
+	} else {
+		db.elements[string(key)] = db.order.PushBack(key)
+	}
+	db.size += int64(len(key) + len(value))
+
+	for db.size > db.opts.MaxBytes && db.order.Len() > 1 {
+		e := db.order.Front()
+		if bytes.Equal(e.Value.([]byte), key) {
+			// Only possible with FIFO, where the key we just replaced may be the oldest one.
+			e = e.Next()
+		}
+		db.evict(e)
+	}
+}
+
+// delete deletes a key without locking the mutex.
+func (db *MemDB) delete(key []byte) {
+	old := db.btree.Delete(newKey(key))
+	if old == nil || !db.bounded() {
+		return
+	}
+
+	db.orderMtx.Lock()
+	defer db.orderMtx.Unlock()
+	db.size -= int64(len(old.(item).key) + len(old.(item).value))
+	db.order.Remove(db.elements[string(key)])
+	delete(db.elements, string(key))
+}
+
+// evict removes the entry for the given element of the eviction order. It requires holding both
+// the write lock and orderMtx.
+func (db *MemDB) evict(e *list.Element) {
+	key := e.Value.([]byte)
+	old := db.btree.Delete(newKey(key))
+	db.size -= int64(len(old.(item).key) + len(old.(item).value))
+	db.order.Remove(e)
+	delete(db.elements, string(key))
+}
+
+// touch marks an existing key as accessed, for LRU eviction. It requires holding at least a
+// read lock.
+func (db *MemDB) touch(key []byte) {
+	if !db.bounded() || db.opts.Policy != EvictionLRU {
+		return
+	}
+	db.orderMtx.Lock()
+	defer db.orderMtx.Unlock()
+	if e, ok := db.elements[string(key)]; ok {
+		db.order.MoveToBack(e)
+	}
+}
+
+// bounded returns whether the database has a size limit.
+func (db *MemDB) bounded() bool {
+	return db.opts.MaxBytes > 0
 }
 
 // SetSync implements DB.
@@ -114,6 +282,28 @@ func (db *MemDB) SetSync(key []byte, value []byte) error {
 	return db.Set(key, value)
 }
 
+// CompareAndSet implements DB.
+func (db *MemDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	var current []byte
+	if i := db.btree.Get(newKey(key)); i != nil {
+		current = i.(item).value
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	db.set(key, newVal)
+	return true, nil
+}
+
 // Delete implements DB.
 func (db *MemDB) Delete(key []byte) error {
 	if len(key) == 0 {
@@ -126,11 +316,6 @@ func (db *MemDB) Delete(key []byte) error {
 	return nil
 }
 
-// delete deletes a key without locking the mutex.
-func (db *MemDB) delete(key []byte) {
-	db.btree.Delete(newKey(key))
-}
-
 // DeleteSync implements DB.
 func (db *MemDB) DeleteSync(key []byte) error {
 	return db.Delete(key)
@@ -138,9 +323,11 @@ func (db *MemDB) DeleteSync(key []byte) error {
 
 // Close implements DB.
 func (db *MemDB) Close() error {
-	// Close is a noop since for an in-memory database, we don't have a destination to flush
-	// contents to nor do we want any data loss on invoking Close().
+	// Other than removing the database from GlobalRegistry, Close is a noop since for an
+	// in-memory database, we don't have a destination to flush contents to nor do we want any data
+	// loss on invoking Close().
 	// See the discussion in https://github.com/tendermint/tendermint/libs/pull/56
+	GlobalRegistry.unregister(db)
 	return nil
 }
 
@@ -168,11 +355,122 @@ func (db *MemDB) Stats() map[string]string {
 	return stats
 }
 
+// ApproxSize implements Sizable. It is the exact total length of all keys and values.
+func (db *MemDB) ApproxSize() (int64, error) {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	var size int64
+	db.btree.Ascend(func(i btree.Item) bool {
+		item := i.(item)
+		size += int64(len(item.key) + len(item.value))
+		return true
+	})
+	return size, nil
+}
+
+// Count implements Countable. The keys are counted in the B-tree, without copying any values.
+func (db *MemDB) Count(start, end []byte) (int64, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return 0, errKeyEmpty
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	if start == nil && end == nil {
+		return int64(db.btree.Len()), nil
+	}
+	if isEmptyDomain(start, end) {
+		return 0, nil
+	}
+	var count int64
+	visitor := func(i btree.Item) bool {
+		count++
+		return true
+	}
+	switch {
+	case start == nil:
+		db.btree.AscendLessThan(newKey(end), visitor)
+	case end == nil:
+		db.btree.AscendGreaterOrEqual(newKey(start), visitor)
+	default:
+		db.btree.AscendRange(newKey(start), newKey(end), visitor)
+	}
+	return count, nil
+}
+
+// DeleteRange implements DB. The whole domain is deleted atomically.
+func (db *MemDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	var keys [][]byte
+	visitor := func(i btree.Item) bool {
+		keys = append(keys, i.(item).key)
+		return true
+	}
+	switch {
+	case start == nil && end == nil:
+		db.btree.Ascend(visitor)
+	case end == nil:
+		db.btree.AscendGreaterOrEqual(newKey(start), visitor)
+	case start == nil:
+		db.btree.AscendLessThan(newKey(end), visitor)
+	default:
+		db.btree.AscendRange(newKey(start), newKey(end), visitor)
+	}
+	for _, key := range keys {
+		db.delete(key)
+	}
+	return nil
+}
+
+// IteratorWithContext implements DB.
+func (db *MemDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB. It is a no-op.
+func (db *MemDB) Compact(start, end []byte) error {
+	return nil
+}
+
+// WriteBatch implements DB.
+func (db *MemDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *MemDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *MemDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *MemDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
 // NewBatch implements DB.
 func (db *MemDB) NewBatch() Batch {
 	return newMemDBBatch(db)
 }
 
+// NewBatchWithSize implements DB.
+func (db *MemDB) NewBatchWithSize(expectedOps int) Batch {
+	return newMemDBBatchWithSize(db, expectedOps)
+}
+
 // Iterator implements DB.
 // Takes out a read-lock on the database until the iterator is closed.
 func (db *MemDB) Iterator(start, end []byte) (Iterator, error) {
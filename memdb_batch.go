@@ -26,9 +26,17 @@ var _ Batch = (*memDBBatch)(nil)
 
 // newMemDBBatch creates a new memDBBatch
 func newMemDBBatch(db *MemDB) *memDBBatch {
+	return newMemDBBatchWithSize(db, 0)
+}
+
+// newMemDBBatchWithSize creates a new memDBBatch with room for the given number of operations.
+func newMemDBBatchWithSize(db *MemDB, size int) *memDBBatch {
+	if size < 0 {
+		size = 0
+	}
 	return &memDBBatch{
 		db:  db,
-		ops: []operation{},
+		ops: make([]operation, 0, size),
 	}
 }
 
@@ -59,6 +67,11 @@ func (b *memDBBatch) Delete(key []byte) error {
 	return nil
 }
 
+// Len implements Batch.
+func (b *memDBBatch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *memDBBatch) Write() error {
 	if b.ops == nil {
@@ -16,12 +16,14 @@ const (
 
 // memDBIterator is a memDB iterator.
 type memDBIterator struct {
-	ch     <-chan *item
-	cancel context.CancelFunc
-	item   *item
-	start  []byte
-	end    []byte
-	useMtx bool
+	db      *MemDB
+	ch      <-chan *item
+	cancel  context.CancelFunc
+	item    *item
+	start   []byte
+	end     []byte
+	reverse bool
+	useMtx  bool
 }
 
 var _ Iterator = (*memDBIterator)(nil)
@@ -32,16 +34,27 @@ func newMemDBIterator(db *MemDB, start []byte, end []byte, reverse bool) *memDBI
 }
 
 func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool, useMtx bool) *memDBIterator {
-	ctx, cancel := context.WithCancel(context.Background())
-	ch := make(chan *item, chBufferSize)
 	iter := &memDBIterator{
-		ch:     ch,
-		cancel: cancel,
-		start:  start,
-		end:    end,
-		useMtx: useMtx,
+		db:      db,
+		start:   start,
+		end:     end,
+		reverse: reverse,
+		useMtx:  useMtx,
 	}
+	iter.traverse(start, end, false)
+	return iter
+}
 
+// traverse starts a traversal goroutine over [start, end), or [start, end] if inclusiveEnd is
+// set, and positions the iterator at the first item. inclusiveEnd is only supported in reverse.
+func (i *memDBIterator) traverse(start, end []byte, inclusiveEnd bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *item, chBufferSize)
+	i.ch = ch
+	i.cancel = cancel
+	i.item = nil
+
+	db, reverse, useMtx := i.db, i.reverse, i.useMtx
 	if useMtx {
 		db.mtx.RLock()
 	}
@@ -87,7 +100,9 @@ func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool
 			db.btree.Descend(visitor)
 		default:
 			// skip end and abort after start, since we use [start, end) while btree uses (start, end]
-			skipEqual = end
+			if !inclusiveEnd {
+				skipEqual = end
+			}
 			abortLessThan = start
 			db.btree.DescendLessOrEqual(newKey(end), visitor)
 		}
@@ -96,18 +111,38 @@ func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool
 
 	// prime the iterator with the first value, if any
 	if item, ok := <-ch; ok {
-		iter.item = item
+		i.item = item
 	}
-
-	return iter
 }
 
-// Close implements Iterator.
-func (i *memDBIterator) Close() error {
+// stop stops the traversal goroutine, if still running.
+func (i *memDBIterator) stop() {
 	i.cancel()
 	for range i.ch { // drain channel
 	}
 	i.item = nil
+}
+
+// Seek implements Iterator. It restarts the traversal from the given key, which takes O(log n).
+func (i *memDBIterator) Seek(key []byte) bool {
+	i.stop()
+	switch {
+	case !i.reverse:
+		if i.start != nil && bytes.Compare(key, i.start) < 0 {
+			key = i.start
+		}
+		i.traverse(key, i.end, false)
+	case i.end != nil && bytes.Compare(key, i.end) >= 0:
+		i.traverse(i.start, i.end, false)
+	default:
+		i.traverse(i.start, key, true)
+	}
+	return i.Valid()
+}
+
+// Close implements Iterator.
+func (i *memDBIterator) Close() error {
+	i.stop()
 	return nil
 }
 
@@ -150,6 +185,11 @@ func (i *memDBIterator) Value() []byte {
 	return i.item.value
 }
 
+// Position implements Iterator.
+func (i *memDBIterator) Position() []byte {
+	return iteratorPosition(i)
+}
+
 func (i *memDBIterator) assertIsValid() {
 	if !i.Valid() {
 		panic("iterator is invalid")
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/google/btree"
+)
+
+// memDBSerializeVersion is the version of the format written by MemDB.Serialize:
+//
+//	header:  version (1 byte, currently 1) | entry count (uvarint)
+//	entry:   key length (uvarint) | key | value length (uvarint) | value
+//
+// Entries are in ascending key order.
+const memDBSerializeVersion = 1
+
+// errMemDBSerializedInvalid is returned by MemDB.Deserialize for invalid data.
+var errMemDBSerializedInvalid = errors.New("invalid serialized MemDB")
+
+// Serialize encodes the contents of the database as bytes, which Deserialize can load into another
+// MemDB. It is consistent with other writes, which are blocked while serializing.
+func (db *MemDB) Serialize() ([]byte, error) {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	var buf bytes.Buffer
+	var lenBuf [binary.MaxVarintLen64]byte
+	buf.WriteByte(memDBSerializeVersion)
+	n := binary.PutUvarint(lenBuf[:], uint64(db.btree.Len()))
+	buf.Write(lenBuf[:n])
+	db.btree.Ascend(func(i btree.Item) bool {
+		for _, bz := range [][]byte{i.(item).key, i.(item).value} {
+			n := binary.PutUvarint(lenBuf[:], uint64(len(bz)))
+			buf.Write(lenBuf[:n])
+			buf.Write(bz)
+		}
+		return true
+	})
+	return buf.Bytes(), nil
+}
+
+// Deserialize replaces the contents of the database with data encoded by Serialize. The data is
+// validated before the database is modified, and all keys are replaced atomically. A size-bounded
+// database evicts entries as usual if the data exceeds its capacity. The data is copied, and may
+// be reused by the caller.
+func (db *MemDB) Deserialize(data []byte) error {
+	items, err := decodeMemDBItems(cp(data))
+	if err != nil {
+		return err
+	}
+
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	db.btree = btree.New(bTreeDegree)
+	if db.bounded() {
+		db.orderMtx.Lock()
+		db.size = 0
+		db.order.Init()
+		db.elements = make(map[string]*list.Element)
+		db.orderMtx.Unlock()
+	}
+	for _, i := range items {
+		db.set(i.key, i.value)
+	}
+	return nil
+}
+
+// decodeMemDBItems decodes the items serialized by MemDB.Serialize. Keys and values point into
+// data.
+func decodeMemDBItems(data []byte) ([]item, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: missing header", errMemDBSerializedInvalid)
+	}
+	if version := data[0]; version != memDBSerializeVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errMemDBSerializedInvalid, version)
+	}
+	data = data[1:]
+	next := func() ([]byte, bool) {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return nil, false
+		}
+		end := n + int(length)
+		bz := data[n:end:end]
+		data = data[end:]
+		return bz, true
+	}
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: invalid entry count", errMemDBSerializedInvalid)
+	}
+	data = data[n:]
+	// Every entry takes at least 3 bytes, which bounds the allocation for a corrupt count.
+	if count > uint64(len(data))/3 {
+		return nil, fmt.Errorf("%w: %d entries can not fit in %d bytes", errMemDBSerializedInvalid,
+			count, len(data))
+	}
+	items := make([]item, 0, count)
+	for i := uint64(0); i < count; i++ {
+		key, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("%w: truncated entry %d", errMemDBSerializedInvalid, i)
+		}
+		value, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("%w: truncated entry %d", errMemDBSerializedInvalid, i)
+		}
+		if len(key) == 0 {
+			return nil, fmt.Errorf("%w: empty key in entry %d", errMemDBSerializedInvalid, i)
+		}
+		if i > 0 && bytes.Compare(key, items[i-1].key) <= 0 {
+			return nil, fmt.Errorf("%w: key %X out of order", errMemDBSerializedInvalid, key)
+		}
+		items = append(items, newPair(key, value))
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the last entry", errMemDBSerializedInvalid, len(data))
+	}
+	return items, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"bytes"
+
+	"github.com/google/btree"
+)
+
+// items returns the items of the database in ascending key order.
+func (db *MemDB) items() []item {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	items := make([]item, 0, db.btree.Len())
+	db.btree.Ascend(func(i btree.Item) bool {
+		items = append(items, i.(item))
+		return true
+	})
+	return items
+}
+
+// Union returns a new, unbounded MemDB with the keys of both databases, taking the value from
+// other for keys in both. Neither database is modified, and the result shares their keys and
+// values, which is safe since MemDB never modifies them in place. Each input is read atomically,
+// but the two are not read at the same time, so they should not be written concurrently.
+func (db *MemDB) Union(other *MemDB) *MemDB {
+	result := NewMemDB()
+	for _, i := range db.items() {
+		result.btree.ReplaceOrInsert(i)
+	}
+	for _, i := range other.items() {
+		result.btree.ReplaceOrInsert(i)
+	}
+	return result
+}
+
+// Intersection returns a new, unbounded MemDB with the keys present in both databases, taking
+// the values from db. Like Union, neither database is modified, and the result shares their keys
+// and values.
+func (db *MemDB) Intersection(other *MemDB) *MemDB {
+	result := NewMemDB()
+	left, right := db.items(), other.items()
+	for len(left) > 0 && len(right) > 0 {
+		switch c := bytes.Compare(left[0].key, right[0].key); {
+		case c < 0:
+			left = left[1:]
+		case c > 0:
+			right = right[1:]
+		default:
+			result.btree.ReplaceOrInsert(left[0])
+			left, right = left[1:], right[1:]
+		}
+	}
+	return result
+}
+
+// FilterKeys returns a new, unbounded MemDB with the keys of the database for which predicate
+// returns true. The database is not modified, and the result shares its keys and values, which
+// predicate must not modify. predicate is called after the database has been read, so it may
+// access the database.
+func (db *MemDB) FilterKeys(predicate func(key []byte) bool) *MemDB {
+	result := NewMemDB()
+	for _, i := range db.items() {
+		if predicate(i.key) {
+			result.btree.ReplaceOrInsert(i)
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemDBUnionIntersection(t *testing.T) {
+	testcases := map[string]struct {
+		a, b         map[string][]byte
+		union        map[string][]byte
+		intersection map[string][]byte
+	}{
+		"disjoint": {
+			a:            map[string][]byte{"a": {1}, "c": {3}},
+			b:            map[string][]byte{"b": {2}, "d": {4}},
+			union:        map[string][]byte{"a": {1}, "b": {2}, "c": {3}, "d": {4}},
+			intersection: map[string][]byte{},
+		},
+		"overlapping": {
+			a:            map[string][]byte{"a": {1}, "b": {2}, "c": {3}},
+			b:            map[string][]byte{"b": {20}, "c": {30}, "d": {40}},
+			union:        map[string][]byte{"a": {1}, "b": {20}, "c": {30}, "d": {40}},
+			intersection: map[string][]byte{"b": {2}, "c": {3}},
+		},
+		"identical": {
+			a:            map[string][]byte{"a": {1}, "b": {}},
+			b:            map[string][]byte{"a": {1}, "b": {}},
+			union:        map[string][]byte{"a": {1}, "b": {}},
+			intersection: map[string][]byte{"a": {1}, "b": {}},
+		},
+		"empty": {
+			a:            map[string][]byte{"a": {1}},
+			b:            map[string][]byte{},
+			union:        map[string][]byte{"a": {1}},
+			intersection: map[string][]byte{},
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			a, b := NewMemDBFromMap(tc.a), NewMemDBFromMap(tc.b)
+			assertKeyValues(t, a.Union(b), tc.union)
+			assertKeyValues(t, a.Intersection(b), tc.intersection)
+			assertKeyValues(t, a, tc.a)
+			assertKeyValues(t, b, tc.b)
+		})
+	}
+
+	// A database can be combined with itself, and the inputs are unaffected by later writes to the
+	// result.
+	a := NewMemDBFromMap(map[string][]byte{"a": {1}, "b": {2}})
+	union := a.Union(a)
+	assertKeyValues(t, union, map[string][]byte{"a": {1}, "b": {2}})
+	assertKeyValues(t, a.Intersection(a), map[string][]byte{"a": {1}, "b": {2}})
+	require.NoError(t, union.Set([]byte("a"), []byte{9}))
+	assertKeyValues(t, a, map[string][]byte{"a": {1}, "b": {2}})
+}
+
+func TestMemDBFilterKeys(t *testing.T) {
+	fixture := map[string][]byte{"a1": {1}, "a2": {2}, "b1": {3}, "b2": {}}
+	db := NewMemDBFromMap(fixture)
+
+	var seen []string
+	filtered := db.FilterKeys(func(key []byte) bool {
+		seen = append(seen, string(key))
+		return key[0] == 'a' || key[1] == '2'
+	})
+	assert.Equal(t, []string{"a1", "a2", "b1", "b2"}, seen)
+	assertKeyValues(t, filtered, map[string][]byte{"a1": {1}, "a2": {2}, "b2": {}})
+	assertKeyValues(t, db.FilterKeys(func([]byte) bool { return false }), map[string][]byte{})
+
+	// Values are shared rather than copied, and the original is unmodified by writes to the result.
+	value, err := filtered.Get([]byte("a1"))
+	require.NoError(t, err)
+	orig, err := db.Get([]byte("a1"))
+	require.NoError(t, err)
+	assert.Same(t, &orig[0], &value[0])
+	require.NoError(t, filtered.Set([]byte("a1"), []byte{9}))
+	require.NoError(t, filtered.Delete([]byte("a2")))
+	assertKeyValues(t, db, fixture)
+
+	// The predicate may read the database.
+	filtered = db.FilterKeys(func(key []byte) bool {
+		value, err := db.Get(key)
+		return err == nil && len(value) > 0 && value[0] > 1
+	})
+	assertKeyValues(t, filtered, map[string][]byte{"a2": {2}, "b1": {3}})
+}
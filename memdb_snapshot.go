@@ -0,0 +1,44 @@
+package db
+
+// memDBSnapshot is a MemDB snapshot. It holds a copy-on-write clone of the B-tree, so taking it
+// is cheap, and nodes are only copied as the live database is modified.
+type memDBSnapshot struct {
+	db *MemDB // nil once released
+}
+
+var (
+	_ Snapshotable = (*MemDB)(nil)
+	_ Snapshot     = (*memDBSnapshot)(nil)
+)
+
+// Snapshot implements Snapshotable.
+func (db *MemDB) Snapshot() (Snapshot, error) {
+	// Cloning the B-tree modifies it, so it must not run concurrently with reads or writes.
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	snap := NewMemDB()
+	snap.btree = db.btree.Clone()
+	return &memDBSnapshot{db: snap}, nil
+}
+
+// Get implements Snapshot.
+func (s *memDBSnapshot) Get(key []byte) ([]byte, error) {
+	if s.db == nil {
+		return nil, errSnapshotReleased
+	}
+	return s.db.Get(key)
+}
+
+// Iterator implements Snapshot.
+func (s *memDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if s.db == nil {
+		return nil, errSnapshotReleased
+	}
+	return s.db.Iterator(start, end)
+}
+
+// Release implements Snapshot.
+func (s *memDBSnapshot) Release() {
+	s.db = nil
+}
@@ -1,9 +1,208 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func TestNewMemDBFromMap(t *testing.T) {
+	m := make(map[string][]byte, 20)
+	expected := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%x", i*7)
+		m[key] = []byte{byte(i)}
+		expected = append(expected, key)
+	}
+	sort.Strings(expected)
+
+	db := NewMemDBFromMap(m)
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+
+	keys := make([]string, 0, 20)
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+		assert.Equal(t, m[string(itr.Key())], itr.Value())
+	}
+	require.NoError(t, itr.Error())
+	require.Equal(t, expected, keys)
+
+	require.Panics(t, func() { NewMemDBFromMap(map[string][]byte{"": {1}}) })
+	require.Panics(t, func() { NewMemDBFromMap(map[string][]byte{"a": nil}) })
+}
+
+func TestNewMemDBFromIterator(t *testing.T) {
+	ldb, err := NewGoLevelDB("test", t.TempDir())
+	require.NoError(t, err)
+	defer ldb.Close()
+	for i := int64(0); i < 50; i++ {
+		require.NoError(t, ldb.Set(int642Bytes(i), int642Bytes(i*2)))
+	}
+
+	itr, err := ldb.Iterator(int642Bytes(10), int642Bytes(40))
+	require.NoError(t, err)
+	db, err := NewMemDBFromIterator(itr)
+	require.NoError(t, err)
+	// The iterator is left open for the caller to close.
+	require.NoError(t, itr.Close())
+
+	expect := map[string][]byte{}
+	for i := int64(10); i < 40; i++ {
+		expect[string(int642Bytes(i))] = int642Bytes(i * 2)
+	}
+	assertKeyValues(t, db, expect)
+
+	// Iterator errors are returned.
+	mock := NewMockDBWrapping(ldb)
+	itr, err = mock.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	mock.Iterators[0].SetError("Error", errors.New("boom"))
+	_, err = NewMemDBFromIterator(itr)
+	require.Error(t, err)
+}
+
+func TestMemDBWithCapEvictsOldest(t *testing.T) {
+	// Each entry takes up 2 bytes, so at most 3 entries fit.
+	db := NewMemDBWithCap(6)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("b"), bz("2")))
+	require.NoError(t, db.Set(bz("c"), bz("3")))
+	assertKeyValues(t, db, map[string][]byte{"a": bz("1"), "b": bz("2"), "c": bz("3")})
+
+	// Reading or replacing a key does not change the FIFO order.
+	checkValue(t, db, bz("a"), bz("1"))
+	require.NoError(t, db.Set(bz("a"), bz("9")))
+	require.NoError(t, db.Set(bz("d"), bz("4")))
+	assertKeyValues(t, db, map[string][]byte{"b": bz("2"), "c": bz("3"), "d": bz("4")})
+
+	// A larger value may evict several entries.
+	require.NoError(t, db.Set(bz("e"), bz("555")))
+	assertKeyValues(t, db, map[string][]byte{"d": bz("4"), "e": bz("555")})
+
+	// Deleted entries free up space.
+	require.NoError(t, db.Delete(bz("d")))
+	require.NoError(t, db.Set(bz("f"), bz("6")))
+	assertKeyValues(t, db, map[string][]byte{"e": bz("555"), "f": bz("6")})
+
+	// An entry larger than the cap is retained, but evicts everything else.
+	require.NoError(t, db.Set(bz("g"), bz("7777777")))
+	assertKeyValues(t, db, map[string][]byte{"g": bz("7777777")})
+}
+
+func TestMemDBWithLRUEviction(t *testing.T) {
+	db := NewMemDBWithOptions(MemDBOptions{MaxBytes: 6, Policy: EvictionLRU})
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("b"), bz("2")))
+	require.NoError(t, db.Set(bz("c"), bz("3")))
+
+	// Reading a makes b the least recently used entry.
+	checkValue(t, db, bz("a"), bz("1"))
+	require.NoError(t, db.Set(bz("d"), bz("4")))
+	assertKeyValues(t, db, map[string][]byte{"a": bz("1"), "c": bz("3"), "d": bz("4")})
+
+	// Has and Set count as accesses as well, while iterators do not.
+	ok, err := db.Has(bz("c"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, db.Set(bz("a"), bz("9")))
+	require.NoError(t, db.Set(bz("e"), bz("5")))
+	assertKeyValues(t, db, map[string][]byte{"a": bz("9"), "c": bz("3"), "e": bz("5")})
+
+	// Batch writes are accounted for and evict entries too.
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set(bz("f"), bz("6")))
+	require.NoError(t, batch.Set(bz("g"), bz("7")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assertKeyValues(t, db, map[string][]byte{"e": bz("5"), "f": bz("6"), "g": bz("7")})
+}
+
+func TestMemDBWithCapIterator(t *testing.T) {
+	db := NewMemDBWithCap(9)
+	for _, key := range []string{"1", "2", "3", "4", "5", "6"} {
+		require.NoError(t, db.Set(bz(key), bz("v"+key)))
+	}
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	assert.Equal(t, []string{"4", "5", "6"}, keys)
+}
+
+func TestMemDBSerialize(t *testing.T) {
+	db := NewMemDBFromMap(map[string][]byte{"a": {1}, "b": {}, "c": {3, 3}})
+	data, err := db.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 3, 1, 'a', 1, 1, 1, 'b', 0, 1, 'c', 2, 3, 3}, data)
+
+	// Modifying the original does not affect the serialized snapshot.
+	require.NoError(t, db.Set([]byte("a"), []byte{9}))
+	require.NoError(t, db.Delete([]byte("c")))
+	require.NoError(t, db.Set([]byte("d"), []byte{4}))
+
+	// Deserializing replaces all existing keys.
+	restored := NewMemDBFromMap(map[string][]byte{"b": {2}, "z": {26}})
+	require.NoError(t, restored.Deserialize(data))
+	assertKeyValues(t, restored, map[string][]byte{"a": {1}, "b": {}, "c": {3, 3}})
+	assertKeyValues(t, db, map[string][]byte{"a": {9}, "b": {}, "d": {4}})
+
+	// The data is copied.
+	data[3] = 'x'
+	checkValue(t, restored, []byte("a"), []byte{1})
+
+	empty, err := NewMemDB().Serialize()
+	require.NoError(t, err)
+	require.NoError(t, restored.Deserialize(empty))
+	assertKeyValues(t, restored, map[string][]byte{})
+
+	// Size-bounded databases keep their accounting, and evict entries over capacity.
+	bounded := NewMemDBWithCap(4)
+	require.NoError(t, bounded.Set([]byte("x"), []byte{1, 2, 3}))
+	data, err = NewMemDBFromMap(map[string][]byte{"a": {1}, "b": {2}, "c": {3}}).Serialize()
+	require.NoError(t, err)
+	require.NoError(t, bounded.Deserialize(data))
+	assertKeyValues(t, bounded, map[string][]byte{"b": {2}, "c": {3}})
+	size, err := bounded.ApproxSize()
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, size)
+}
+
+func TestMemDBDeserializeInvalid(t *testing.T) {
+	data, err := NewMemDBFromMap(map[string][]byte{"a": {1}, "b": {2}}).Serialize()
+	require.NoError(t, err)
+
+	for name, invalid := range map[string][]byte{
+		"empty":          {},
+		"version":        append([]byte{2}, data[1:]...),
+		"truncated":      data[:len(data)-1],
+		"trailing bytes": append(cp(data), 0),
+		"count":          {1, 0xff, 0xff, 0xff, 0xff, 0x0f},
+		"empty key":      {1, 1, 0, 1, 1},
+		"order":          {1, 2, 1, 'b', 0, 1, 'a', 0},
+		"duplicate":      {1, 2, 1, 'a', 0, 1, 'a', 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := NewMemDBFromMap(map[string][]byte{"x": {1}})
+			err := db.Deserialize(invalid)
+			require.ErrorIs(t, err, errMemDBSerializedInvalid)
+			// The database is left unchanged.
+			assertKeyValues(t, db, map[string][]byte{"x": {1}})
+		})
+	}
+}
+
 func BenchmarkMemDBRangeScans1M(b *testing.B) {
 	db := NewMemDB()
 	defer db.Close()
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// MergeDB is a read-only view of an overlay database on top of a base database, e.g. the writes
+// of a block execution sandbox on top of the committed state. Keys present in the overlay shadow
+// the base, and keys in the deleted set are hidden from the base, representing deletions made in
+// the overlay. A key that is both deleted and present in the overlay has been set again after
+// the deletion, so the overlay value is used.
+//
+// All writes fail with ErrReadOnly. Closing a MergeDB does not close the merged databases.
+type MergeDB struct {
+	overlay DB
+	base    DB
+	deleted map[string]struct{}
+}
+
+var _ DB = (*MergeDB)(nil)
+
+// NewMergeDB creates a MergeDB of overlay on top of base, where the given keys are deleted from
+// base.
+func NewMergeDB(overlay, base DB, deleted [][]byte) *MergeDB {
+	set := make(map[string]struct{}, len(deleted))
+	for _, key := range deleted {
+		set[string(key)] = struct{}{}
+	}
+	return &MergeDB{
+		overlay: overlay,
+		base:    base,
+		deleted: set,
+	}
+}
+
+// isDeleted returns whether a key is deleted from the base.
+func (mdb *MergeDB) isDeleted(key []byte) bool {
+	_, ok := mdb.deleted[string(key)]
+	return ok
+}
+
+// Get implements DB.
+func (mdb *MergeDB) Get(key []byte) ([]byte, error) {
+	value, err := mdb.overlay.Get(key)
+	if err != nil || value != nil || mdb.isDeleted(key) {
+		return value, err
+	}
+	return mdb.base.Get(key)
+}
+
+// Has implements DB.
+func (mdb *MergeDB) Has(key []byte) (bool, error) {
+	ok, err := mdb.overlay.Has(key)
+	if err != nil || ok || mdb.isDeleted(key) {
+		return ok, err
+	}
+	return mdb.base.Has(key)
+}
+
+// MultiGet implements DB.
+func (mdb *MergeDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(mdb, keys)
+}
+
+// Set implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) Set(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+// SetSync implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) SetSync(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+// Delete implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) Delete(key []byte) error {
+	return ErrReadOnly
+}
+
+// DeleteSync implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) DeleteSync(key []byte) error {
+	return ErrReadOnly
+}
+
+// CompareAndSet implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return false, ErrReadOnly
+}
+
+// DeleteRange implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) DeleteRange(start, end []byte) error {
+	return ErrReadOnly
+}
+
+// Iterator implements DB.
+func (mdb *MergeDB) Iterator(start, end []byte) (Iterator, error) {
+	overlay, err := mdb.overlay.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	base, err := mdb.base.Iterator(start, end)
+	if err != nil {
+		overlay.Close()
+		return nil, err
+	}
+	return newMergeDBIterator(mdb, overlay, base, false), nil
+}
+
+// ReverseIterator implements DB.
+func (mdb *MergeDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	overlay, err := mdb.overlay.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	base, err := mdb.base.ReverseIterator(start, end)
+	if err != nil {
+		overlay.Close()
+		return nil, err
+	}
+	return newMergeDBIterator(mdb, overlay, base, true), nil
+}
+
+// IteratorWithContext implements DB.
+func (mdb *MergeDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, mdb, start, end)
+}
+
+// Compact implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) Compact(start, end []byte) error {
+	return ErrReadOnly
+}
+
+// WriteBatch implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) WriteBatch(ops []BatchOp) error {
+	return ErrReadOnly
+}
+
+// WriteBatchSync implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) WriteBatchSync(ops []BatchOp) error {
+	return ErrReadOnly
+}
+
+// ApplyLog implements DB. It always fails with ErrReadOnly.
+func (mdb *MergeDB) ApplyLog(ops BatchOpList) error {
+	return ErrReadOnly
+}
+
+// ForEach implements DB.
+func (mdb *MergeDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(mdb, fn)
+}
+
+// Close implements DB. The merged databases are not closed.
+func (mdb *MergeDB) Close() error {
+	return nil
+}
+
+// NewBatch implements DB. Writing the batch fails with ErrReadOnly.
+func (mdb *MergeDB) NewBatch() Batch {
+	return readOnlyBatch{}
+}
+
+// NewBatchWithSize implements DB. Writing the batch fails with ErrReadOnly.
+func (mdb *MergeDB) NewBatchWithSize(expectedOps int) Batch {
+	return readOnlyBatch{}
+}
+
+// Print implements DB.
+func (mdb *MergeDB) Print() error {
+	itr, err := mdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of both databases are included, prefixed by "overlay." and
+// "base.".
+func (mdb *MergeDB) Stats() map[string]string {
+	stats := map[string]string{
+		"database.type":    "mergeDB",
+		"database.deleted": fmt.Sprintf("%d", len(mdb.deleted)),
+	}
+	for k, v := range mdb.overlay.Stats() {
+		stats["overlay."+k] = v
+	}
+	for k, v := range mdb.base.Stats() {
+		stats["base."+k] = v
+	}
+	return stats
+}
+
+// mergeDBIterator merges an overlay and a base iterator, skipping base keys which are deleted.
+type mergeDBIterator struct {
+	*mergedIterator
+	db *MergeDB
+}
+
+var _ Iterator = (*mergeDBIterator)(nil)
+
+func newMergeDBIterator(db *MergeDB, overlay, base Iterator, reverse bool) *mergeDBIterator {
+	itr := &mergeDBIterator{
+		mergedIterator: newMergedIterator([]Iterator{overlay, base}, reverse),
+		db:             db,
+	}
+	itr.skipDeleted()
+	return itr
+}
+
+// skipDeleted advances past deleted keys which are only present in the base.
+func (itr *mergeDBIterator) skipDeleted() {
+	for itr.mergedIterator.Valid() && itr.current() == 1 && itr.db.isDeleted(itr.mergedIterator.Key()) {
+		itr.mergedIterator.Next()
+	}
+}
+
+// Next implements Iterator.
+func (itr *mergeDBIterator) Next() {
+	itr.mergedIterator.Next()
+	itr.skipDeleted()
+}
+
+// Seek implements Iterator.
+func (itr *mergeDBIterator) Seek(key []byte) bool {
+	itr.mergedIterator.Seek(key)
+	itr.skipDeleted()
+	return itr.Valid()
+}
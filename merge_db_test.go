@@ -0,0 +1,77 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDB(t *testing.T) {
+	base := NewMemDBFromMap(map[string][]byte{
+		"a": {1},
+		"b": {2},
+		"c": {3},
+		"d": {4},
+	})
+	overlay := NewMemDBFromMap(map[string][]byte{
+		"b": {20}, // shadows base
+		"d": {40}, // deleted, then set again
+		"e": {50}, // only in overlay
+	})
+	mdb := NewMergeDB(overlay, base, [][]byte{[]byte("c"), []byte("d"), []byte("x")})
+
+	checkValue(t, mdb, []byte("a"), []byte{1})
+	checkValue(t, mdb, []byte("b"), []byte{20})
+	checkValue(t, mdb, []byte("c"), nil)
+	checkValue(t, mdb, []byte("d"), []byte{40})
+	checkValue(t, mdb, []byte("e"), []byte{50})
+	checkValue(t, mdb, []byte("x"), nil)
+	ok, err := mdb.Has([]byte("c"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = mdb.Has([]byte("a"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assertKeyValues(t, mdb, map[string][]byte{
+		"a": {1},
+		"b": {20},
+		"d": {40},
+		"e": {50},
+	})
+
+	// Range iteration, in both directions.
+	itr, err := mdb.Iterator([]byte("b"), []byte("e"))
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("b"), []byte{20})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("d"), []byte{40})
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	itr, err = mdb.ReverseIterator([]byte("a"), []byte("d"))
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("b"), []byte{20})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	// Seeking skips deleted keys.
+	itr, err = mdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Seek([]byte("c")))
+	checkItem(t, itr, []byte("d"), []byte{40})
+	require.NoError(t, itr.Close())
+
+	// The view is read-only, and leaves the merged databases untouched.
+	require.ErrorIs(t, mdb.Set([]byte("a"), []byte{9}), ErrReadOnly)
+	require.ErrorIs(t, mdb.Delete([]byte("a")), ErrReadOnly)
+	batch := mdb.NewBatch()
+	require.ErrorIs(t, batch.Set([]byte("a"), []byte{9}), ErrReadOnly)
+	require.NoError(t, batch.Close())
+	require.NoError(t, mdb.Close())
+	checkValue(t, base, []byte("c"), []byte{3})
+	checkValue(t, overlay, []byte("a"), nil)
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// mergedIterator merges several iterators over the same domain into one, yielding each key once.
+// When several sources contain the same key, the value from the first of them is used. Valid
+// sources are kept in a heap ordered by their current key, so each step takes logarithmic time
+// in the number of sources.
+type mergedIterator struct {
+	sources []Iterator
+	heap    mergeHeap
+}
+
+var _ Iterator = (*mergedIterator)(nil)
+
+// newMergedIterator merges the given iterators, which must all iterate in the same direction.
+// The merged iterator takes ownership of them, and closes them when closed.
+func newMergedIterator(sources []Iterator, reverse bool) *mergedIterator {
+	itr := &mergedIterator{
+		sources: sources,
+		heap: mergeHeap{
+			sources: sources,
+			reverse: reverse,
+			indexes: make([]int, 0, len(sources)),
+		},
+	}
+	itr.init()
+	return itr
+}
+
+// init rebuilds the heap from all valid sources.
+func (itr *mergedIterator) init() {
+	itr.heap.indexes = itr.heap.indexes[:0]
+	for i, source := range itr.sources {
+		if source.Valid() {
+			itr.heap.indexes = append(itr.heap.indexes, i)
+		}
+	}
+	heap.Init(&itr.heap)
+}
+
+// current returns the index of the source holding the current item, or -1 if invalid.
+func (itr *mergedIterator) current() int {
+	if itr.heap.Len() == 0 {
+		return -1
+	}
+	return itr.heap.indexes[0]
+}
+
+// Domain implements Iterator.
+func (itr *mergedIterator) Domain() (start []byte, end []byte) {
+	return itr.sources[0].Domain()
+}
+
+// Valid implements Iterator.
+func (itr *mergedIterator) Valid() bool {
+	return itr.heap.Len() > 0 && itr.Error() == nil
+}
+
+// Next implements Iterator. Every source positioned at the current key is advanced.
+func (itr *mergedIterator) Next() {
+	itr.assertIsValid()
+	key := cp(itr.Key())
+	for itr.heap.Len() > 0 {
+		source := itr.sources[itr.current()]
+		if !bytes.Equal(source.Key(), key) {
+			break
+		}
+		source.Next()
+		if source.Valid() {
+			heap.Fix(&itr.heap, 0)
+		} else {
+			heap.Pop(&itr.heap)
+		}
+	}
+}
+
+// Seek implements Iterator.
+func (itr *mergedIterator) Seek(key []byte) bool {
+	for _, source := range itr.sources {
+		source.Seek(key)
+	}
+	itr.init()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *mergedIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.sources[itr.current()].Key()
+}
+
+// Value implements Iterator.
+func (itr *mergedIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.sources[itr.current()].Value()
+}
+
+// Position implements Iterator.
+func (itr *mergedIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *mergedIterator) Error() error {
+	for _, source := range itr.sources {
+		if err := source.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Iterator.
+func (itr *mergedIterator) Close() error {
+	var err error
+	for _, source := range itr.sources {
+		if cerr := source.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (itr *mergedIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
+
+// mergeHeap is a heap of the indexes of valid sources, ordered by their current keys in the
+// iteration direction, and then by index so that earlier sources take precedence.
+type mergeHeap struct {
+	sources []Iterator
+	reverse bool
+	indexes []int
+}
+
+var _ heap.Interface = (*mergeHeap)(nil)
+
+// Len implements heap.Interface.
+func (h *mergeHeap) Len() int {
+	return len(h.indexes)
+}
+
+// Less implements heap.Interface.
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.indexes[i], h.indexes[j]
+	cmp := bytes.Compare(h.sources[a].Key(), h.sources[b].Key())
+	if h.reverse {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return a < b
+}
+
+// Swap implements heap.Interface.
+func (h *mergeHeap) Swap(i, j int) {
+	h.indexes[i], h.indexes[j] = h.indexes[j], h.indexes[i]
+}
+
+// Push implements heap.Interface.
+func (h *mergeHeap) Push(x interface{}) {
+	h.indexes = append(h.indexes, x.(int))
+}
+
+// Pop implements heap.Interface.
+func (h *mergeHeap) Pop() interface{} {
+	last := h.indexes[len(h.indexes)-1]
+	h.indexes = h.indexes[:len(h.indexes)-1]
+	return last
+}
@@ -0,0 +1,27 @@
+package db
+
+// MigrateDB copies all key/value pairs from src to dst, as transformed by transform, e.g. to
+// change the key schema. transform returns the new key and value for each pair, and false to
+// skip it; it must not retain its arguments, which are only valid until it returns.
+//
+// It returns the first error encountered, in which case dst contains a partial migration.
+func MigrateDB(src, dst DB, transform func(k, v []byte) ([]byte, []byte, bool)) error {
+	return MigrateDBWithOptions(src, dst, transform, CopyOptions{})
+}
+
+// MigrateDBWithOptions is like MigrateDB, but writes pairs to dst in batches of opts.BatchSize
+// pairs after transformation.
+func MigrateDBWithOptions(src, dst DB, transform func(k, v []byte) ([]byte, []byte, bool),
+	opts CopyOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCopyBatchSize
+	}
+
+	itr, err := src.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	return copyIterator(itr, dst, batchSize, transform)
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateDB(t *testing.T) {
+	src := NewMemDB()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, src.Set([]byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)}))
+	}
+	require.NoError(t, src.Set([]byte("skip"), []byte{0xff}))
+
+	dst := NewMockDBWrapping(NewMemDB())
+	err := MigrateDBWithOptions(src, dst, func(k, v []byte) ([]byte, []byte, bool) {
+		if bytes.Equal(k, []byte("skip")) {
+			return nil, nil, false
+		}
+		return bytes.ToUpper(k), append(cp(v), 1), true
+	}, CopyOptions{BatchSize: 10})
+	require.NoError(t, err)
+
+	expect := map[string][]byte{}
+	for i := 0; i < 25; i++ {
+		expect[fmt.Sprintf("KEY%02d", i)] = []byte{byte(i), 1}
+	}
+	assertKeyValues(t, dst, expect)
+	assert.Equal(t, 3, dst.Calls["NewBatchWithSize"])
+
+	// Without options, the default batch size is used.
+	dst = NewMockDBWrapping(NewMemDB())
+	err = MigrateDB(src, dst, func(k, v []byte) ([]byte, []byte, bool) {
+		return k, v, true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, dst.Calls["NewBatchWithSize"])
+	expect = map[string][]byte{"skip": {0xff}}
+	for i := 0; i < 25; i++ {
+		expect[fmt.Sprintf("key%02d", i)] = []byte{byte(i)}
+	}
+	assertKeyValues(t, dst, expect)
+}
+
+func TestMigrateDBPartial(t *testing.T) {
+	src := NewMemDB()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, src.Set([]byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)}))
+	}
+
+	// Transforming a key to an empty key makes the batch fail, after the first batch was written.
+	dst := NewMemDB()
+	err := MigrateDBWithOptions(src, dst, func(k, v []byte) ([]byte, []byte, bool) {
+		if bytes.Equal(k, []byte("key15")) {
+			return []byte{}, v, true
+		}
+		return bytes.ToUpper(k), v, true
+	}, CopyOptions{BatchSize: 10})
+	require.Equal(t, errKeyEmpty, err)
+	assert.Equal(t, 10, dst.btree.Len())
+}
@@ -0,0 +1,438 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// MockDB is a DB for use in tests only. It wraps another database, counts the calls made to each
+// of its methods, and can be told to fail them with SetError. Batches and iterators created by it
+// are MockBatches and MockIterators, which behave the same way.
+//
+// Calls may be made concurrently, but the recorded state must not be inspected while calls are in
+// progress.
+type MockDB struct {
+	// Calls counts the calls made to each method, keyed by method name.
+	Calls map[string]int
+	// Batches and Iterators hold the batches and iterators created so far, in order.
+	Batches   []*MockBatch
+	Iterators []*MockIterator
+
+	db   DB
+	mtx  sync.Mutex
+	errs map[string]error
+}
+
+var _ DB = (*MockDB)(nil)
+
+// NewMockDB creates a MockDB backed by a new MemDB.
+func NewMockDB() *MockDB {
+	return NewMockDBWrapping(NewMemDB())
+}
+
+// NewMockDBWrapping creates a MockDB wrapping the given database.
+func NewMockDBWrapping(db DB) *MockDB {
+	return &MockDB{
+		Calls: make(map[string]int),
+		db:    db,
+		errs:  make(map[string]error),
+	}
+}
+
+// SetError makes calls to the given method, e.g. "Get", fail with err without calling the wrapped
+// database. A nil err clears the error. Methods without an error result ignore it.
+func (m *MockDB) SetError(method string, err error) {
+	setMockError(&m.mtx, m.errs, method, err)
+}
+
+func (m *MockDB) call(method string) error {
+	return recordMockCall(&m.mtx, m.Calls, m.errs, method)
+}
+
+func (m *MockDB) newIterator(itr Iterator) *MockIterator {
+	mitr := &MockIterator{
+		Calls:  make(map[string]int),
+		source: itr,
+		errs:   make(map[string]error),
+	}
+	m.mtx.Lock()
+	m.Iterators = append(m.Iterators, mitr)
+	m.mtx.Unlock()
+	return mitr
+}
+
+func (m *MockDB) newBatch(batch Batch) *MockBatch {
+	mb := &MockBatch{
+		Calls: make(map[string]int),
+		batch: batch,
+		errs:  make(map[string]error),
+	}
+	m.mtx.Lock()
+	m.Batches = append(m.Batches, mb)
+	m.mtx.Unlock()
+	return mb
+}
+
+// Get implements DB.
+func (m *MockDB) Get(key []byte) ([]byte, error) {
+	if err := m.call("Get"); err != nil {
+		return nil, err
+	}
+	return m.db.Get(key)
+}
+
+// Has implements DB.
+func (m *MockDB) Has(key []byte) (bool, error) {
+	if err := m.call("Has"); err != nil {
+		return false, err
+	}
+	return m.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (m *MockDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	if err := m.call("MultiGet"); err != nil {
+		return nil, err
+	}
+	return m.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (m *MockDB) Set(key []byte, value []byte) error {
+	if err := m.call("Set"); err != nil {
+		return err
+	}
+	return m.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (m *MockDB) SetSync(key []byte, value []byte) error {
+	if err := m.call("SetSync"); err != nil {
+		return err
+	}
+	return m.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (m *MockDB) Delete(key []byte) error {
+	if err := m.call("Delete"); err != nil {
+		return err
+	}
+	return m.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (m *MockDB) DeleteSync(key []byte) error {
+	if err := m.call("DeleteSync"); err != nil {
+		return err
+	}
+	return m.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (m *MockDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if err := m.call("CompareAndSet"); err != nil {
+		return false, err
+	}
+	return m.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (m *MockDB) DeleteRange(start, end []byte) error {
+	if err := m.call("DeleteRange"); err != nil {
+		return err
+	}
+	return m.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (m *MockDB) Iterator(start, end []byte) (Iterator, error) {
+	if err := m.call("Iterator"); err != nil {
+		return nil, err
+	}
+	itr, err := m.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return m.newIterator(itr), nil
+}
+
+// ReverseIterator implements DB.
+func (m *MockDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if err := m.call("ReverseIterator"); err != nil {
+		return nil, err
+	}
+	itr, err := m.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return m.newIterator(itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (m *MockDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	if err := m.call("IteratorWithContext"); err != nil {
+		return nil, err
+	}
+	itr, err := m.db.IteratorWithContext(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return m.newIterator(itr), nil
+}
+
+// Compact implements DB.
+func (m *MockDB) Compact(start, end []byte) error {
+	if err := m.call("Compact"); err != nil {
+		return err
+	}
+	return m.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (m *MockDB) WriteBatch(ops []BatchOp) error {
+	if err := m.call("WriteBatch"); err != nil {
+		return err
+	}
+	return m.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (m *MockDB) WriteBatchSync(ops []BatchOp) error {
+	if err := m.call("WriteBatchSync"); err != nil {
+		return err
+	}
+	return m.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (m *MockDB) ApplyLog(ops BatchOpList) error {
+	if err := m.call("ApplyLog"); err != nil {
+		return err
+	}
+	return m.db.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (m *MockDB) ForEach(fn func(key, value []byte) error) error {
+	if err := m.call("ForEach"); err != nil {
+		return err
+	}
+	return m.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (m *MockDB) Close() error {
+	if err := m.call("Close"); err != nil {
+		return err
+	}
+	return m.db.Close()
+}
+
+// NewBatch implements DB.
+func (m *MockDB) NewBatch() Batch {
+	_ = m.call("NewBatch")
+	return m.newBatch(m.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (m *MockDB) NewBatchWithSize(expectedOps int) Batch {
+	_ = m.call("NewBatchWithSize")
+	return m.newBatch(m.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (m *MockDB) Print() error {
+	if err := m.call("Print"); err != nil {
+		return err
+	}
+	return m.db.Print()
+}
+
+// Stats implements DB.
+func (m *MockDB) Stats() map[string]string {
+	_ = m.call("Stats")
+	return m.db.Stats()
+}
+
+// MockBatch is a Batch for use in tests only. It wraps another batch, counts the calls made to
+// each of its methods, and can be told to fail them with SetError.
+type MockBatch struct {
+	// Calls counts the calls made to each method, keyed by method name.
+	Calls map[string]int
+
+	batch Batch
+	mtx   sync.Mutex
+	errs  map[string]error
+}
+
+var _ Batch = (*MockBatch)(nil)
+
+// SetError makes calls to the given method, e.g. "Write", fail with err without calling the
+// wrapped batch. A nil err clears the error. Methods without an error result ignore it.
+func (m *MockBatch) SetError(method string, err error) {
+	setMockError(&m.mtx, m.errs, method, err)
+}
+
+func (m *MockBatch) call(method string) error {
+	return recordMockCall(&m.mtx, m.Calls, m.errs, method)
+}
+
+// Set implements Batch.
+func (m *MockBatch) Set(key, value []byte) error {
+	if err := m.call("Set"); err != nil {
+		return err
+	}
+	return m.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (m *MockBatch) Delete(key []byte) error {
+	if err := m.call("Delete"); err != nil {
+		return err
+	}
+	return m.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (m *MockBatch) Len() int {
+	_ = m.call("Len")
+	return m.batch.Len()
+}
+
+// Write implements Batch.
+func (m *MockBatch) Write() error {
+	if err := m.call("Write"); err != nil {
+		return err
+	}
+	return m.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (m *MockBatch) WriteSync() error {
+	if err := m.call("WriteSync"); err != nil {
+		return err
+	}
+	return m.batch.WriteSync()
+}
+
+// Close implements Batch. The wrapped batch is closed even if an error has been set.
+func (m *MockBatch) Close() error {
+	err := m.call("Close")
+	if cerr := m.batch.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// MockIterator is an Iterator for use in tests only. It wraps another iterator, and counts the
+// calls made to each of its methods. Errors set with SetError are returned by Error and Close;
+// an error set for "Error" also makes the iterator invalid.
+type MockIterator struct {
+	// Calls counts the calls made to each method, keyed by method name.
+	Calls map[string]int
+
+	source Iterator
+	mtx    sync.Mutex
+	errs   map[string]error
+}
+
+var _ Iterator = (*MockIterator)(nil)
+
+// SetError makes calls to the given method, i.e. "Error" or "Close", fail with err. A nil err
+// clears the error.
+func (m *MockIterator) SetError(method string, err error) {
+	setMockError(&m.mtx, m.errs, method, err)
+}
+
+func (m *MockIterator) call(method string) error {
+	return recordMockCall(&m.mtx, m.Calls, m.errs, method)
+}
+
+// failed returns whether an error has been set for Error, without recording a call.
+func (m *MockIterator) failed() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.errs["Error"] != nil
+}
+
+// Domain implements Iterator.
+func (m *MockIterator) Domain() (start []byte, end []byte) {
+	_ = m.call("Domain")
+	return m.source.Domain()
+}
+
+// Valid implements Iterator.
+func (m *MockIterator) Valid() bool {
+	_ = m.call("Valid")
+	return !m.failed() && m.source.Valid()
+}
+
+// Seek implements Iterator.
+func (m *MockIterator) Seek(key []byte) bool {
+	_ = m.call("Seek")
+	return !m.failed() && m.source.Seek(key)
+}
+
+// Next implements Iterator.
+func (m *MockIterator) Next() {
+	_ = m.call("Next")
+	m.source.Next()
+}
+
+// Key implements Iterator.
+func (m *MockIterator) Key() []byte {
+	_ = m.call("Key")
+	return m.source.Key()
+}
+
+// Value implements Iterator.
+func (m *MockIterator) Value() []byte {
+	_ = m.call("Value")
+	return m.source.Value()
+}
+
+// Position implements Iterator.
+func (m *MockIterator) Position() []byte {
+	_ = m.call("Position")
+	if m.failed() {
+		return nil
+	}
+	return m.source.Position()
+}
+
+// Error implements Iterator.
+func (m *MockIterator) Error() error {
+	if err := m.call("Error"); err != nil {
+		return err
+	}
+	return m.source.Error()
+}
+
+// Close implements Iterator. The wrapped iterator is closed even if an error has been set.
+func (m *MockIterator) Close() error {
+	err := m.call("Close")
+	if cerr := m.source.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// recordMockCall counts a call to the given method, and returns the error set for it, if any.
+func recordMockCall(mtx *sync.Mutex, calls map[string]int, errs map[string]error, method string) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+	calls[method]++
+	return errs[method]
+}
+
+// setMockError sets or clears the error for the given method.
+func setMockError(mtx *sync.Mutex, errs map[string]error, method string, err error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if err == nil {
+		delete(errs, method)
+	} else {
+		errs[method] = err
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockDB(t *testing.T) {
+	db := NewMockDB()
+	errMock := errors.New("mock error")
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	checkValue(t, db, []byte("a"), []byte{1})
+	checkValue(t, db, []byte("b"), nil)
+	require.Equal(t, 1, db.Calls["Set"])
+	require.Equal(t, 2, db.Calls["Get"])
+
+	// Injected errors are returned without calling the wrapped database, until cleared.
+	db.SetError("Set", errMock)
+	require.ErrorIs(t, db.Set([]byte("b"), []byte{2}), errMock)
+	checkValue(t, db, []byte("b"), nil)
+	db.SetError("Set", nil)
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	checkValue(t, db, []byte("b"), []byte{2})
+	require.Equal(t, 3, db.Calls["Set"])
+
+	db.SetError("Iterator", errMock)
+	_, err := db.Iterator(nil, nil)
+	require.ErrorIs(t, err, errMock)
+	require.Empty(t, db.Iterators)
+}
+
+func TestMockBatch(t *testing.T) {
+	db := NewMockDB()
+	errMock := errors.New("mock error")
+
+	batch := db.NewBatch()
+	require.Len(t, db.Batches, 1)
+	require.Same(t, batch, db.Batches[0])
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.Equal(t, 1, batch.Len())
+
+	db.Batches[0].SetError("Write", errMock)
+	require.ErrorIs(t, batch.Write(), errMock)
+	require.NoError(t, batch.Close())
+	checkValue(t, db, []byte("a"), nil)
+
+	require.Equal(t, map[string]int{"Set": 1, "Len": 1, "Write": 1, "Close": 1}, db.Batches[0].Calls)
+	require.Equal(t, 1, db.Calls["NewBatch"])
+}
+
+func TestMockIterator(t *testing.T) {
+	db := NewMockDB()
+	errMock := errors.New("mock error")
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.Len(t, db.Iterators, 1)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	itr.Next()
+
+	// An error makes the iterator invalid.
+	db.Iterators[0].SetError("Error", errMock)
+	require.False(t, itr.Valid())
+	require.ErrorIs(t, itr.Error(), errMock)
+
+	db.Iterators[0].SetError("Close", errMock)
+	require.ErrorIs(t, itr.Close(), errMock)
+
+	calls := db.Iterators[0].Calls
+	require.Equal(t, 1, calls["Next"])
+	require.Equal(t, 1, calls["Key"])
+	require.Equal(t, 1, calls["Value"])
+	require.Equal(t, 1, calls["Close"])
+}
@@ -0,0 +1,34 @@
+package db
+
+import "errors"
+
+// errVersionInvalid is returned when attempting to use a version below 1.
+var errVersionInvalid = errors.New("version must be positive")
+
+// MVCCCapable is implemented by databases which keep multiple versions of each value. Versions
+// are positive, and a missing key has version 0. Use AsMVCC to check whether a database supports
+// it.
+type MVCCCapable interface {
+	// GetWithVersion fetches the latest value of the given key along with its version, or nil
+	// and 0 if it does not exist.
+	// CONTRACT: key readonly []byte
+	GetWithVersion(key []byte) (value []byte, version int64, err error)
+
+	// GetAtVersion fetches the value of the given key as of the given version, i.e. the value
+	// with the highest version not above it, along with that version. It returns nil and 0 if
+	// there is no such value.
+	// CONTRACT: key readonly []byte
+	GetAtVersion(key []byte, version int64) (value []byte, valueVersion int64, err error)
+
+	// SetWithVersion sets the value of the given key at the given version, replacing any value
+	// already stored at that version. Setting a version below the latest one leaves the latest
+	// value unchanged.
+	// CONTRACT: key, value readonly []byte
+	SetWithVersion(key []byte, value []byte, version int64) error
+}
+
+// AsMVCC returns the database as an MVCCCapable, if it supports it.
+func AsMVCC(db DB) (MVCCCapable, bool) {
+	mdb, ok := db.(MVCCCapable)
+	return mdb, ok
+}
@@ -0,0 +1,369 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MVCCMemDB is an in-memory database which keeps every version of each value, implementing both
+// DB and MVCCCapable. The DB methods operate on the latest versions: Set and batches store the
+// value at the version after the latest one, while Delete and DeleteRange remove all versions of
+// a key.
+type MVCCMemDB struct {
+	mtx      sync.RWMutex
+	versions map[string]map[int64][]byte
+	heads    map[string]int64 // latest version of each key
+	// latest holds the latest version of every value, for reads and iteration. It is only
+	// written while holding mtx.
+	latest *MemDB
+}
+
+var (
+	_ DB          = (*MVCCMemDB)(nil)
+	_ MVCCCapable = (*MVCCMemDB)(nil)
+)
+
+// NewMVCCMemDB creates a new, empty MVCCMemDB.
+func NewMVCCMemDB() *MVCCMemDB {
+	return &MVCCMemDB{
+		versions: make(map[string]map[int64][]byte),
+		heads:    make(map[string]int64),
+		latest:   NewMemDB(),
+	}
+}
+
+// latestVersion returns the latest version of a key, or 0 if it does not exist. It requires
+// holding at least a read lock.
+func (db *MVCCMemDB) latestVersion(key []byte) int64 {
+	return db.heads[string(key)]
+}
+
+// set stores a value at the given version. It requires holding the write lock.
+func (db *MVCCMemDB) set(key []byte, value []byte, version int64) error {
+	latest := db.latestVersion(key)
+	versions, ok := db.versions[string(key)]
+	if !ok {
+		versions = make(map[int64][]byte)
+		db.versions[string(key)] = versions
+	}
+	versions[version] = value
+	if version >= latest {
+		db.heads[string(key)] = version
+		return db.latest.Set(key, value)
+	}
+	return nil
+}
+
+// delete removes all versions of a key. It requires holding the write lock.
+func (db *MVCCMemDB) delete(key []byte) error {
+	delete(db.versions, string(key))
+	delete(db.heads, string(key))
+	return db.latest.Delete(key)
+}
+
+// GetWithVersion implements MVCCCapable.
+func (db *MVCCMemDB) GetWithVersion(key []byte) ([]byte, int64, error) {
+	if len(key) == 0 {
+		return nil, 0, errKeyEmpty
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	version := db.latestVersion(key)
+	if version == 0 {
+		return nil, 0, nil
+	}
+	return db.versions[string(key)][version], version, nil
+}
+
+// GetAtVersion implements MVCCCapable.
+func (db *MVCCMemDB) GetAtVersion(key []byte, version int64) ([]byte, int64, error) {
+	if len(key) == 0 {
+		return nil, 0, errKeyEmpty
+	}
+	if version < 1 {
+		return nil, 0, errVersionInvalid
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	var found int64
+	for v := range db.versions[string(key)] {
+		if v <= version && v > found {
+			found = v
+		}
+	}
+	if found == 0 {
+		return nil, 0, nil
+	}
+	return db.versions[string(key)][found], found, nil
+}
+
+// SetWithVersion implements MVCCCapable.
+func (db *MVCCMemDB) SetWithVersion(key []byte, value []byte, version int64) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if version < 1 {
+		return errVersionInvalid
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	return db.set(key, value, version)
+}
+
+// Get implements DB.
+func (db *MVCCMemDB) Get(key []byte) ([]byte, error) {
+	return db.latest.Get(key)
+}
+
+// Has implements DB.
+func (db *MVCCMemDB) Has(key []byte) (bool, error) {
+	return db.latest.Has(key)
+}
+
+// MultiGet implements DB.
+func (db *MVCCMemDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return db.latest.MultiGet(keys)
+}
+
+// Set implements DB. The value is stored at the version after the latest one.
+func (db *MVCCMemDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	return db.set(key, value, db.latestVersion(key)+1)
+}
+
+// SetSync implements DB.
+func (db *MVCCMemDB) SetSync(key []byte, value []byte) error {
+	return db.Set(key, value)
+}
+
+// Delete implements DB. All versions of the key are deleted.
+func (db *MVCCMemDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+	return db.delete(key)
+}
+
+// DeleteSync implements DB.
+func (db *MVCCMemDB) DeleteSync(key []byte) error {
+	return db.Delete(key)
+}
+
+// CompareAndSet implements DB. The latest value is compared, and replaced by a new version.
+func (db *MVCCMemDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	current, err := db.latest.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	return true, db.set(key, newVal, db.latestVersion(key)+1)
+}
+
+// DeleteRange implements DB. All versions of the keys are deleted, atomically.
+func (db *MVCCMemDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	for key := range db.versions {
+		if IsKeyInDomain([]byte(key), start, end) {
+			delete(db.versions, key)
+			delete(db.heads, key)
+		}
+	}
+	return db.latest.DeleteRange(start, end)
+}
+
+// Iterator implements DB. It iterates over the latest versions.
+func (db *MVCCMemDB) Iterator(start, end []byte) (Iterator, error) {
+	return db.latest.Iterator(start, end)
+}
+
+// ReverseIterator implements DB. It iterates over the latest versions.
+func (db *MVCCMemDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return db.latest.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB. It iterates over the latest versions.
+func (db *MVCCMemDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return db.latest.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB. It is a no-op.
+func (db *MVCCMemDB) Compact(start, end []byte) error {
+	return nil
+}
+
+// WriteBatch implements DB.
+func (db *MVCCMemDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *MVCCMemDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *MVCCMemDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB. It iterates over the latest versions.
+func (db *MVCCMemDB) ForEach(fn func(key, value []byte) error) error {
+	return db.latest.ForEach(fn)
+}
+
+// Close implements DB.
+func (db *MVCCMemDB) Close() error {
+	return nil
+}
+
+// NewBatch implements DB.
+func (db *MVCCMemDB) NewBatch() Batch {
+	return newMVCCMemDBBatch(db, 0)
+}
+
+// NewBatchWithSize implements DB.
+func (db *MVCCMemDB) NewBatchWithSize(expectedOps int) Batch {
+	return newMVCCMemDBBatch(db, expectedOps)
+}
+
+// Print implements DB. It prints the latest versions.
+func (db *MVCCMemDB) Print() error {
+	return db.latest.Print()
+}
+
+// Stats implements DB.
+func (db *MVCCMemDB) Stats() map[string]string {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	versions := 0
+	for _, v := range db.versions {
+		versions += len(v)
+	}
+	stats := db.latest.Stats()
+	stats["database.type"] = "mvccMemDB"
+	stats["database.versions"] = fmt.Sprintf("%d", versions)
+	return stats
+}
+
+// mvccMemDBBatch handles in-memory batching for MVCCMemDB. Every set stores a new version.
+type mvccMemDBBatch struct {
+	db  *MVCCMemDB
+	ops []operation
+}
+
+var _ Batch = (*mvccMemDBBatch)(nil)
+
+func newMVCCMemDBBatch(db *MVCCMemDB, size int) *mvccMemDBBatch {
+	if size < 0 {
+		size = 0
+	}
+	return &mvccMemDBBatch{
+		db:  db,
+		ops: make([]operation, 0, size),
+	}
+}
+
+// Set implements Batch.
+func (b *mvccMemDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *mvccMemDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Len implements Batch.
+func (b *mvccMemDBBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *mvccMemDBBatch) Write() error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			err = b.db.set(op.key, op.value, b.db.latestVersion(op.key)+1)
+		case opTypeDelete:
+			err = b.db.delete(op.key)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *mvccMemDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *mvccMemDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
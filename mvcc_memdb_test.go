@@ -0,0 +1,123 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+func checkVersion(t *testing.T, db MVCCCapable, key []byte, atVersion int64, value []byte, version int64) {
+	var (
+		v   []byte
+		ver int64
+		err error
+	)
+	if atVersion == 0 {
+		v, ver, err = db.GetWithVersion(key)
+	} else {
+		v, ver, err = db.GetAtVersion(key, atVersion)
+	}
+	require.NoError(t, err)
+	require.Equal(t, value, v)
+	require.Equal(t, version, ver)
+}
+
+func TestMVCCMemDB(t *testing.T) {
+	db := NewMVCCMemDB()
+	mdb, ok := AsMVCC(db)
+	require.True(t, ok)
+	_, ok = AsMVCC(NewMemDB())
+	require.False(t, ok)
+
+	checkVersion(t, mdb, []byte("a"), 0, nil, 0)
+
+	// Plain sets create new versions.
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("a"), []byte{2}))
+	checkVersion(t, mdb, []byte("a"), 0, []byte{2}, 2)
+	checkVersion(t, mdb, []byte("a"), 1, []byte{1}, 1)
+	checkValue(t, db, []byte("a"), []byte{2})
+
+	// Explicit versions may leave gaps, and older versions do not replace the latest value.
+	require.NoError(t, mdb.SetWithVersion([]byte("a"), []byte{10}, 10))
+	require.NoError(t, mdb.SetWithVersion([]byte("a"), []byte{5}, 5))
+	checkVersion(t, mdb, []byte("a"), 0, []byte{10}, 10)
+	checkVersion(t, mdb, []byte("a"), 7, []byte{5}, 5)
+	checkVersion(t, mdb, []byte("a"), 100, []byte{10}, 10)
+	checkValue(t, db, []byte("a"), []byte{10})
+
+	// Replacing the latest version updates the value.
+	require.NoError(t, mdb.SetWithVersion([]byte("a"), []byte{11}, 10))
+	checkValue(t, db, []byte("a"), []byte{11})
+
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{12}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{1}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkVersion(t, mdb, []byte("a"), 0, []byte{12}, 11)
+	checkVersion(t, mdb, []byte("b"), 0, []byte{1}, 1)
+
+	swapped, err := db.CompareAndSet([]byte("b"), []byte{1}, []byte{2})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	checkVersion(t, mdb, []byte("b"), 0, []byte{2}, 2)
+
+	// Deletes remove all versions.
+	require.NoError(t, db.Delete([]byte("a")))
+	checkVersion(t, mdb, []byte("a"), 0, nil, 0)
+	checkVersion(t, mdb, []byte("a"), 5, nil, 0)
+	require.NoError(t, db.DeleteRange(nil, nil))
+	checkVersion(t, mdb, []byte("b"), 0, nil, 0)
+
+	require.ErrorIs(t, mdb.SetWithVersion([]byte("a"), []byte{1}, 0), errVersionInvalid)
+	_, _, err = mdb.GetAtVersion([]byte("a"), -1)
+	require.ErrorIs(t, err, errVersionInvalid)
+	_, _, err = mdb.GetWithVersion(nil)
+	require.ErrorIs(t, err, errKeyEmpty)
+}
+
+func TestMVCCMemDBConcurrentVersions(t *testing.T) {
+	const (
+		workers  = 10
+		versions = 100
+	)
+	db := NewMVCCMemDB()
+
+	// Explicit versions of the same key, written in parallel and interleaved between workers.
+	g := errgroup.Group{}
+	for w := 0; w < workers; w++ {
+		w := w
+		g.Go(func() error {
+			for v := int64(w + 1); v <= workers*versions; v += workers {
+				if err := db.SetWithVersion([]byte("explicit"), int642Bytes(v), v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	// Implicit versions of another key, which must each get a distinct version.
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for i := 0; i < versions; i++ {
+				if err := db.Set([]byte("implicit"), []byte{1}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	require.NoError(t, g.Wait())
+
+	latest := int64(workers * versions)
+	checkVersion(t, db, []byte("explicit"), 0, int642Bytes(latest), latest)
+	checkValue(t, db, []byte("explicit"), int642Bytes(latest))
+	for v := int64(1); v <= latest; v++ {
+		checkVersion(t, db, []byte("explicit"), v, int642Bytes(v), v)
+	}
+	checkVersion(t, db, []byte("implicit"), 0, []byte{1}, latest)
+	require.Equal(t, fmt.Sprintf("%d", 2*latest), db.Stats()["database.versions"])
+}
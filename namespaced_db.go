@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// namespaces is the registry of namespaces in use by open NamespacedDBs, by wrapped database.
+var namespaces = struct {
+	sync.Mutex
+	byDB map[DB]map[string]struct{}
+}{byDB: make(map[DB]map[string]struct{})}
+
+// NamespacedDB wraps a namespace of another database as a logical database, like PrefixDB, for
+// application modules sharing a single database. Unlike PrefixDB, namespaces can not collide:
+//
+// - Keys are prefixed by the namespace's length (as a single byte) and then the namespace, so
+// that no namespace's keys can overlap another's, even if one namespace is a prefix of another.
+//
+// - Namespaces are registered with the wrapped database while open, and opening a namespace which
+// is already in use on the same database panics, since it is a bug in the application.
+type NamespacedDB struct {
+	namespace []byte
+	inner     DB
+	pdb       *PrefixDB
+}
+
+var _ DB = (*NamespacedDB)(nil)
+
+// NewNamespacedDB creates a NamespacedDB for the given namespace of the inner database. The
+// namespace must be between 1 and 255 bytes, and not in use by another open NamespacedDB on the
+// same database, otherwise it panics. The namespace is released by Close.
+func NewNamespacedDB(inner DB, namespace []byte) *NamespacedDB {
+	if len(namespace) == 0 || len(namespace) > 255 {
+		panic(fmt.Sprintf("namespace %q must be between 1 and 255 bytes, got %v",
+			namespace, len(namespace)))
+	}
+
+	namespaces.Lock()
+	defer namespaces.Unlock()
+	inUse := namespaces.byDB[inner]
+	if inUse == nil {
+		inUse = make(map[string]struct{})
+		namespaces.byDB[inner] = inUse
+	}
+	if _, ok := inUse[string(namespace)]; ok {
+		panic(fmt.Sprintf("namespace %q is already in use on this database, keys would collide",
+			namespace))
+	}
+	inUse[string(namespace)] = struct{}{}
+
+	prefix := make([]byte, 0, 1+len(namespace))
+	prefix = append(prefix, byte(len(namespace)))
+	prefix = append(prefix, namespace...)
+	return &NamespacedDB{
+		namespace: cp(namespace),
+		inner:     inner,
+		pdb:       NewPrefixDB(inner, prefix),
+	}
+}
+
+// release removes the namespace from the registry.
+func (ndb *NamespacedDB) release() {
+	namespaces.Lock()
+	defer namespaces.Unlock()
+	inUse := namespaces.byDB[ndb.inner]
+	delete(inUse, string(ndb.namespace))
+	if len(inUse) == 0 {
+		delete(namespaces.byDB, ndb.inner)
+	}
+}
+
+// Namespace returns the namespace of the database.
+func (ndb *NamespacedDB) Namespace() []byte {
+	return ndb.namespace
+}
+
+// Get implements DB.
+func (ndb *NamespacedDB) Get(key []byte) ([]byte, error) {
+	return ndb.pdb.Get(key)
+}
+
+// Has implements DB.
+func (ndb *NamespacedDB) Has(key []byte) (bool, error) {
+	return ndb.pdb.Has(key)
+}
+
+// MultiGet implements DB.
+func (ndb *NamespacedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return ndb.pdb.MultiGet(keys)
+}
+
+// Set implements DB.
+func (ndb *NamespacedDB) Set(key []byte, value []byte) error {
+	return ndb.pdb.Set(key, value)
+}
+
+// SetSync implements DB.
+func (ndb *NamespacedDB) SetSync(key []byte, value []byte) error {
+	return ndb.pdb.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (ndb *NamespacedDB) Delete(key []byte) error {
+	return ndb.pdb.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (ndb *NamespacedDB) DeleteSync(key []byte) error {
+	return ndb.pdb.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (ndb *NamespacedDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return ndb.pdb.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (ndb *NamespacedDB) DeleteRange(start, end []byte) error {
+	return ndb.pdb.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (ndb *NamespacedDB) Iterator(start, end []byte) (Iterator, error) {
+	return ndb.pdb.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (ndb *NamespacedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return ndb.pdb.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (ndb *NamespacedDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return ndb.pdb.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (ndb *NamespacedDB) Compact(start, end []byte) error {
+	return ndb.pdb.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (ndb *NamespacedDB) WriteBatch(ops []BatchOp) error {
+	return ndb.pdb.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (ndb *NamespacedDB) WriteBatchSync(ops []BatchOp) error {
+	return ndb.pdb.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (ndb *NamespacedDB) ApplyLog(ops BatchOpList) error {
+	return ndb.pdb.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (ndb *NamespacedDB) ForEach(fn func(key, value []byte) error) error {
+	return ndb.pdb.ForEach(fn)
+}
+
+// Close implements DB. It releases the namespace, and closes the inner database like PrefixDB.
+func (ndb *NamespacedDB) Close() error {
+	ndb.release()
+	return ndb.pdb.Close()
+}
+
+// NewBatch implements DB.
+func (ndb *NamespacedDB) NewBatch() Batch {
+	return ndb.pdb.NewBatch()
+}
+
+// NewBatchWithSize implements DB.
+func (ndb *NamespacedDB) NewBatchWithSize(expectedOps int) Batch {
+	return ndb.pdb.NewBatchWithSize(expectedOps)
+}
+
+// Print implements DB.
+func (ndb *NamespacedDB) Print() error {
+	return ndb.pdb.Print()
+}
+
+// Stats implements DB.
+func (ndb *NamespacedDB) Stats() map[string]string {
+	return ndb.pdb.Stats()
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespacedDB(t *testing.T) {
+	inner := NewMemDB()
+	// "a" is a prefix of "ab", and "a"+"bkey" would collide with "ab"+"key" with plain prefixes.
+	a := NewNamespacedDB(inner, []byte("a"))
+	ab := NewNamespacedDB(inner, []byte("ab"))
+
+	require.NoError(t, a.Set([]byte("bkey"), []byte{1}))
+	require.NoError(t, ab.Set([]byte("key"), []byte{2}))
+	checkValue(t, a, []byte("bkey"), []byte{1})
+	checkValue(t, ab, []byte("key"), []byte{2})
+	checkValue(t, a, []byte("key"), nil)
+
+	assertKeyValues(t, inner, map[string][]byte{
+		"\x01abkey": {1},
+		"\x02abkey": {2},
+	})
+
+	// Iteration and range deletion are confined to the namespace.
+	itr, err := a.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("bkey"), []byte{1})
+	itr.Next()
+	checkValid(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	require.NoError(t, a.DeleteRange(nil, nil))
+	checkValue(t, ab, []byte("key"), []byte{2})
+}
+
+func TestNamespacedDBRegistry(t *testing.T) {
+	inner := NewMemDB()
+	ndb := NewNamespacedDB(inner, []byte("ns"))
+
+	assert.PanicsWithValue(t, `namespace "ns" is already in use on this database, keys would collide`,
+		func() { NewNamespacedDB(inner, []byte("ns")) })
+
+	// The same namespace can be used on another database.
+	other := NewNamespacedDB(NewMemDB(), []byte("ns"))
+	require.NoError(t, other.Close())
+
+	// Closing releases the namespace.
+	require.NoError(t, ndb.Close())
+	ndb = NewNamespacedDB(inner, []byte("ns"))
+	assert.Equal(t, []byte("ns"), ndb.Namespace())
+	ndb.release()
+}
+
+func TestNamespacedDBLength(t *testing.T) {
+	assert.Panics(t, func() { NewNamespacedDB(NewMemDB(), nil) })
+	assert.Panics(t, func() { NewNamespacedDB(NewMemDB(), []byte{}) })
+	assert.Panics(t, func() { NewNamespacedDB(NewMemDB(), bytes.Repeat([]byte{1}, 256)) })
+
+	inner := NewMemDB()
+	ndb := NewNamespacedDB(inner, bytes.Repeat([]byte{0xff}, 255))
+	t.Cleanup(ndb.release)
+	require.NoError(t, ndb.Set([]byte{0xff}, []byte{1}))
+	assertKeyValues(t, inner, map[string][]byte{
+		string(bytes.Repeat([]byte{0xff}, 257)): {1},
+	})
+	itr, err := ndb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0xff}, []byte{1})
+	require.NoError(t, itr.Close())
+}
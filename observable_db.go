@@ -0,0 +1,455 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// ChangeOpType is the type of a ChangeOp.
+type ChangeOpType uint8
+
+const (
+	// ChangeOpSet is a key being set to a value.
+	ChangeOpSet ChangeOpType = iota
+	// ChangeOpDelete is a key being deleted.
+	ChangeOpDelete
+)
+
+// String implements fmt.Stringer.
+func (t ChangeOpType) String() string {
+	switch t {
+	case ChangeOpSet:
+		return "set"
+	case ChangeOpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeOp is a change made to an ObservableDB. Value is nil for deletes.
+type ChangeOp struct {
+	Op    ChangeOpType
+	Key   []byte
+	Value []byte
+}
+
+// ObservableOptions configures an ObservableDB.
+type ObservableOptions struct {
+	// OnChange, if given, is called for every change.
+	OnChange func(op ChangeOp)
+	// ChangesBuffer is the buffer size of the Changes channel. Zero or less disables the channel.
+	ChangesBuffer int
+}
+
+// ObservableDB wraps a database, and reports every change made through it, in order, to a
+// callback and/or a channel. Changes are reported after they have been written, while holding a
+// write lock which serializes all writes, so observers see changes in the order they were
+// applied. Each operation of a batch, and each key deleted by DeleteRange, is reported as a
+// separate change.
+//
+// Since writes wait for observers, the OnChange callback must not write to the database, and
+// Changes consumers must keep up: writes block while the channel is full.
+type ObservableDB struct {
+	db       DB
+	onChange func(op ChangeOp)
+
+	mtx      sync.Mutex // serializes writes and their notifications
+	changes  chan ChangeOp
+	watchers map[*observableWatcher]struct{}
+	closed   bool
+}
+
+// observableWatcher is a watcher created by ObservableDB.WatchIterator.
+type observableWatcher struct {
+	ctx     context.Context
+	start   []byte
+	end     []byte
+	changes chan KeyValue // closed when the database is closed
+}
+
+var _ DB = (*ObservableDB)(nil)
+
+// NewObservableDB creates an ObservableDB wrapping the given database.
+func NewObservableDB(inner DB, opts ObservableOptions) *ObservableDB {
+	odb := &ObservableDB{
+		db:       inner,
+		onChange: opts.OnChange,
+		watchers: make(map[*observableWatcher]struct{}),
+	}
+	if opts.ChangesBuffer > 0 {
+		odb.changes = make(chan ChangeOp, opts.ChangesBuffer)
+	}
+	return odb
+}
+
+// Changes returns a channel receiving every change, which is closed when the database is
+// closed. It is nil if ObservableOptions.ChangesBuffer is not positive.
+func (odb *ObservableDB) Changes() <-chan ChangeOp {
+	return odb.changes
+}
+
+// notify reports a change. It requires holding mtx.
+func (odb *ObservableDB) notify(op ChangeOpType, key, value []byte) {
+	change := ChangeOp{Op: op, Key: cp(key)}
+	if op == ChangeOpSet {
+		change.Value = cp(value)
+	}
+	if odb.onChange != nil {
+		odb.onChange(change)
+	}
+	if odb.changes != nil {
+		odb.changes <- change
+	}
+	for w := range odb.watchers {
+		if !IsKeyInDomain(change.Key, w.start, w.end) {
+			continue
+		}
+		select {
+		case w.changes <- KeyValue{Key: change.Key, Value: change.Value}:
+		case <-w.ctx.Done():
+		}
+	}
+}
+
+// WatchIterator returns a channel which first receives every item in the domain [start, end),
+// in order, and then every change made to a key in the domain, with a nil Value for deletes. The
+// channel is closed when ctx is done or the database is closed.
+//
+// Like the Changes channel, the consumer must keep up, since writes to keys in the domain block
+// until the change is received. The existing items are read into memory up front.
+func (odb *ObservableDB) WatchIterator(ctx context.Context, start, end []byte) (<-chan KeyValue, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+
+	// Read the existing items while holding the write lock, so that no changes are missed.
+	odb.mtx.Lock()
+	var existing []KeyValue
+	if !isEmptyDomain(start, end) {
+		itr, err := odb.db.Iterator(start, end)
+		if err != nil {
+			odb.mtx.Unlock()
+			return nil, err
+		}
+		for ; itr.Valid(); itr.Next() {
+			existing = append(existing, KeyValue{Key: cp(itr.Key()), Value: cp(itr.Value())})
+		}
+		err = itr.Error()
+		if cerr := itr.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			odb.mtx.Unlock()
+			return nil, err
+		}
+	}
+	w := &observableWatcher{
+		ctx:     ctx,
+		start:   cp(start),
+		end:     cp(end),
+		changes: make(chan KeyValue),
+	}
+	if odb.closed {
+		close(w.changes)
+	} else {
+		odb.watchers[w] = struct{}{}
+	}
+	odb.mtx.Unlock()
+
+	out := make(chan KeyValue)
+	go func() {
+		defer close(out)
+		defer odb.unwatch(w)
+		for _, kv := range existing {
+			select {
+			case out <- kv:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for {
+			select {
+			case kv, ok := <-w.changes:
+				if !ok {
+					return
+				}
+				select {
+				case out <- kv:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// unwatch removes a watcher, if the database has not been closed yet.
+func (odb *ObservableDB) unwatch(w *observableWatcher) {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	delete(odb.watchers, w)
+}
+
+// notifyOps reports the changes made by batch operations. It requires holding mtx.
+func (odb *ObservableDB) notifyOps(ops []BatchOp) {
+	for _, op := range ops {
+		if op.Delete {
+			odb.notify(ChangeOpDelete, op.Key, nil)
+		} else {
+			odb.notify(ChangeOpSet, op.Key, op.Value)
+		}
+	}
+}
+
+// Get implements DB.
+func (odb *ObservableDB) Get(key []byte) ([]byte, error) {
+	return odb.db.Get(key)
+}
+
+// Has implements DB.
+func (odb *ObservableDB) Has(key []byte) (bool, error) {
+	return odb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (odb *ObservableDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return odb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (odb *ObservableDB) Set(key []byte, value []byte) error {
+	return odb.set(key, value, odb.db.Set)
+}
+
+// SetSync implements DB.
+func (odb *ObservableDB) SetSync(key []byte, value []byte) error {
+	return odb.set(key, value, odb.db.SetSync)
+}
+
+func (odb *ObservableDB) set(key []byte, value []byte, setFn func([]byte, []byte) error) error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	if err := setFn(key, value); err != nil {
+		return err
+	}
+	odb.notify(ChangeOpSet, key, value)
+	return nil
+}
+
+// Delete implements DB.
+func (odb *ObservableDB) Delete(key []byte) error {
+	return odb.delete(key, odb.db.Delete)
+}
+
+// DeleteSync implements DB.
+func (odb *ObservableDB) DeleteSync(key []byte) error {
+	return odb.delete(key, odb.db.DeleteSync)
+}
+
+func (odb *ObservableDB) delete(key []byte, deleteFn func([]byte) error) error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	if err := deleteFn(key); err != nil {
+		return err
+	}
+	odb.notify(ChangeOpDelete, key, nil)
+	return nil
+}
+
+// CompareAndSet implements DB. A change is only reported if the value was swapped.
+func (odb *ObservableDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	swapped, err := odb.db.CompareAndSet(key, expected, newVal)
+	if swapped && err == nil {
+		odb.notify(ChangeOpSet, key, newVal)
+	}
+	return swapped, err
+}
+
+// DeleteRange implements DB. A delete is reported for every key which existed in the domain.
+func (odb *ObservableDB) DeleteRange(start, end []byte) error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+
+	var keys [][]byte
+	if !isEmptyDomain(start, end) {
+		itr, err := odb.db.Iterator(start, end)
+		if err != nil {
+			return err
+		}
+		for ; itr.Valid(); itr.Next() {
+			keys = append(keys, cp(itr.Key()))
+		}
+		err = itr.Error()
+		if cerr := itr.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := odb.db.DeleteRange(start, end); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		odb.notify(ChangeOpDelete, key, nil)
+	}
+	return nil
+}
+
+// Iterator implements DB.
+func (odb *ObservableDB) Iterator(start, end []byte) (Iterator, error) {
+	return odb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (odb *ObservableDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return odb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (odb *ObservableDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return odb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (odb *ObservableDB) Compact(start, end []byte) error {
+	return odb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (odb *ObservableDB) WriteBatch(ops []BatchOp) error {
+	return odb.writeBatch(ops, odb.db.WriteBatch)
+}
+
+// WriteBatchSync implements DB.
+func (odb *ObservableDB) WriteBatchSync(ops []BatchOp) error {
+	return odb.writeBatch(ops, odb.db.WriteBatchSync)
+}
+
+func (odb *ObservableDB) writeBatch(ops []BatchOp, writeFn func([]BatchOp) error) error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	if err := writeFn(ops); err != nil {
+		return err
+	}
+	odb.notifyOps(ops)
+	return nil
+}
+
+// ApplyLog implements DB.
+func (odb *ObservableDB) ApplyLog(ops BatchOpList) error {
+	return odb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (odb *ObservableDB) ForEach(fn func(key, value []byte) error) error {
+	return odb.db.ForEach(fn)
+}
+
+// Close implements DB. The Changes channel and all WatchIterator channels are closed.
+func (odb *ObservableDB) Close() error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	if !odb.closed && odb.changes != nil {
+		close(odb.changes)
+	}
+	for w := range odb.watchers {
+		close(w.changes)
+		delete(odb.watchers, w)
+	}
+	odb.closed = true
+	return odb.db.Close()
+}
+
+// NewBatch implements DB.
+func (odb *ObservableDB) NewBatch() Batch {
+	return newObservableDBBatch(odb, odb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (odb *ObservableDB) NewBatchWithSize(expectedOps int) Batch {
+	return newObservableDBBatch(odb, odb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (odb *ObservableDB) Print() error {
+	return odb.db.Print()
+}
+
+// Stats implements DB.
+func (odb *ObservableDB) Stats() map[string]string {
+	return odb.db.Stats()
+}
+
+// observableDBBatch wraps a batch, recording its operations so that they can be reported once
+// written.
+type observableDBBatch struct {
+	db    *ObservableDB
+	batch Batch
+	ops   []BatchOp
+}
+
+var _ Batch = (*observableDBBatch)(nil)
+
+func newObservableDBBatch(db *ObservableDB, batch Batch) *observableDBBatch {
+	return &observableDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *observableDBBatch) Set(key, value []byte) error {
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Value: cp(value)})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *observableDBBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Delete: true})
+	return nil
+}
+
+// Len implements Batch.
+func (b *observableDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *observableDBBatch) Write() error {
+	return b.write(b.batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *observableDBBatch) WriteSync() error {
+	return b.write(b.batch.WriteSync)
+}
+
+func (b *observableDBBatch) write(writeFn func() error) error {
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+	if err := writeFn(); err != nil {
+		return err
+	}
+	b.db.notifyOps(b.ops)
+	b.ops = nil
+	return nil
+}
+
+// Close implements Batch.
+func (b *observableDBBatch) Close() error {
+	b.ops = nil
+	return b.batch.Close()
+}
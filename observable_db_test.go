@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservableDBEvents(t *testing.T) {
+	var events []ChangeOp
+	odb := NewObservableDB(NewMemDB(), ObservableOptions{
+		OnChange:      func(op ChangeOp) { events = append(events, op) },
+		ChangesBuffer: 16,
+	})
+
+	require.NoError(t, odb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, odb.SetSync([]byte("b"), []byte{2}))
+	require.NoError(t, odb.Delete([]byte("a")))
+	swapped, err := odb.CompareAndSet([]byte("b"), []byte{9}, []byte{3})
+	require.NoError(t, err)
+	require.False(t, swapped)
+	swapped, err = odb.CompareAndSet([]byte("b"), []byte{2}, []byte{3})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	require.NoError(t, odb.Set([]byte("c"), []byte{4}))
+	require.NoError(t, odb.DeleteRange([]byte("b"), nil))
+
+	expect := []ChangeOp{
+		{Op: ChangeOpSet, Key: []byte("a"), Value: []byte{1}},
+		{Op: ChangeOpSet, Key: []byte("b"), Value: []byte{2}},
+		{Op: ChangeOpDelete, Key: []byte("a")},
+		{Op: ChangeOpSet, Key: []byte("b"), Value: []byte{3}},
+		{Op: ChangeOpSet, Key: []byte("c"), Value: []byte{4}},
+		{Op: ChangeOpDelete, Key: []byte("b")},
+		{Op: ChangeOpDelete, Key: []byte("c")},
+	}
+	assert.Equal(t, expect, events)
+
+	require.NoError(t, odb.Close())
+	var received []ChangeOp
+	for op := range odb.Changes() {
+		received = append(received, op)
+	}
+	assert.Equal(t, expect, received)
+}
+
+func TestObservableDBBatch(t *testing.T) {
+	var events []ChangeOp
+	odb := NewObservableDB(NewMemDB(), ObservableOptions{
+		OnChange: func(op ChangeOp) { events = append(events, op) },
+	})
+	defer odb.Close()
+	assert.Nil(t, odb.Changes())
+
+	batch := odb.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.Delete([]byte("a")))
+	assert.Empty(t, events)
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	require.NoError(t, odb.WriteBatch([]BatchOp{
+		{Key: []byte("c"), Value: []byte{3}},
+		{Key: []byte("b"), Delete: true},
+	}))
+
+	// Discarded batches and failed writes are not reported.
+	batch = odb.NewBatch()
+	require.NoError(t, batch.Set([]byte("d"), []byte{4}))
+	require.NoError(t, batch.Close())
+	require.Error(t, odb.WriteBatch([]BatchOp{{Key: []byte("e")}}))
+
+	assert.Equal(t, []ChangeOp{
+		{Op: ChangeOpSet, Key: []byte("a"), Value: []byte{1}},
+		{Op: ChangeOpSet, Key: []byte("b"), Value: []byte{2}},
+		{Op: ChangeOpDelete, Key: []byte("a")},
+		{Op: ChangeOpSet, Key: []byte("c"), Value: []byte{3}},
+		{Op: ChangeOpDelete, Key: []byte("b")},
+	}, events)
+	assertKeyValues(t, odb, map[string][]byte{"c": {3}})
+}
+
+func TestObservableDBWatchIterator(t *testing.T) {
+	odb := NewObservableDB(NewMemDB(), ObservableOptions{})
+	require.NoError(t, odb.Set([]byte("key0"), []byte{0}))
+	require.NoError(t, odb.Set([]byte("other"), []byte{0}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := odb.WatchIterator(ctx, []byte("key"), []byte("kez"))
+	require.NoError(t, err)
+
+	// Writes block until the watcher receives them, so write concurrently.
+	errCh := make(chan error, 1)
+	go func() {
+		for i := 1; i <= 5; i++ {
+			if err := odb.Set([]byte("other"), []byte{byte(i)}); err != nil {
+				errCh <- err
+				return
+			}
+			if err := odb.Set([]byte(fmt.Sprintf("key%v", i)), []byte{byte(i)}); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- odb.Delete([]byte("key0"))
+	}()
+
+	// The existing item comes first, followed by the 5 writes and the delete.
+	expect := []KeyValue{{Key: []byte("key0"), Value: []byte{0}}}
+	for i := 1; i <= 5; i++ {
+		expect = append(expect, KeyValue{Key: []byte(fmt.Sprintf("key%v", i)), Value: []byte{byte(i)}})
+	}
+	expect = append(expect, KeyValue{Key: []byte("key0")})
+	received := make([]KeyValue, 0, len(expect))
+	for len(received) < len(expect) {
+		received = append(received, <-watch)
+	}
+	require.NoError(t, <-errCh)
+	assert.Equal(t, expect, received)
+
+	// Cancelling the context closes the channel, and writes no longer block.
+	cancel()
+	for range watch {
+	}
+	require.NoError(t, odb.Set([]byte("key6"), []byte{6}))
+}
+
+func TestObservableDBWatchIteratorClose(t *testing.T) {
+	odb := NewObservableDB(NewMemDB(), ObservableOptions{})
+	watch, err := odb.WatchIterator(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, odb.Close())
+	_, ok := <-watch
+	assert.False(t, ok)
+
+	_, err = odb.WatchIterator(context.Background(), []byte{}, nil)
+	assert.Equal(t, errKeyEmpty, err)
+}
@@ -0,0 +1,380 @@
+package db
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentationName is the name of the tracer used by OpenTelemetryDB by default.
+const otelInstrumentationName = "github.com/tendermint/tm-db"
+
+// Span attribute keys set by OpenTelemetryDB.
+const (
+	otelAttrBackend     = attribute.Key("db.backend")
+	otelAttrKeyLength   = attribute.Key("db.key_length")
+	otelAttrKeys        = attribute.Key("db.keys")
+	otelAttrValueLength = attribute.Key("db.value_length")
+	otelAttrOps         = attribute.Key("db.ops")
+	otelAttrItems       = attribute.Key("db.items")
+	otelAttrNextCalls   = attribute.Key("db.next_calls")
+)
+
+// OpenTelemetryDB wraps a database, and creates an OpenTelemetry span for every operation
+// accessing it, named after the method (e.g. "db.Get"). Spans are tagged with db.backend, and
+// with db.key_length and db.value_length where applicable; batch writes are tagged with their
+// number of operations as db.ops. Failed operations record the error on their span.
+//
+// Iterators get a single span covering their whole lifetime, from creation until Close, tagged
+// with the number of Next calls as db.next_calls, rather than a span per step. ForEach tags its
+// span with the number of items visited as db.items.
+//
+// Since DB methods take no context, spans are children of the context given to WithContext,
+// or root spans by default. IteratorWithContext uses its own context.
+type OpenTelemetryDB struct {
+	db      DB
+	tracer  oteltrace.Tracer
+	backend string
+	ctx     context.Context
+}
+
+var _ DB = (*OpenTelemetryDB)(nil)
+
+// NewOpenTelemetryDB creates an OpenTelemetryDB wrapping the given database, tagging spans with
+// the given backend name. A nil tracer uses the global tracer provider.
+func NewOpenTelemetryDB(inner DB, tracer oteltrace.Tracer, backend string) *OpenTelemetryDB {
+	if tracer == nil {
+		tracer = otel.Tracer(otelInstrumentationName)
+	}
+	return &OpenTelemetryDB{
+		db:      inner,
+		tracer:  tracer,
+		backend: backend,
+		ctx:     context.Background(),
+	}
+}
+
+// WithContext returns a copy of the database whose spans are children of the span in ctx, if
+// any. Both share the wrapped database.
+func (otdb *OpenTelemetryDB) WithContext(ctx context.Context) *OpenTelemetryDB {
+	db := *otdb
+	db.ctx = ctx
+	return &db
+}
+
+// start starts a span for an operation.
+func (otdb *OpenTelemetryDB) start(ctx context.Context, name string, attrs ...attribute.KeyValue) oteltrace.Span {
+	attrs = append(attrs, otelAttrBackend.String(otdb.backend))
+	_, span := otdb.tracer.Start(ctx, name, oteltrace.WithAttributes(attrs...))
+	return span
+}
+
+// endSpan ends a span, recording the error of the operation if any.
+func endSpan(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Get implements DB.
+func (otdb *OpenTelemetryDB) Get(key []byte) ([]byte, error) {
+	span := otdb.start(otdb.ctx, "db.Get", otelAttrKeyLength.Int(len(key)))
+	value, err := otdb.db.Get(key)
+	span.SetAttributes(otelAttrValueLength.Int(len(value)))
+	endSpan(span, err)
+	return value, err
+}
+
+// Has implements DB.
+func (otdb *OpenTelemetryDB) Has(key []byte) (bool, error) {
+	span := otdb.start(otdb.ctx, "db.Has", otelAttrKeyLength.Int(len(key)))
+	ok, err := otdb.db.Has(key)
+	endSpan(span, err)
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (otdb *OpenTelemetryDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	span := otdb.start(otdb.ctx, "db.MultiGet", otelAttrKeys.Int(len(keys)))
+	values, err := otdb.db.MultiGet(keys)
+	endSpan(span, err)
+	return values, err
+}
+
+// Set implements DB.
+func (otdb *OpenTelemetryDB) Set(key []byte, value []byte) error {
+	span := otdb.start(otdb.ctx, "db.Set",
+		otelAttrKeyLength.Int(len(key)), otelAttrValueLength.Int(len(value)))
+	err := otdb.db.Set(key, value)
+	endSpan(span, err)
+	return err
+}
+
+// SetSync implements DB.
+func (otdb *OpenTelemetryDB) SetSync(key []byte, value []byte) error {
+	span := otdb.start(otdb.ctx, "db.SetSync",
+		otelAttrKeyLength.Int(len(key)), otelAttrValueLength.Int(len(value)))
+	err := otdb.db.SetSync(key, value)
+	endSpan(span, err)
+	return err
+}
+
+// Delete implements DB.
+func (otdb *OpenTelemetryDB) Delete(key []byte) error {
+	span := otdb.start(otdb.ctx, "db.Delete", otelAttrKeyLength.Int(len(key)))
+	err := otdb.db.Delete(key)
+	endSpan(span, err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (otdb *OpenTelemetryDB) DeleteSync(key []byte) error {
+	span := otdb.start(otdb.ctx, "db.DeleteSync", otelAttrKeyLength.Int(len(key)))
+	err := otdb.db.DeleteSync(key)
+	endSpan(span, err)
+	return err
+}
+
+// CompareAndSet implements DB.
+func (otdb *OpenTelemetryDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	span := otdb.start(otdb.ctx, "db.CompareAndSet",
+		otelAttrKeyLength.Int(len(key)), otelAttrValueLength.Int(len(newVal)))
+	swapped, err := otdb.db.CompareAndSet(key, expected, newVal)
+	span.SetAttributes(attribute.Bool("db.swapped", swapped))
+	endSpan(span, err)
+	return swapped, err
+}
+
+// DeleteRange implements DB.
+func (otdb *OpenTelemetryDB) DeleteRange(start, end []byte) error {
+	span := otdb.start(otdb.ctx, "db.DeleteRange")
+	err := otdb.db.DeleteRange(start, end)
+	endSpan(span, err)
+	return err
+}
+
+// Iterator implements DB.
+func (otdb *OpenTelemetryDB) Iterator(start, end []byte) (Iterator, error) {
+	span := otdb.start(otdb.ctx, "db.Iterator")
+	itr, err := otdb.db.Iterator(start, end)
+	return newOtelIterator(span, itr, err)
+}
+
+// ReverseIterator implements DB.
+func (otdb *OpenTelemetryDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	span := otdb.start(otdb.ctx, "db.ReverseIterator")
+	itr, err := otdb.db.ReverseIterator(start, end)
+	return newOtelIterator(span, itr, err)
+}
+
+// IteratorWithContext implements DB. The span is a child of the span in ctx, if any.
+func (otdb *OpenTelemetryDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	span := otdb.start(ctx, "db.IteratorWithContext")
+	itr, err := otdb.db.IteratorWithContext(ctx, start, end)
+	return newOtelIterator(span, itr, err)
+}
+
+// Compact implements DB.
+func (otdb *OpenTelemetryDB) Compact(start, end []byte) error {
+	span := otdb.start(otdb.ctx, "db.Compact")
+	err := otdb.db.Compact(start, end)
+	endSpan(span, err)
+	return err
+}
+
+// WriteBatch implements DB.
+func (otdb *OpenTelemetryDB) WriteBatch(ops []BatchOp) error {
+	span := otdb.start(otdb.ctx, "db.WriteBatch", otelAttrOps.Int(len(ops)))
+	err := otdb.db.WriteBatch(ops)
+	endSpan(span, err)
+	return err
+}
+
+// WriteBatchSync implements DB.
+func (otdb *OpenTelemetryDB) WriteBatchSync(ops []BatchOp) error {
+	span := otdb.start(otdb.ctx, "db.WriteBatchSync", otelAttrOps.Int(len(ops)))
+	err := otdb.db.WriteBatchSync(ops)
+	endSpan(span, err)
+	return err
+}
+
+// ApplyLog implements DB.
+func (otdb *OpenTelemetryDB) ApplyLog(ops BatchOpList) error {
+	span := otdb.start(otdb.ctx, "db.ApplyLog", otelAttrOps.Int(len(ops)))
+	err := otdb.db.ApplyLog(ops)
+	endSpan(span, err)
+	return err
+}
+
+// ForEach implements DB.
+func (otdb *OpenTelemetryDB) ForEach(fn func(key, value []byte) error) error {
+	span := otdb.start(otdb.ctx, "db.ForEach")
+	items := 0
+	err := otdb.db.ForEach(func(key, value []byte) error {
+		items++
+		return fn(key, value)
+	})
+	span.SetAttributes(otelAttrItems.Int(items))
+	endSpan(span, err)
+	return err
+}
+
+// Close implements DB.
+func (otdb *OpenTelemetryDB) Close() error {
+	span := otdb.start(otdb.ctx, "db.Close")
+	err := otdb.db.Close()
+	endSpan(span, err)
+	return err
+}
+
+// NewBatch implements DB.
+func (otdb *OpenTelemetryDB) NewBatch() Batch {
+	return newOtelBatch(otdb, otdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (otdb *OpenTelemetryDB) NewBatchWithSize(expectedOps int) Batch {
+	return newOtelBatch(otdb, otdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (otdb *OpenTelemetryDB) Print() error {
+	return otdb.db.Print()
+}
+
+// Stats implements DB.
+func (otdb *OpenTelemetryDB) Stats() map[string]string {
+	return otdb.db.Stats()
+}
+
+// otelBatch wraps a batch, creating a span when it is written.
+type otelBatch struct {
+	db    *OpenTelemetryDB
+	batch Batch
+}
+
+var _ Batch = (*otelBatch)(nil)
+
+func newOtelBatch(db *OpenTelemetryDB, batch Batch) *otelBatch {
+	return &otelBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *otelBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *otelBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *otelBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *otelBatch) Write() error {
+	span := b.db.start(b.db.ctx, "db.Batch.Write", otelAttrOps.Int(b.batch.Len()))
+	err := b.batch.Write()
+	endSpan(span, err)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *otelBatch) WriteSync() error {
+	span := b.db.start(b.db.ctx, "db.Batch.WriteSync", otelAttrOps.Int(b.batch.Len()))
+	err := b.batch.WriteSync()
+	endSpan(span, err)
+	return err
+}
+
+// Close implements Batch.
+func (b *otelBatch) Close() error {
+	return b.batch.Close()
+}
+
+// otelIterator wraps an iterator, ending its span when closed.
+type otelIterator struct {
+	source    Iterator
+	span      oteltrace.Span
+	nextCalls int
+	ended     bool
+}
+
+var _ Iterator = (*otelIterator)(nil)
+
+// newOtelIterator wraps an iterator created within a span. If creating the iterator failed, the
+// span is ended right away.
+func newOtelIterator(span oteltrace.Span, source Iterator, err error) (Iterator, error) {
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &otelIterator{source: source, span: span}, nil
+}
+
+// Domain implements Iterator.
+func (itr *otelIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *otelIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Seek implements Iterator.
+func (itr *otelIterator) Seek(key []byte) bool {
+	return itr.source.Seek(key)
+}
+
+// Next implements Iterator.
+func (itr *otelIterator) Next() {
+	itr.source.Next()
+	itr.nextCalls++
+}
+
+// Key implements Iterator.
+func (itr *otelIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *otelIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Position implements Iterator.
+func (itr *otelIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *otelIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator. The span records the iterator's error, if any.
+func (itr *otelIterator) Close() error {
+	err := itr.source.Close()
+	if !itr.ended {
+		itr.ended = true
+		itr.span.SetAttributes(otelAttrNextCalls.Int(itr.nextCalls))
+		spanErr := itr.source.Error()
+		if spanErr == nil {
+			spanErr = err
+		}
+		endSpan(itr.span, spanErr)
+	}
+	return err
+}
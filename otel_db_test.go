@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestOpenTelemetryDB(t *testing.T, inner DB) (*OpenTelemetryDB, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) }) // nolint: errcheck
+	return NewOpenTelemetryDB(inner, provider.Tracer("test"), "memdb"), exporter
+}
+
+// spanAttrs returns the attributes of a span as a map.
+func spanAttrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestOpenTelemetryDB(t *testing.T) {
+	mock := NewMockDBWrapping(NewMemDB())
+	otdb, exporter := newTestOpenTelemetryDB(t, mock)
+
+	require.NoError(t, otdb.Set([]byte("key"), []byte("value")))
+	checkValue(t, otdb, []byte("key"), []byte("value"))
+	mock.SetError("Delete", errors.New("boom"))
+	require.Error(t, otdb.Delete([]byte("key")))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "db.Set", spans[0].Name)
+	assert.Equal(t, "db.Get", spans[1].Name)
+	assert.Equal(t, "db.Delete", spans[2].Name)
+
+	attrs := spanAttrs(spans[0])
+	assert.Equal(t, "memdb", attrs[otelAttrBackend].AsString())
+	assert.EqualValues(t, 3, attrs[otelAttrKeyLength].AsInt64())
+	assert.EqualValues(t, 5, attrs[otelAttrValueLength].AsInt64())
+	assert.EqualValues(t, 5, spanAttrs(spans[1])[otelAttrValueLength].AsInt64())
+
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[2].Status.Code)
+	assert.Equal(t, "boom", spans[2].Status.Description)
+}
+
+func TestOpenTelemetryDBIterator(t *testing.T) {
+	otdb, exporter := newTestOpenTelemetryDB(t, NewMemDB())
+	require.NoError(t, otdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, otdb.Set([]byte("b"), []byte{2}))
+	exporter.Reset()
+
+	// The iterator has a single span, ended when it is closed.
+	itr, err := otdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+		assert.Empty(t, exporter.GetSpans())
+	}
+	require.NoError(t, itr.Close())
+	require.NoError(t, itr.Close())
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "db.Iterator", spans[0].Name)
+	assert.EqualValues(t, 2, spanAttrs(spans[0])[otelAttrNextCalls].AsInt64())
+}
+
+func TestOpenTelemetryDBParentContext(t *testing.T) {
+	otdb, exporter := newTestOpenTelemetryDB(t, NewMemDB())
+
+	ctx, parent := otdb.tracer.Start(context.Background(), "parent")
+	require.NoError(t, otdb.WithContext(ctx).Set([]byte("a"), []byte{1}))
+	batch := otdb.WithContext(ctx).NewBatch()
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	parent.End()
+
+	// Without a context, spans are roots.
+	require.NoError(t, otdb.Set([]byte("c"), []byte{3}))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 4)
+	assert.Equal(t, "db.Set", spans[0].Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, "db.Batch.Write", spans[1].Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[1].Parent.SpanID())
+	assert.EqualValues(t, 1, spanAttrs(spans[1])[otelAttrOps].AsInt64())
+	assert.Equal(t, "parent", spans[2].Name)
+	assert.False(t, spans[3].Parent.IsValid())
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// scanSamplesPerWorker is the number of sampled keys per worker used by ScanParallel to split the
+// keyspace.
+const scanSamplesPerWorker = 16
+
+// ScanParallel calls fn for every key/value pair in the database, like DB.ForEach, but splits the
+// keyspace into nWorkers non-overlapping domains which are scanned concurrently. The domains are
+// chosen from an evenly spaced sample of the keys, taken by a first pass over the keys, so they
+// hold roughly the same number of entries. Within a domain, fn is called in ascending key order,
+// but calls for different domains are made concurrently, so fn must be safe for concurrent use.
+// If fn returns an error, all workers are cancelled and the first error is returned.
+// fn must not write to the database.
+// CONTRACT: key, value readonly []byte, and only valid until fn returns
+func ScanParallel(db DB, nWorkers int, fn func(key, value []byte) error) error {
+	if nWorkers <= 0 {
+		return fmt.Errorf("number of workers must be positive, got %d", nWorkers)
+	}
+	if nWorkers == 1 {
+		return db.ForEach(fn)
+	}
+	splits, err := sampleSplitKeys(db, nWorkers)
+	if err != nil {
+		return err
+	}
+
+	g, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i <= len(splits); i++ {
+		var start, end []byte
+		if i > 0 {
+			start = splits[i-1]
+		}
+		if i < len(splits) {
+			end = splits[i]
+		}
+		g.Go(func() error {
+			return scanDomain(ctx, db, start, end, fn)
+		})
+	}
+	return g.Wait()
+}
+
+// scanDomain calls fn for every key/value pair in the domain [start, end), until ctx is done.
+func scanDomain(ctx context.Context, db DB, start, end []byte, fn func(key, value []byte) error) error {
+	itr, err := db.IteratorWithContext(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		if err := fn(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+// sampleSplitKeys returns up to n-1 distinct keys, in ascending order, which split the keys of the
+// database into n domains of roughly equal size. The keys are sampled at an even stride, which is
+// doubled whenever the sample grows too large, so memory use does not depend on the database size.
+func sampleSplitKeys(db DB, n int) ([][]byte, error) {
+	var (
+		itr Iterator
+		err error
+	)
+	if k, ok := db.(KeysOnlyIterator); ok {
+		itr, err = k.KeysOnlyIterator(nil, nil)
+	} else {
+		itr, err = db.Iterator(nil, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	maxSamples := n * scanSamplesPerWorker
+	samples := make([][]byte, 0, maxSamples)
+	stride, count := 1, 0
+	for ; itr.Valid(); itr.Next() {
+		if count%stride == 0 {
+			if len(samples) == maxSamples {
+				for i := 0; i < maxSamples/2; i++ {
+					samples[i] = samples[2*i]
+				}
+				samples = samples[:maxSamples/2]
+				stride *= 2
+			}
+			if count%stride == 0 {
+				samples = append(samples, cp(itr.Key()))
+			}
+		}
+		count++
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+
+	// Pick n-1 quantiles of the sample. Small samples may repeat quantiles, or give the first key,
+	// which would give empty domains, so those are skipped.
+	splits := make([][]byte, 0, n-1)
+	last := 0
+	for i := 1; i < n; i++ {
+		idx := i * len(samples) / n
+		if idx <= last {
+			continue
+		}
+		splits = append(splits, samples[idx])
+		last = idx
+	}
+	return splits, nil
+}
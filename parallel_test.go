@@ -0,0 +1,109 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanParallel(t *testing.T) {
+	db := NewMemDB()
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i*2)))
+	}
+
+	for _, nWorkers := range []int{1, 2, 4, 7, 2000} {
+		var mtx sync.Mutex
+		seen := make(map[int64]int)
+		err := ScanParallel(db, nWorkers, func(key, value []byte) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			seen[bytes2Int64(key)]++
+			assert.Equal(t, bytes2Int64(key)*2, bytes2Int64(value))
+			return nil
+		})
+		require.NoError(t, err, nWorkers)
+		require.Len(t, seen, 1000, nWorkers)
+		for i, n := range seen {
+			require.Equal(t, 1, n, "key %d scanned %d times with %d workers", i, n, nWorkers)
+		}
+	}
+
+	// The sampled domains hold roughly the same number of keys.
+	splits, err := sampleSplitKeys(db, 4)
+	require.NoError(t, err)
+	require.Len(t, splits, 3)
+	for i, split := range splits {
+		assert.InDelta(t, (i+1)*250, bytes2Int64(split), 50)
+	}
+
+	// Empty and tiny databases are scanned too.
+	require.NoError(t, ScanParallel(NewMemDB(), 4, func(key, value []byte) error {
+		return errors.New("called")
+	}))
+	tiny := NewMemDB()
+	require.NoError(t, tiny.Set([]byte("a"), []byte{1}))
+	count := 0
+	require.NoError(t, ScanParallel(tiny, 4, func(key, value []byte) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 1, count)
+
+	require.Error(t, ScanParallel(db, 0, nil))
+}
+
+func TestScanParallelError(t *testing.T) {
+	db := NewMemDB()
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+	}
+	boom := errors.New("boom")
+	var mtx sync.Mutex
+	count := 0
+	err := ScanParallel(db, 4, func(key, value []byte) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		count++
+		if bytes2Int64(key) == 10 {
+			return boom
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, boom)
+	assert.Less(t, count, 1000)
+}
+
+func BenchmarkScanParallel(b *testing.B) {
+	db := NewMemDB()
+	for i := int64(0); i < 100000; i++ {
+		if err := db.Set(int642Bytes(i), int642Bytes(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// Simulate some work per entry, as done by migrations.
+	fn := func(key, value []byte) error {
+		sum := 0
+		for i := 0; i < 100; i++ {
+			sum += int(value[i%len(value)])
+		}
+		if sum < 0 {
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+	for _, nWorkers := range []int{1, 4} {
+		nWorkers := nWorkers
+		b.Run(fmt.Sprintf("workers=%d", nWorkers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := ScanParallel(db, nWorkers, fn); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
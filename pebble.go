@@ -0,0 +1,352 @@
+//go:build pebbledb
+// +build pebbledb
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+)
+
+func init() {
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newPebbleDBWithOptions(name, dir, opts)
+	}
+	registerDBCreator(PebbleDBBackend, dbCreator, false)
+}
+
+// PebbleDB is a PebbleDB backend.
+type PebbleDB struct {
+	db       *pebble.DB
+	readOnly bool
+	logger   Logger
+
+	// casMtx makes CompareAndSet atomic, since Pebble has no native support for it: writes hold a
+	// read lock, so they still run in parallel, and CompareAndSet holds the write lock.
+	casMtx sync.RWMutex
+}
+
+var _ DB = (*PebbleDB)(nil)
+var _ Sizable = (*PebbleDB)(nil)
+
+// NewPebbleDB creates a new PebbleDB with default options.
+func NewPebbleDB(name string, dir string) (*PebbleDB, error) {
+	return newPebbleDBWithOptions(name, dir, Options{})
+}
+
+// newPebbleDBWithOptions creates a PebbleDB, mapping Options to Pebble options.
+func newPebbleDBWithOptions(name string, dir string, o Options) (*PebbleDB, error) {
+	opts := &pebble.Options{
+		ReadOnly:     o.ReadOnly,
+		MaxOpenFiles: o.MaxOpenFiles,
+	}
+	if o.BlockCacheSize > 0 {
+		cache := pebble.NewCache(o.BlockCacheSize)
+		// The database holds its own reference once opened.
+		defer cache.Unref()
+		opts.Cache = cache
+	}
+	if o.Logger != nil {
+		opts.Logger = pebbleLogger{o.Logger}
+	}
+	opts.EnsureDefaults()
+	db, err := NewPebbleDBWithOpts(name, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	db.logger = loggerOrNop(o.Logger)
+	return db, nil
+}
+
+// pebbleLogger adapts a Logger to the Pebble logging interface.
+type pebbleLogger struct {
+	Logger
+}
+
+// Infof implements pebble.Logger.
+func (l pebbleLogger) Infof(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+// Fatalf implements pebble.Logger. Like the default Pebble logger, it exits the process.
+func (l pebbleLogger) Fatalf(format string, args ...interface{}) {
+	l.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// NewPebbleDBWithOpts creates a new PebbleDB with the given options.
+func NewPebbleDBWithOpts(name string, dir string, opts *pebble.Options) (*PebbleDB, error) {
+	dbPath := filepath.Join(dir, name+".db")
+	db, err := pebble.Open(dbPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	database := &PebbleDB{
+		db:       db,
+		readOnly: opts.ReadOnly,
+		logger:   nopLogger{},
+	}
+	return database, nil
+}
+
+// Get implements DB.
+func (db *PebbleDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, closer, err := db.db.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer closer.Close()
+	// The returned slice is only valid until the closer is closed, so copy it out.
+	return cp(res), nil
+}
+
+// Has implements DB.
+func (db *PebbleDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	_, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+// MultiGet implements DB.
+func (db *PebbleDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(db, keys)
+}
+
+// Set implements DB.
+func (db *PebbleDB) Set(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, pebble.NoSync)
+}
+
+// SetSync implements DB.
+func (db *PebbleDB) SetSync(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, pebble.Sync)
+}
+
+// syncWriteDurable implements syncWriteDurable. Pebble journals every write to a single log, so
+// a sync write persists all earlier ones.
+func (db *PebbleDB) syncWriteDurable() {}
+
+// set sets a value without locking casMtx.
+func (db *PebbleDB) set(key []byte, value []byte, opts *pebble.WriteOptions) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return db.db.Set(key, value, opts)
+}
+
+// CompareAndSet implements DB.
+func (db *PebbleDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if db.readOnly {
+		return false, ErrReadOnly
+	}
+	db.casMtx.Lock()
+	defer db.casMtx.Unlock()
+
+	return compareAndSet(db, key, expected, newVal, func(key, value []byte) error {
+		return db.set(key, value, pebble.NoSync)
+	})
+}
+
+// Delete implements DB.
+func (db *PebbleDB) Delete(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return db.db.Delete(key, pebble.NoSync)
+}
+
+// DeleteSync implements DB.
+func (db *PebbleDB) DeleteSync(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return db.db.Delete(key, pebble.Sync)
+}
+
+func (db *PebbleDB) DB() *pebble.DB {
+	return db.db
+}
+
+// Close implements DB.
+func (db *PebbleDB) Close() error {
+	GlobalRegistry.unregister(db)
+	return db.db.Close()
+}
+
+// Print implements DB.
+func (db *PebbleDB) Print() error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return nil
+}
+
+// Stats implements DB.
+func (db *PebbleDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	stats["pebble.stats"] = db.db.Metrics().String()
+	return stats
+}
+
+// ApproxSize implements Sizable. It is the disk space used by the database, including its WAL.
+func (db *PebbleDB) ApproxSize() (int64, error) {
+	return int64(db.db.Metrics().DiskSpaceUsage()), nil
+}
+
+// DeleteRange implements DB. Bounded domains are deleted atomically using a native range
+// deletion, while a nil end falls back to deleting individual keys.
+func (db *PebbleDB) DeleteRange(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if end == nil {
+		return deleteRange(db, start, end)
+	}
+	if (start != nil && len(start) == 0) || len(end) == 0 {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	if start == nil {
+		start = []byte{}
+	}
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.db.DeleteRange(start, end, pebble.NoSync)
+}
+
+// IteratorWithContext implements DB.
+func (db *PebbleDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB. Pebble does not support open-ended compactions, so nil bounds are
+// replaced by the first and last keys in the database.
+func (db *PebbleDB) Compact(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if start == nil || end == nil {
+		itr := db.db.NewIter(nil)
+		defer itr.Close()
+		if start == nil && itr.First() {
+			start = cp(itr.Key())
+		}
+		if end == nil && itr.Last() {
+			// Pebble treats end as exclusive, so extend it past the last key.
+			end = append(cp(itr.Key()), 0)
+		}
+		if err := itr.Error(); err != nil {
+			return err
+		}
+	}
+	if start == nil || end == nil || bytes.Compare(start, end) >= 0 {
+		// The database is empty, or the domain is.
+		return nil
+	}
+	return logCompaction(db.logger, start, end, func() error {
+		return db.db.Compact(start, end, true)
+	})
+}
+
+// WriteBatch implements DB.
+func (db *PebbleDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *PebbleDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *PebbleDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *PebbleDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
+// NewBatch implements DB.
+func (db *PebbleDB) NewBatch() Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
+	return newPebbleDBBatch(db)
+}
+
+// NewBatchWithSize implements DB. Pebble grows its batches itself, so the size hint is ignored.
+func (db *PebbleDB) NewBatchWithSize(_ int) Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
+	return db.NewBatch()
+}
+
+// Iterator implements DB.
+func (db *PebbleDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := db.db.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return newPebbleDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements DB.
+func (db *PebbleDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := db.db.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return newPebbleDBIterator(itr, start, end, true), nil
+}
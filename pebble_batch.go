@@ -0,0 +1,87 @@
+//go:build pebbledb
+// +build pebbledb
+
+package db
+
+import "github.com/cockroachdb/pebble"
+
+type pebbleDBBatch struct {
+	db    *PebbleDB
+	batch *pebble.Batch
+}
+
+var _ Batch = (*pebbleDBBatch)(nil)
+
+func newPebbleDBBatch(db *PebbleDB) *pebbleDBBatch {
+	return &pebbleDBBatch{
+		db:    db,
+		batch: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *pebbleDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.batch.Set(key, value, nil)
+}
+
+// Delete implements Batch.
+func (b *pebbleDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.batch.Delete(key, nil)
+}
+
+// Len implements Batch.
+func (b *pebbleDBBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return int(b.batch.Count())
+}
+
+// Write implements Batch.
+func (b *pebbleDBBatch) Write() error {
+	return b.write(pebble.NoSync)
+}
+
+// WriteSync implements Batch.
+func (b *pebbleDBBatch) WriteSync() error {
+	return b.write(pebble.Sync)
+}
+
+func (b *pebbleDBBatch) write(opts *pebble.WriteOptions) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	b.db.casMtx.RLock()
+	err := b.batch.Commit(opts)
+	b.db.casMtx.RUnlock()
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *pebbleDBBatch) Close() error {
+	if b.batch != nil {
+		err := b.batch.Close()
+		b.batch = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+//go:build pebbledb
+// +build pebbledb
+
+package db
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/pebble"
+)
+
+type pebbleDBIterator struct {
+	source    *pebble.Iterator
+	start     []byte
+	end       []byte
+	isReverse bool
+	isInvalid bool
+}
+
+var _ Iterator = (*pebbleDBIterator)(nil)
+
+func newPebbleDBIterator(source *pebble.Iterator, start, end []byte, isReverse bool) *pebbleDBIterator {
+	// The source iterator is bounded by [start, end) already, so we only need to position it.
+	if isReverse {
+		source.Last()
+	} else {
+		source.First()
+	}
+	return &pebbleDBIterator{
+		source:    source,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+		isInvalid: false,
+	}
+}
+
+// Domain implements Iterator.
+func (itr *pebbleDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *pebbleDBIterator) Valid() bool {
+	// Once invalid, forever invalid.
+	if itr.isInvalid {
+		return false
+	}
+
+	// If source errors, invalid.
+	if err := itr.source.Error(); err != nil {
+		itr.isInvalid = true
+		return false
+	}
+
+	// If source is invalid, invalid.
+	if !itr.source.Valid() {
+		itr.isInvalid = true
+		return false
+	}
+
+	return true
+}
+
+// Seek implements Iterator.
+func (itr *pebbleDBIterator) Seek(key []byte) bool {
+	if itr.source.Error() != nil {
+		return false
+	}
+	itr.isInvalid = false
+	switch {
+	case !itr.isReverse:
+		if itr.start != nil && bytes.Compare(key, itr.start) < 0 {
+			key = itr.start
+		}
+		itr.source.SeekGE(key)
+	case itr.end != nil && bytes.Compare(key, itr.end) >= 0:
+		itr.source.Last()
+	default:
+		// SeekLT is exclusive, so seek to the immediate successor of the key.
+		itr.source.SeekLT(append(cp(key), 0x00))
+	}
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *pebbleDBIterator) Key() []byte {
+	// The source key is only valid until the next positioning call, so return a copy.
+	itr.assertIsValid()
+	return cp(itr.source.Key())
+}
+
+// Value implements Iterator.
+func (itr *pebbleDBIterator) Value() []byte {
+	// The source value is only valid until the next positioning call, so return a copy.
+	itr.assertIsValid()
+	return cp(itr.source.Value())
+}
+
+// Position implements Iterator.
+func (itr *pebbleDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Next implements Iterator.
+func (itr *pebbleDBIterator) Next() {
+	itr.assertIsValid()
+	if itr.isReverse {
+		itr.source.Prev()
+	} else {
+		itr.source.Next()
+	}
+}
+
+// Error implements Iterator.
+func (itr *pebbleDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *pebbleDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *pebbleDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
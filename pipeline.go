@@ -0,0 +1,106 @@
+package db
+
+import "sync"
+
+// Pipeline buffers writes to a database from many goroutines, and writes them together as a
+// batch once maxBatchSize writes are pending or Flush is called, for fire-and-forget write
+// patterns. Writes queued while a full batch is being flushed may be included in it. Each
+// write's callback is called with the outcome of the batch it was written in.
+//
+// Batches are written by the goroutine whose write fills the batch, or which calls Flush, and
+// are written in order. Callbacks are called after the batch has been written, without holding
+// any locks, so they may write to the pipeline again. Callers must call Flush when done, to write
+// any remaining writes.
+type Pipeline struct {
+	db           DB
+	maxBatchSize int
+
+	writeMtx  sync.Mutex // serializes batch writes, so they are applied in order
+	mtx       sync.Mutex // protects the pending writes below
+	ops       []BatchOp
+	callbacks []func(error)
+}
+
+// NewPipeline creates a pipeline writing to the given database in batches of maxBatchSize
+// writes. Values below 1 are treated as 1.
+func NewPipeline(db DB, maxBatchSize int) *Pipeline {
+	if maxBatchSize < 1 {
+		maxBatchSize = 1
+	}
+	return &Pipeline{
+		db:           db,
+		maxBatchSize: maxBatchSize,
+	}
+}
+
+// Set queues setting the given key, and calls callback, if not nil, once it has been written.
+// The key and value are copied. Invalid writes are not queued, and their callback is called with
+// the error right away.
+func (p *Pipeline) Set(key, value []byte, callback func(error)) {
+	if len(key) == 0 {
+		p.done(callback, errKeyEmpty)
+		return
+	}
+	if value == nil {
+		p.done(callback, errValueNil)
+		return
+	}
+	p.queue(BatchOp{Key: cp(key), Value: cp(value)}, callback)
+}
+
+// Delete queues deleting the given key, and calls callback, if not nil, once it has been
+// written. The key is copied.
+func (p *Pipeline) Delete(key []byte, callback func(error)) {
+	if len(key) == 0 {
+		p.done(callback, errKeyEmpty)
+		return
+	}
+	p.queue(BatchOp{Key: cp(key), Delete: true}, callback)
+}
+
+// Pending returns the number of queued writes.
+func (p *Pipeline) Pending() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.ops)
+}
+
+// Flush writes all queued writes as a batch, calls their callbacks, and returns the outcome.
+func (p *Pipeline) Flush() error {
+	p.writeMtx.Lock()
+	p.mtx.Lock()
+	ops, callbacks := p.ops, p.callbacks
+	p.ops, p.callbacks = nil, nil
+	p.mtx.Unlock()
+
+	var err error
+	if len(ops) > 0 {
+		err = p.db.WriteBatch(ops)
+	}
+	p.writeMtx.Unlock()
+
+	for _, callback := range callbacks {
+		p.done(callback, err)
+	}
+	return err
+}
+
+// queue queues a write, and flushes the pipeline if the batch is full.
+func (p *Pipeline) queue(op BatchOp, callback func(error)) {
+	p.mtx.Lock()
+	p.ops = append(p.ops, op)
+	p.callbacks = append(p.callbacks, callback)
+	full := len(p.ops) >= p.maxBatchSize
+	p.mtx.Unlock()
+
+	if full {
+		_ = p.Flush() // the error is passed to the callbacks
+	}
+}
+
+// done calls a callback, if not nil.
+func (p *Pipeline) done(callback func(error), err error) {
+	if callback != nil {
+		callback(err)
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineConcurrentWriters(t *testing.T) {
+	db := NewMockDBWrapping(NewMemDB())
+	p := NewPipeline(db, 16)
+
+	const writers = 200
+	var (
+		wg    sync.WaitGroup
+		calls [writers]int32
+	)
+	for i := 0; i < writers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Set([]byte(fmt.Sprintf("key%03d", i)), []byte{byte(i)}, func(err error) {
+				assert.NoError(t, err)
+				atomic.AddInt32(&calls[i], 1)
+			})
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, p.Flush())
+
+	for i := range calls {
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls[i]), "callback %v", i)
+	}
+	assert.Zero(t, p.Pending())
+	// At most 12 full batches of 16 and the remaining 8, but concurrent writes may join a batch.
+	assert.LessOrEqual(t, db.Calls["WriteBatch"], 13)
+	for i := 0; i < writers; i++ {
+		checkValue(t, db, []byte(fmt.Sprintf("key%03d", i)), []byte{byte(i)})
+	}
+}
+
+func TestPipelineFlush(t *testing.T) {
+	db := NewMockDBWrapping(NewMemDB())
+	p := NewPipeline(db, 10)
+
+	var errs []error
+	callback := func(err error) { errs = append(errs, err) }
+	p.Set([]byte("a"), []byte{1}, callback)
+	p.Set([]byte("b"), []byte{2}, nil)
+	p.Delete([]byte("a"), callback)
+	assert.Equal(t, 3, p.Pending())
+	assert.Empty(t, errs)
+	checkValue(t, db, []byte("b"), nil)
+
+	require.NoError(t, p.Flush())
+	assert.Equal(t, []error{nil, nil}, errs)
+	assertKeyValues(t, db, map[string][]byte{"b": {2}})
+	require.NoError(t, p.Flush()) // nothing to write
+	assert.Equal(t, 1, db.Calls["WriteBatch"])
+
+	// Invalid writes fail right away, batch failures are passed to all callbacks.
+	errs = nil
+	p.Set(nil, []byte{1}, callback)
+	p.Set([]byte("c"), nil, callback)
+	assert.Equal(t, []error{errKeyEmpty, errValueNil}, errs)
+	assert.Zero(t, p.Pending())
+
+	errs = nil
+	db.SetError("WriteBatch", errors.New("boom"))
+	p.Set([]byte("c"), []byte{3}, callback)
+	p.Delete([]byte("d"), callback)
+	require.Error(t, p.Flush())
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "boom")
+	assert.EqualError(t, errs[1], "boom")
+}
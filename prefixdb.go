@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -50,6 +51,18 @@ func (pdb *PrefixDB) Has(key []byte) (bool, error) {
 	return ok, nil
 }
 
+// MultiGet implements DB.
+func (pdb *PrefixDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	pkeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		pkeys[i] = pdb.prefixed(key)
+	}
+	return pdb.db.MultiGet(pkeys)
+}
+
 // Set implements DB.
 func (pdb *PrefixDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -78,6 +91,18 @@ func (pdb *PrefixDB) SetSync(key []byte, value []byte) error {
 	return pdb.db.SetSync(pdb.prefixed(key), value)
 }
 
+// CompareAndSet implements DB.
+func (pdb *PrefixDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+
+	return pdb.db.CompareAndSet(pdb.prefixed(key), expected, newVal)
+}
+
 // Delete implements DB.
 func (pdb *PrefixDB) Delete(key []byte) error {
 	if len(key) == 0 {
@@ -138,11 +163,72 @@ func (pdb *PrefixDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	return newPrefixIterator(pdb.prefix, start, end, ritr)
 }
 
+// DeleteRange implements DB.
+func (pdb *PrefixDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+
+	pstart, pend := prefixDomain(pdb.prefix)
+	if start != nil {
+		pstart = pdb.prefixed(start)
+	}
+	if end != nil {
+		pend = pdb.prefixed(end)
+	}
+	return pdb.db.DeleteRange(pstart, pend)
+}
+
+// IteratorWithContext implements DB.
+func (pdb *PrefixDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, pdb, start, end)
+}
+
+// Compact implements DB.
+func (pdb *PrefixDB) Compact(start, end []byte) error {
+	pstart, pend := prefixDomain(pdb.prefix)
+	if start != nil {
+		pstart = pdb.prefixed(start)
+	}
+	if end != nil {
+		pend = pdb.prefixed(end)
+	}
+	return pdb.db.Compact(pstart, pend)
+}
+
+// WriteBatch implements DB.
+func (pdb *PrefixDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(pdb, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (pdb *PrefixDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(pdb, ops, true)
+}
+
+// ApplyLog implements DB.
+func (pdb *PrefixDB) ApplyLog(ops BatchOpList) error {
+	return pdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (pdb *PrefixDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(pdb, fn)
+}
+
 // NewBatch implements DB.
 func (pdb *PrefixDB) NewBatch() Batch {
 	return newPrefixBatch(pdb.prefix, pdb.db.NewBatch())
 }
 
+// NewBatchWithSize implements DB.
+func (pdb *PrefixDB) NewBatchWithSize(expectedOps int) Batch {
+	return newPrefixBatch(pdb.prefix, pdb.db.NewBatchWithSize(expectedOps))
+}
+
 // Close implements DB.
 func (pdb *PrefixDB) Close() error {
 	pdb.mtx.Lock()
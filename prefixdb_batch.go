@@ -35,6 +35,11 @@ func (pb prefixDBBatch) Delete(key []byte) error {
 	return pb.source.Delete(pkey)
 }
 
+// Len implements Batch.
+func (pb prefixDBBatch) Len() int {
+	return pb.source.Len()
+}
+
 // Write implements Batch.
 func (pb prefixDBBatch) Write() error {
 	return pb.source.Write()
@@ -8,14 +8,7 @@ import (
 // IteratePrefix is a convenience function for iterating over a key domain
 // restricted by prefix.
 func IteratePrefix(db DB, prefix []byte) (Iterator, error) {
-	var start, end []byte
-	if len(prefix) == 0 {
-		start = nil
-		end = nil
-	} else {
-		start = cp(prefix)
-		end = cpIncr(prefix)
-	}
+	start, end := prefixDomain(prefix)
 	itr, err := db.Iterator(start, end)
 	if err != nil {
 		return nil, err
@@ -23,6 +16,60 @@ func IteratePrefix(db DB, prefix []byte) (Iterator, error) {
 	return itr, nil
 }
 
+// ReverseIteratePrefix is a convenience function for iterating over a key domain
+// restricted by prefix, in descending order.
+func ReverseIteratePrefix(db DB, prefix []byte) (Iterator, error) {
+	start, end := prefixDomain(prefix)
+	itr, err := db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return itr, nil
+}
+
+// WalkPrefixOptions configures WalkPrefixWithOptions.
+type WalkPrefixOptions struct {
+	// KeepPrefix passes keys to fn including the prefix, rather than stripping it.
+	KeepPrefix bool
+}
+
+// WalkPrefix calls fn for every key/value pair whose key has the given prefix, in ascending key
+// order, with the prefix stripped from the keys. If fn returns an error, iteration stops and the
+// error is returned. fn must not write to the database.
+// CONTRACT: key, value readonly []byte, and only valid until fn returns
+func WalkPrefix(db DB, prefix []byte, fn func(key, value []byte) error) error {
+	return WalkPrefixWithOptions(db, prefix, WalkPrefixOptions{}, fn)
+}
+
+// WalkPrefixWithOptions is like WalkPrefix, configured by the given options.
+func WalkPrefixWithOptions(db DB, prefix []byte, opts WalkPrefixOptions, fn func(key, value []byte) error) error {
+	itr, err := IteratePrefix(db, prefix)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		if !opts.KeepPrefix {
+			key = key[len(prefix):]
+		}
+		if err := fn(key, itr.Value()); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+// prefixDomain returns the iterator domain covering all keys with the given prefix. If the
+// prefix consists only of 0xFF bytes there is no exclusive upper bound, so end is nil.
+func prefixDomain(prefix []byte) (start []byte, end []byte) {
+	if len(prefix) == 0 {
+		return nil, nil
+	}
+	return cp(prefix), cpIncr(prefix)
+}
+
 // Strips prefix while iterating from Iterator.
 type prefixDBIterator struct {
 	prefix []byte
@@ -98,6 +145,20 @@ func (itr *prefixDBIterator) Next() {
 	}
 }
 
+// Seek implements Iterator.
+func (itr *prefixDBIterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	itr.source.Seek(append(cp(itr.prefix), key...))
+	itr.valid = itr.source.Valid() && bytes.HasPrefix(itr.source.Key(), itr.prefix)
+	if itr.valid && bytes.Equal(itr.source.Key(), itr.prefix) {
+		// Skip a key exactly matching the prefix, as in Next.
+		itr.Next()
+	}
+	return itr.Valid()
+}
+
 // Next implements Iterator.
 func (itr *prefixDBIterator) Key() []byte {
 	itr.assertIsValid()
@@ -111,6 +172,11 @@ func (itr *prefixDBIterator) Value() []byte {
 	return itr.source.Value()
 }
 
+// Position implements Iterator.
+func (itr *prefixDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
 // Error implements Iterator.
 func (itr *prefixDBIterator) Error() error {
 	if err := itr.source.Error(); err != nil {
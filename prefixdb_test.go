@@ -45,18 +45,6 @@ func TestGolevelDB(t *testing.T) {
 	Run(t, db)
 }
 
-/* We don't seem to test badger anywhere.
-func TestWithBadgerDB(t *testing.T) {
-	dir := t.TempDir()
-	path := filepath.Join(dir, "badgerdb")
-
-	db, err := NewBadgerDB(path, "")
-	require.NoError(t, err)
-
-	t.Run("BadgerDB", func(t *testing.T) { Run(t, db) })
-}
-*/
-
 func TestWithMemDB(t *testing.T) {
 	db := NewMemDB()
 
@@ -224,3 +212,91 @@ func TestPrefixDBReverseIterator7(t *testing.T) {
 	checkInvalid(t, itr)
 	itr.Close()
 }
+
+func TestPrefixDBNamespacing(t *testing.T) {
+	db := NewMemDB()
+	pdb := NewPrefixDB(db, bz("p/"))
+
+	require.NoError(t, db.Set(bz("a"), bz("outside")))
+	require.NoError(t, pdb.Set(bz("a"), bz("1")))
+	require.NoError(t, pdb.SetSync(bz("b"), bz("2")))
+	require.NoError(t, pdb.Set(bz("c"), bz("3")))
+	require.NoError(t, pdb.DeleteSync(bz("c")))
+
+	batch := pdb.NewBatch()
+	require.NoError(t, batch.Set(bz("d"), bz("4")))
+	require.NoError(t, batch.Set(bz("e"), bz("5")))
+	require.NoError(t, batch.Delete(bz("b")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	// All writes should have been prefixed in the underlying database.
+	assertKeyValues(t, db, map[string][]byte{
+		"a":   bz("outside"),
+		"p/a": bz("1"),
+		"p/d": bz("4"),
+		"p/e": bz("5"),
+	})
+	// And the prefix should be stripped again when reading through the PrefixDB.
+	assertKeyValues(t, pdb, map[string][]byte{
+		"a": bz("1"),
+		"d": bz("4"),
+		"e": bz("5"),
+	})
+
+	ok, err := pdb.Has(bz("a"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = pdb.Has(bz("b"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, pdb.Delete(bz("a")))
+	checkValue(t, pdb, bz("a"), nil)
+	checkValue(t, db, bz("a"), bz("outside"))
+}
+
+func TestPrefixDBDeleteRange(t *testing.T) {
+	db := NewMemDB()
+	pdb := NewPrefixDB(db, bz("p/"))
+
+	require.NoError(t, db.Set(bz("a"), bz("outside")))
+	require.NoError(t, db.Set(bz("q"), bz("outside")))
+	require.NoError(t, pdb.Set(bz("a"), bz("1")))
+	require.NoError(t, pdb.Set(bz("b"), bz("2")))
+	require.NoError(t, pdb.Set(bz("c"), bz("3")))
+
+	require.NoError(t, pdb.DeleteRange(bz("b"), nil))
+	assertKeyValues(t, db, map[string][]byte{
+		"a":   bz("outside"),
+		"q":   bz("outside"),
+		"p/a": bz("1"),
+	})
+
+	// An unbounded range only deletes keys within the prefix.
+	require.NoError(t, pdb.DeleteRange(nil, nil))
+	assertKeyValues(t, db, map[string][]byte{
+		"a": bz("outside"),
+		"q": bz("outside"),
+	})
+}
+
+func TestPrefixDBStats(t *testing.T) {
+	db := NewMemDB()
+	pdb := NewPrefixDB(db, bz("p/"))
+	require.NoError(t, pdb.Set(bz("a"), bz("1")))
+
+	stats := pdb.Stats()
+	require.Equal(t, "p/", stats["prefixdb.prefix.string"])
+	require.Equal(t, "702F", stats["prefixdb.prefix.hex"])
+	require.Equal(t, "memDB", stats["prefixdb.source.database.type"])
+}
+
+func TestPrefixDBMultiGet(t *testing.T) {
+	db := mockDBWithStuff(t)
+	pdb := NewPrefixDB(db, bz("key"))
+
+	values, err := pdb.MultiGet([][]byte{bz("1"), bz("4"), bz("2")})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{bz("value1"), nil, bz("value2")}, values)
+}
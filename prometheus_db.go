@@ -0,0 +1,369 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Operation label values of PrometheusDB metrics.
+const (
+	promOpGet           = "get"
+	promOpHas           = "has"
+	promOpMultiGet      = "multi_get"
+	promOpSet           = "set"
+	promOpDelete        = "delete"
+	promOpCompareAndSet = "compare_and_set"
+	promOpDeleteRange   = "delete_range"
+	promOpIterate       = "iterate"
+	promOpWriteBatch    = "write_batch"
+	promOpCompact       = "compact"
+)
+
+// promDurationBuckets are the buckets of the operation duration histogram, from 1µs to ~4s.
+var promDurationBuckets = prometheus.ExponentialBuckets(1e-6, 4, 12)
+
+// prometheusMetrics are the metrics shared by all PrometheusDBs using the same registerer.
+type prometheusMetrics struct {
+	duration *prometheus.HistogramVec
+	total    *prometheus.CounterVec
+}
+
+// PrometheusDB wraps a database, and records Prometheus metrics for its operations:
+//
+//	db_operation_duration_seconds{db, operation}  histogram of operation latencies
+//	db_operation_total{db, operation, status}     counter of operations, by status "ok" or "error"
+//
+// The db label is the name given to NewPrometheusDB, so that several databases can share the
+// metrics. Sync variants are recorded as their non-sync operation, and batch writes and
+// WriteBatch calls as "write_batch". Iterators record the creation of the iterator, and every
+// Next and Seek step, as "iterate".
+type PrometheusDB struct {
+	db      DB
+	name    string
+	metrics prometheusMetrics
+}
+
+var _ DB = (*PrometheusDB)(nil)
+
+// NewPrometheusDB creates a PrometheusDB wrapping the given database, registering its metrics
+// with registerer, or prometheus.DefaultRegisterer if nil. The metrics are reused if another
+// PrometheusDB already registered them.
+func NewPrometheusDB(inner DB, name string, registerer prometheus.Registerer) (*PrometheusDB, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_operation_duration_seconds",
+		Help:    "Duration of database operations in seconds.",
+		Buckets: promDurationBuckets,
+	}, []string{"db", "operation"})
+	total := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_operation_total",
+		Help: "Number of database operations, by status.",
+	}, []string{"db", "operation", "status"})
+
+	if err := registerer.Register(duration); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return nil, err
+		}
+		duration = are.ExistingCollector.(*prometheus.HistogramVec)
+	}
+	if err := registerer.Register(total); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return nil, err
+		}
+		total = are.ExistingCollector.(*prometheus.CounterVec)
+	}
+
+	return &PrometheusDB{
+		db:      inner,
+		name:    name,
+		metrics: prometheusMetrics{duration: duration, total: total},
+	}, nil
+}
+
+// observe records an operation which started at the given time.
+func (m prometheusMetrics) observe(name, op string, start time.Time, err error) {
+	m.duration.WithLabelValues(name, op).Observe(time.Since(start).Seconds())
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.total.WithLabelValues(name, op, status).Inc()
+}
+
+// Get implements DB.
+func (pdb *PrometheusDB) Get(key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := pdb.db.Get(key)
+	pdb.metrics.observe(pdb.name, promOpGet, start, err)
+	return value, err
+}
+
+// Has implements DB.
+func (pdb *PrometheusDB) Has(key []byte) (bool, error) {
+	start := time.Now()
+	ok, err := pdb.db.Has(key)
+	pdb.metrics.observe(pdb.name, promOpHas, start, err)
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (pdb *PrometheusDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	start := time.Now()
+	values, err := pdb.db.MultiGet(keys)
+	pdb.metrics.observe(pdb.name, promOpMultiGet, start, err)
+	return values, err
+}
+
+// Set implements DB.
+func (pdb *PrometheusDB) Set(key []byte, value []byte) error {
+	start := time.Now()
+	err := pdb.db.Set(key, value)
+	pdb.metrics.observe(pdb.name, promOpSet, start, err)
+	return err
+}
+
+// SetSync implements DB.
+func (pdb *PrometheusDB) SetSync(key []byte, value []byte) error {
+	start := time.Now()
+	err := pdb.db.SetSync(key, value)
+	pdb.metrics.observe(pdb.name, promOpSet, start, err)
+	return err
+}
+
+// Delete implements DB.
+func (pdb *PrometheusDB) Delete(key []byte) error {
+	start := time.Now()
+	err := pdb.db.Delete(key)
+	pdb.metrics.observe(pdb.name, promOpDelete, start, err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (pdb *PrometheusDB) DeleteSync(key []byte) error {
+	start := time.Now()
+	err := pdb.db.DeleteSync(key)
+	pdb.metrics.observe(pdb.name, promOpDelete, start, err)
+	return err
+}
+
+// CompareAndSet implements DB.
+func (pdb *PrometheusDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	start := time.Now()
+	swapped, err := pdb.db.CompareAndSet(key, expected, newVal)
+	pdb.metrics.observe(pdb.name, promOpCompareAndSet, start, err)
+	return swapped, err
+}
+
+// DeleteRange implements DB.
+func (pdb *PrometheusDB) DeleteRange(start, end []byte) error {
+	now := time.Now()
+	err := pdb.db.DeleteRange(start, end)
+	pdb.metrics.observe(pdb.name, promOpDeleteRange, now, err)
+	return err
+}
+
+// Iterator implements DB.
+func (pdb *PrometheusDB) Iterator(start, end []byte) (Iterator, error) {
+	return pdb.iterator(func() (Iterator, error) { return pdb.db.Iterator(start, end) })
+}
+
+// ReverseIterator implements DB.
+func (pdb *PrometheusDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return pdb.iterator(func() (Iterator, error) { return pdb.db.ReverseIterator(start, end) })
+}
+
+// IteratorWithContext implements DB.
+func (pdb *PrometheusDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return pdb.iterator(func() (Iterator, error) { return pdb.db.IteratorWithContext(ctx, start, end) })
+}
+
+func (pdb *PrometheusDB) iterator(newFn func() (Iterator, error)) (Iterator, error) {
+	start := time.Now()
+	itr, err := newFn()
+	pdb.metrics.observe(pdb.name, promOpIterate, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &prometheusIterator{db: pdb, source: itr}, nil
+}
+
+// Compact implements DB.
+func (pdb *PrometheusDB) Compact(start, end []byte) error {
+	now := time.Now()
+	err := pdb.db.Compact(start, end)
+	pdb.metrics.observe(pdb.name, promOpCompact, now, err)
+	return err
+}
+
+// WriteBatch implements DB.
+func (pdb *PrometheusDB) WriteBatch(ops []BatchOp) error {
+	start := time.Now()
+	err := pdb.db.WriteBatch(ops)
+	pdb.metrics.observe(pdb.name, promOpWriteBatch, start, err)
+	return err
+}
+
+// WriteBatchSync implements DB.
+func (pdb *PrometheusDB) WriteBatchSync(ops []BatchOp) error {
+	start := time.Now()
+	err := pdb.db.WriteBatchSync(ops)
+	pdb.metrics.observe(pdb.name, promOpWriteBatch, start, err)
+	return err
+}
+
+// ApplyLog implements DB.
+func (pdb *PrometheusDB) ApplyLog(ops BatchOpList) error {
+	start := time.Now()
+	err := pdb.db.ApplyLog(ops)
+	pdb.metrics.observe(pdb.name, promOpWriteBatch, start, err)
+	return err
+}
+
+// ForEach implements DB. It is recorded as a single "iterate" operation.
+func (pdb *PrometheusDB) ForEach(fn func(key, value []byte) error) error {
+	start := time.Now()
+	err := pdb.db.ForEach(fn)
+	pdb.metrics.observe(pdb.name, promOpIterate, start, err)
+	return err
+}
+
+// Close implements DB.
+func (pdb *PrometheusDB) Close() error {
+	return pdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (pdb *PrometheusDB) NewBatch() Batch {
+	return newPrometheusBatch(pdb, pdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (pdb *PrometheusDB) NewBatchWithSize(expectedOps int) Batch {
+	return newPrometheusBatch(pdb, pdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (pdb *PrometheusDB) Print() error {
+	return pdb.db.Print()
+}
+
+// Stats implements DB.
+func (pdb *PrometheusDB) Stats() map[string]string {
+	return pdb.db.Stats()
+}
+
+// prometheusBatch wraps a batch, recording its writes. Queueing operations is not recorded.
+type prometheusBatch struct {
+	db    *PrometheusDB
+	batch Batch
+}
+
+var _ Batch = (*prometheusBatch)(nil)
+
+func newPrometheusBatch(db *PrometheusDB, batch Batch) *prometheusBatch {
+	return &prometheusBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *prometheusBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *prometheusBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *prometheusBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *prometheusBatch) Write() error {
+	start := time.Now()
+	err := b.batch.Write()
+	b.db.metrics.observe(b.db.name, promOpWriteBatch, start, err)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *prometheusBatch) WriteSync() error {
+	start := time.Now()
+	err := b.batch.WriteSync()
+	b.db.metrics.observe(b.db.name, promOpWriteBatch, start, err)
+	return err
+}
+
+// Close implements Batch.
+func (b *prometheusBatch) Close() error {
+	return b.batch.Close()
+}
+
+// prometheusIterator wraps an iterator, recording every step.
+type prometheusIterator struct {
+	db     *PrometheusDB
+	source Iterator
+}
+
+var _ Iterator = (*prometheusIterator)(nil)
+
+// Domain implements Iterator.
+func (itr *prometheusIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *prometheusIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Seek implements Iterator.
+func (itr *prometheusIterator) Seek(key []byte) bool {
+	start := time.Now()
+	ok := itr.source.Seek(key)
+	itr.db.metrics.observe(itr.db.name, promOpIterate, start, itr.source.Error())
+	return ok
+}
+
+// Next implements Iterator.
+func (itr *prometheusIterator) Next() {
+	start := time.Now()
+	itr.source.Next()
+	itr.db.metrics.observe(itr.db.name, promOpIterate, start, itr.source.Error())
+}
+
+// Key implements Iterator.
+func (itr *prometheusIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *prometheusIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Position implements Iterator.
+func (itr *prometheusIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Error implements Iterator.
+func (itr *prometheusIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *prometheusIterator) Close() error {
+	return itr.source.Close()
+}
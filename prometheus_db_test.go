@@ -0,0 +1,71 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusDB(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mock := NewMockDBWrapping(NewMemDB())
+	pdb, err := NewPrometheusDB(mock, "test", registry)
+	require.NoError(t, err)
+
+	require.NoError(t, pdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, pdb.SetSync([]byte("b"), []byte{2}))
+	checkValue(t, pdb, []byte("a"), []byte{1})
+	require.NoError(t, pdb.Delete([]byte("b")))
+
+	mock.SetError("Get", errors.New("boom"))
+	_, err = pdb.Get([]byte("a"))
+	require.Error(t, err)
+
+	itr, err := pdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	itr.Next()
+	checkValid(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	batch := pdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	total := pdb.metrics.total
+	assert.Equal(t, 2.0, testutil.ToFloat64(total.WithLabelValues("test", "set", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("test", "get", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("test", "get", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("test", "delete", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(total.WithLabelValues("test", "write_batch", "ok")))
+	// Creating the iterator, and stepping over its only item.
+	assert.Equal(t, 2.0, testutil.ToFloat64(total.WithLabelValues("test", "iterate", "ok")))
+
+	// Every operation is observed by the histogram as well.
+	count, err := testutil.GatherAndCount(registry, "db_operation_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 5, count) // one series per operation label
+	count, err = testutil.GatherAndCount(registry, "db_operation_total")
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+}
+
+func TestPrometheusDBSharedRegisterer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	a, err := NewPrometheusDB(NewMemDB(), "a", registry)
+	require.NoError(t, err)
+	b, err := NewPrometheusDB(NewMemDB(), "b", registry)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Set([]byte("key"), []byte{1}))
+	require.NoError(t, b.Set([]byte("key"), []byte{1}))
+	require.NoError(t, b.Set([]byte("key"), []byte{2}))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(a.metrics.total.WithLabelValues("a", "set", "ok")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(a.metrics.total.WithLabelValues("b", "set", "ok")))
+}
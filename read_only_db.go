@@ -0,0 +1,127 @@
+package db
+
+import "context"
+
+// ReadOnlyDB wraps a database, and provides a strict read-only view of it, e.g. for audits or
+// follower nodes. All mutations, including compaction, fail with ErrReadOnly, while reads are
+// passed on to the wrapped database. Writes made directly to the wrapped database are visible.
+type ReadOnlyDB struct {
+	db DB
+}
+
+var _ DB = (*ReadOnlyDB)(nil)
+
+// NewReadOnlyDB creates a ReadOnlyDB wrapping the given database.
+func NewReadOnlyDB(db DB) *ReadOnlyDB {
+	return &ReadOnlyDB{db: db}
+}
+
+// Get implements DB.
+func (rodb *ReadOnlyDB) Get(key []byte) ([]byte, error) {
+	return rodb.db.Get(key)
+}
+
+// Has implements DB.
+func (rodb *ReadOnlyDB) Has(key []byte) (bool, error) {
+	return rodb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (rodb *ReadOnlyDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return rodb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (rodb *ReadOnlyDB) Set(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+// SetSync implements DB.
+func (rodb *ReadOnlyDB) SetSync(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+// Delete implements DB.
+func (rodb *ReadOnlyDB) Delete(key []byte) error {
+	return ErrReadOnly
+}
+
+// DeleteSync implements DB.
+func (rodb *ReadOnlyDB) DeleteSync(key []byte) error {
+	return ErrReadOnly
+}
+
+// CompareAndSet implements DB.
+func (rodb *ReadOnlyDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return false, ErrReadOnly
+}
+
+// DeleteRange implements DB.
+func (rodb *ReadOnlyDB) DeleteRange(start, end []byte) error {
+	return ErrReadOnly
+}
+
+// Iterator implements DB.
+func (rodb *ReadOnlyDB) Iterator(start, end []byte) (Iterator, error) {
+	return rodb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (rodb *ReadOnlyDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return rodb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (rodb *ReadOnlyDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return rodb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (rodb *ReadOnlyDB) Compact(start, end []byte) error {
+	return ErrReadOnly
+}
+
+// WriteBatch implements DB.
+func (rodb *ReadOnlyDB) WriteBatch(ops []BatchOp) error {
+	return ErrReadOnly
+}
+
+// WriteBatchSync implements DB.
+func (rodb *ReadOnlyDB) WriteBatchSync(ops []BatchOp) error {
+	return ErrReadOnly
+}
+
+// ApplyLog implements DB.
+func (rodb *ReadOnlyDB) ApplyLog(ops BatchOpList) error {
+	return ErrReadOnly
+}
+
+// ForEach implements DB.
+func (rodb *ReadOnlyDB) ForEach(fn func(key, value []byte) error) error {
+	return rodb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (rodb *ReadOnlyDB) Close() error {
+	return rodb.db.Close()
+}
+
+// NewBatch implements DB. Writes to the batch fail with ErrReadOnly.
+func (rodb *ReadOnlyDB) NewBatch() Batch {
+	return readOnlyBatch{}
+}
+
+// NewBatchWithSize implements DB. Writes to the batch fail with ErrReadOnly.
+func (rodb *ReadOnlyDB) NewBatchWithSize(expectedOps int) Batch {
+	return readOnlyBatch{}
+}
+
+// Print implements DB.
+func (rodb *ReadOnlyDB) Print() error {
+	return rodb.db.Print()
+}
+
+// Stats implements DB.
+func (rodb *ReadOnlyDB) Stats() map[string]string {
+	return rodb.db.Stats()
+}
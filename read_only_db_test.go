@@ -0,0 +1,49 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyDB(t *testing.T) {
+	inner := NewMemDB()
+	require.NoError(t, inner.Set([]byte("a"), []byte{1}))
+	require.NoError(t, inner.Set([]byte("b"), []byte{2}))
+	rodb := NewReadOnlyDB(inner)
+
+	// Reads are passed on.
+	checkValue(t, rodb, []byte("a"), []byte{1})
+	has, err := rodb.Has([]byte("b"))
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	itr, err := rodb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	require.NoError(t, itr.Close())
+	itr, err = rodb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("b"), []byte{2})
+	require.NoError(t, itr.Close())
+
+	// All writes fail.
+	assert.Equal(t, ErrReadOnly, rodb.Set([]byte("c"), []byte{3}))
+	assert.Equal(t, ErrReadOnly, rodb.SetSync([]byte("c"), []byte{3}))
+	assert.Equal(t, ErrReadOnly, rodb.Delete([]byte("a")))
+	assert.Equal(t, ErrReadOnly, rodb.DeleteSync([]byte("a")))
+	assert.Equal(t, ErrReadOnly, rodb.DeleteRange(nil, nil))
+	assert.Equal(t, ErrReadOnly, rodb.Compact(nil, nil))
+	assert.Equal(t, ErrReadOnly, rodb.WriteBatch([]BatchOp{{Key: []byte("c"), Value: []byte{3}}}))
+	_, err = rodb.CompareAndSet([]byte("a"), []byte{1}, []byte{3})
+	assert.Equal(t, ErrReadOnly, err)
+
+	batch := rodb.NewBatch()
+	assert.Equal(t, ErrReadOnly, batch.Set([]byte("c"), []byte{3}))
+	assert.Equal(t, ErrReadOnly, batch.Write())
+	require.NoError(t, batch.Close())
+
+	// Nothing was written to the wrapped database.
+	assertKeyValues(t, inner, map[string][]byte{"a": {1}, "b": {2}})
+}
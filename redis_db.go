@@ -0,0 +1,341 @@
+//go:build redis
+// +build redis
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	registerDBCreator(RedisDBBackend, func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
+		return NewRedisDB(name, &redis.Options{Addr: dir})
+	}, false)
+}
+
+// redisPageSize is the number of keys fetched at a time by iterators and range deletions.
+const redisPageSize = 1000
+
+// RedisDB is a database stored in a Redis server, for deployments sharing a Redis instance.
+//
+// All keys of a database are stored under a namespace, so several databases can share a Redis
+// server: the value of each key is a Redis string at <namespace>/k/<key>, and every key is also a
+// member of the sorted set <namespace>/index, whose lexicographic ordering is used for
+// iteration. Writes update both in a MULTI/EXEC transaction.
+//
+// Iterators fetch keys and values in pages while iterating, so unlike most backends they do not
+// read a consistent snapshot, and may or may not see writes made while iterating. DeleteRange is
+// not atomic either. Durability depends on the server's persistence settings, so SetSync and
+// friends are the same as their non-sync variants.
+type RedisDB struct {
+	client   *redis.Client
+	valueKey string // prefix of value keys
+	indexKey string
+}
+
+var _ DB = (*RedisDB)(nil)
+
+// NewRedisDB connects to a Redis server, storing the database under the given namespace.
+func NewRedisDB(namespace string, opts *redis.Options) (*RedisDB, error) {
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &RedisDB{
+		client:   client,
+		valueKey: namespace + "/k/",
+		indexKey: namespace + "/index",
+	}, nil
+}
+
+// Client returns the underlying Redis client.
+func (db *RedisDB) Client() *redis.Client {
+	return db.client
+}
+
+// key returns the Redis key holding the value of a key.
+func (db *RedisDB) key(key []byte) string {
+	return db.valueKey + string(key)
+}
+
+// queueSet queues setting a key in a pipeline.
+func (db *RedisDB) queueSet(ctx context.Context, pipe redis.Pipeliner, key, value []byte) {
+	pipe.Set(ctx, db.key(key), value, 0)
+	pipe.ZAdd(ctx, db.indexKey, redis.Z{Member: string(key)})
+}
+
+// queueDelete queues deleting a key in a pipeline.
+func (db *RedisDB) queueDelete(ctx context.Context, pipe redis.Pipeliner, key []byte) {
+	pipe.Del(ctx, db.key(key))
+	pipe.ZRem(ctx, db.indexKey, string(key))
+}
+
+// Get implements DB.
+func (db *RedisDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, err := db.client.Get(context.Background(), db.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Has implements DB.
+func (db *RedisDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	n, err := db.client.Exists(context.Background(), db.key(key)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// MultiGet implements DB. The keys are fetched with a single MGET.
+func (db *RedisDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	if len(keys) == 0 {
+		return [][]byte{}, nil
+	}
+	valueKeys := make([]string, len(keys))
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		valueKeys[i] = db.key(key)
+	}
+	results, err := db.client.MGet(context.Background(), valueKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	for i, result := range results {
+		if s, ok := result.(string); ok {
+			values[i] = []byte(s)
+		}
+	}
+	return values, nil
+}
+
+// Set implements DB.
+func (db *RedisDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	ctx := context.Background()
+	_, err := db.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		db.queueSet(ctx, pipe, key, value)
+		return nil
+	})
+	return err
+}
+
+// SetSync implements DB.
+func (db *RedisDB) SetSync(key []byte, value []byte) error {
+	return db.Set(key, value)
+}
+
+// CompareAndSet implements DB. The key is watched while comparing, and the comparison is retried
+// if it is modified concurrently.
+func (db *RedisDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	ctx := context.Background()
+	for {
+		swapped := false
+		err := db.client.Watch(ctx, func(tx *redis.Tx) error {
+			current, err := tx.Get(ctx, db.key(key)).Bytes()
+			if errors.Is(err, redis.Nil) {
+				current = nil
+			} else if err != nil {
+				return err
+			}
+			if !valueMatches(current, expected) {
+				return nil
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				db.queueSet(ctx, pipe, key, newVal)
+				return nil
+			})
+			swapped = err == nil
+			return err
+		}, db.key(key))
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return swapped, nil
+	}
+}
+
+// Delete implements DB.
+func (db *RedisDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	ctx := context.Background()
+	_, err := db.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		db.queueDelete(ctx, pipe, key)
+		return nil
+	})
+	return err
+}
+
+// DeleteSync implements DB.
+func (db *RedisDB) DeleteSync(key []byte) error {
+	return db.Delete(key)
+}
+
+// DeleteRange implements DB. Keys are deleted in chunks, each in its own transaction.
+func (db *RedisDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	ctx := context.Background()
+	bounds := redisLexBounds(start, end)
+	for {
+		// Deleted keys are gone from the index, so the range does not need to be advanced.
+		keys, err := db.client.ZRangeByLex(ctx, db.indexKey, &redis.ZRangeBy{
+			Min: bounds.Min, Max: bounds.Max, Count: redisPageSize,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		_, err = db.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				db.queueDelete(ctx, pipe, []byte(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(keys) < redisPageSize {
+			return nil
+		}
+	}
+}
+
+// redisLexBounds returns the ZRANGEBYLEX bounds of a domain.
+func redisLexBounds(start, end []byte) redis.ZRangeBy {
+	bounds := redis.ZRangeBy{Min: "-", Max: "+"}
+	if start != nil {
+		bounds.Min = "[" + string(start)
+	}
+	if end != nil {
+		bounds.Max = "(" + string(end)
+	}
+	return bounds
+}
+
+// Iterator implements DB.
+func (db *RedisDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newRedisDBIterator(db, start, end, false), nil
+}
+
+// ReverseIterator implements DB.
+func (db *RedisDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newRedisDBIterator(db, start, end, true), nil
+}
+
+// IteratorWithContext implements DB.
+func (db *RedisDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB. Redis manages its own memory, so this is a no-op.
+func (db *RedisDB) Compact(start, end []byte) error {
+	return nil
+}
+
+// WriteBatch implements DB.
+func (db *RedisDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *RedisDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *RedisDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *RedisDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
+// Close implements DB.
+func (db *RedisDB) Close() error {
+	GlobalRegistry.unregister(db)
+	return db.client.Close()
+}
+
+// NewBatch implements DB.
+func (db *RedisDB) NewBatch() Batch {
+	return newRedisDBBatch(db)
+}
+
+// NewBatchWithSize implements DB. Pipelines grow as needed, so the size hint is ignored.
+func (db *RedisDB) NewBatchWithSize(_ int) Batch {
+	return newRedisDBBatch(db)
+}
+
+// Print implements DB.
+func (db *RedisDB) Print() error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (db *RedisDB) Stats() map[string]string {
+	stats := map[string]string{"database.type": "redis"}
+	if n, err := db.client.ZCard(context.Background(), db.indexKey).Result(); err == nil {
+		stats["database.size"] = fmt.Sprintf("%d", n)
+	}
+	return stats
+}
@@ -0,0 +1,89 @@
+//go:build redis
+// +build redis
+
+package db
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDBBatch queues operations in a MULTI/EXEC pipeline, which is sent to Redis on Write().
+type redisDBBatch struct {
+	db   *RedisDB
+	pipe redis.Pipeliner
+	size int
+}
+
+var _ Batch = (*redisDBBatch)(nil)
+
+func newRedisDBBatch(db *RedisDB) *redisDBBatch {
+	return &redisDBBatch{
+		db:   db,
+		pipe: db.client.TxPipeline(),
+	}
+}
+
+// Set implements Batch.
+func (b *redisDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.pipe == nil {
+		return errBatchClosed
+	}
+	b.db.queueSet(context.Background(), b.pipe, key, value)
+	b.size++
+	return nil
+}
+
+// Delete implements Batch.
+func (b *redisDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.pipe == nil {
+		return errBatchClosed
+	}
+	b.db.queueDelete(context.Background(), b.pipe, key)
+	b.size++
+	return nil
+}
+
+// Len implements Batch.
+func (b *redisDBBatch) Len() int {
+	return b.size
+}
+
+// Write implements Batch.
+func (b *redisDBBatch) Write() error {
+	if b.pipe == nil {
+		return errBatchClosed
+	}
+	if b.size > 0 {
+		if _, err := b.pipe.Exec(context.Background()); err != nil {
+			return err
+		}
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *redisDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *redisDBBatch) Close() error {
+	if b.pipe != nil {
+		b.pipe.Discard()
+		b.pipe = nil
+	}
+	b.size = 0
+	return nil
+}
@@ -0,0 +1,155 @@
+//go:build redis
+// +build redis
+
+package db
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDBIterator iterates over the index of a RedisDB, fetching a page of keys and their values
+// at a time.
+type redisDBIterator struct {
+	db        *RedisDB
+	start     []byte
+	end       []byte
+	isReverse bool
+
+	bounds    redis.ZRangeBy // the lexicographic range not fetched yet
+	exhausted bool           // whether the whole range has been fetched
+	keys      [][]byte       // the current page, starting at the current item
+	values    [][]byte
+	err       error
+	closed    bool
+}
+
+var _ Iterator = (*redisDBIterator)(nil)
+
+func newRedisDBIterator(db *RedisDB, start, end []byte, isReverse bool) *redisDBIterator {
+	itr := &redisDBIterator{
+		db:        db,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+		bounds:    redisLexBounds(start, end),
+	}
+	itr.fill()
+	return itr
+}
+
+// fill fetches pages until there is a current item or the range is exhausted. Keys deleted
+// after being fetched from the index are skipped.
+func (itr *redisDBIterator) fill() {
+	ctx := context.Background()
+	for len(itr.keys) == 0 && !itr.exhausted && itr.err == nil {
+		bounds := itr.bounds
+		bounds.Count = redisPageSize
+		var keys []string
+		if itr.isReverse {
+			keys, itr.err = itr.db.client.ZRevRangeByLex(ctx, itr.db.indexKey, &bounds).Result()
+		} else {
+			keys, itr.err = itr.db.client.ZRangeByLex(ctx, itr.db.indexKey, &bounds).Result()
+		}
+		if itr.err != nil || len(keys) == 0 {
+			itr.exhausted = true
+			return
+		}
+		if len(keys) < redisPageSize {
+			itr.exhausted = true
+		} else if itr.isReverse {
+			itr.bounds.Max = "(" + keys[len(keys)-1]
+		} else {
+			itr.bounds.Min = "(" + keys[len(keys)-1]
+		}
+
+		valueKeys := make([]string, 0, len(keys))
+		for _, key := range keys {
+			valueKeys = append(valueKeys, itr.db.valueKey+key)
+		}
+		var values []interface{}
+		values, itr.err = itr.db.client.MGet(ctx, valueKeys...).Result()
+		if itr.err != nil {
+			return
+		}
+		for i, value := range values {
+			if s, ok := value.(string); ok {
+				itr.keys = append(itr.keys, []byte(keys[i]))
+				itr.values = append(itr.values, []byte(s))
+			}
+		}
+	}
+}
+
+// Domain implements Iterator.
+func (itr *redisDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *redisDBIterator) Valid() bool {
+	return len(itr.keys) > 0 && itr.err == nil && !itr.closed
+}
+
+// Seek implements Iterator.
+func (itr *redisDBIterator) Seek(key []byte) bool {
+	if itr.err != nil || itr.closed {
+		return false
+	}
+	itr.bounds = redisLexBounds(itr.start, itr.end)
+	switch {
+	case !itr.isReverse:
+		if itr.start == nil || bytes.Compare(key, itr.start) > 0 {
+			itr.bounds.Min = "[" + string(key)
+		}
+	case itr.end == nil || bytes.Compare(key, itr.end) < 0:
+		itr.bounds.Max = "[" + string(key)
+	}
+	itr.keys, itr.values, itr.exhausted = nil, nil, false
+	itr.fill()
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *redisDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.keys[0]
+}
+
+// Value implements Iterator.
+func (itr *redisDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.values[0]
+}
+
+// Position implements Iterator.
+func (itr *redisDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Next implements Iterator.
+func (itr *redisDBIterator) Next() {
+	itr.assertIsValid()
+	itr.keys, itr.values = itr.keys[1:], itr.values[1:]
+	itr.fill()
+}
+
+// Error implements Iterator.
+func (itr *redisDBIterator) Error() error {
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *redisDBIterator) Close() error {
+	itr.closed = true
+	itr.keys, itr.values = nil, nil
+	return nil
+}
+
+func (itr *redisDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
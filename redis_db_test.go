@@ -0,0 +1,114 @@
+//go:build redis
+// +build redis
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Replace the redis backend for the shared backend tests, which pass a directory rather than a
+// server address, with one starting a miniredis server per directory.
+func init() {
+	var mtx sync.Mutex
+	servers := map[string]*miniredis.Miniredis{}
+	registerDBCreator(RedisDBBackend, func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		server, ok := servers[dir]
+		if !ok {
+			var err error
+			server, err = miniredis.Run()
+			if err != nil {
+				return nil, err
+			}
+			servers[dir] = server
+		}
+		return NewRedisDB(name, &redis.Options{Addr: server.Addr()})
+	}, true)
+}
+
+func newTestRedisDB(t *testing.T, namespace string) (*RedisDB, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	db, err := NewRedisDB(namespace, &redis.Options{Addr: server.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, server
+}
+
+func TestRedisDB(t *testing.T) {
+	db, _ := newTestRedisDB(t, "test")
+	t.Run("RedisDB", func(t *testing.T) { Run(t, db) })
+}
+
+func TestRedisDBNamespaces(t *testing.T) {
+	db, server := newTestRedisDB(t, "a")
+	other, err := NewRedisDB("b", &redis.Options{Addr: server.Addr()})
+	require.NoError(t, err)
+	defer other.Close()
+
+	require.NoError(t, db.Set([]byte("key"), []byte{1}))
+	require.NoError(t, other.Set([]byte("key"), []byte{2}))
+	require.NoError(t, other.Set([]byte("other"), []byte{3}))
+
+	assertKeyValues(t, db, map[string][]byte{"key": {1}})
+	assertKeyValues(t, other, map[string][]byte{"key": {2}, "other": {3}})
+	assert.Equal(t, "1", db.Stats()["database.size"])
+
+	got, err := server.Get("a/k/key")
+	require.NoError(t, err)
+	assert.Equal(t, "\x01", got)
+}
+
+func TestRedisDBIteratorPages(t *testing.T) {
+	db, _ := newTestRedisDB(t, "test")
+
+	// Iterate over multiple pages, with some keys deleted behind the index's back.
+	n := redisPageSize*2 + 10
+	batch := db.NewBatch()
+	for i := 0; i < n; i++ {
+		require.NoError(t, batch.Set([]byte(fmt.Sprintf("key%05d", i)), []byte{byte(i)}))
+	}
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.NoError(t, db.client.Del(context.Background(), db.key([]byte("key00005"))).Err())
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	count := 0
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	assert.Equal(t, n-1, count)
+
+	itr, err = db.ReverseIterator([]byte("key00100"), nil)
+	require.NoError(t, err)
+	count = 0
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Close())
+	assert.Equal(t, n-100, count)
+
+	require.NoError(t, db.DeleteRange([]byte("key00010"), nil))
+	expect := map[string][]byte{}
+	for i := 0; i < 10; i++ {
+		if i != 5 {
+			expect[fmt.Sprintf("key%05d", i)] = []byte{byte(i)}
+		}
+	}
+	assertKeyValues(t, db, expect)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DBInfo describes an open database in a Registry.
+type DBInfo struct {
+	Name     string
+	Backend  BackendType
+	Dir      string
+	OpenedAt time.Time
+	// Stats are the database's Stats when listed, which include operation counters for backends
+	// which track them.
+	Stats map[string]string
+}
+
+// Registry tracks open databases, for debugging.
+type Registry struct {
+	mtx sync.RWMutex
+	dbs map[DB]DBInfo
+}
+
+// GlobalRegistry tracks every database opened with Open (and hence NewDB and OpenWithRetry), until
+// it is closed. Databases created directly with a backend's constructor, such as NewMemDB, are not
+// registered.
+var GlobalRegistry = &Registry{dbs: make(map[DB]DBInfo)}
+
+// register adds an open database to the registry.
+func (r *Registry) register(db DB, name string, backend BackendType, dir string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.dbs[db] = DBInfo{
+		Name:     name,
+		Backend:  backend,
+		Dir:      dir,
+		OpenedAt: time.Now(),
+	}
+}
+
+// unregister removes a database from the registry, if registered. Backends call it at the start
+// of Close, which therefore waits for a concurrent List to finish calling the database's Stats.
+func (r *Registry) unregister(db DB) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.dbs, db)
+}
+
+// List returns the open databases, ordered by name and then by opening time.
+func (r *Registry) List() []DBInfo {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	infos := make([]DBInfo, 0, len(r.dbs))
+	for db, info := range r.dbs {
+		info.Stats = db.Stats()
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].OpenedAt.Before(infos[j].OpenedAt)
+	})
+	return infos
+}
+
+// PrintRegistry writes a table of the databases in GlobalRegistry to w.
+func PrintRegistry(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tBACKEND\tDIR\tOPENED")
+	for _, info := range GlobalRegistry.List() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Name, info.Backend, info.Dir,
+			info.OpenedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registeredIn returns the registered databases in the given directory.
+func registeredIn(dir string) []DBInfo {
+	var infos []DBInfo
+	for _, info := range GlobalRegistry.List() {
+		if info.Dir == dir {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	before := time.Now()
+	ldb, err := NewDB("registry_a", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	mdb, err := Open(MemDBBackend, "registry_b", dir, Options{})
+	require.NoError(t, err)
+	// Databases created directly are not registered.
+	NewMemDB()
+
+	infos := registeredIn(dir)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "registry_a", infos[0].Name)
+	assert.Equal(t, GoLevelDBBackend, infos[0].Backend)
+	assert.Contains(t, infos[0].Stats, "leveldb.stats")
+	assert.Equal(t, "registry_b", infos[1].Name)
+	assert.Equal(t, MemDBBackend, infos[1].Backend)
+	assert.Equal(t, "memDB", infos[1].Stats["database.type"])
+	for _, info := range infos {
+		assert.False(t, info.OpenedAt.Before(before))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintRegistry(&buf))
+	var rows [][]string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[2] == dir {
+			rows = append(rows, fields[:3])
+		}
+	}
+	assert.True(t, strings.HasPrefix(buf.String(), "NAME"))
+	assert.Equal(t, [][]string{{"registry_a", "goleveldb", dir}, {"registry_b", "memdb", dir}}, rows)
+
+	require.NoError(t, ldb.Close())
+	infos = registeredIn(dir)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "registry_b", infos[0].Name)
+	require.NoError(t, mdb.Close())
+	assert.Empty(t, registeredIn(dir))
+}
@@ -18,9 +18,16 @@ type batch struct {
 var _ db.Batch = (*batch)(nil)
 
 func newBatch(rdb *RemoteDB) *batch {
+	return newBatchWithSize(rdb, 0)
+}
+
+func newBatchWithSize(rdb *RemoteDB, size int) *batch {
+	if size < 0 {
+		size = 0
+	}
 	return &batch{
 		db:  rdb,
-		ops: []*protodb.Operation{},
+		ops: make([]*protodb.Operation, 0, size),
 	}
 }
 
@@ -50,6 +57,11 @@ func (b *batch) Delete(key []byte) error {
 	return nil
 }
 
+// Len implements Batch.
+func (b *batch) Len() int {
+	return len(b.ops)
+}
+
 // Write implements Batch.
 func (b *batch) Write() error {
 	if b.ops == nil {
@@ -28,5 +28,8 @@ or
 			log.Fatalf("BindServer: %v", err)
 		}
 	}()
+
+To serve an existing local database instead of one created by Init,
+use NewServerForDB.
 */
 package grpcdb
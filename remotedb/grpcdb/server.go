@@ -2,6 +2,7 @@ package grpcdb
 
 import (
 	"context"
+	"errors"
 	"net"
 	"sync"
 	"time"
@@ -29,21 +30,35 @@ func ListenAndServe(addr, cert, key string, opts ...grpc.ServerOption) error {
 }
 
 func NewServer(cert, key string, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	return newGRPCServer(new(server), cert, key, opts...)
+}
+
+// NewServerForDB creates a gRPC server serving the given local database, e.g. so that a state
+// machine can use a database owned by another process. Clients must not call Init, which fails.
+func NewServerForDB(database db.DB, cert, key string, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	return newGRPCServer(&server{db: database, provided: true}, cert, key, opts...)
+}
+
+func newGRPCServer(s *server, cert, key string, opts ...grpc.ServerOption) (*grpc.Server, error) {
 	creds, err := credentials.NewServerTLSFromFile(cert, key)
 	if err != nil {
 		return nil, err
 	}
 	opts = append(opts, grpc.Creds(creds))
 	srv := grpc.NewServer(opts...)
-	protodb.RegisterDBServer(srv, new(server))
+	protodb.RegisterDBServer(srv, s)
 	return srv, nil
 }
 
 type server struct {
 	mu sync.Mutex
 	db db.DB
+	// provided is true if the database was given by NewServerForDB, rather than by Init.
+	provided bool
 }
 
+var errDBProvided = errors.New("server database is already provided, and can not be initialized")
+
 var _ protodb.DBServer = (*server)(nil)
 
 // Init initializes the server's database. Only one type of database
@@ -63,6 +78,9 @@ func (s *server) Init(ctx context.Context, in *protodb.Init) (*protodb.Entity, e
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.provided {
+		return nil, errDBProvided
+	}
 	var err error
 	s.db, err = db.NewDB(in.Name, db.BackendType(in.Type), in.Dir)
 	if err != nil {
@@ -1,12 +1,20 @@
 package remotedb
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
 	db "github.com/tendermint/tm-db"
 	protodb "github.com/tendermint/tm-db/remotedb/proto"
 )
 
-func makeIterator(dic protodb.DB_IteratorClient) db.Iterator {
-	itr := &iterator{dic: dic}
+// errSeekBackwards is returned by iterators asked to seek to a key they have already passed.
+var errSeekBackwards = errors.New("remoteDB iterator: can not seek backwards")
+
+func makeIterator(ctx context.Context, dic protodb.DB_IteratorClient) db.Iterator {
+	itr := &iterator{ctx: ctx, dic: dic}
 	itr.Next() // We need to call Next to prime the iterator
 	return itr
 }
@@ -20,6 +28,7 @@ func makeReverseIterator(dric protodb.DB_ReverseIteratorClient) db.Iterator {
 type reverseIterator struct {
 	dric protodb.DB_ReverseIteratorClient
 	cur  *protodb.Iterator
+	prev []byte // key of the previous item, if any
 	err  error
 }
 
@@ -40,13 +49,33 @@ func (rItr *reverseIterator) Domain() (start, end []byte) {
 
 // Next implements Iterator.
 func (rItr *reverseIterator) Next() {
+	if rItr.Valid() {
+		rItr.prev = rItr.cur.Key
+	}
 	var err error
 	rItr.cur, err = rItr.dric.Recv()
-	if err != nil {
+	// The end of the stream is a regular exhaustion of the iterator, not an error.
+	if err != nil && err != io.EOF {
 		rItr.err = err
 	}
 }
 
+// Seek implements Iterator. Items are streamed, so the iterator can only skip ahead: seeking to a
+// key at or after one which has already been passed fails with an error.
+func (rItr *reverseIterator) Seek(key []byte) bool {
+	if rItr.err != nil {
+		return false
+	}
+	if rItr.prev != nil && bytes.Compare(key, rItr.prev) >= 0 {
+		rItr.cur, rItr.err = nil, errSeekBackwards
+		return false
+	}
+	for rItr.Valid() && bytes.Compare(rItr.cur.Key, key) > 0 {
+		rItr.Next()
+	}
+	return rItr.Valid()
+}
+
 // Key implements Iterator.
 func (rItr *reverseIterator) Key() []byte {
 	rItr.assertIsValid()
@@ -59,6 +88,14 @@ func (rItr *reverseIterator) Value() []byte {
 	return rItr.cur.Value
 }
 
+// Position implements Iterator.
+func (rItr *reverseIterator) Position() []byte {
+	if !rItr.Valid() {
+		return nil
+	}
+	return append([]byte(nil), rItr.Key()...)
+}
+
 // Error implements Iterator.
 func (rItr *reverseIterator) Error() error {
 	return rItr.err
@@ -80,9 +117,11 @@ func (rItr *reverseIterator) assertIsValid() {
 // needed. It is NOT safe for concurrent usage,
 // matching the behavior of other iterators.
 type iterator struct {
-	dic protodb.DB_IteratorClient
-	cur *protodb.Iterator
-	err error
+	ctx  context.Context
+	dic  protodb.DB_IteratorClient
+	cur  *protodb.Iterator
+	prev []byte // key of the previous item, if any
+	err  error
 }
 
 var _ db.Iterator = (*iterator)(nil)
@@ -102,13 +141,38 @@ func (itr *iterator) Domain() (start, end []byte) {
 
 // Next implements Iterator.
 func (itr *iterator) Next() {
+	if itr.Valid() {
+		itr.prev = itr.cur.Key
+	}
+	// Already streamed items are buffered, so check the context explicitly.
+	if err := itr.ctx.Err(); err != nil {
+		itr.cur, itr.err = nil, err
+		return
+	}
 	var err error
 	itr.cur, err = itr.dic.Recv()
-	if err != nil {
+	// The end of the stream is a regular exhaustion of the iterator, not an error.
+	if err != nil && err != io.EOF {
 		itr.err = err
 	}
 }
 
+// Seek implements Iterator. Items are streamed, so the iterator can only skip ahead: seeking to a
+// key at or before one which has already been passed fails with an error.
+func (itr *iterator) Seek(key []byte) bool {
+	if itr.err != nil {
+		return false
+	}
+	if itr.prev != nil && bytes.Compare(key, itr.prev) <= 0 {
+		itr.cur, itr.err = nil, errSeekBackwards
+		return false
+	}
+	for itr.Valid() && bytes.Compare(itr.cur.Key, key) < 0 {
+		itr.Next()
+	}
+	return itr.Valid()
+}
+
 // Key implements Iterator.
 func (itr *iterator) Key() []byte {
 	itr.assertIsValid()
@@ -121,6 +185,14 @@ func (itr *iterator) Value() []byte {
 	return itr.cur.Value
 }
 
+// Position implements Iterator.
+func (itr *iterator) Position() []byte {
+	if !itr.Valid() {
+		return nil
+	}
+	return append([]byte(nil), itr.Key()...)
+}
+
 // Error implements Iterator.
 func (itr *iterator) Error() error {
 	return itr.err
@@ -72,6 +72,21 @@ func (rd *RemoteDB) SetSync(key, value []byte) error {
 	return nil
 }
 
+// CompareAndSet is not supported by the remote protocol yet, and always errors.
+func (rd *RemoteDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return false, errors.New("remoteDB.CompareAndSet: unimplemented")
+}
+
+// DeleteRange is not supported by the remote protocol yet, and always errors.
+func (rd *RemoteDB) DeleteRange(start, end []byte) error {
+	return errors.New("remoteDB.DeleteRange: unimplemented")
+}
+
+// Compact is not supported by the remote protocol yet, and always errors.
+func (rd *RemoteDB) Compact(start, end []byte) error {
+	return errors.New("remoteDB.Compact: unimplemented")
+}
+
 func (rd *RemoteDB) Get(key []byte) ([]byte, error) {
 	res, err := rd.dc.Get(rd.ctx, &protodb.Entity{Key: key})
 	if err != nil {
@@ -80,6 +95,44 @@ func (rd *RemoteDB) Get(key []byte) ([]byte, error) {
 	return res.Value, nil
 }
 
+// MultiGet fetches all keys with a single GetStream call.
+func (rd *RemoteDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(rd.ctx)
+	defer cancel()
+	stream, err := rd.dc.GetStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+	}
+
+	// Send while receiving, so that neither side can be blocked by flow control.
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, key := range keys {
+			if err := stream.Send(&protodb.Entity{Key: key}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	values := make([][]byte, len(keys))
+	for i := range keys {
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+		}
+		if res.Err != "" {
+			return nil, fmt.Errorf("remoteDB.MultiGet: %s", res.Err)
+		}
+		values[i] = res.Value
+	}
+	if err := <-sendErr; err != nil {
+		return nil, fmt.Errorf("remoteDB.MultiGet: %w", err)
+	}
+	return values, nil
+}
+
 func (rd *RemoteDB) Has(key []byte) (bool, error) {
 	res, err := rd.dc.Has(rd.ctx, &protodb.Entity{Key: key})
 	if err != nil {
@@ -88,6 +141,16 @@ func (rd *RemoteDB) Has(key []byte) (bool, error) {
 	return res.Exists, nil
 }
 
+// IteratorWithContext uses the given context for the underlying stream, so it is aborted as soon
+// as the context is done.
+func (rd *RemoteDB) IteratorWithContext(ctx context.Context, start, end []byte) (db.Iterator, error) {
+	dic, err := rd.dc.Iterator(ctx, &protodb.Entity{Start: start, End: end})
+	if err != nil {
+		return nil, fmt.Errorf("RemoteDB.IteratorWithContext error: %w", err)
+	}
+	return makeIterator(ctx, dic), nil
+}
+
 func (rd *RemoteDB) ReverseIterator(start, end []byte) (db.Iterator, error) {
 	dic, err := rd.dc.ReverseIterator(rd.ctx, &protodb.Entity{Start: start, End: end})
 	if err != nil {
@@ -96,10 +159,62 @@ func (rd *RemoteDB) ReverseIterator(start, end []byte) (db.Iterator, error) {
 	return makeReverseIterator(dic), nil
 }
 
+func (rd *RemoteDB) ForEach(fn func(key, value []byte) error) error {
+	itr, err := rd.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	for ; itr.Valid(); itr.Next() {
+		if err := fn(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+func (rd *RemoteDB) WriteBatch(ops []db.BatchOp) error {
+	return rd.writeBatch(ops, false)
+}
+
+func (rd *RemoteDB) WriteBatchSync(ops []db.BatchOp) error {
+	return rd.writeBatch(ops, true)
+}
+
+func (rd *RemoteDB) ApplyLog(ops db.BatchOpList) error {
+	return rd.writeBatch(ops, true)
+}
+
+func (rd *RemoteDB) writeBatch(ops []db.BatchOp, sync bool) error {
+	batch := rd.NewBatchWithSize(len(ops))
+	defer batch.Close()
+
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = batch.Delete(op.Key)
+		} else {
+			err = batch.Set(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
 func (rd *RemoteDB) NewBatch() db.Batch {
 	return newBatch(rd)
 }
 
+func (rd *RemoteDB) NewBatchWithSize(expectedOps int) db.Batch {
+	return newBatchWithSize(rd, expectedOps)
+}
+
 // TODO: Implement Print when db.DB implements a method
 // to print to a string and not db.Print to stdout.
 func (rd *RemoteDB) Print() error {
@@ -108,8 +223,8 @@ func (rd *RemoteDB) Print() error {
 
 func (rd *RemoteDB) Stats() map[string]string {
 	stats, err := rd.dc.Stats(rd.ctx, &protodb.Nothing{})
-	if err != nil || stats == nil {
-		return nil
+	if err != nil || stats == nil || stats.Data == nil {
+		return make(map[string]string)
 	}
 	return stats.Data
 }
@@ -119,5 +234,5 @@ func (rd *RemoteDB) Iterator(start, end []byte) (db.Iterator, error) {
 	if err != nil {
 		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", err)
 	}
-	return makeIterator(dic), nil
+	return makeIterator(rd.ctx, dic), nil
 }
@@ -1,6 +1,8 @@
 package remotedb_test
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"os"
 	"testing"
@@ -8,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	tmdb "github.com/tendermint/tm-db"
 	"github.com/tendermint/tm-db/remotedb"
 	"github.com/tendermint/tm-db/remotedb/grpcdb"
 )
@@ -44,6 +47,9 @@ func TestRemoteDB(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, gv1, vv1)
 
+	// Stats are fetched from the remote backend
+	require.NotEmpty(t, client.Stats())
+
 	// Simple iteration
 	itr, err := client.Iterator(nil, nil)
 	require.NoError(t, err)
@@ -129,6 +135,11 @@ func TestRemoteDB(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, rv4, v4, "expecting k4 to have been stored")
 
+	// MultiGet
+	mv, err := client.MultiGet([][]byte{k3, k1, k4})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{v3, nil, v4}, mv, "expecting nil for the missing k1")
+
 	// Batch tests - deletion
 	bat = client.NewBatch()
 	err = bat.Delete(k4)
@@ -138,13 +149,13 @@ func TestRemoteDB(t *testing.T) {
 	err = bat.WriteSync()
 	require.NoError(t, err)
 
-	rv3, err = client.Get(k3)
+	has, err = client.Has(k3)
 	require.NoError(t, err)
-	require.Equal(t, 0, len(rv3), "expecting k3 to have been deleted")
+	require.False(t, has, "expecting k3 to have been deleted")
 
-	rv4, err = client.Get(k4)
+	has, err = client.Has(k4)
 	require.NoError(t, err)
-	require.Equal(t, 0, len(rv4), "expecting k4 to have been deleted")
+	require.False(t, has, "expecting k4 to have been deleted")
 
 	// Batch tests - set and delete
 	bat = client.NewBatch()
@@ -157,11 +168,107 @@ func TestRemoteDB(t *testing.T) {
 	err = bat.WriteSync()
 	require.NoError(t, err)
 
-	rv4, err = client.Get(k4)
+	has, err = client.Has(k4)
 	require.NoError(t, err)
-	require.Equal(t, 0, len(rv4), "expecting k4 to have been deleted")
+	require.False(t, has, "expecting k4 to have been deleted")
 
 	rv5, err := client.Get(k5)
 	require.NoError(t, err)
 	require.Equal(t, rv5, v5, "expecting k5 to have been stored")
+
+	// WriteBatch tests
+	err = client.WriteBatch([]tmdb.BatchOp{
+		{Key: k4, Value: v4},
+		{Key: k5, Delete: true},
+	})
+	require.NoError(t, err)
+
+	has, err = client.Has(k5)
+	require.NoError(t, err)
+	require.False(t, has, "expecting k5 to have been deleted")
+
+	rv4, err = client.Get(k4)
+	require.NoError(t, err)
+	require.Equal(t, rv4, v4, "expecting k4 to have been stored")
+
+	// ForEach tests
+	keys := 0
+	err = client.ForEach(func(key, value []byte) error {
+		keys++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Positive(t, keys)
+
+	// Seek tests
+	err = client.Set([]byte("seek1"), []byte("v1"))
+	require.NoError(t, err)
+	err = client.Set([]byte("seek3"), []byte("v3"))
+	require.NoError(t, err)
+	itr, err = client.Iterator([]byte("seek"), nil)
+	require.NoError(t, err)
+	require.True(t, itr.Seek([]byte("seek2")), "expecting seeking forward to succeed")
+	require.Equal(t, []byte("seek3"), itr.Key())
+	require.False(t, itr.Seek([]byte("seek1")), "expecting seeking backwards to fail")
+	require.Error(t, itr.Error())
+	require.NoError(t, itr.Close())
+
+	// IteratorWithContext tests
+	ctx, cancel := context.WithCancel(context.Background())
+	itr, err = client.IteratorWithContext(ctx, nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Valid())
+	cancel()
+	for itr.Valid() {
+		itr.Next()
+	}
+	require.Error(t, itr.Error())
+	require.NoError(t, itr.Close())
+}
+
+func TestRemoteDBForLocalDB(t *testing.T) {
+	cert := "test.crt"
+	key := "test.key"
+	local := tmdb.NewMemDB()
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv, err := grpcdb.NewServerForDB(local, cert, key)
+	require.NoError(t, err)
+	defer srv.Stop()
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			panic(err)
+		}
+	}()
+
+	client, err := remotedb.NewRemoteDB(ln.Addr().String(), cert)
+	require.NoError(t, err)
+	require.Error(t, client.InitRemote(&remotedb.Init{Name: "test-remote-db", Type: "memdb"}))
+
+	const numKeys = 100
+	for i := 0; i < numKeys; i++ {
+		require.NoError(t, client.Set([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%d", i))))
+	}
+	for i := 0; i < numKeys; i++ {
+		key := []byte(fmt.Sprintf("key-%03d", i))
+		value, err := client.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte(fmt.Sprintf("value-%d", i)), value)
+
+		// The writes went to the local database.
+		value, err = local.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, []byte(fmt.Sprintf("value-%d", i)), value)
+	}
+
+	itr, err := client.Iterator(nil, nil)
+	require.NoError(t, err)
+	count := 0
+	for ; itr.Valid(); itr.Next() {
+		require.Equal(t, []byte(fmt.Sprintf("key-%03d", count)), itr.Key())
+		count++
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	require.Equal(t, numKeys, count)
 }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ReplayLog applies the operations read from r to db in order, returning the number of operations
+// applied. The stream may be a backup written by BackupDB, whose records are applied as Sets, or
+// the WAL file of a WALDB, where each operation of a journaled batch counts separately and a
+// DeleteRange counts as one. Unlike the recovery done by NewWALDB, which ignores a partially
+// written last record, a truncated stream returns io.ErrUnexpectedEOF, along with the number of
+// operations applied before the truncation.
+func ReplayLog(r io.Reader, db DB) (n int, err error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(walMagic))
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	if string(magic) == walMagic {
+		return replayWAL(br, db)
+	}
+	return replayBackup(br, db)
+}
+
+// replayBackup applies the records of a backup written by BackupDB.
+func replayBackup(r *bufio.Reader, db DB) (int, error) {
+	if _, err := r.Peek(len(backupMagic) + 1); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	br, err := newBackupReader(r)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		key, value, err := br.next()
+		if err == io.EOF {
+			return n, nil
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			return n, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return n, err
+		}
+		if err := db.Set(key, value); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// replayWAL applies the records of a WALDB write-ahead log.
+func replayWAL(r *bufio.Reader, db DB) (int, error) {
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	if version := header[len(walMagic)]; version != walVersion {
+		return 0, fmt.Errorf("unsupported WAL version %d", version)
+	}
+
+	n := 0
+	var recordHeader [walRecordHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, recordHeader[:]); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		length := binary.BigEndian.Uint32(recordHeader[:])
+		checksum := binary.BigEndian.Uint32(recordHeader[4:])
+		// As in backupReader, the buffer only grows as the payload is actually read.
+		payload, err := io.ReadAll(io.LimitReader(r, int64(length)))
+		if err != nil {
+			return n, err
+		}
+		if len(payload) < int(length) {
+			return n, io.ErrUnexpectedEOF
+		}
+		if crc32.Checksum(payload, walCRCTable) != checksum {
+			return n, errors.New("WAL record checksum mismatch")
+		}
+		applied, err := applyWALRecord(db, payload)
+		if err != nil {
+			return n, err
+		}
+		n += applied
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayLogBackup(t *testing.T) {
+	src := NewMemDB()
+	require.NoError(t, src.Set([]byte("a"), []byte{1}))
+	require.NoError(t, src.Set([]byte("b"), []byte{}))
+	require.NoError(t, src.Set([]byte("c"), []byte{3}))
+	var buf bytes.Buffer
+	require.NoError(t, BackupDB(src, &buf))
+	data := buf.Bytes()
+
+	// Records are applied on top of the existing items.
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("a"), []byte{9}))
+	require.NoError(t, db.Set([]byte("d"), []byte{4}))
+	n, err := ReplayLog(bytes.NewReader(data), db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {}, "c": {3}, "d": {4}})
+
+	// A truncated backup applies the complete records before the truncation. The last record
+	// ends 4 bytes before the end of the backup.
+	for _, tc := range []struct {
+		size   int
+		expect int
+	}{
+		{0, 0},
+		{5, 0},
+		{8, 0},
+		{len(data) - 5, 2},
+		{len(data) - 4, 3},
+		{len(data) - 1, 3},
+	} {
+		db := NewMemDB()
+		n, err := ReplayLog(bytes.NewReader(data[:tc.size]), db)
+		assert.Equal(t, io.ErrUnexpectedEOF, err, "size %d", tc.size)
+		assert.Equal(t, tc.expect, n, "size %d", tc.size)
+		count, err := db.Count(nil, nil)
+		require.NoError(t, err)
+		assert.EqualValues(t, tc.expect, count, "size %d", tc.size)
+	}
+
+	_, err = ReplayLog(bytes.NewReader([]byte("not a log file")), NewMemDB())
+	require.ErrorIs(t, err, errBackupInvalid)
+}
+
+func TestReplayLogWAL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, walFileName)
+	wdb, err := NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	writeWALEntries(t, wdb)
+	require.NoError(t, wdb.file.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Every operation of a batch is counted, and a DeleteRange counts as one.
+	db := NewMemDB()
+	n, err := ReplayLog(bytes.NewReader(data), db)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assertKeyValues(t, db, map[string][]byte{"c": {}, "d": {7}, "f": {6}})
+
+	// A truncated log applies the records before the truncation, unlike WALDB recovery, which
+	// silently discards the partial record.
+	db = NewMemDB()
+	n, err = ReplayLog(bytes.NewReader(data[:len(data)-3]), db)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 9, n)
+	assertKeyValues(t, db, map[string][]byte{"c": {}, "d": {7}, "e": {5}, "f": {6}})
+
+	n, err = ReplayLog(bytes.NewReader(data[:walHeaderSize+3]), NewMemDB())
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Zero(t, n)
+
+	// A corrupted record stops the replay with an error.
+	corrupt := cp(data)
+	corrupt[len(corrupt)-1] ^= 0x01
+	n, err = ReplayLog(bytes.NewReader(corrupt), NewMemDB())
+	require.Error(t, err)
+	assert.NotEqual(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 9, n)
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RetryOptions configures a RetryDB.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts per call, including the first one. Values
+	// below 1 are treated as 1, i.e. no retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It is doubled for every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries. Zero means no cap.
+	MaxDelay time.Duration
+	// IsRetryable returns whether an error is transient, and the call should be retried. If nil,
+	// no errors are retried.
+	IsRetryable func(error) bool
+}
+
+// RetryDB wraps a database, and retries calls that fail with transient errors with exponential
+// backoff, as configured by RetryOptions. Calls are retried as a whole, so callers should be
+// aware that e.g. a failed CompareAndSet may have been applied before the error occurred.
+//
+// Only the creation of iterators and batches is covered: errors returned by iterators, batches,
+// and ForEach callbacks are not retried, since the operation can not safely be resumed.
+type RetryDB struct {
+	db   DB
+	opts RetryOptions
+}
+
+var _ DB = (*RetryDB)(nil)
+
+// NewRetryDB creates a RetryDB wrapping the given database.
+func NewRetryDB(db DB, opts RetryOptions) *RetryDB {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	return &RetryDB{
+		db:   db,
+		opts: opts,
+	}
+}
+
+// retry calls fn until it succeeds, fails with a non-retryable error, or the maximum number of
+// attempts is reached, and returns the last error. It gives up early if ctx is done while
+// waiting for the next attempt.
+func (rdb *RetryDB) retry(ctx context.Context, fn func() error) error {
+	delay := rdb.opts.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= rdb.opts.MaxAttempts ||
+			rdb.opts.IsRetryable == nil || !rdb.opts.IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if rdb.opts.MaxDelay > 0 && delay > rdb.opts.MaxDelay {
+			delay = rdb.opts.MaxDelay
+		}
+	}
+}
+
+// Get implements DB.
+func (rdb *RetryDB) Get(key []byte) (value []byte, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		value, err = rdb.db.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// Has implements DB.
+func (rdb *RetryDB) Has(key []byte) (ok bool, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		ok, err = rdb.db.Has(key)
+		return err
+	})
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (rdb *RetryDB) MultiGet(keys [][]byte) (values [][]byte, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		values, err = rdb.db.MultiGet(keys)
+		return err
+	})
+	return values, err
+}
+
+// Set implements DB.
+func (rdb *RetryDB) Set(key []byte, value []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.Set(key, value)
+	})
+}
+
+// SetSync implements DB.
+func (rdb *RetryDB) SetSync(key []byte, value []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.SetSync(key, value)
+	})
+}
+
+// Delete implements DB.
+func (rdb *RetryDB) Delete(key []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.Delete(key)
+	})
+}
+
+// DeleteSync implements DB.
+func (rdb *RetryDB) DeleteSync(key []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.DeleteSync(key)
+	})
+}
+
+// CompareAndSet implements DB.
+func (rdb *RetryDB) CompareAndSet(key, expected, newVal []byte) (swapped bool, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		swapped, err = rdb.db.CompareAndSet(key, expected, newVal)
+		return err
+	})
+	return swapped, err
+}
+
+// DeleteRange implements DB.
+func (rdb *RetryDB) DeleteRange(start, end []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.DeleteRange(start, end)
+	})
+}
+
+// Iterator implements DB.
+func (rdb *RetryDB) Iterator(start, end []byte) (itr Iterator, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		itr, err = rdb.db.Iterator(start, end)
+		return err
+	})
+	return itr, err
+}
+
+// ReverseIterator implements DB.
+func (rdb *RetryDB) ReverseIterator(start, end []byte) (itr Iterator, err error) {
+	err = rdb.retry(context.Background(), func() error {
+		itr, err = rdb.db.ReverseIterator(start, end)
+		return err
+	})
+	return itr, err
+}
+
+// IteratorWithContext implements DB. Retries are aborted once the context is done.
+func (rdb *RetryDB) IteratorWithContext(ctx context.Context, start, end []byte) (itr Iterator, err error) {
+	err = rdb.retry(ctx, func() error {
+		itr, err = rdb.db.IteratorWithContext(ctx, start, end)
+		return err
+	})
+	return itr, err
+}
+
+// Compact implements DB.
+func (rdb *RetryDB) Compact(start, end []byte) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.Compact(start, end)
+	})
+}
+
+// WriteBatch implements DB.
+func (rdb *RetryDB) WriteBatch(ops []BatchOp) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.WriteBatch(ops)
+	})
+}
+
+// WriteBatchSync implements DB.
+func (rdb *RetryDB) WriteBatchSync(ops []BatchOp) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.WriteBatchSync(ops)
+	})
+}
+
+// ApplyLog implements DB.
+func (rdb *RetryDB) ApplyLog(ops BatchOpList) error {
+	return rdb.retry(context.Background(), func() error {
+		return rdb.db.ApplyLog(ops)
+	})
+}
+
+// ForEach implements DB.
+func (rdb *RetryDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(rdb, fn)
+}
+
+// Close implements DB.
+func (rdb *RetryDB) Close() error {
+	return rdb.retry(context.Background(), rdb.db.Close)
+}
+
+// NewBatch implements DB.
+func (rdb *RetryDB) NewBatch() Batch {
+	return rdb.db.NewBatch()
+}
+
+// NewBatchWithSize implements DB.
+func (rdb *RetryDB) NewBatchWithSize(expectedOps int) Batch {
+	return rdb.db.NewBatchWithSize(expectedOps)
+}
+
+// Print implements DB.
+func (rdb *RetryDB) Print() error {
+	return rdb.retry(context.Background(), rdb.db.Print)
+}
+
+// Stats implements DB.
+func (rdb *RetryDB) Stats() map[string]string {
+	return rdb.db.Stats()
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errTransient = errors.New("transient error")
+
+// flakyDB wraps a MemDB, and fails the first failures calls to Get and Set with errTransient.
+type flakyDB struct {
+	*MemDB
+	failures int
+	calls    int
+}
+
+func (db *flakyDB) fail() error {
+	db.calls++
+	if db.calls <= db.failures {
+		return errTransient
+	}
+	return nil
+}
+
+func (db *flakyDB) Get(key []byte) ([]byte, error) {
+	if err := db.fail(); err != nil {
+		return nil, err
+	}
+	return db.MemDB.Get(key)
+}
+
+func (db *flakyDB) Set(key []byte, value []byte) error {
+	if err := db.fail(); err != nil {
+		return err
+	}
+	return db.MemDB.Set(key, value)
+}
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestRetryDB(t *testing.T) {
+	mdb := NewMemDB()
+	require.NoError(t, mdb.Set([]byte("key"), []byte("value")))
+	fdb := &flakyDB{MemDB: mdb, failures: 2}
+	rdb := NewRetryDB(fdb, RetryOptions{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    2 * time.Millisecond,
+		IsRetryable: isTransient,
+	})
+
+	value, err := rdb.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	require.Equal(t, 3, fdb.calls)
+}
+
+func TestRetryDBMaxAttempts(t *testing.T) {
+	fdb := &flakyDB{MemDB: NewMemDB(), failures: 3}
+	rdb := NewRetryDB(fdb, RetryOptions{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		IsRetryable: isTransient,
+	})
+
+	require.Equal(t, errTransient, rdb.Set([]byte("key"), []byte("value")))
+	require.Equal(t, 3, fdb.calls)
+
+	// Once the failures are used up, calls succeed again.
+	require.NoError(t, rdb.Set([]byte("key"), []byte("value")))
+	require.Equal(t, 4, fdb.calls)
+}
+
+func TestRetryDBNotRetryable(t *testing.T) {
+	fdb := &flakyDB{MemDB: NewMemDB(), failures: 2}
+	rdb := NewRetryDB(fdb, RetryOptions{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		IsRetryable: func(error) bool { return false },
+	})
+
+	_, err := rdb.Get([]byte("key"))
+	require.Equal(t, errTransient, err)
+	require.Equal(t, 1, fdb.calls)
+
+	// Errors from the wrapped database are not retryable by default either.
+	rdb = NewRetryDB(fdb, RetryOptions{MaxAttempts: 3})
+	_, err = rdb.Get([]byte("key"))
+	require.Equal(t, errTransient, err)
+	require.Equal(t, 2, fdb.calls)
+}
@@ -4,16 +4,19 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync"
 
 	"github.com/cosmos/gorocksdb"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewRocksDB(name, dir)
+	dbCreator := func(name string, dir string, opts Options) (DB, error) {
+		return newRocksDBWithOptions(name, dir, opts)
 	}
 	registerDBCreator(RocksDBBackend, dbCreator, false)
 }
@@ -24,32 +27,149 @@ type RocksDB struct {
 	ro     *gorocksdb.ReadOptions
 	wo     *gorocksdb.WriteOptions
 	woSync *gorocksdb.WriteOptions
+
+	readOnly bool
+	logger   Logger
+
+	// cfs are the column families the database was opened with, by name, if any.
+	cfs map[string]*gorocksdb.ColumnFamilyHandle
+
+	// casMtx makes CompareAndSet atomic, since a plain (non-transactional) RocksDB database has
+	// no native support for it: writes to the default column family hold a read lock, so they
+	// still run in parallel, and CompareAndSet holds the write lock.
+	casMtx sync.RWMutex
 }
 
-var _ DB = (*RocksDB)(nil)
+var (
+	_ DB             = (*RocksDB)(nil)
+	_ Checkpointable = (*RocksDB)(nil)
+	_ MultiCFDB      = (*RocksDB)(nil)
+	_ KeyEstimator   = (*RocksDB)(nil)
+)
+
+// defaultColumnFamily is the name of the column family used by the DB methods.
+const defaultColumnFamily = "default"
+
+// ColumnFamilyDescriptor describes a RocksDB column family.
+type ColumnFamilyDescriptor struct {
+	// Name is the name of the column family. It must not be "default".
+	Name string
+	// Options are the options of the column family. Nil means the database options.
+	Options *gorocksdb.Options
+}
+
+// RocksDBOptions configures a RocksDB opened with NewRocksDBWithColumnFamilies.
+type RocksDBOptions struct {
+	// Options are the database options. Nil means the defaults used by NewRocksDB.
+	Options *gorocksdb.Options
+	// ReadOnly opens an existing database in read-only mode.
+	ReadOnly bool
+	// ColumnFamilies are the column families besides the default one, which are created if
+	// missing. Every existing column family must be given.
+	ColumnFamilies []ColumnFamilyDescriptor
+}
 
 func NewRocksDB(name string, dir string) (*RocksDB, error) {
+	return newRocksDBWithOptions(name, dir, Options{})
+}
+
+// newRocksDBWithOptions creates a RocksDB, mapping Options to RocksDB options on top of the
+// defaults.
+func newRocksDBWithOptions(name string, dir string, o Options) (*RocksDB, error) {
+	// Check for the CURRENT file of the database, to return the sentinel errors rather than
+	// RocksDB's own.
+	dbPath := filepath.Join(dir, name+".db")
+	if err := o.checkExistence(dbPath, fileExists(filepath.Join(dbPath, "CURRENT"))); err != nil {
+		return nil, err
+	}
+	db, err := openRocksDB(name, dir, defaultRocksDBOptions(o), o.ReadOnly, nil)
+	if err != nil {
+		return nil, err
+	}
+	db.logger = loggerOrNop(o.Logger)
+	return db, nil
+}
+
+// defaultRocksDBOptions returns the default RocksDB options, with Options applied.
+func defaultRocksDBOptions(o Options) *gorocksdb.Options {
 	// default rocksdb option, good enough for most cases, including heavy workloads.
 	// 1GB table cache, 512MB write buffer(may use 50% more on heavy workloads).
 	// compression: snappy as default, need to -lsnappy to enable.
+	cacheSize := o.BlockCacheSize
+	if cacheSize <= 0 {
+		cacheSize = 1 << 30
+	}
 	bbto := gorocksdb.NewDefaultBlockBasedTableOptions()
-	bbto.SetBlockCache(gorocksdb.NewLRUCache(1 << 30))
+	bbto.SetBlockCache(gorocksdb.NewLRUCache(uint64(cacheSize)))
 	bbto.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
 
 	opts := gorocksdb.NewDefaultOptions()
 	opts.SetBlockBasedTableFactory(bbto)
 	// SetMaxOpenFiles to 4096 seems to provide a reliable performance boost
-	opts.SetMaxOpenFiles(4096)
-	opts.SetCreateIfMissing(true)
+	maxOpenFiles := o.MaxOpenFiles
+	if maxOpenFiles <= 0 {
+		maxOpenFiles = 4096
+	}
+	opts.SetMaxOpenFiles(maxOpenFiles)
+	opts.SetCreateIfMissing(!o.ErrorIfMissing)
+	opts.SetErrorIfExists(o.ErrorIfExists)
 	opts.IncreaseParallelism(runtime.NumCPU())
 	// 1.5GB maximum memory use for writebuffer.
 	opts.OptimizeLevelStyleCompaction(512 * 1024 * 1024)
-	return NewRocksDBWithOptions(name, dir, opts)
+	return opts
 }
 
 func NewRocksDBWithOptions(name string, dir string, opts *gorocksdb.Options) (*RocksDB, error) {
+	return openRocksDB(name, dir, opts, false, nil)
+}
+
+// NewRocksDBWithColumnFamilies creates a RocksDB with the given column families, which are
+// accessed through the MultiCFDB methods.
+func NewRocksDBWithColumnFamilies(name string, dir string, o RocksDBOptions) (*RocksDB, error) {
+	opts := o.Options
+	if opts == nil {
+		opts = defaultRocksDBOptions(Options{})
+	}
+	opts.SetCreateIfMissingColumnFamilies(true)
+	return openRocksDB(name, dir, opts, o.ReadOnly, o.ColumnFamilies)
+}
+
+// openRocksDB opens a RocksDB database, optionally in read-only mode. If column families are
+// given, the database is opened with them and the default column family.
+func openRocksDB(
+	name string, dir string, opts *gorocksdb.Options, readOnly bool, cfs []ColumnFamilyDescriptor,
+) (*RocksDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
-	db, err := gorocksdb.OpenDb(opts, dbPath)
+	var (
+		db      *gorocksdb.DB
+		handles []*gorocksdb.ColumnFamilyHandle
+		err     error
+	)
+	switch {
+	case len(cfs) > 0:
+		names := []string{defaultColumnFamily}
+		cfOpts := []*gorocksdb.Options{opts}
+		for _, cf := range cfs {
+			if cf.Name == defaultColumnFamily {
+				return nil, fmt.Errorf("column family %q is always opened", defaultColumnFamily)
+			}
+			names = append(names, cf.Name)
+			if cf.Options != nil {
+				cfOpts = append(cfOpts, cf.Options)
+			} else {
+				cfOpts = append(cfOpts, opts)
+			}
+		}
+		if readOnly {
+			db, handles, err = gorocksdb.OpenDbForReadOnlyColumnFamilies(opts, dbPath, names, cfOpts, false)
+		} else {
+			db, handles, err = gorocksdb.OpenDbColumnFamilies(opts, dbPath, names, cfOpts)
+		}
+	case readOnly:
+		db, err = gorocksdb.OpenDbForReadOnly(opts, dbPath, false)
+	default:
+		db, err = gorocksdb.OpenDb(opts, dbPath)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +178,32 @@ func NewRocksDBWithOptions(name string, dir string, opts *gorocksdb.Options) (*R
 	woSync := gorocksdb.NewDefaultWriteOptions()
 	woSync.SetSync(true)
 	database := &RocksDB{
-		db:     db,
-		ro:     ro,
-		wo:     wo,
-		woSync: woSync,
+		db:       db,
+		ro:       ro,
+		wo:       wo,
+		woSync:   woSync,
+		readOnly: readOnly,
+		logger:   nopLogger{},
+	}
+	if len(handles) > 0 {
+		database.cfs = make(map[string]*gorocksdb.ColumnFamilyHandle, len(handles))
+		database.cfs[defaultColumnFamily] = handles[0]
+		for i, cf := range cfs {
+			database.cfs[cf.Name] = handles[i+1]
+		}
 	}
 	return database, nil
 }
 
+// columnFamily returns the handle of the given column family.
+func (db *RocksDB) columnFamily(name string) (*gorocksdb.ColumnFamilyHandle, error) {
+	cf, ok := db.cfs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown column family %q", name)
+	}
+	return cf, nil
+}
+
 // Get implements DB.
 func (db *RocksDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
@@ -80,37 +218,91 @@ func (db *RocksDB) Get(key []byte) ([]byte, error) {
 
 // Has implements DB.
 func (db *RocksDB) Has(key []byte) (bool, error) {
-	bytes, err := db.Get(key)
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	// Inspect the C-allocated slice directly, instead of copying the value into Go memory.
+	res, err := db.db.Get(db.ro, key)
 	if err != nil {
 		return false, err
 	}
-	return bytes != nil, nil
+	defer res.Free()
+	return res.Exists(), nil
 }
 
-// Set implements DB.
-func (db *RocksDB) Set(key []byte, value []byte) error {
-	if len(key) == 0 {
-		return errKeyEmpty
+// MultiGet implements DB, using RocksDB's native multi-get.
+func (db *RocksDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
 	}
-	if value == nil {
-		return errValueNil
+	if len(keys) == 0 {
+		return [][]byte{}, nil
 	}
-	return db.db.Put(db.wo, key, value)
+	slices, err := db.db.MultiGet(db.ro, keys...)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(slices))
+	for i, slice := range slices {
+		values[i] = moveSliceToBytes(slice)
+	}
+	return values, nil
+}
+
+// Set implements DB.
+func (db *RocksDB) Set(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, db.wo)
 }
 
 // SetSync implements DB.
 func (db *RocksDB) SetSync(key []byte, value []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.set(key, value, db.woSync)
+}
+
+// syncWriteDurable implements syncWriteDurable. RocksDB journals every write to a single log, so
+// a sync write persists all earlier ones.
+func (db *RocksDB) syncWriteDurable() {}
+
+// set sets a value without locking casMtx.
+func (db *RocksDB) set(key []byte, value []byte, wo *gorocksdb.WriteOptions) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
-	return db.db.Put(db.woSync, key, value)
+	return db.db.Put(wo, key, value)
+}
+
+// CompareAndSet implements DB.
+func (db *RocksDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if db.readOnly {
+		return false, ErrReadOnly
+	}
+	db.casMtx.Lock()
+	defer db.casMtx.Unlock()
+
+	return compareAndSet(db, key, expected, newVal, func(key, value []byte) error {
+		return db.set(key, value, db.wo)
+	})
 }
 
 // Delete implements DB.
 func (db *RocksDB) Delete(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -119,6 +311,11 @@ func (db *RocksDB) Delete(key []byte) error {
 
 // DeleteSync implements DB.
 func (db *RocksDB) DeleteSync(key []byte) error {
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	if db.readOnly {
+		return ErrReadOnly
+	}
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
@@ -129,8 +326,74 @@ func (db *RocksDB) DB() *gorocksdb.DB {
 	return db.db
 }
 
+// GetCF implements MultiCFDB.
+func (db *RocksDB) GetCF(cf string, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	handle, err := db.columnFamily(cf)
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.db.GetCF(db.ro, handle, key)
+	if err != nil {
+		return nil, err
+	}
+	return moveSliceToBytes(res), nil
+}
+
+// SetCF implements MultiCFDB.
+func (db *RocksDB) SetCF(cf string, key, value []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	handle, err := db.columnFamily(cf)
+	if err != nil {
+		return err
+	}
+	return db.db.PutCF(db.wo, handle, key, value)
+}
+
+// DeleteCF implements MultiCFDB.
+func (db *RocksDB) DeleteCF(cf string, key []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	handle, err := db.columnFamily(cf)
+	if err != nil {
+		return err
+	}
+	return db.db.DeleteCF(db.wo, handle, key)
+}
+
+// IteratorCF implements MultiCFDB.
+func (db *RocksDB) IteratorCF(cf string, start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	handle, err := db.columnFamily(cf)
+	if err != nil {
+		return nil, err
+	}
+	itr := db.db.NewIteratorCF(db.ro, handle)
+	return newRocksDBIterator(itr, start, end, false), nil
+}
+
 // Close implements DB.
 func (db *RocksDB) Close() error {
+	GlobalRegistry.unregister(db)
+	for _, handle := range db.cfs {
+		handle.Destroy()
+	}
 	db.ro.Destroy()
 	db.wo.Destroy()
 	db.woSync.Destroy()
@@ -163,11 +426,109 @@ func (db *RocksDB) Stats() map[string]string {
 	return stats
 }
 
+// ApproxSize implements Sizable. It is the "rocksdb.total-sst-files-size" property, the size of
+// the table files, which does not include writes still held in the memtable.
+func (db *RocksDB) ApproxSize() (int64, error) {
+	return strconv.ParseInt(db.db.GetProperty("rocksdb.total-sst-files-size"), 10, 64)
+}
+
+// EstimateKeys implements KeyEstimator. It is the "rocksdb.estimate-num-keys" property, which
+// RocksDB maintains from its table and memtable statistics.
+func (db *RocksDB) EstimateKeys() (int64, error) {
+	return strconv.ParseInt(db.db.GetProperty("rocksdb.estimate-num-keys"), 10, 64)
+}
+
+// DeleteRange implements DB. Bounded domains are deleted atomically using a native range
+// deletion, while a nil end falls back to deleting individual keys.
+func (db *RocksDB) DeleteRange(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	if end == nil {
+		return deleteRange(db, start, end)
+	}
+	if (start != nil && len(start) == 0) || len(end) == 0 {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	if start == nil {
+		start = []byte{}
+	}
+	batch := gorocksdb.NewWriteBatch()
+	defer batch.Destroy()
+	batch.DeleteRange(start, end)
+	db.casMtx.RLock()
+	defer db.casMtx.RUnlock()
+	return db.db.Write(db.wo, batch)
+}
+
+// IteratorWithContext implements DB.
+func (db *RocksDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB.
+func (db *RocksDB) Compact(start, end []byte) error {
+	if db.readOnly {
+		return ErrReadOnly
+	}
+	return logCompaction(db.logger, start, end, func() error {
+		db.db.CompactRange(gorocksdb.Range{Start: start, Limit: end})
+		return nil
+	})
+}
+
+// WriteBatch implements DB.
+func (db *RocksDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *RocksDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *RocksDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// Checkpoint implements Checkpointable, using RocksDB's native checkpoints which hard-link the
+// table files.
+func (db *RocksDB) Checkpoint(destDir string) error {
+	checkpoint, err := db.db.NewCheckpoint()
+	if err != nil {
+		return err
+	}
+	defer checkpoint.Destroy()
+	// A log size of 0 always flushes the memtables, so the checkpoint is up to date.
+	return checkpoint.CreateCheckpoint(destDir, 0)
+}
+
+// ForEach implements DB.
+func (db *RocksDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
 // NewBatch implements DB.
 func (db *RocksDB) NewBatch() Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
 	return newRocksDBBatch(db)
 }
 
+// NewBatchWithSize implements DB. gorocksdb has no way to preallocate a batch, so the size hint
+// is ignored.
+func (db *RocksDB) NewBatchWithSize(_ int) Batch {
+	if db.readOnly {
+		return readOnlyBatch{}
+	}
+	return db.NewBatch()
+}
+
 // Iterator implements DB.
 func (db *RocksDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
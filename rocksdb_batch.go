@@ -46,12 +46,22 @@ func (b *rocksDBBatch) Delete(key []byte) error {
 	return nil
 }
 
+// Len implements Batch.
+func (b *rocksDBBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Count()
+}
+
 // Write implements Batch.
 func (b *rocksDBBatch) Write() error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.db.casMtx.RLock()
 	err := b.db.db.Write(b.db.wo, b.batch)
+	b.db.casMtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -65,7 +75,9 @@ func (b *rocksDBBatch) WriteSync() error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
+	b.db.casMtx.RLock()
 	err := b.db.db.Write(b.db.woSync, b.batch)
+	b.db.casMtx.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -93,6 +93,32 @@ func (itr *rocksDBIterator) Valid() bool {
 	return true
 }
 
+// Seek implements Iterator.
+func (itr *rocksDBIterator) Seek(key []byte) bool {
+	if itr.source.Err() != nil {
+		return false
+	}
+	itr.isInvalid = false
+	switch {
+	case !itr.isReverse:
+		if itr.start != nil && bytes.Compare(key, itr.start) < 0 {
+			key = itr.start
+		}
+		itr.source.Seek(key)
+	case itr.end != nil && bytes.Compare(key, itr.end) >= 0:
+		// The end is exclusive, so move to the last key before it.
+		itr.source.Seek(itr.end)
+		if itr.source.Valid() {
+			itr.source.Prev()
+		} else {
+			itr.source.SeekToLast()
+		}
+	default:
+		itr.source.SeekForPrev(key)
+	}
+	return itr.Valid()
+}
+
 // Key implements Iterator.
 func (itr *rocksDBIterator) Key() []byte {
 	itr.assertIsValid()
@@ -105,6 +131,11 @@ func (itr *rocksDBIterator) Value() []byte {
 	return moveSliceToBytes(itr.source.Value())
 }
 
+// Position implements Iterator.
+func (itr *rocksDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
 // Next implements Iterator.
 func (itr rocksDBIterator) Next() {
 	itr.assertIsValid()
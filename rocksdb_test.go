@@ -44,4 +44,50 @@ func TestRocksDBStats(t *testing.T) {
 	assert.NotEmpty(t, db.Stats())
 }
 
-// TODO: Add tests for rocksdb
+func TestRocksDBColumnFamilies(t *testing.T) {
+	dir := t.TempDir()
+	opts := RocksDBOptions{ColumnFamilies: []ColumnFamilyDescriptor{{Name: "a"}, {Name: "b"}}}
+	db, err := NewRocksDBWithColumnFamilies("testdb", dir, opts)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Set([]byte("default"), []byte{0}))
+	for i := byte(1); i <= 3; i++ {
+		require.NoError(t, db.SetCF("a", []byte{'a', i}, []byte{i}))
+		require.NoError(t, db.SetCF("b", []byte{'b', i}, []byte{i}))
+	}
+	require.NoError(t, db.DeleteCF("b", []byte{'b', 2}))
+
+	// Each column family only sees its own keys.
+	collect := func(cf string) [][]byte {
+		itr, err := db.IteratorCF(cf, nil, nil)
+		require.NoError(t, err)
+		defer itr.Close()
+		var keys [][]byte
+		for ; itr.Valid(); itr.Next() {
+			keys = append(keys, itr.Key())
+		}
+		require.NoError(t, itr.Error())
+		return keys
+	}
+	assert.Equal(t, [][]byte{{'a', 1}, {'a', 2}, {'a', 3}}, collect("a"))
+	assert.Equal(t, [][]byte{{'b', 1}, {'b', 3}}, collect("b"))
+	assert.Equal(t, [][]byte{[]byte("default")}, collect("default"))
+	assertKeyValues(t, db, map[string][]byte{"default": {0}})
+
+	value, err := db.GetCF("a", []byte{'a', 2})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2}, value)
+	value, err = db.GetCF("b", []byte{'a', 2})
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	_, err = db.GetCF("c", []byte{'a', 1})
+	require.Error(t, err)
+	require.NoError(t, db.Close())
+
+	// The column families persist when reopened.
+	db, err = NewRocksDBWithColumnFamilies("testdb", dir, opts)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, [][]byte{{'b', 1}, {'b', 3}}, collect("b"))
+}
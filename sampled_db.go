@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SampledDB wraps a database, and traces a random fraction of operations like TracingDB does,
+// to keep the cost of tracing down in production. Every call is sampled independently; a
+// sampled iterator or batch has all of its operations traced.
+type SampledDB struct {
+	db     DB
+	traced *TracingDB
+	rate   float64
+
+	mtx  sync.Mutex // rand.Rand is not safe for concurrent use
+	rand *rand.Rand
+}
+
+var _ DB = (*SampledDB)(nil)
+
+// NewSampledDB creates a SampledDB wrapping the given database, tracing operations to logger at
+// the given sampling rate in [0, 1]. Samples are drawn from src, or from a time-seeded source if
+// nil; tests can pass a fixed source for deterministic sampling.
+func NewSampledDB(inner DB, logger Logger, rate float64, src rand.Source) (*SampledDB, error) {
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("sampling rate %v is not in [0, 1]", rate)
+	}
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return &SampledDB{
+		db:     inner,
+		traced: NewTracingDB(inner, logger),
+		rate:   rate,
+		rand:   rand.New(src), // nolint:gosec // G404: sampling does not need a secure source
+	}, nil
+}
+
+// pick returns the database to run an operation on: the tracing database if the operation is
+// sampled, and the wrapped one otherwise.
+func (sdb *SampledDB) pick() DB {
+	sdb.mtx.Lock()
+	sampled := sdb.rand.Float64() < sdb.rate
+	sdb.mtx.Unlock()
+	if sampled {
+		return sdb.traced
+	}
+	return sdb.db
+}
+
+// Get implements DB.
+func (sdb *SampledDB) Get(key []byte) ([]byte, error) {
+	return sdb.pick().Get(key)
+}
+
+// Has implements DB.
+func (sdb *SampledDB) Has(key []byte) (bool, error) {
+	return sdb.pick().Has(key)
+}
+
+// MultiGet implements DB.
+func (sdb *SampledDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return sdb.pick().MultiGet(keys)
+}
+
+// Set implements DB.
+func (sdb *SampledDB) Set(key []byte, value []byte) error {
+	return sdb.pick().Set(key, value)
+}
+
+// SetSync implements DB.
+func (sdb *SampledDB) SetSync(key []byte, value []byte) error {
+	return sdb.pick().SetSync(key, value)
+}
+
+// Delete implements DB.
+func (sdb *SampledDB) Delete(key []byte) error {
+	return sdb.pick().Delete(key)
+}
+
+// DeleteSync implements DB.
+func (sdb *SampledDB) DeleteSync(key []byte) error {
+	return sdb.pick().DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (sdb *SampledDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return sdb.pick().CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (sdb *SampledDB) DeleteRange(start, end []byte) error {
+	return sdb.pick().DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (sdb *SampledDB) Iterator(start, end []byte) (Iterator, error) {
+	return sdb.pick().Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (sdb *SampledDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return sdb.pick().ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (sdb *SampledDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return sdb.pick().IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (sdb *SampledDB) Compact(start, end []byte) error {
+	return sdb.pick().Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (sdb *SampledDB) WriteBatch(ops []BatchOp) error {
+	return sdb.pick().WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (sdb *SampledDB) WriteBatchSync(ops []BatchOp) error {
+	return sdb.pick().WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (sdb *SampledDB) ApplyLog(ops BatchOpList) error {
+	return sdb.pick().ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (sdb *SampledDB) ForEach(fn func(key, value []byte) error) error {
+	return sdb.pick().ForEach(fn)
+}
+
+// Close implements DB.
+func (sdb *SampledDB) Close() error {
+	return sdb.pick().Close()
+}
+
+// NewBatch implements DB.
+func (sdb *SampledDB) NewBatch() Batch {
+	return sdb.pick().NewBatch()
+}
+
+// NewBatchWithSize implements DB.
+func (sdb *SampledDB) NewBatchWithSize(expectedOps int) Batch {
+	return sdb.pick().NewBatchWithSize(expectedOps)
+}
+
+// Print implements DB.
+func (sdb *SampledDB) Print() error {
+	return sdb.pick().Print()
+}
+
+// Stats implements DB.
+func (sdb *SampledDB) Stats() map[string]string {
+	return sdb.pick().Stats()
+}
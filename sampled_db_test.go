@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countTraced returns the number of operations of the given kind logged by a TracingDB.
+func countTraced(logger *bufferLogger, op string) int {
+	return strings.Count(logger.String(), "op="+op+" ")
+}
+
+func TestSampledDB(t *testing.T) {
+	logger := &bufferLogger{}
+	sdb, err := NewSampledDB(NewMemDB(), logger, 0.5, rand.NewSource(1))
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key%03d", i))
+		require.NoError(t, sdb.Set(key, []byte{1}))
+		checkValue(t, sdb, key, []byte{1})
+		require.NoError(t, sdb.Delete(key))
+	}
+
+	// Each operation is sampled independently, at roughly the sampling rate.
+	for _, op := range []string{"Set", "Get", "Delete"} {
+		n := countTraced(logger, op)
+		assert.InDelta(t, 500, n, 100, "%s traced %d times", op, n)
+	}
+}
+
+func TestSampledDBRates(t *testing.T) {
+	for _, rate := range []float64{0, 1} {
+		logger := &bufferLogger{}
+		sdb, err := NewSampledDB(NewMemDB(), logger, rate, nil)
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, sdb.Set([]byte("key"), []byte{1}))
+		}
+		assert.Equal(t, int(rate*100), countTraced(logger, "Set"))
+	}
+
+	_, err := NewSampledDB(NewMemDB(), &bufferLogger{}, 1.5, nil)
+	require.Error(t, err)
+	_, err = NewSampledDB(NewMemDB(), &bufferLogger{}, -0.1, nil)
+	require.Error(t, err)
+}
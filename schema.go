@@ -0,0 +1,81 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SchemaVersionKey is the reserved key storing the schema version of a database, as a 4-byte
+// big-endian integer. Applications should skip it when iterating over their own keys.
+const SchemaVersionKey = "__schema_version__"
+
+// Migration migrates a database to a schema version from the previous one.
+type Migration struct {
+	// Version is the schema version of the database after the migration.
+	Version uint32
+	// Up migrates the database.
+	Up func(db DB) error
+}
+
+// GetSchemaVersion returns the schema version of the database, or 0 if it was never set.
+func GetSchemaVersion(db DB) (uint32, error) {
+	bz, err := db.Get([]byte(SchemaVersionKey))
+	if err != nil {
+		return 0, err
+	}
+	if bz == nil {
+		return 0, nil
+	}
+	if len(bz) != 4 {
+		return 0, fmt.Errorf("invalid schema version: expected 4 bytes, got %d", len(bz))
+	}
+	return binary.BigEndian.Uint32(bz), nil
+}
+
+// SetSchemaVersion sets the schema version of the database, syncing it to disk.
+func SetSchemaVersion(db DB, v uint32) error {
+	var bz [4]byte
+	binary.BigEndian.PutUint32(bz[:], v)
+	return db.SetSync([]byte(SchemaVersionKey), bz[:])
+}
+
+// RunMigrations runs the migrations with a version above the database's schema version, in
+// version order, setting the schema version after each one. If a migration fails, its error is
+// returned, and the schema version is left at the last successful migration, so that the rest
+// are run by the next call; a migration which fails partway must therefore be safe to run again.
+// Migrations must have distinct, non-zero versions.
+func RunMigrations(db DB, migrations []Migration) error {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	for i, m := range sorted {
+		if m.Version == 0 {
+			return errors.New("invalid migration version 0")
+		}
+		if i > 0 && m.Version == sorted[i-1].Version {
+			return fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+
+	current, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range sorted {
+		if m.Version <= current {
+			continue
+		}
+		if err := m.Up(db); err != nil {
+			return fmt.Errorf("migration to schema version %d failed: %w", m.Version, err)
+		}
+		if err := SetSchemaVersion(db, m.Version); err != nil {
+			return err
+		}
+		current = m.Version
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	db := NewMemDB()
+	v, err := GetSchemaVersion(db)
+	require.NoError(t, err)
+	assert.Zero(t, v)
+
+	require.NoError(t, SetSchemaVersion(db, 258))
+	v, err = GetSchemaVersion(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 258, v)
+	checkValue(t, db, []byte("__schema_version__"), []byte{0, 0, 1, 2})
+
+	require.NoError(t, db.Set([]byte(SchemaVersionKey), []byte{1}))
+	_, err = GetSchemaVersion(db)
+	require.Error(t, err)
+}
+
+func TestRunMigrations(t *testing.T) {
+	db := NewMemDB()
+	var ran []uint32
+	failAt := uint32(0)
+	migration := func(version uint32, up func(db DB) error) Migration {
+		return Migration{Version: version, Up: func(db DB) error {
+			if version == failAt {
+				return errors.New("boom")
+			}
+			ran = append(ran, version)
+			return up(db)
+		}}
+	}
+	// Migrations are given out of order, and run in version order.
+	migrations := []Migration{
+		migration(3, func(db DB) error {
+			// Renames key "b" to "c".
+			value, err := db.Get([]byte("b"))
+			if err != nil {
+				return err
+			}
+			if err := db.Set([]byte("c"), value); err != nil {
+				return err
+			}
+			return db.Delete([]byte("b"))
+		}),
+		migration(1, func(db DB) error {
+			return db.Set([]byte("a"), []byte{1})
+		}),
+		migration(2, func(db DB) error {
+			value, err := db.Get([]byte("a"))
+			if err != nil {
+				return err
+			}
+			return db.Set([]byte("b"), append(value, 2))
+		}),
+	}
+
+	// A failing migration stops at the previous version, and the next run resumes from there.
+	failAt = 3
+	err := RunMigrations(db, migrations)
+	require.Error(t, err)
+	assert.Equal(t, []uint32{1, 2}, ran)
+	v, err := GetSchemaVersion(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, v)
+
+	failAt = 0
+	require.NoError(t, RunMigrations(db, migrations))
+	assert.Equal(t, []uint32{1, 2, 3}, ran)
+	v, err = GetSchemaVersion(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, v)
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "c": {1, 2}, SchemaVersionKey: {0, 0, 0, 3}})
+
+	// Migrations are not run again once applied.
+	require.NoError(t, RunMigrations(db, migrations))
+	assert.Equal(t, []uint32{1, 2, 3}, ran)
+
+	// Invalid versions are rejected before running anything.
+	err = RunMigrations(NewMemDB(), append(migrations, Migration{Version: 2}))
+	require.Error(t, err)
+	err = RunMigrations(NewMemDB(), []Migration{{Version: 0}})
+	require.Error(t, err)
+	assert.Equal(t, []uint32{1, 2, 3}, ran)
+}
@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ShardedDB partitions keys across several databases by the FNV-1a hash of the key, so that
+// writes to different shards can proceed in parallel. Iterators merge all shards in key order.
+//
+// Operations spanning several shards, i.e. batches and DeleteRange, are atomic within each shard
+// but not across shards. The shards must always be given in the same order, or keys will be
+// looked up in the wrong shard.
+type ShardedDB struct {
+	shards []DB
+}
+
+var _ DB = (*ShardedDB)(nil)
+
+// NewShardedDB creates a ShardedDB over the given shards. It panics if no shards are given.
+func NewShardedDB(shards []DB) *ShardedDB {
+	if len(shards) == 0 {
+		panic("ShardedDB requires at least one shard")
+	}
+	return &ShardedDB{shards: shards}
+}
+
+// shardIndex returns the index of the shard holding the given key.
+func (sdb *ShardedDB) shardIndex(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key) // nolint:errcheck // never fails
+	return int(h.Sum32() % uint32(len(sdb.shards)))
+}
+
+// shard returns the shard holding the given key.
+func (sdb *ShardedDB) shard(key []byte) DB {
+	return sdb.shards[sdb.shardIndex(key)]
+}
+
+// eachShard calls fn for every shard in parallel, returning the first error.
+func (sdb *ShardedDB) eachShard(fn func(i int, shard DB) error) error {
+	var g errgroup.Group
+	for i, shard := range sdb.shards {
+		i, shard := i, shard
+		g.Go(func() error {
+			return fn(i, shard)
+		})
+	}
+	return g.Wait()
+}
+
+// Get implements DB.
+func (sdb *ShardedDB) Get(key []byte) ([]byte, error) {
+	return sdb.shard(key).Get(key)
+}
+
+// Has implements DB.
+func (sdb *ShardedDB) Has(key []byte) (bool, error) {
+	return sdb.shard(key).Has(key)
+}
+
+// MultiGet implements DB.
+func (sdb *ShardedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(sdb, keys)
+}
+
+// Set implements DB.
+func (sdb *ShardedDB) Set(key []byte, value []byte) error {
+	return sdb.shard(key).Set(key, value)
+}
+
+// SetSync implements DB.
+func (sdb *ShardedDB) SetSync(key []byte, value []byte) error {
+	return sdb.shard(key).SetSync(key, value)
+}
+
+// Delete implements DB.
+func (sdb *ShardedDB) Delete(key []byte) error {
+	return sdb.shard(key).Delete(key)
+}
+
+// DeleteSync implements DB.
+func (sdb *ShardedDB) DeleteSync(key []byte) error {
+	return sdb.shard(key).DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (sdb *ShardedDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return sdb.shard(key).CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (sdb *ShardedDB) DeleteRange(start, end []byte) error {
+	return sdb.eachShard(func(_ int, shard DB) error {
+		return shard.DeleteRange(start, end)
+	})
+}
+
+// Iterator implements DB.
+func (sdb *ShardedDB) Iterator(start, end []byte) (Iterator, error) {
+	return sdb.iterator(start, end, false)
+}
+
+// ReverseIterator implements DB.
+func (sdb *ShardedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return sdb.iterator(start, end, true)
+}
+
+func (sdb *ShardedDB) iterator(start, end []byte, reverse bool) (Iterator, error) {
+	sources := make([]Iterator, 0, len(sdb.shards))
+	for _, shard := range sdb.shards {
+		var (
+			itr Iterator
+			err error
+		)
+		if reverse {
+			itr, err = shard.ReverseIterator(start, end)
+		} else {
+			itr, err = shard.Iterator(start, end)
+		}
+		if err != nil {
+			for _, source := range sources {
+				source.Close()
+			}
+			return nil, err
+		}
+		sources = append(sources, itr)
+	}
+	return newMergedIterator(sources, reverse), nil
+}
+
+// IteratorWithContext implements DB.
+func (sdb *ShardedDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, sdb, start, end)
+}
+
+// Compact implements DB.
+func (sdb *ShardedDB) Compact(start, end []byte) error {
+	return sdb.eachShard(func(_ int, shard DB) error {
+		return shard.Compact(start, end)
+	})
+}
+
+// WriteBatch implements DB. The operations are split by shard, and written to the shards in
+// parallel.
+func (sdb *ShardedDB) WriteBatch(ops []BatchOp) error {
+	return sdb.writeBatch(ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (sdb *ShardedDB) WriteBatchSync(ops []BatchOp) error {
+	return sdb.writeBatch(ops, true)
+}
+
+func (sdb *ShardedDB) writeBatch(ops []BatchOp, sync bool) error {
+	for _, op := range ops {
+		if len(op.Key) == 0 {
+			return errKeyEmpty
+		}
+		if !op.Delete && op.Value == nil {
+			return errValueNil
+		}
+	}
+	shardOps := make([][]BatchOp, len(sdb.shards))
+	for _, op := range ops {
+		i := sdb.shardIndex(op.Key)
+		shardOps[i] = append(shardOps[i], op)
+	}
+	return sdb.eachShard(func(i int, shard DB) error {
+		if len(shardOps[i]) == 0 {
+			return nil
+		}
+		if sync {
+			return shard.WriteBatchSync(shardOps[i])
+		}
+		return shard.WriteBatch(shardOps[i])
+	})
+}
+
+// ApplyLog implements DB.
+func (sdb *ShardedDB) ApplyLog(ops BatchOpList) error {
+	return sdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (sdb *ShardedDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(sdb, fn)
+}
+
+// Close implements DB. Every shard is closed, and the first error is returned.
+func (sdb *ShardedDB) Close() error {
+	var err error
+	for _, shard := range sdb.shards {
+		if cerr := shard.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (sdb *ShardedDB) NewBatch() Batch {
+	return newShardedBatch(sdb, 0)
+}
+
+// NewBatchWithSize implements DB.
+func (sdb *ShardedDB) NewBatchWithSize(expectedOps int) Batch {
+	return newShardedBatch(sdb, expectedOps)
+}
+
+// Print implements DB.
+func (sdb *ShardedDB) Print() error {
+	itr, err := sdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of each shard are included, prefixed by the shard index.
+func (sdb *ShardedDB) Stats() map[string]string {
+	stats := map[string]string{
+		"database.type":   "shardedDB",
+		"database.shards": fmt.Sprintf("%d", len(sdb.shards)),
+	}
+	for i, shard := range sdb.shards {
+		for k, v := range shard.Stats() {
+			stats[fmt.Sprintf("shard%d.%s", i, k)] = v
+		}
+	}
+	return stats
+}
+
+// ShardedBatch is a batch created by ShardedDB. It buffers operations, and routes them to a
+// batch for each shard when written. Like ShardedDB.WriteBatch, the write is atomic within each
+// shard but not across shards.
+type ShardedBatch struct {
+	db  *ShardedDB
+	ops []BatchOp
+}
+
+var _ Batch = (*ShardedBatch)(nil)
+
+func newShardedBatch(db *ShardedDB, size int) *ShardedBatch {
+	if size < 0 {
+		size = 0
+	}
+	return &ShardedBatch{
+		db:  db,
+		ops: make([]BatchOp, 0, size),
+	}
+}
+
+// Set implements Batch.
+func (b *ShardedBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, BatchOp{Key: key, Value: value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *ShardedBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, BatchOp{Key: key, Delete: true})
+	return nil
+}
+
+// Len implements Batch.
+func (b *ShardedBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *ShardedBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *ShardedBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *ShardedBatch) write(sync bool) error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	if err := b.db.writeBatch(b.ops, sync); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *ShardedBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedDBRandom(t *testing.T) {
+	const numKeys = 10000
+	shards := []DB{NewMemDB(), NewMemDB(), NewMemDB(), NewMemDB()}
+	sdb := NewShardedDB(shards)
+
+	r := rand.New(rand.NewSource(1)) // nolint:gosec // G404: Use of weak random number generator
+	expect := make(map[string][]byte, numKeys)
+	batch := sdb.NewBatch()
+	for len(expect) < numKeys {
+		key := make([]byte, 1+r.Intn(16))
+		r.Read(key)
+		value := make([]byte, r.Intn(16))
+		r.Read(value)
+		if _, ok := expect[string(key)]; ok {
+			continue
+		}
+		expect[string(key)] = value
+		// Write half of the keys directly, and half through a batch.
+		if len(expect)%2 == 0 {
+			require.NoError(t, sdb.Set(key, value))
+		} else {
+			require.NoError(t, batch.Set(key, value))
+		}
+	}
+	require.Equal(t, numKeys/2, batch.Len())
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	// Every key is read back from its shard, and the keys are spread across all shards.
+	for key, value := range expect {
+		v, err := sdb.Get([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, value, v)
+	}
+	for _, shard := range shards {
+		stats := shard.Stats()
+		assert.NotEqual(t, "0", stats["database.size"])
+	}
+
+	keys := make([]string, 0, len(expect))
+	for key := range expect {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	itr, err := sdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	for _, key := range keys {
+		require.True(t, itr.Valid())
+		require.Equal(t, []byte(key), itr.Key())
+		require.Equal(t, expect[key], itr.Value())
+		itr.Next()
+	}
+	require.False(t, itr.Valid())
+	require.NoError(t, itr.Close())
+
+	// Reverse iteration over a random range.
+	start, end := []byte(keys[1000]), []byte(keys[9000])
+	itr, err = sdb.ReverseIterator(start, end)
+	require.NoError(t, err)
+	var prev []byte
+	count := 0
+	for ; itr.Valid(); itr.Next() {
+		if prev != nil {
+			require.Equal(t, 1, bytes.Compare(prev, itr.Key()), "keys out of order")
+		}
+		prev = cp(itr.Key())
+		count++
+	}
+	require.NoError(t, itr.Close())
+	require.Equal(t, 8000, count)
+
+	// Deleting a range removes keys from every shard.
+	require.NoError(t, sdb.DeleteRange(start, end))
+	itr, err = sdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	count = 0
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Close())
+	require.Equal(t, numKeys-8000, count)
+}
@@ -0,0 +1,125 @@
+package db
+
+// SizeBoundedBatch is a batch on a database which bounds its memory use, by writing the buffered
+// operations as a batch and starting a new one whenever their size, the sum of key and value
+// lengths, exceeds a threshold. The operations are therefore not written atomically as a whole,
+// only in chunks.
+//
+// Unlike other batches, closing a SizeBoundedBatch which has not been written yet writes the
+// remaining operations, so that streaming writers only need to call Close.
+type SizeBoundedBatch struct {
+	db        DB
+	threshold int
+	batch     Batch // nil once written or closed
+	size      int
+	flushes   int
+}
+
+var _ Batch = (*SizeBoundedBatch)(nil)
+
+// NewSizeBoundedBatch creates a SizeBoundedBatch on the given database, flushing whenever the
+// buffered operations exceed threshold bytes.
+func NewSizeBoundedBatch(db DB, threshold int) *SizeBoundedBatch {
+	return &SizeBoundedBatch{
+		db:        db,
+		threshold: threshold,
+		batch:     db.NewBatch(),
+	}
+}
+
+// Flushes returns the number of batches written so far.
+func (b *SizeBoundedBatch) Flushes() int {
+	return b.flushes
+}
+
+// Set implements Batch.
+func (b *SizeBoundedBatch) Set(key, value []byte) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	return b.added(len(key) + len(value))
+}
+
+// Delete implements Batch.
+func (b *SizeBoundedBatch) Delete(key []byte) error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	return b.added(len(key))
+}
+
+// added accounts for an operation of the given size, flushing if the threshold is exceeded.
+func (b *SizeBoundedBatch) added(size int) error {
+	b.size += size
+	if b.size <= b.threshold {
+		return nil
+	}
+	if err := b.flush(false); err != nil {
+		return err
+	}
+	b.batch = b.db.NewBatch()
+	b.size = 0
+	return nil
+}
+
+// flush writes and closes the current batch.
+func (b *SizeBoundedBatch) flush(sync bool) error {
+	batch := b.batch
+	b.batch = nil
+	var err error
+	if sync {
+		err = batch.WriteSync()
+	} else {
+		err = batch.Write()
+	}
+	if err != nil {
+		batch.Close()
+		return err
+	}
+	b.flushes++
+	return batch.Close()
+}
+
+// Len implements Batch. It is the number of operations buffered since the last flush.
+func (b *SizeBoundedBatch) Len() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Len()
+}
+
+// Write implements Batch. It writes the operations buffered since the last flush.
+func (b *SizeBoundedBatch) Write() error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.flush(false)
+}
+
+// WriteSync implements Batch. It writes the operations buffered since the last flush, and syncs
+// them to disk, which on most backends also syncs the earlier flushes.
+func (b *SizeBoundedBatch) WriteSync() error {
+	if b.batch == nil {
+		return errBatchClosed
+	}
+	return b.flush(true)
+}
+
+// Close implements Batch. If the batch has not been written, the buffered operations are written.
+func (b *SizeBoundedBatch) Close() error {
+	if b.batch == nil {
+		return nil
+	}
+	if b.batch.Len() == 0 {
+		err := b.batch.Close()
+		b.batch = nil
+		return err
+	}
+	return b.flush(false)
+}
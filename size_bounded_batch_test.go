@@ -0,0 +1,70 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeBoundedBatch(t *testing.T) {
+	db := NewMemDB()
+	batch := NewSizeBoundedBatch(db, 1<<20)
+
+	// 10 MB in writes of 1 KB.
+	value := bytes.Repeat([]byte{1}, 1024-8)
+	for i := int64(0); i < 10*1024; i++ {
+		require.NoError(t, batch.Set(int642Bytes(i), value))
+	}
+	assert.GreaterOrEqual(t, batch.Flushes(), 9)
+	assert.Less(t, batch.Len(), 1024)
+
+	// Flushed operations are visible, buffered ones are not until written.
+	checkValue(t, db, int642Bytes(0), value)
+	checkValue(t, db, int642Bytes(10*1024-1), nil)
+
+	require.NoError(t, batch.Delete(int642Bytes(0)))
+	flushes := batch.Flushes()
+	require.NoError(t, batch.Close())
+	assert.Equal(t, flushes+1, batch.Flushes())
+	checkValue(t, db, int642Bytes(0), nil)
+	checkValue(t, db, int642Bytes(10*1024-1), value)
+	count, err := Count(db, nil, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10*1024-1, count)
+
+	require.Equal(t, errBatchClosed, batch.Set([]byte("a"), []byte{1}))
+	require.Equal(t, errBatchClosed, batch.Write())
+	require.NoError(t, batch.Close())
+}
+
+func TestSizeBoundedBatchWrite(t *testing.T) {
+	db := NewMemDB()
+	batch := NewSizeBoundedBatch(db, 10)
+	require.NoError(t, batch.Set([]byte("a"), []byte("1234")))
+	require.NoError(t, batch.Set([]byte("b"), []byte("1234")))
+	assert.Equal(t, 0, batch.Flushes())
+	assert.Equal(t, 2, batch.Len())
+
+	// Exceeding the threshold flushes, including the operation exceeding it.
+	require.NoError(t, batch.Set([]byte("c"), []byte("1")))
+	assert.Equal(t, 1, batch.Flushes())
+	assert.Equal(t, 0, batch.Len())
+	checkValue(t, db, []byte("c"), []byte("1"))
+
+	require.NoError(t, batch.Set([]byte("d"), []byte("1")))
+	require.NoError(t, batch.WriteSync())
+	assert.Equal(t, 2, batch.Flushes())
+	checkValue(t, db, []byte("d"), []byte("1"))
+	require.NoError(t, batch.Close())
+	assert.Equal(t, 2, batch.Flushes())
+
+	// Closing an empty batch writes nothing.
+	empty := NewSizeBoundedBatch(db, 10)
+	require.NoError(t, empty.Close())
+	assert.Equal(t, 0, empty.Flushes())
+	invalid := NewSizeBoundedBatch(db, 10)
+	defer invalid.Close()
+	require.Equal(t, errKeyEmpty, invalid.Set(nil, []byte{1}))
+}
@@ -0,0 +1,27 @@
+package db
+
+// Sizable is implemented by databases which can report their size more cheaply than by scanning
+// all of their items.
+type Sizable interface {
+	// ApproxSize returns the approximate number of bytes stored by the database.
+	ApproxSize() (int64, error)
+}
+
+// SizeOf returns the approximate number of bytes stored by a database, for monitoring its
+// growth. Databases implementing Sizable report it themselves, which for on-disk backends is
+// usually the size of their data files on disk, and may not include recent writes which have not
+// been flushed yet. Other databases are scanned, summing the lengths of all keys and values.
+func SizeOf(db DB) (int64, error) {
+	if s, ok := db.(Sizable); ok {
+		return s.ApproxSize()
+	}
+	var size int64
+	err := db.ForEach(func(key, value []byte) error {
+		size += int64(len(key) + len(value))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import "errors"
+
+// errSnapshotReleased is returned when a released snapshot is used.
+var errSnapshotReleased = errors.New("snapshot has been released")
+
+// Snapshot is a consistent, read-only, point-in-time view of a database. Writes made to the
+// database after the snapshot was taken are not visible through it. Callers must call Release
+// when done, after which other methods will error.
+//
+// As with DB, returned keys and values should be considered read-only.
+type Snapshot interface {
+	// Get fetches the value of the given key as of the snapshot, or nil if it did not exist.
+	// CONTRACT: key readonly []byte
+	Get(key []byte) ([]byte, error)
+
+	// Iterator returns an iterator over a domain of keys as of the snapshot, in ascending order,
+	// with the same semantics as DB.Iterator. Iterators must be closed before releasing the
+	// snapshot.
+	// CONTRACT: start, end readonly []byte
+	Iterator(start, end []byte) (Iterator, error)
+
+	// Release releases the snapshot, and any resources held by it. It is idempotent.
+	Release()
+}
+
+// Snapshotable is implemented by databases which can take snapshots.
+type Snapshotable interface {
+	// Snapshot takes a snapshot of the current state of the database.
+	Snapshot() (Snapshot, error)
+}
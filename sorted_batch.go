@@ -0,0 +1,115 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// SortedBatch wraps a batch, and buffers its operations so that they can be passed on to the
+// wrapped batch in key order when written. This gives better on-disk layouts on LSM-tree
+// backends such as LevelDB for callers which accumulate operations out of order. Only the last
+// operation for each key is passed on.
+//
+// Until written, operations are only buffered, so Len returns the number of buffered operations.
+type SortedBatch struct {
+	batch Batch
+	ops   []operation
+}
+
+var _ Batch = (*SortedBatch)(nil)
+
+// NewSortedBatch creates a SortedBatch wrapping the given batch.
+func NewSortedBatch(batch Batch) *SortedBatch {
+	return &SortedBatch{
+		batch: batch,
+		ops:   []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *SortedBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *SortedBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Len implements Batch.
+func (b *SortedBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *SortedBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *SortedBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *SortedBatch) write(sync bool) error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	// A stable sort keeps operations on the same key in order, so the last one can be picked.
+	sort.SliceStable(b.ops, func(i, j int) bool {
+		return bytes.Compare(b.ops[i].key, b.ops[j].key) < 0
+	})
+	for i, op := range b.ops {
+		if i+1 < len(b.ops) && bytes.Equal(op.key, b.ops[i+1].key) {
+			continue
+		}
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			err = b.batch.Set(op.key, op.value)
+		case opTypeDelete:
+			err = b.batch.Delete(op.key)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if sync {
+		err = b.batch.WriteSync()
+	} else {
+		err = b.batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *SortedBatch) Close() error {
+	b.ops = nil
+	return b.batch.Close()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBatch wraps a batch, and records the keys of its operations in order.
+type recordingBatch struct {
+	Batch
+	keys []string
+}
+
+func (b *recordingBatch) Set(key, value []byte) error {
+	b.keys = append(b.keys, "set:"+string(key))
+	return b.Batch.Set(key, value)
+}
+
+func (b *recordingBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, "delete:"+string(key))
+	return b.Batch.Delete(key)
+}
+
+func TestSortedBatch(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("b"), []byte{0}))
+	inner := &recordingBatch{Batch: db.NewBatch()}
+	batch := NewSortedBatch(inner)
+
+	for _, key := range []string{"e", "d", "c", "b", "a"} {
+		require.NoError(t, batch.Set([]byte(key), []byte(key)))
+	}
+	// The last operation on a key wins.
+	require.NoError(t, batch.Delete([]byte("c")))
+	require.NoError(t, batch.Set([]byte("a"), []byte("A")))
+	require.NoError(t, batch.Delete([]byte("b")))
+	assert.Equal(t, 8, batch.Len())
+	assert.Empty(t, inner.keys, "operations must be buffered until written")
+
+	require.NoError(t, batch.Write())
+	assert.Equal(t, []string{"set:a", "delete:b", "delete:c", "set:d", "set:e"}, inner.keys)
+	assert.Equal(t, 0, batch.Len())
+	assertKeyValues(t, db, map[string][]byte{"a": []byte("A"), "d": []byte("d"), "e": []byte("e")})
+
+	assert.Equal(t, errBatchClosed, batch.Set([]byte("f"), []byte{1}))
+	assert.Equal(t, errBatchClosed, batch.Write())
+	require.NoError(t, batch.Close())
+}
+
+func TestSortedBatchClose(t *testing.T) {
+	db := NewMemDB()
+	batch := NewSortedBatch(db.NewBatch())
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Close())
+	require.NoError(t, batch.Close())
+	assert.Equal(t, errBatchClosed, batch.WriteSync())
+	assertKeyValues(t, db, map[string][]byte{})
+
+	assert.Equal(t, errKeyEmpty, NewSortedBatch(db.NewBatch()).Set(nil, []byte{1}))
+	assert.Equal(t, errValueNil, NewSortedBatch(db.NewBatch()).Set([]byte("a"), nil))
+}
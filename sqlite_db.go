@@ -0,0 +1,350 @@
+//go:build sqlite
+// +build sqlite
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+func init() {
+	registerDBCreator(SQLiteDBBackend, func(name, dir string, opts Options) (DB, error) {
+		if opts.ReadOnly {
+			return nil, errReadOnlyUnsupported
+		}
+		return NewSQLiteDB(name, dir)
+	}, false)
+}
+
+// sqliteSchema creates the table holding all key/value pairs. Keys are BLOBs, which SQLite sorts
+// with memcmp, i.e. in the same order as bytes.Compare.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS kv (key BLOB PRIMARY KEY, value BLOB NOT NULL) WITHOUT ROWID`
+
+// SQLiteDB is a database backed by a single SQLite table, using the pure-Go SQLite port at
+// modernc.org/sqlite, so it does not require CGo.
+//
+// The database uses WAL mode with synchronous=FULL, so all writes (including Set and Delete)
+// are synchronous. SQLite only supports one writer at a time, so writes are serialized by a
+// mutex, while reads and iterators run concurrently with them on their own snapshot.
+type SQLiteDB struct {
+	db *sql.DB
+
+	// writeMtx serializes writes, since SQLite only allows a single writer.
+	writeMtx   sync.Mutex
+	getStmt    *sql.Stmt
+	hasStmt    *sql.Stmt
+	setStmt    *sql.Stmt
+	deleteStmt *sql.Stmt
+}
+
+var _ DB = (*SQLiteDB)(nil)
+var _ Sizable = (*SQLiteDB)(nil)
+
+// NewSQLiteDB opens or creates the SQLite database file name.db in dir.
+func NewSQLiteDB(name, dir string) (*SQLiteDB, error) {
+	dbPath := filepath.Join(dir, name+".db")
+	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	sdb := &SQLiteDB{db: db}
+	for _, s := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&sdb.getStmt, `SELECT value FROM kv WHERE key = ?`},
+		{&sdb.hasStmt, `SELECT 1 FROM kv WHERE key = ?`},
+		{&sdb.setStmt, `INSERT OR REPLACE INTO kv (key, value) VALUES (?, ?)`},
+		{&sdb.deleteStmt, `DELETE FROM kv WHERE key = ?`},
+	} {
+		*s.stmt, err = db.Prepare(s.query)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return sdb, nil
+}
+
+// sqliteDomain returns an SQL condition matching the keys in a domain, and its arguments. If
+// seek is given, it also only matches keys at or after it, or at or before it if reverse.
+func sqliteDomain(start, end, seek []byte, reverse bool) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if start != nil {
+		conds = append(conds, "key >= ?")
+		args = append(args, start)
+	}
+	if end != nil {
+		conds = append(conds, "key < ?")
+		args = append(args, end)
+	}
+	if seek != nil {
+		if reverse {
+			conds = append(conds, "key <= ?")
+		} else {
+			conds = append(conds, "key >= ?")
+		}
+		args = append(args, seek)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// get reads a value using the given statement, which may belong to a transaction.
+func sqliteGet(stmt *sql.Stmt, key []byte) ([]byte, error) {
+	var value []byte
+	err := stmt.QueryRow(key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		// Empty BLOBs are scanned as nil.
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// update runs fn in a write transaction, which is committed if fn succeeds.
+func (db *SQLiteDB) update(fn func(tx *sql.Tx) error) error {
+	db.writeMtx.Lock()
+	defer db.writeMtx.Unlock()
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get implements DB.
+func (db *SQLiteDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return sqliteGet(db.getStmt, key)
+}
+
+// Has implements DB.
+func (db *SQLiteDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	var one int
+	err := db.hasStmt.QueryRow(key).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MultiGet implements DB.
+func (db *SQLiteDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(db, keys)
+}
+
+// Set implements DB.
+func (db *SQLiteDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	db.writeMtx.Lock()
+	defer db.writeMtx.Unlock()
+	_, err := db.setStmt.Exec(key, value)
+	return err
+}
+
+// SetSync implements DB.
+func (db *SQLiteDB) SetSync(key []byte, value []byte) error {
+	return db.Set(key, value)
+}
+
+// CompareAndSet implements DB. The comparison and write happen in a single transaction.
+func (db *SQLiteDB) CompareAndSet(key, expected, newVal []byte) (swapped bool, err error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	err = db.update(func(tx *sql.Tx) error {
+		current, err := sqliteGet(tx.Stmt(db.getStmt), key)
+		if err != nil {
+			return err
+		}
+		if !valueMatches(current, expected) {
+			return nil
+		}
+		if _, err := tx.Stmt(db.setStmt).Exec(key, newVal); err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// Delete implements DB.
+func (db *SQLiteDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	db.writeMtx.Lock()
+	defer db.writeMtx.Unlock()
+	_, err := db.deleteStmt.Exec(key)
+	return err
+}
+
+// DeleteSync implements DB.
+func (db *SQLiteDB) DeleteSync(key []byte) error {
+	return db.Delete(key)
+}
+
+// DeleteRange implements DB. The whole domain is deleted by a single statement.
+func (db *SQLiteDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	where, args := sqliteDomain(start, end, nil, false)
+	db.writeMtx.Lock()
+	defer db.writeMtx.Unlock()
+	_, err := db.db.Exec("DELETE FROM kv"+where, args...)
+	return err
+}
+
+// Iterator implements DB.
+func (db *SQLiteDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newSQLiteDBIterator(db, start, end, false)
+}
+
+// ReverseIterator implements DB.
+func (db *SQLiteDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newSQLiteDBIterator(db, start, end, true)
+}
+
+// IteratorWithContext implements DB.
+func (db *SQLiteDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, db, start, end)
+}
+
+// Compact implements DB. SQLite can not compact part of a database, so the whole database file
+// is rebuilt with VACUUM regardless of the domain.
+func (db *SQLiteDB) Compact(start, end []byte) error {
+	db.writeMtx.Lock()
+	defer db.writeMtx.Unlock()
+	_, err := db.db.Exec("VACUUM")
+	return err
+}
+
+// WriteBatch implements DB.
+func (db *SQLiteDB) WriteBatch(ops []BatchOp) error {
+	return writeBatch(db, ops, false)
+}
+
+// WriteBatchSync implements DB.
+func (db *SQLiteDB) WriteBatchSync(ops []BatchOp) error {
+	return writeBatch(db, ops, true)
+}
+
+// ApplyLog implements DB.
+func (db *SQLiteDB) ApplyLog(ops BatchOpList) error {
+	return db.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (db *SQLiteDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(db, fn)
+}
+
+// Close implements DB.
+func (db *SQLiteDB) Close() error {
+	GlobalRegistry.unregister(db)
+	for _, stmt := range []*sql.Stmt{db.getStmt, db.hasStmt, db.setStmt, db.deleteStmt} {
+		stmt.Close()
+	}
+	return db.db.Close()
+}
+
+// NewBatch implements DB.
+func (db *SQLiteDB) NewBatch() Batch {
+	return newSQLiteDBBatch(db, 0)
+}
+
+// NewBatchWithSize implements DB.
+func (db *SQLiteDB) NewBatchWithSize(expectedOps int) Batch {
+	return newSQLiteDBBatch(db, expectedOps)
+}
+
+// Print implements DB.
+func (db *SQLiteDB) Print() error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (db *SQLiteDB) Stats() map[string]string {
+	stats := db.db.Stats()
+	m := make(map[string]string)
+	m["database.type"] = "sqlite"
+	m["sqlite.open-connections"] = fmt.Sprintf("%d", stats.OpenConnections)
+	m["sqlite.in-use"] = fmt.Sprintf("%d", stats.InUse)
+	if size, err := db.ApproxSize(); err == nil {
+		m["sqlite.size"] = fmt.Sprintf("%d", size)
+	}
+	return m
+}
+
+// ApproxSize implements Sizable. It is the size of the database pages in use, excluding the WAL.
+func (db *SQLiteDB) ApproxSize() (int64, error) {
+	var size int64
+	err := db.db.QueryRow(
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size)
+	return size, err
+}
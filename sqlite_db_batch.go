@@ -0,0 +1,96 @@
+//go:build sqlite
+// +build sqlite
+
+package db
+
+import "database/sql"
+
+// sqliteDBBatch stores operations internally and applies them to SQLite in a single
+// transaction on Write().
+type sqliteDBBatch struct {
+	db  *SQLiteDB
+	ops []operation
+}
+
+var _ Batch = (*sqliteDBBatch)(nil)
+
+func newSQLiteDBBatch(db *SQLiteDB, size int) *sqliteDBBatch {
+	if size < 0 {
+		size = 0
+	}
+	return &sqliteDBBatch{
+		db:  db,
+		ops: make([]operation, 0, size),
+	}
+}
+
+// Set implements Batch.
+func (b *sqliteDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeSet, cp(key), cp(value)})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *sqliteDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, cp(key), nil})
+	return nil
+}
+
+// Len implements Batch.
+func (b *sqliteDBBatch) Len() int {
+	return len(b.ops)
+}
+
+// Write implements Batch.
+func (b *sqliteDBBatch) Write() error {
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	err := b.db.update(func(tx *sql.Tx) error {
+		setStmt, deleteStmt := tx.Stmt(b.db.setStmt), tx.Stmt(b.db.deleteStmt)
+		for _, op := range b.ops {
+			var err error
+			switch op.opType {
+			case opTypeSet:
+				_, err = setStmt.Exec(op.key, op.value)
+			case opTypeDelete:
+				_, err = deleteStmt.Exec(op.key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *sqliteDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *sqliteDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
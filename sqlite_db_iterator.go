@@ -0,0 +1,142 @@
+//go:build sqlite
+// +build sqlite
+
+package db
+
+import "database/sql"
+
+// sqliteDBIterator iterates over the rows of a SELECT cursor. Seeking runs a new query.
+type sqliteDBIterator struct {
+	db        *SQLiteDB
+	start     []byte
+	end       []byte
+	isReverse bool
+
+	rows   *sql.Rows
+	key    []byte
+	value  []byte
+	valid  bool
+	err    error
+	closed bool
+}
+
+var _ Iterator = (*sqliteDBIterator)(nil)
+
+func newSQLiteDBIterator(db *SQLiteDB, start, end []byte, isReverse bool) (*sqliteDBIterator, error) {
+	itr := &sqliteDBIterator{
+		db:        db,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+	if err := itr.query(nil); err != nil {
+		return nil, err
+	}
+	return itr, nil
+}
+
+// query replaces the cursor with one starting at seek, if given, and moves to the first row.
+func (itr *sqliteDBIterator) query(seek []byte) error {
+	if itr.rows != nil {
+		if err := itr.rows.Close(); err != nil {
+			return err
+		}
+		itr.rows = nil
+	}
+	where, args := sqliteDomain(itr.start, itr.end, seek, itr.isReverse)
+	order := " ORDER BY key"
+	if itr.isReverse {
+		order += " DESC"
+	}
+	rows, err := itr.db.db.Query("SELECT key, value FROM kv"+where+order, args...)
+	if err != nil {
+		return err
+	}
+	itr.rows = rows
+	itr.advance()
+	return itr.err
+}
+
+// advance moves to the next row of the cursor.
+func (itr *sqliteDBIterator) advance() {
+	itr.valid = false
+	if !itr.rows.Next() {
+		itr.err = itr.rows.Err()
+		return
+	}
+	var key, value []byte
+	if err := itr.rows.Scan(&key, &value); err != nil {
+		itr.err = err
+		return
+	}
+	if value == nil {
+		// Empty BLOBs are scanned as nil.
+		value = []byte{}
+	}
+	itr.key, itr.value, itr.valid = key, value, true
+}
+
+// Domain implements Iterator.
+func (itr *sqliteDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *sqliteDBIterator) Valid() bool {
+	return itr.valid && itr.err == nil && !itr.closed
+}
+
+// Seek implements Iterator.
+func (itr *sqliteDBIterator) Seek(key []byte) bool {
+	if itr.err != nil || itr.closed {
+		return false
+	}
+	if err := itr.query(key); err != nil {
+		itr.err = err
+	}
+	return itr.Valid()
+}
+
+// Key implements Iterator.
+func (itr *sqliteDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.key
+}
+
+// Value implements Iterator.
+func (itr *sqliteDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Position implements Iterator.
+func (itr *sqliteDBIterator) Position() []byte {
+	return iteratorPosition(itr)
+}
+
+// Next implements Iterator.
+func (itr *sqliteDBIterator) Next() {
+	itr.assertIsValid()
+	itr.advance()
+}
+
+// Error implements Iterator.
+func (itr *sqliteDBIterator) Error() error {
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *sqliteDBIterator) Close() error {
+	if itr.closed {
+		return nil
+	}
+	itr.closed = true
+	itr.valid = false
+	return itr.rows.Close()
+}
+
+func (itr *sqliteDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,90 @@
+//go:build sqlite
+// +build sqlite
+
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteDBBackend(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("testdb", SQLiteDBBackend, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, ok := db.(*SQLiteDB)
+	assert.True(t, ok)
+	assert.NotEmpty(t, db.Stats())
+}
+
+func TestWithSQLiteDB(t *testing.T) {
+	db, err := NewSQLiteDB("testdb", t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("SQLiteDB", func(t *testing.T) { Run(t, db) })
+}
+
+func TestSQLiteDBReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewSQLiteDB("testdb", dir)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)}))
+	}
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set([]byte("batch"), []byte{}))
+	require.NoError(t, batch.Delete([]byte("key00")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.NoError(t, db.DeleteRange([]byte("key50"), nil))
+	require.NoError(t, db.Close())
+
+	// Reopening the file must see everything written before closing it.
+	db, err = NewSQLiteDB("testdb", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	expect := map[string][]byte{"batch": {}}
+	for i := 1; i < 50; i++ {
+		expect[fmt.Sprintf("key%02d", i)] = []byte{byte(i)}
+	}
+	assertKeyValues(t, db, expect)
+}
+
+func TestSQLiteDBIteratorConcurrentWrites(t *testing.T) {
+	db, err := NewSQLiteDB("testdb", t.TempDir())
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	// Writes do not block on open iterators, which keep reading their own snapshot.
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+	checkItem(t, itr, []byte("a"), []byte{1})
+	itr.Next()
+	checkItem(t, itr, []byte("b"), []byte{2})
+	itr.Next()
+	checkValid(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	checkValue(t, db, []byte("c"), []byte{3})
+}
+
+func BenchmarkSQLiteDBRandomReadsWrites(b *testing.B) {
+	db, err := NewSQLiteDB("testdb", b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	benchmarkRandomReadsWrites(b, db)
+}
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Histogram records the distribution of sizes, e.g. of keys or values. It counts every distinct
+// size separately, so percentiles are exact.
+type Histogram struct {
+	counts map[int]uint64
+	count  uint64
+	sum    uint64
+	min    int
+	max    int
+}
+
+// NewHistogram creates an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make(map[int]uint64)}
+}
+
+// Add records a size.
+func (h *Histogram) Add(size int) {
+	if h.count == 0 || size < h.min {
+		h.min = size
+	}
+	if h.count == 0 || size > h.max {
+		h.max = size
+	}
+	h.counts[size]++
+	h.count++
+	h.sum += uint64(size)
+}
+
+// Count returns the number of recorded sizes.
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Min returns the smallest recorded size, or 0 if empty.
+func (h *Histogram) Min() int {
+	return h.min
+}
+
+// Max returns the largest recorded size, or 0 if empty.
+func (h *Histogram) Max() int {
+	return h.max
+}
+
+// Mean returns the mean of the recorded sizes, or 0 if empty.
+func (h *Histogram) Mean() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.count)
+}
+
+// Percentile returns the smallest recorded size such that at least p percent of sizes are less
+// than or equal to it, or 0 if empty.
+func (h *Histogram) Percentile(p float64) int {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	sizes := make([]int, 0, len(h.counts))
+	for size := range h.counts {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+	var seen uint64
+	for _, size := range sizes {
+		seen += h.counts[size]
+		if seen >= rank {
+			return size
+		}
+	}
+	return h.max
+}
+
+// P50 returns the median size.
+func (h *Histogram) P50() int {
+	return h.Percentile(50)
+}
+
+// P95 returns the 95th percentile size.
+func (h *Histogram) P95() int {
+	return h.Percentile(95)
+}
+
+// P99 returns the 99th percentile size.
+func (h *Histogram) P99() int {
+	return h.Percentile(99)
+}
+
+// IteratorStats iterates over the entire database, and returns histograms of its key and value
+// sizes.
+func IteratorStats(db DB) (keys *Histogram, values *Histogram, err error) {
+	keys, values = NewHistogram(), NewHistogram()
+	err = db.ForEach(func(key, value []byte) error {
+		keys.Add(len(key))
+		values.Add(len(value))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, values, nil
+}
+
+// estimateExactLimit is the number of keys below which EstimateKeys counts keys exactly.
+const estimateExactLimit = 1024
+
+// KeyEstimator is implemented by databases which can estimate their number of keys natively.
+type KeyEstimator interface {
+	// EstimateKeys returns an estimate of the number of keys in the database.
+	EstimateKeys() (int64, error)
+}
+
+// EstimateKeys estimates the number of keys in the database, without a full scan. Databases
+// implementing KeyEstimator estimate it themselves. Otherwise, databases with few keys are counted
+// exactly, and for larger ones about sqrt(n) keys are read: the keyspace between the first and
+// last key is split into sqrt(n) strata, the keys are counted in a small window at a random
+// position in each stratum, and the total is extrapolated from their density. The estimate is most
+// accurate when keys are spread evenly, e.g. hashes or sequence numbers; clustered keys may give
+// large errors.
+func EstimateKeys(db DB) (int64, error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano())) // nolint:gosec // G404
+	return estimateKeys(db, rng)
+}
+
+func estimateKeys(db DB, rng *rand.Rand) (int64, error) {
+	if e, ok := db.(KeyEstimator); ok {
+		return e.EstimateKeys()
+	}
+
+	// Count small databases exactly, which also gives the first key.
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	var first []byte
+	count := int64(0)
+	for ; itr.Valid() && count < estimateExactLimit; itr.Next() {
+		if first == nil {
+			first = cp(itr.Key())
+		}
+		count++
+	}
+	more := itr.Valid()
+	err = itr.Error()
+	itr.Close()
+	if err != nil || !more {
+		return count, err
+	}
+
+	ritr, err := db.ReverseIterator(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	last := cp(ritr.Key())
+	err = ritr.Error()
+	ritr.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	space := newKeySpace(first, last)
+	if space.size() < estimateExactLimit*estimateExactLimit {
+		// The keys only differ after the sampled bytes, so they can not be sampled.
+		return Count(db, nil, nil)
+	}
+
+	// Estimate with the minimum of keys, then refine with sqrt(n) strata.
+	estimate, err := sampleKeyDensity(db, space, int64(math.Sqrt(estimateExactLimit)), rng)
+	if err != nil {
+		return 0, err
+	}
+	strata := int64(math.Sqrt(float64(estimate)))
+	if float64(strata*strata) > space.size() {
+		strata = int64(math.Sqrt(space.size()))
+	}
+	return sampleKeyDensity(db, space, strata, rng)
+}
+
+// sampleKeyDensity estimates the number of keys from a window in each of the given number of
+// strata of the keyspace, each window covering 1/strata of its stratum.
+func sampleKeyDensity(db DB, space keySpace, strata int64, rng *rand.Rand) (int64, error) {
+	stratum := space.size() / float64(strata)
+	window := stratum / float64(strata)
+	var found int64
+	for i := int64(0); i < strata; i++ {
+		offset := float64(i)*stratum + rng.Float64()*(stratum-window)
+		n, err := Count(db, space.key(offset), space.key(offset+window))
+		if err != nil {
+			return 0, err
+		}
+		found += n
+	}
+	// The windows cover 1/strata of the keyspace.
+	return found * strata, nil
+}
+
+// keySpace maps keys between a first and last key to numbers, using the 8 bytes following their
+// common prefix, so that positions in the keyspace can be sampled.
+type keySpace struct {
+	prefix []byte
+	lo, hi uint64
+}
+
+func newKeySpace(first, last []byte) keySpace {
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+	space := keySpace{prefix: first[:n]}
+	space.lo = space.position(first)
+	space.hi = space.position(last)
+	return space
+}
+
+// position returns the position of a key sharing the prefix.
+func (s keySpace) position(key []byte) uint64 {
+	var bz [8]byte
+	copy(bz[:], key[len(s.prefix):])
+	return binary.BigEndian.Uint64(bz[:])
+}
+
+// size returns the size of the keyspace, up to and including the last key's position.
+func (s keySpace) size() float64 {
+	return float64(s.hi-s.lo) + 1
+}
+
+// key returns the key at the given offset into the keyspace, which is clamped to its size.
+func (s keySpace) key(offset float64) []byte {
+	pos := s.hi
+	if offset < float64(s.hi-s.lo) {
+		pos = s.lo + uint64(offset)
+	}
+	key := make([]byte, len(s.prefix)+8)
+	copy(key, s.prefix)
+	binary.BigEndian.PutUint64(key[len(s.prefix):], pos)
+	return key
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorStats(t *testing.T) {
+	db := NewMemDB()
+	// Keys are 4 or 5 bytes long, values of entry i are i+1 bytes long.
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("k%03d", i))
+		if i%4 == 0 {
+			key = append(key, 'x')
+		}
+		require.NoError(t, db.Set(key, bytes.Repeat([]byte{1}, i+1)))
+	}
+
+	keys, values, err := IteratorStats(db)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1000, keys.Count())
+	assert.Equal(t, 4, keys.Min())
+	assert.Equal(t, 5, keys.Max())
+	assert.InDelta(t, 4.25, keys.Mean(), 1e-9)
+	assert.Equal(t, 4, keys.P50())
+	assert.Equal(t, 5, keys.P95())
+
+	const epsilon = 1
+	assert.EqualValues(t, 1000, values.Count())
+	assert.Equal(t, 1, values.Min())
+	assert.Equal(t, 1000, values.Max())
+	assert.InDelta(t, 500.5, values.Mean(), 1e-9)
+	assert.InDelta(t, 500, values.P50(), epsilon)
+	assert.InDelta(t, 950, values.P95(), epsilon)
+	assert.InDelta(t, 990, values.P99(), epsilon)
+}
+
+func TestIteratorStatsEmpty(t *testing.T) {
+	keys, values, err := IteratorStats(NewMemDB())
+	require.NoError(t, err)
+	for _, h := range []*Histogram{keys, values} {
+		assert.Zero(t, h.Count())
+		assert.Zero(t, h.Min())
+		assert.Zero(t, h.Max())
+		assert.Zero(t, h.Mean())
+		assert.Zero(t, h.P99())
+	}
+
+	mock := NewMockDBWrapping(NewMemDB())
+	mock.SetError("ForEach", errors.New("boom"))
+	_, _, err = IteratorStats(mock)
+	require.Error(t, err)
+}
+
+func TestEstimateKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// Small databases are counted exactly.
+	db := NewMemDB()
+	estimate, err := estimateKeys(db, rng)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, estimate)
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), []byte{1}))
+	}
+	estimate, err = estimateKeys(db, rng)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, estimate)
+
+	// Larger ones are estimated within 20%, for sequential and random keys.
+	sequential := NewMemDB()
+	random := NewMemDB()
+	for i := int64(0); i < 10000; i++ {
+		require.NoError(t, sequential.Set(int642Bytes(i*3), []byte{1}))
+		key := make([]byte, 16)
+		rng.Read(key)
+		require.NoError(t, random.Set(key, []byte{1}))
+	}
+	for name, db := range map[string]DB{"sequential": sequential, "random": random} {
+		estimate, err = estimateKeys(db, rng)
+		require.NoError(t, err, name)
+		assert.InEpsilon(t, 10000, estimate, 0.2, name)
+	}
+
+	// Keys which only differ after the 8 bytes following their common prefix can not be sampled,
+	// so they are counted.
+	dense := NewMemDB()
+	require.NoError(t, dense.Set([]byte("a"), []byte{1}))
+	for i := int64(0); i < 2000; i++ {
+		key := append(append([]byte("a"), int642Bytes(0)...), int642Bytes(i)...)
+		require.NoError(t, dense.Set(key, []byte{1}))
+	}
+	estimate, err = EstimateKeys(dense)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2001, estimate)
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// SyncDB wraps a database which is not safe for concurrent use, and serializes access to it with
+// a read-write mutex: reads take a read lock, while writes take the write lock. Batches only take
+// the write lock when written.
+//
+// Like MemDB iterators, iterators hold a read lock until they are closed, so writes block while
+// any iterator is open. The goroutine holding an iterator must therefore not write to the
+// database, or take further read locks e.g. with Get while another goroutine may be writing,
+// since that deadlocks.
+type SyncDB struct {
+	mtx sync.RWMutex
+	db  DB
+}
+
+var _ DB = (*SyncDB)(nil)
+
+// NewSyncDB creates a SyncDB wrapping the given database.
+func NewSyncDB(db DB) *SyncDB {
+	return &SyncDB{db: db}
+}
+
+// Get implements DB.
+func (sdb *SyncDB) Get(key []byte) ([]byte, error) {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.Get(key)
+}
+
+// Has implements DB.
+func (sdb *SyncDB) Has(key []byte) (bool, error) {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (sdb *SyncDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (sdb *SyncDB) Set(key []byte, value []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (sdb *SyncDB) SetSync(key []byte, value []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (sdb *SyncDB) Delete(key []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (sdb *SyncDB) DeleteSync(key []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB. Holding the write lock makes it atomic on any backend.
+func (sdb *SyncDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (sdb *SyncDB) DeleteRange(start, end []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (sdb *SyncDB) Iterator(start, end []byte) (Iterator, error) {
+	sdb.mtx.RLock()
+	itr, err := sdb.db.Iterator(start, end)
+	if err != nil {
+		sdb.mtx.RUnlock()
+		return nil, err
+	}
+	return newSyncIterator(&sdb.mtx, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (sdb *SyncDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	sdb.mtx.RLock()
+	itr, err := sdb.db.ReverseIterator(start, end)
+	if err != nil {
+		sdb.mtx.RUnlock()
+		return nil, err
+	}
+	return newSyncIterator(&sdb.mtx, itr), nil
+}
+
+// IteratorWithContext implements DB.
+func (sdb *SyncDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	sdb.mtx.RLock()
+	itr, err := sdb.db.IteratorWithContext(ctx, start, end)
+	if err != nil {
+		sdb.mtx.RUnlock()
+		return nil, err
+	}
+	return newSyncIterator(&sdb.mtx, itr), nil
+}
+
+// Compact implements DB.
+func (sdb *SyncDB) Compact(start, end []byte) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (sdb *SyncDB) WriteBatch(ops []BatchOp) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (sdb *SyncDB) WriteBatchSync(ops []BatchOp) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (sdb *SyncDB) ApplyLog(ops BatchOpList) error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.ApplyLog(ops)
+}
+
+// ForEach implements DB. The read lock is held while calling fn.
+func (sdb *SyncDB) ForEach(fn func(key, value []byte) error) error {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (sdb *SyncDB) Close() error {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return sdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (sdb *SyncDB) NewBatch() Batch {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return newSyncBatch(&sdb.mtx, sdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (sdb *SyncDB) NewBatchWithSize(expectedOps int) Batch {
+	sdb.mtx.Lock()
+	defer sdb.mtx.Unlock()
+	return newSyncBatch(&sdb.mtx, sdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (sdb *SyncDB) Print() error {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.Print()
+}
+
+// Stats implements DB.
+func (sdb *SyncDB) Stats() map[string]string {
+	sdb.mtx.RLock()
+	defer sdb.mtx.RUnlock()
+	return sdb.db.Stats()
+}
+
+// SyncBatch is a batch created by SyncDB. It takes the database's write lock when written, so
+// it may be written concurrently with other database operations. Like other batches, the batch
+// itself must not be used concurrently.
+type SyncBatch struct {
+	mtx   *sync.RWMutex
+	batch Batch
+}
+
+var _ Batch = (*SyncBatch)(nil)
+
+func newSyncBatch(mtx *sync.RWMutex, batch Batch) *SyncBatch {
+	return &SyncBatch{
+		mtx:   mtx,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *SyncBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *SyncBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *SyncBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *SyncBatch) Write() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *SyncBatch) WriteSync() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *SyncBatch) Close() error {
+	return b.batch.Close()
+}
+
+// syncIterator wraps an iterator, and releases the database's read lock when closed.
+type syncIterator struct {
+	Iterator
+	once sync.Once
+	mtx  *sync.RWMutex
+}
+
+var _ Iterator = (*syncIterator)(nil)
+
+// newSyncIterator wraps an iterator which was created while holding a read lock on mtx, and
+// takes ownership of the lock.
+func newSyncIterator(mtx *sync.RWMutex, source Iterator) *syncIterator {
+	return &syncIterator{
+		Iterator: source,
+		mtx:      mtx,
+	}
+}
+
+// Close implements Iterator.
+func (itr *syncIterator) Close() error {
+	err := itr.Iterator.Close()
+	itr.once.Do(itr.mtx.RUnlock)
+	return err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// racyDB is a MemDB with unsynchronized bookkeeping, so that the race detector catches
+// conflicting concurrent calls.
+type racyDB struct {
+	*MemDB
+	writes int
+}
+
+func (db *racyDB) Get(key []byte) ([]byte, error) {
+	_ = db.writes
+	return db.MemDB.Get(key)
+}
+
+func (db *racyDB) Set(key []byte, value []byte) error {
+	db.writes++
+	return db.MemDB.Set(key, value)
+}
+
+func (db *racyDB) NewBatch() Batch {
+	return &racyBatch{Batch: db.MemDB.NewBatch(), db: db}
+}
+
+type racyBatch struct {
+	Batch
+	db *racyDB
+}
+
+func (b *racyBatch) Write() error {
+	b.db.writes++
+	return b.Batch.Write()
+}
+
+func TestSyncDB(t *testing.T) {
+	inner := &racyDB{MemDB: NewMemDB()}
+	db := NewSyncDB(inner)
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			i := i
+			t.Run(fmt.Sprintf("worker%d", i), func(t *testing.T) {
+				t.Parallel()
+				for j := 0; j < 50; j++ {
+					key := []byte(fmt.Sprintf("key%02d-%02d", i, j))
+					if j%2 == 0 {
+						require.NoError(t, db.Set(key, []byte{byte(j)}))
+					} else {
+						batch := db.NewBatch()
+						require.NoError(t, batch.Set(key, []byte{byte(j)}))
+						require.NoError(t, batch.Write())
+						require.NoError(t, batch.Close())
+					}
+					checkValue(t, db, key, []byte{byte(j)})
+
+					// Iterators hold a read lock until closed, so collect the keys before checking
+					// any of them.
+					itr, err := db.Iterator(nil, nil)
+					require.NoError(t, err)
+					keys := 0
+					for ; itr.Valid(); itr.Next() {
+						keys++
+					}
+					require.NoError(t, itr.Error())
+					require.NoError(t, itr.Close())
+					require.Positive(t, keys)
+				}
+			})
+		}
+	})
+
+	require.Equal(t, 20*50, inner.writes)
+	count := 0
+	require.NoError(t, db.ForEach(func(key, value []byte) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 20*50, count)
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// errRowInvalid is returned when a stored row can not be decoded.
+var errRowInvalid = errors.New("invalid table row")
+
+// TableDB stores rows of named columns in a table of a database, keyed by primary key. Rows are
+// stored under the key "<table>/<pk>", so the table name must not contain a "/", or the rows of
+// two tables could overlap.
+//
+// A row is encoded as a sequence of columns sorted by name, each encoded as the column name and
+// then the value, both prefixed by their length as a uvarint.
+type TableDB struct {
+	table string
+	pdb   *PrefixDB
+}
+
+// NewTableDB creates a TableDB for the given table of a database. It panics if the table name is
+// empty or contains a "/".
+func NewTableDB(db DB, table string) *TableDB {
+	if table == "" || strings.Contains(table, "/") {
+		panic(fmt.Sprintf("invalid table name %q", table))
+	}
+	return &TableDB{
+		table: table,
+		pdb:   NewPrefixDB(db, []byte(table+"/")),
+	}
+}
+
+// Put stores a row, replacing any existing row with the same primary key. Nil column values are
+// stored as empty values.
+func (tdb *TableDB) Put(pk []byte, row map[string][]byte) error {
+	return tdb.pdb.Set(pk, encodeRow(row))
+}
+
+// Get fetches the row with the given primary key, or nil if it does not exist.
+func (tdb *TableDB) Get(pk []byte) (map[string][]byte, error) {
+	bz, err := tdb.pdb.Get(pk)
+	if err != nil || bz == nil {
+		return nil, err
+	}
+	return decodeRow(bz)
+}
+
+// Delete deletes the row with the given primary key, if it exists.
+func (tdb *TableDB) Delete(pk []byte) error {
+	return tdb.pdb.Delete(pk)
+}
+
+// Scan calls fn for every row with a primary key in the domain [pkStart, pkEnd), in ascending
+// order. A nil pkStart or pkEnd is unbounded. Scanning stops at the first error returned by fn,
+// which is returned. The primary key passed to fn may be reused once fn returns, but the row may
+// be retained.
+func (tdb *TableDB) Scan(pkStart, pkEnd []byte, fn func(pk []byte, row map[string][]byte) error) error {
+	itr, err := tdb.pdb.Iterator(pkStart, pkEnd)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		row, err := decodeRow(itr.Value())
+		if err != nil {
+			return fmt.Errorf("row %X of table %q: %w", itr.Key(), tdb.table, err)
+		}
+		if err := fn(itr.Key(), row); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+// encodeRow encodes a row, with its columns sorted by name so that equal rows encode equally.
+func encodeRow(row map[string][]byte) []byte {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, column := range columns {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(column)))
+		buf.Write(lenBuf[:n])
+		buf.WriteString(column)
+		n = binary.PutUvarint(lenBuf[:], uint64(len(row[column])))
+		buf.Write(lenBuf[:n])
+		buf.Write(row[column])
+	}
+	if buf.Len() == 0 {
+		// Rows without columns are still stored, and must have a non-nil value.
+		return []byte{}
+	}
+	return buf.Bytes()
+}
+
+// decodeRow decodes a row encoded by encodeRow. Values are copied out of bz, which may be reused
+// by the caller.
+func decodeRow(bz []byte) (map[string][]byte, error) {
+	row := make(map[string][]byte)
+	next := func() ([]byte, error) {
+		length, n := binary.Uvarint(bz)
+		if n <= 0 || length > uint64(len(bz)-n) {
+			return nil, errRowInvalid
+		}
+		field := bz[n : n+int(length)]
+		bz = bz[n+int(length):]
+		return field, nil
+	}
+	for len(bz) > 0 {
+		column, err := next()
+		if err != nil {
+			return nil, err
+		}
+		value, err := next()
+		if err != nil {
+			return nil, err
+		}
+		row[string(column)] = cp(value)
+	}
+	return row, nil
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableDB(t *testing.T) {
+	db := NewMemDB()
+	users := NewTableDB(db, "users")
+	orders := NewTableDB(db, "orders")
+
+	alice := map[string][]byte{"name": []byte("alice"), "email": []byte("alice@example.com"), "bio": {}}
+	require.NoError(t, users.Put([]byte("1"), alice))
+	require.NoError(t, users.Put([]byte("2"), map[string][]byte{"name": []byte("bob")}))
+	require.NoError(t, users.Put([]byte("3"), map[string][]byte{}))
+	require.NoError(t, orders.Put([]byte("1"), map[string][]byte{"total": {0x10}}))
+
+	row, err := users.Get([]byte("1"))
+	require.NoError(t, err)
+	assert.Equal(t, alice, row)
+	row, err = users.Get([]byte("3"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{}, row)
+	row, err = users.Get([]byte("4"))
+	require.NoError(t, err)
+	assert.Nil(t, row)
+	_, err = users.Get(nil)
+	require.Error(t, err)
+
+	// Rows are stored under <table>/<pk>, and the tables do not overlap.
+	value, err := db.Get([]byte("orders/1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{5, 't', 'o', 't', 'a', 'l', 1, 0x10}, value)
+	row, err = orders.Get([]byte("2"))
+	require.NoError(t, err)
+	assert.Nil(t, row)
+
+	scan := func(tdb *TableDB, start, end []byte) []string {
+		var pks []string
+		require.NoError(t, tdb.Scan(start, end, func(pk []byte, row map[string][]byte) error {
+			pks = append(pks, fmt.Sprintf("%s:%d", pk, len(row)))
+			return nil
+		}))
+		return pks
+	}
+	assert.Equal(t, []string{"1:3", "2:1", "3:0"}, scan(users, nil, nil))
+	assert.Equal(t, []string{"2:1"}, scan(users, []byte("2"), []byte("3")))
+	assert.Equal(t, []string{"2:1", "3:0"}, scan(users, []byte("2"), nil))
+	assert.Equal(t, []string{"1:1"}, scan(orders, nil, nil))
+
+	// Put replaces the whole row, and Delete removes it.
+	require.NoError(t, users.Put([]byte("1"), map[string][]byte{"name": []byte("carol")}))
+	row, err = users.Get([]byte("1"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"name": []byte("carol")}, row)
+	require.NoError(t, users.Delete([]byte("2")))
+	assert.Equal(t, []string{"1:1", "3:0"}, scan(users, nil, nil))
+
+	// Errors from the callback stop the scan.
+	stop := errors.New("stop")
+	calls := 0
+	err = users.Scan(nil, nil, func(pk []byte, row map[string][]byte) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+
+	// Corrupt rows are reported.
+	require.NoError(t, db.Set([]byte("users/9"), []byte{5, 'n'}))
+	_, err = users.Get([]byte("9"))
+	require.ErrorIs(t, err, errRowInvalid)
+	err = users.Scan(nil, nil, func(pk []byte, row map[string][]byte) error { return nil })
+	require.ErrorIs(t, err, errRowInvalid)
+
+	assert.Panics(t, func() { NewTableDB(db, "") })
+	assert.Panics(t, func() { NewTableDB(db, "a/b") })
+}
+
+func TestTableDBConcurrentScan(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			db, dir := newTempDB(t, dbType)
+			defer os.RemoveAll(dir)
+			defer db.Close()
+			tdb := NewTableDB(db, "t")
+			for i := 0; i < 100; i++ {
+				require.NoError(t, tdb.Put([]byte(fmt.Sprintf("%03d", i)), map[string][]byte{
+					"a": int642Bytes(int64(i)),
+					"b": []byte(fmt.Sprintf("row%d", i)),
+				}))
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 8)
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					i := 0
+					errs <- tdb.Scan(nil, nil, func(pk []byte, row map[string][]byte) error {
+						if string(pk) != fmt.Sprintf("%03d", i) || bytes2Int64(row["a"]) != int64(i) ||
+							string(row["b"]) != fmt.Sprintf("row%d", i) {
+							return fmt.Errorf("unexpected row %s = %v", pk, row)
+						}
+						i++
+						return nil
+					})
+				}()
+			}
+			// Writes to other keys proceed while scanning.
+			other := NewTableDB(db, "u")
+			for i := 0; i < 100; i++ {
+				require.NoError(t, other.Put([]byte{byte(i)}, map[string][]byte{"x": {1}}))
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TeeFailMode determines how TeeDB handles failed writes to its secondary database.
+type TeeFailMode int
+
+const (
+	// TeeFailIgnore logs failed writes to the secondary database, without failing the write.
+	TeeFailIgnore TeeFailMode = iota
+	// TeeFailError returns failed writes to the secondary database as errors. The write has been
+	// applied to the primary database regardless.
+	TeeFailError
+)
+
+// TeeOptions configures a TeeDB.
+type TeeOptions struct {
+	// FailMode determines how failed writes to the secondary database are handled.
+	FailMode TeeFailMode
+	// Logger receives failed writes to the secondary database. Defaults to no logging.
+	Logger Logger
+}
+
+// TeeDB wraps a primary database, and mirrors all writes made through it to a secondary
+// database, e.g. for audit logging or replication. Reads only go to the primary database.
+//
+// Writes are applied to the primary database first, and only mirrored if they succeed. They are
+// serialized, so that both databases see them in the same order. Compaction is not mirrored.
+type TeeDB struct {
+	primary   DB
+	secondary DB
+	failMode  TeeFailMode
+	logger    Logger
+
+	mtx sync.Mutex // serializes writes, so they are mirrored in order
+}
+
+var _ DB = (*TeeDB)(nil)
+
+// NewTeeDB creates a TeeDB mirroring writes to primary onto secondary.
+func NewTeeDB(primary, secondary DB, opts TeeOptions) *TeeDB {
+	return &TeeDB{
+		primary:   primary,
+		secondary: secondary,
+		failMode:  opts.FailMode,
+		logger:    loggerOrNop(opts.Logger),
+	}
+}
+
+// mirror applies a write which succeeded on the primary database to the secondary one. It
+// requires holding mtx.
+func (tdb *TeeDB) mirror(op string, fn func(DB) error) error {
+	err := fn(tdb.secondary)
+	if err == nil {
+		return nil
+	}
+	if tdb.failMode == TeeFailError {
+		return fmt.Errorf("failed to mirror %v to secondary database: %w", op, err)
+	}
+	tdb.logger.Error("failed to mirror write to secondary database", "op", op, "err", err)
+	return nil
+}
+
+// write applies a write to both databases.
+func (tdb *TeeDB) write(op string, fn func(DB) error) error {
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	if err := fn(tdb.primary); err != nil {
+		return err
+	}
+	return tdb.mirror(op, fn)
+}
+
+// Get implements DB.
+func (tdb *TeeDB) Get(key []byte) ([]byte, error) {
+	return tdb.primary.Get(key)
+}
+
+// Has implements DB.
+func (tdb *TeeDB) Has(key []byte) (bool, error) {
+	return tdb.primary.Has(key)
+}
+
+// MultiGet implements DB.
+func (tdb *TeeDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return tdb.primary.MultiGet(keys)
+}
+
+// Set implements DB.
+func (tdb *TeeDB) Set(key []byte, value []byte) error {
+	return tdb.write("Set", func(db DB) error { return db.Set(key, value) })
+}
+
+// SetSync implements DB.
+func (tdb *TeeDB) SetSync(key []byte, value []byte) error {
+	return tdb.write("SetSync", func(db DB) error { return db.SetSync(key, value) })
+}
+
+// Delete implements DB.
+func (tdb *TeeDB) Delete(key []byte) error {
+	return tdb.write("Delete", func(db DB) error { return db.Delete(key) })
+}
+
+// DeleteSync implements DB.
+func (tdb *TeeDB) DeleteSync(key []byte) error {
+	return tdb.write("DeleteSync", func(db DB) error { return db.DeleteSync(key) })
+}
+
+// CompareAndSet implements DB. The value is compared on the primary database only, and set on
+// the secondary one if swapped.
+func (tdb *TeeDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	swapped, err := tdb.primary.CompareAndSet(key, expected, newVal)
+	if err != nil || !swapped {
+		return swapped, err
+	}
+	return true, tdb.mirror("CompareAndSet", func(db DB) error { return db.Set(key, newVal) })
+}
+
+// DeleteRange implements DB.
+func (tdb *TeeDB) DeleteRange(start, end []byte) error {
+	return tdb.write("DeleteRange", func(db DB) error { return db.DeleteRange(start, end) })
+}
+
+// Iterator implements DB.
+func (tdb *TeeDB) Iterator(start, end []byte) (Iterator, error) {
+	return tdb.primary.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (tdb *TeeDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return tdb.primary.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (tdb *TeeDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return tdb.primary.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB. Only the primary database is compacted.
+func (tdb *TeeDB) Compact(start, end []byte) error {
+	return tdb.primary.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (tdb *TeeDB) WriteBatch(ops []BatchOp) error {
+	return tdb.write("WriteBatch", func(db DB) error { return db.WriteBatch(ops) })
+}
+
+// WriteBatchSync implements DB.
+func (tdb *TeeDB) WriteBatchSync(ops []BatchOp) error {
+	return tdb.write("WriteBatchSync", func(db DB) error { return db.WriteBatchSync(ops) })
+}
+
+// ApplyLog implements DB.
+func (tdb *TeeDB) ApplyLog(ops BatchOpList) error {
+	return tdb.write("ApplyLog", func(db DB) error { return db.ApplyLog(ops) })
+}
+
+// ForEach implements DB.
+func (tdb *TeeDB) ForEach(fn func(key, value []byte) error) error {
+	return tdb.primary.ForEach(fn)
+}
+
+// Close implements DB. Both databases are closed.
+func (tdb *TeeDB) Close() error {
+	err := tdb.primary.Close()
+	if serr := tdb.secondary.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (tdb *TeeDB) NewBatch() Batch {
+	return newTeeDBBatch(tdb, tdb.primary.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (tdb *TeeDB) NewBatchWithSize(expectedOps int) Batch {
+	return newTeeDBBatch(tdb, tdb.primary.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (tdb *TeeDB) Print() error {
+	return tdb.primary.Print()
+}
+
+// Stats implements DB.
+func (tdb *TeeDB) Stats() map[string]string {
+	return tdb.primary.Stats()
+}
+
+// teeDBBatch wraps a batch of the primary database, recording its operations so that they can be
+// mirrored once written.
+type teeDBBatch struct {
+	db    *TeeDB
+	batch Batch
+	ops   []BatchOp
+}
+
+var _ Batch = (*teeDBBatch)(nil)
+
+func newTeeDBBatch(db *TeeDB, batch Batch) *teeDBBatch {
+	return &teeDBBatch{
+		db:    db,
+		batch: batch,
+	}
+}
+
+// Set implements Batch.
+func (b *teeDBBatch) Set(key, value []byte) error {
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Value: cp(value)})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *teeDBBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Delete: true})
+	return nil
+}
+
+// Len implements Batch.
+func (b *teeDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *teeDBBatch) Write() error {
+	return b.write(b.batch.Write, false)
+}
+
+// WriteSync implements Batch.
+func (b *teeDBBatch) WriteSync() error {
+	return b.write(b.batch.WriteSync, true)
+}
+
+func (b *teeDBBatch) write(writeFn func() error, sync bool) error {
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+	if err := writeFn(); err != nil {
+		return err
+	}
+	ops := b.ops
+	b.ops = nil
+	return b.db.mirror("Batch.Write", func(db DB) error {
+		if sync {
+			return db.WriteBatchSync(ops)
+		}
+		return db.WriteBatch(ops)
+	})
+}
+
+// Close implements Batch.
+func (b *teeDBBatch) Close() error {
+	b.ops = nil
+	return b.batch.Close()
+}
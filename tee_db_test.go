@@ -0,0 +1,73 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeeDB(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	tdb := NewTeeDB(primary, secondary, TeeOptions{})
+
+	require.NoError(t, tdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, tdb.SetSync([]byte("b"), []byte{2}))
+	require.NoError(t, tdb.Set([]byte("c"), []byte{3}))
+	require.NoError(t, tdb.Delete([]byte("c")))
+	swapped, err := tdb.CompareAndSet([]byte("a"), []byte{1}, []byte{4})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	swapped, err = tdb.CompareAndSet([]byte("a"), []byte{1}, []byte{5})
+	require.NoError(t, err)
+	require.False(t, swapped)
+	require.NoError(t, tdb.WriteBatch([]BatchOp{{Key: []byte("d"), Value: []byte{6}}, {Key: []byte("b"), Delete: true}}))
+
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("e"), []byte{7}))
+	require.NoError(t, batch.Delete([]byte("d")))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+
+	expect := map[string][]byte{"a": {4}, "e": {7}}
+	assertKeyValues(t, primary, expect)
+	assertKeyValues(t, secondary, expect)
+	assertKeyValues(t, tdb, expect)
+
+	require.NoError(t, tdb.DeleteRange(nil, nil))
+	assertKeyValues(t, secondary, map[string][]byte{})
+}
+
+func TestTeeDBSecondaryFailure(t *testing.T) {
+	primary := NewMemDB()
+	secondary := NewMockDBWrapping(NewMemDB())
+	secondary.SetError("Set", errors.New("boom"))
+	secondary.SetError("WriteBatch", errors.New("boom"))
+
+	// Failures are logged and ignored by default.
+	logger := &bufferLogger{}
+	tdb := NewTeeDB(primary, secondary, TeeOptions{FailMode: TeeFailIgnore, Logger: logger})
+	require.NoError(t, tdb.Set([]byte("a"), []byte{1}))
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assertKeyValues(t, primary, map[string][]byte{"a": {1}, "b": {2}})
+	assertKeyValues(t, secondary, map[string][]byte{})
+	assert.Len(t, logger.lines(), 2)
+
+	// Or returned, after the write was applied to the primary database.
+	tdb = NewTeeDB(primary, secondary, TeeOptions{FailMode: TeeFailError})
+	err := tdb.Set([]byte("c"), []byte{3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	checkValue(t, primary, []byte("c"), []byte{3})
+
+	// Failed writes to the primary database are not mirrored.
+	failing := NewMockDBWrapping(NewMemDB())
+	failing.SetError("Delete", errors.New("boom"))
+	tdb = NewTeeDB(failing, NewMockDBWrapping(NewMemDB()), TeeOptions{})
+	require.Error(t, tdb.Delete([]byte("a")))
+	assert.Zero(t, tdb.secondary.(*MockDB).Calls["Delete"])
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ThrottledDB wraps a database, and limits the rate of reads and writes to it, to keep a single
+// component from overwhelming a shared database. Operations over the limit block until they are
+// allowed. Reads are Get, Has, MultiGet, ForEach and the creation of iterators, while writes are
+// all mutations, including writing a batch; each counts as one operation regardless of its size.
+//
+// The limits do not allow bursts: operations are spread out evenly, one per 1/rps seconds.
+type ThrottledDB struct {
+	db    DB
+	read  *rate.Limiter       // nil if unlimited
+	write *rate.Limiter       // nil if unlimited
+	now   func() time.Time    // the clock, replaced in tests
+	sleep func(time.Duration) // replaced in tests
+}
+
+var _ DB = (*ThrottledDB)(nil)
+
+// NewThrottledDB creates a ThrottledDB wrapping the given database, allowing at most readRPS
+// reads and writeRPS writes per second. A non-positive rate means unlimited.
+func NewThrottledDB(inner DB, readRPS, writeRPS float64) *ThrottledDB {
+	return newThrottledDB(inner, readRPS, writeRPS, time.Now, time.Sleep)
+}
+
+// newThrottledDB creates a ThrottledDB using the given clock.
+func newThrottledDB(inner DB, readRPS, writeRPS float64, now func() time.Time,
+	sleep func(time.Duration)) *ThrottledDB {
+	return &ThrottledDB{
+		db:    inner,
+		read:  newThrottleLimiter(readRPS),
+		write: newThrottleLimiter(writeRPS),
+		now:   now,
+		sleep: sleep,
+	}
+}
+
+// newThrottleLimiter returns a limiter for the given rate, or nil if it is unlimited.
+func newThrottleLimiter(rps float64) *rate.Limiter {
+	if rps <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(rps), 1)
+}
+
+// wait blocks until the limiter allows an operation.
+func (tdb *ThrottledDB) wait(limiter *rate.Limiter) {
+	if limiter == nil {
+		return
+	}
+	now := tdb.now()
+	// With a burst of 1, a single token can always be reserved.
+	if delay := limiter.ReserveN(now, 1).DelayFrom(now); delay > 0 {
+		tdb.sleep(delay)
+	}
+}
+
+// Get implements DB.
+func (tdb *ThrottledDB) Get(key []byte) ([]byte, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.Get(key)
+}
+
+// Has implements DB.
+func (tdb *ThrottledDB) Has(key []byte) (bool, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (tdb *ThrottledDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (tdb *ThrottledDB) Set(key []byte, value []byte) error {
+	tdb.wait(tdb.write)
+	return tdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (tdb *ThrottledDB) SetSync(key []byte, value []byte) error {
+	tdb.wait(tdb.write)
+	return tdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (tdb *ThrottledDB) Delete(key []byte) error {
+	tdb.wait(tdb.write)
+	return tdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (tdb *ThrottledDB) DeleteSync(key []byte) error {
+	tdb.wait(tdb.write)
+	return tdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (tdb *ThrottledDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	tdb.wait(tdb.write)
+	return tdb.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (tdb *ThrottledDB) DeleteRange(start, end []byte) error {
+	tdb.wait(tdb.write)
+	return tdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (tdb *ThrottledDB) Iterator(start, end []byte) (Iterator, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (tdb *ThrottledDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (tdb *ThrottledDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	tdb.wait(tdb.read)
+	return tdb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (tdb *ThrottledDB) Compact(start, end []byte) error {
+	return tdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (tdb *ThrottledDB) WriteBatch(ops []BatchOp) error {
+	tdb.wait(tdb.write)
+	return tdb.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (tdb *ThrottledDB) WriteBatchSync(ops []BatchOp) error {
+	tdb.wait(tdb.write)
+	return tdb.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (tdb *ThrottledDB) ApplyLog(ops BatchOpList) error {
+	tdb.wait(tdb.write)
+	return tdb.db.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (tdb *ThrottledDB) ForEach(fn func(key, value []byte) error) error {
+	tdb.wait(tdb.read)
+	return tdb.db.ForEach(fn)
+}
+
+// Close implements DB.
+func (tdb *ThrottledDB) Close() error {
+	return tdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (tdb *ThrottledDB) NewBatch() Batch {
+	return &throttledBatch{db: tdb, batch: tdb.db.NewBatch()}
+}
+
+// NewBatchWithSize implements DB.
+func (tdb *ThrottledDB) NewBatchWithSize(expectedOps int) Batch {
+	return &throttledBatch{db: tdb, batch: tdb.db.NewBatchWithSize(expectedOps)}
+}
+
+// Print implements DB.
+func (tdb *ThrottledDB) Print() error {
+	return tdb.db.Print()
+}
+
+// Stats implements DB.
+func (tdb *ThrottledDB) Stats() map[string]string {
+	return tdb.db.Stats()
+}
+
+// throttledBatch is a batch whose writes are limited by a ThrottledDB.
+type throttledBatch struct {
+	db    *ThrottledDB
+	batch Batch
+}
+
+var _ Batch = (*throttledBatch)(nil)
+
+// Set implements Batch.
+func (b *throttledBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *throttledBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+// Len implements Batch.
+func (b *throttledBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *throttledBatch) Write() error {
+	b.db.wait(b.db.write)
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *throttledBatch) WriteSync() error {
+	b.db.wait(b.db.write)
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *throttledBatch) Close() error {
+	return b.batch.Close()
+}
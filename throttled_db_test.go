@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock which only advances when sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestThrottledDBWrites(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	tdb := newThrottledDB(NewMemDB(), 0, 10, clock.Now, clock.Sleep)
+	start := clock.Now()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tdb.Set([]byte("key"), []byte{byte(i)}))
+	}
+	assert.GreaterOrEqual(t, clock.Now().Sub(start), 9*time.Second)
+
+	// Reads are unlimited, and do not count against writes.
+	before := clock.Now()
+	for i := 0; i < 100; i++ {
+		checkValue(t, tdb, []byte("key"), []byte{99})
+	}
+	assert.Equal(t, before, clock.Now())
+}
+
+func TestThrottledDBReadsAndBatches(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	tdb := newThrottledDB(NewMemDB(), 2, 1, clock.Now, clock.Sleep)
+	start := clock.Now()
+
+	// Writing a batch counts as a single write, regardless of its size.
+	for i := 0; i < 3; i++ {
+		batch := tdb.NewBatch()
+		require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+		require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+		require.NoError(t, batch.Write())
+		require.NoError(t, batch.Close())
+	}
+	assert.Equal(t, 2*time.Second, clock.Now().Sub(start))
+
+	start = clock.Now()
+	for i := 0; i < 5; i++ {
+		_, err := tdb.Has([]byte("a"))
+		require.NoError(t, err)
+	}
+	itr, err := tdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, itr.Close())
+	assert.Equal(t, 2500*time.Millisecond, clock.Now().Sub(start))
+}
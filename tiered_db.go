@@ -0,0 +1,362 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// TieredDB layers several databases ordered from fastest to slowest, e.g. a MemDB in front of a
+// GoLevelDB. Reads check each tier in order, and values found in a slower tier are promoted to
+// all faster tiers. Writes go to every tier, from the slowest to the fastest, so that a value is
+// persisted before it is cached. Iterators merge all tiers, with faster tiers taking precedence
+// for keys present in several of them.
+//
+// Writes and promotions lock the keys they touch, so that a Get racing with a write can not
+// promote the value it read after the write has replaced or deleted it. Writes to several keys,
+// i.e. batches and DeleteRange, lock all keys.
+//
+// Unlike CachingDB, faster tiers are not bounded in size, and may be persistent databases. The
+// slowest tier is the source of truth: faster tiers should only hold values written through the
+// TieredDB, or promoted from slower tiers.
+type TieredDB struct {
+	tiers []DB
+	locks LockManager
+}
+
+var _ DB = (*TieredDB)(nil)
+
+// NewTieredDB creates a TieredDB over the given databases, ordered from fastest to slowest. It
+// panics if no tiers are given.
+func NewTieredDB(tiers []DB) *TieredDB {
+	if len(tiers) == 0 {
+		panic("TieredDB requires at least one tier")
+	}
+	return &TieredDB{tiers: tiers}
+}
+
+// slowest returns the slowest tier.
+func (tdb *TieredDB) slowest() DB {
+	return tdb.tiers[len(tdb.tiers)-1]
+}
+
+// eachTier calls fn for each tier from the slowest to the fastest, stopping at the first error.
+func (tdb *TieredDB) eachTier(fn func(DB) error) error {
+	for i := len(tdb.tiers) - 1; i >= 0; i-- {
+		if err := fn(tdb.tiers[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get implements DB. A value found in a slower tier is promoted to all faster tiers.
+func (tdb *TieredDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, i, err := tdb.find(key)
+	if err != nil || value == nil || i == 0 {
+		return value, err
+	}
+
+	// The value must be promoted. It is read again under the key's lock, since a write may have
+	// replaced or deleted it since it was read.
+	tdb.locks.Lock(key)
+	defer tdb.locks.Unlock(key)
+	value, i, err = tdb.find(key)
+	if err != nil || value == nil {
+		return value, err
+	}
+	for j := i - 1; j >= 0; j-- {
+		if err := tdb.tiers[j].Set(key, value); err != nil {
+			return nil, fmt.Errorf("failed to promote key %X to tier %d: %w", key, j, err)
+		}
+	}
+	return value, nil
+}
+
+// find returns the value of a key from the fastest tier holding it, and the index of that tier.
+func (tdb *TieredDB) find(key []byte) ([]byte, int, error) {
+	for i, tier := range tdb.tiers {
+		value, err := tier.Get(key)
+		if err != nil || value != nil {
+			return value, i, err
+		}
+	}
+	return nil, 0, nil
+}
+
+// writeKey calls fn for each tier from the slowest to the fastest while holding the key's lock.
+func (tdb *TieredDB) writeKey(key []byte, fn func(DB) error) error {
+	tdb.locks.Lock(key)
+	defer tdb.locks.Unlock(key)
+	return tdb.eachTier(fn)
+}
+
+// writeAll calls fn for each tier from the slowest to the fastest while holding all key locks.
+func (tdb *TieredDB) writeAll(fn func(DB) error) error {
+	tdb.locks.LockRange(nil, nil)
+	defer tdb.locks.UnlockRange(nil, nil)
+	return tdb.eachTier(fn)
+}
+
+// Has implements DB. It does not promote values.
+func (tdb *TieredDB) Has(key []byte) (bool, error) {
+	for _, tier := range tdb.tiers {
+		ok, err := tier.Has(key)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// MultiGet implements DB.
+func (tdb *TieredDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return multiGet(tdb, keys)
+}
+
+// Set implements DB.
+func (tdb *TieredDB) Set(key []byte, value []byte) error {
+	return tdb.writeKey(key, func(tier DB) error {
+		return tier.Set(key, value)
+	})
+}
+
+// SetSync implements DB.
+func (tdb *TieredDB) SetSync(key []byte, value []byte) error {
+	return tdb.writeKey(key, func(tier DB) error {
+		return tier.SetSync(key, value)
+	})
+}
+
+// Delete implements DB.
+func (tdb *TieredDB) Delete(key []byte) error {
+	return tdb.writeKey(key, func(tier DB) error {
+		return tier.Delete(key)
+	})
+}
+
+// DeleteSync implements DB.
+func (tdb *TieredDB) DeleteSync(key []byte) error {
+	return tdb.writeKey(key, func(tier DB) error {
+		return tier.DeleteSync(key)
+	})
+}
+
+// CompareAndSet implements DB. The comparison is made atomically against the slowest tier, and
+// the new value is then written to the faster tiers.
+func (tdb *TieredDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	tdb.locks.Lock(key)
+	defer tdb.locks.Unlock(key)
+	swapped, err := tdb.slowest().CompareAndSet(key, expected, newVal)
+	if err != nil || !swapped {
+		return swapped, err
+	}
+	for i := len(tdb.tiers) - 2; i >= 0; i-- {
+		if err := tdb.tiers[i].Set(key, newVal); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// DeleteRange implements DB.
+func (tdb *TieredDB) DeleteRange(start, end []byte) error {
+	return tdb.writeAll(func(tier DB) error {
+		return tier.DeleteRange(start, end)
+	})
+}
+
+// Iterator implements DB. Values are not promoted.
+func (tdb *TieredDB) Iterator(start, end []byte) (Iterator, error) {
+	return tdb.iterator(start, end, false)
+}
+
+// ReverseIterator implements DB. Values are not promoted.
+func (tdb *TieredDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return tdb.iterator(start, end, true)
+}
+
+func (tdb *TieredDB) iterator(start, end []byte, reverse bool) (Iterator, error) {
+	sources := make([]Iterator, 0, len(tdb.tiers))
+	for _, tier := range tdb.tiers {
+		var (
+			itr Iterator
+			err error
+		)
+		if reverse {
+			itr, err = tier.ReverseIterator(start, end)
+		} else {
+			itr, err = tier.Iterator(start, end)
+		}
+		if err != nil {
+			for _, source := range sources {
+				source.Close()
+			}
+			return nil, err
+		}
+		sources = append(sources, itr)
+	}
+	return newMergedIterator(sources, reverse), nil
+}
+
+// IteratorWithContext implements DB.
+func (tdb *TieredDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return iteratorWithContext(ctx, tdb, start, end)
+}
+
+// Compact implements DB.
+func (tdb *TieredDB) Compact(start, end []byte) error {
+	return tdb.eachTier(func(tier DB) error {
+		return tier.Compact(start, end)
+	})
+}
+
+// WriteBatch implements DB. The operations are applied atomically within each tier, but not
+// across tiers.
+func (tdb *TieredDB) WriteBatch(ops []BatchOp) error {
+	return tdb.writeAll(func(tier DB) error {
+		return tier.WriteBatch(ops)
+	})
+}
+
+// WriteBatchSync implements DB.
+func (tdb *TieredDB) WriteBatchSync(ops []BatchOp) error {
+	return tdb.writeAll(func(tier DB) error {
+		return tier.WriteBatchSync(ops)
+	})
+}
+
+// ApplyLog implements DB.
+func (tdb *TieredDB) ApplyLog(ops BatchOpList) error {
+	return tdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (tdb *TieredDB) ForEach(fn func(key, value []byte) error) error {
+	return forEach(tdb, fn)
+}
+
+// Close implements DB. Every tier is closed, and the first error is returned.
+func (tdb *TieredDB) Close() error {
+	var err error
+	for _, tier := range tdb.tiers {
+		if cerr := tier.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (tdb *TieredDB) NewBatch() Batch {
+	return newTieredDBBatch(tdb, func(tier DB) Batch { return tier.NewBatch() })
+}
+
+// NewBatchWithSize implements DB.
+func (tdb *TieredDB) NewBatchWithSize(expectedOps int) Batch {
+	return newTieredDBBatch(tdb, func(tier DB) Batch { return tier.NewBatchWithSize(expectedOps) })
+}
+
+// Print implements DB.
+func (tdb *TieredDB) Print() error {
+	itr, err := tdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of each tier are included, prefixed by the tier index.
+func (tdb *TieredDB) Stats() map[string]string {
+	stats := map[string]string{
+		"database.type":  "tieredDB",
+		"database.tiers": fmt.Sprintf("%d", len(tdb.tiers)),
+	}
+	for i, tier := range tdb.tiers {
+		for k, v := range tier.Stats() {
+			stats[fmt.Sprintf("tier%d.%s", i, k)] = v
+		}
+	}
+	return stats
+}
+
+// tieredDBBatch holds a batch for each tier. Like TieredDB writes, it is written to each tier
+// from the slowest to the fastest, holding all key locks.
+type tieredDBBatch struct {
+	db      *TieredDB
+	batches []Batch
+}
+
+var _ Batch = (*tieredDBBatch)(nil)
+
+func newTieredDBBatch(tdb *TieredDB, newBatch func(DB) Batch) *tieredDBBatch {
+	batches := make([]Batch, 0, len(tdb.tiers))
+	for _, tier := range tdb.tiers {
+		batches = append(batches, newBatch(tier))
+	}
+	return &tieredDBBatch{db: tdb, batches: batches}
+}
+
+// each calls fn for each batch from the slowest tier to the fastest, stopping at the first error.
+func (b *tieredDBBatch) each(fn func(Batch) error) error {
+	for i := len(b.batches) - 1; i >= 0; i-- {
+		if err := fn(b.batches[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set implements Batch.
+func (b *tieredDBBatch) Set(key, value []byte) error {
+	return b.each(func(batch Batch) error {
+		return batch.Set(key, value)
+	})
+}
+
+// Delete implements Batch.
+func (b *tieredDBBatch) Delete(key []byte) error {
+	return b.each(func(batch Batch) error {
+		return batch.Delete(key)
+	})
+}
+
+// Len implements Batch.
+func (b *tieredDBBatch) Len() int {
+	return b.batches[0].Len()
+}
+
+// Write implements Batch.
+func (b *tieredDBBatch) Write() error {
+	b.db.locks.LockRange(nil, nil)
+	defer b.db.locks.UnlockRange(nil, nil)
+	return b.each(func(batch Batch) error {
+		return batch.Write()
+	})
+}
+
+// WriteSync implements Batch.
+func (b *tieredDBBatch) WriteSync() error {
+	b.db.locks.LockRange(nil, nil)
+	defer b.db.locks.UnlockRange(nil, nil)
+	return b.each(func(batch Batch) error {
+		return batch.WriteSync()
+	})
+}
+
+// Close implements Batch. Every batch is closed, and the first error is returned.
+func (b *tieredDBBatch) Close() error {
+	var err error
+	for _, batch := range b.batches {
+		if cerr := batch.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
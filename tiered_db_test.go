@@ -0,0 +1,180 @@
+package db
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredDBPromotion(t *testing.T) {
+	fast, medium, slow := NewMemDB(), NewMemDB(), NewMemDB()
+	tdb := NewTieredDB([]DB{fast, medium, slow})
+
+	// Writes go to every tier.
+	require.NoError(t, tdb.Set([]byte("a"), []byte{1}))
+	for _, tier := range []DB{fast, medium, slow} {
+		checkValue(t, tier, []byte("a"), []byte{1})
+	}
+
+	// Values only present in slower tiers are promoted to all faster tiers when read.
+	require.NoError(t, slow.Set([]byte("b"), []byte{2}))
+	require.NoError(t, medium.Set([]byte("c"), []byte{3}))
+	checkValue(t, tdb, []byte("b"), []byte{2})
+	checkValue(t, tdb, []byte("c"), []byte{3})
+	checkValue(t, fast, []byte("b"), []byte{2})
+	checkValue(t, medium, []byte("b"), []byte{2})
+	checkValue(t, fast, []byte("c"), []byte{3})
+	checkValue(t, slow, []byte("c"), nil)
+
+	// Has does not promote.
+	require.NoError(t, slow.Set([]byte("d"), []byte{4}))
+	ok, err := tdb.Has([]byte("d"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	checkValue(t, fast, []byte("d"), nil)
+
+	// Deletes remove keys from every tier.
+	require.NoError(t, tdb.Delete([]byte("b")))
+	for _, tier := range []DB{fast, medium, slow} {
+		checkValue(t, tier, []byte("b"), nil)
+	}
+	checkValue(t, tdb, []byte("b"), nil)
+
+	// CompareAndSet compares against the slowest tier, and updates them all.
+	swapped, err := tdb.CompareAndSet([]byte("d"), []byte{4}, []byte{5})
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	for _, tier := range []DB{fast, medium, slow} {
+		checkValue(t, tier, []byte("d"), []byte{5})
+	}
+
+	// Batches write to every tier.
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("e"), []byte{6}))
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.Equal(t, 2, batch.Len())
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	for _, tier := range []DB{fast, medium, slow} {
+		checkValue(t, tier, []byte("a"), nil)
+		checkValue(t, tier, []byte("e"), []byte{6})
+	}
+}
+
+// pausingGetDB is a MemDB whose next Get, once armed, reads its value and then waits for resume
+// before returning it.
+type pausingGetDB struct {
+	*MemDB
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (db *pausingGetDB) Get(key []byte) ([]byte, error) {
+	value, err := db.MemDB.Get(key)
+	select {
+	case db.paused <- struct{}{}:
+		<-db.resume
+	default:
+	}
+	return value, err
+}
+
+func TestTieredDBConcurrentGetDelete(t *testing.T) {
+	// A Get which read a value from the slow tier before a Delete completed must not promote it
+	// afterwards, or the deleted value would shadow the slow tier for good.
+	fast := NewMemDB()
+	slow := &pausingGetDB{MemDB: NewMemDB(), paused: make(chan struct{}), resume: make(chan struct{})}
+	tdb := NewTieredDB([]DB{fast, slow})
+	require.NoError(t, slow.Set([]byte("a"), []byte{1}))
+
+	done := make(chan []byte)
+	go func() {
+		value, err := tdb.Get([]byte("a"))
+		assert.NoError(t, err)
+		done <- value
+	}()
+	<-slow.paused
+	require.NoError(t, tdb.Delete([]byte("a")))
+	close(slow.resume)
+	<-done
+	checkValue(t, fast, []byte("a"), nil)
+	checkValue(t, tdb, []byte("a"), nil)
+
+	// Racing promotions with writes and deletes always leaves the tiers consistent.
+	fast, slowest := NewMemDB(), NewMemDB()
+	tdb = NewTieredDB([]DB{fast, slowest})
+	for i := 0; i < 200; i++ {
+		key := []byte{byte(i)}
+		require.NoError(t, slowest.Set(key, []byte{1}))
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := tdb.Get(key)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, tdb.Delete(key))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := tdb.Get(key)
+			assert.NoError(t, err)
+		}()
+		wg.Wait()
+		checkValue(t, fast, key, nil)
+		checkValue(t, slowest, key, nil)
+	}
+}
+
+func TestTieredDBIterator(t *testing.T) {
+	fast, slow := NewMemDB(), NewMemDB()
+	tdb := NewTieredDB([]DB{fast, slow})
+
+	require.NoError(t, slow.Set([]byte("a"), []byte{1}))
+	require.NoError(t, slow.Set([]byte("b"), []byte{2}))
+	require.NoError(t, slow.Set([]byte("d"), []byte{4}))
+	require.NoError(t, fast.Set([]byte("b"), []byte{20})) // stale in slow, takes precedence
+	require.NoError(t, fast.Set([]byte("c"), []byte{30}))
+	require.NoError(t, fast.Set([]byte("e"), []byte{50}))
+
+	itr, err := tdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("a"), []byte{1})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("b"), []byte{20})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("c"), []byte{30})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("d"), []byte{4})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("e"), []byte{50})
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+
+	itr, err = tdb.ReverseIterator([]byte("b"), []byte("e"))
+	require.NoError(t, err)
+	checkItem(t, itr, []byte("d"), []byte{4})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("c"), []byte{30})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte("b"), []byte{20})
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	// Seeking repositions every tier.
+	itr, err = tdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Seek([]byte("bb")))
+	checkItem(t, itr, []byte("c"), []byte{30})
+	require.True(t, itr.Seek([]byte("a")))
+	checkItem(t, itr, []byte("a"), []byte{1})
+	require.NoError(t, itr.Close())
+
+	// Iteration does not promote values.
+	checkValue(t, fast, []byte("a"), nil)
+}
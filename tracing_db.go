@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+)
+
+// tracingKeyLimit is the maximum number of key bytes logged by TracingDB.
+const tracingKeyLimit = 32
+
+// TracingDB wraps a database, and logs every operation at debug level with the operation name,
+// the hex-encoded key truncated to 32 bytes, the value length, and the duration of the call.
+// Batches created by it log their operations as well.
+type TracingDB struct {
+	db     DB
+	logger Logger
+}
+
+var _ DB = (*TracingDB)(nil)
+
+// NewTracingDB creates a TracingDB wrapping the given database.
+func NewTracingDB(db DB, logger Logger) *TracingDB {
+	return &TracingDB{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// traceKey formats a key for logging.
+func traceKey(key []byte) string {
+	if len(key) > tracingKeyLimit {
+		return hex.EncodeToString(key[:tracingKeyLimit]) + "..."
+	}
+	return hex.EncodeToString(key)
+}
+
+// trace logs an operation which started at the given time. A negative valueLen is omitted.
+func trace(logger Logger, op string, key []byte, valueLen int, start time.Time, err error) {
+	keyvals := []interface{}{"op", op, "key", traceKey(key)}
+	if valueLen >= 0 {
+		keyvals = append(keyvals, "valueLen", valueLen)
+	}
+	keyvals = append(keyvals, "duration", time.Since(start))
+	if err != nil {
+		keyvals = append(keyvals, "err", err)
+	}
+	logger.Debug("db operation", keyvals...)
+}
+
+// Get implements DB.
+func (tdb *TracingDB) Get(key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := tdb.db.Get(key)
+	trace(tdb.logger, "Get", key, len(value), start, err)
+	return value, err
+}
+
+// Has implements DB.
+func (tdb *TracingDB) Has(key []byte) (bool, error) {
+	start := time.Now()
+	ok, err := tdb.db.Has(key)
+	trace(tdb.logger, "Has", key, -1, start, err)
+	return ok, err
+}
+
+// MultiGet implements DB.
+func (tdb *TracingDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	start := time.Now()
+	values, err := tdb.db.MultiGet(keys)
+	trace(tdb.logger, "MultiGet", nil, len(keys), start, err)
+	return values, err
+}
+
+// Set implements DB.
+func (tdb *TracingDB) Set(key []byte, value []byte) error {
+	start := time.Now()
+	err := tdb.db.Set(key, value)
+	trace(tdb.logger, "Set", key, len(value), start, err)
+	return err
+}
+
+// SetSync implements DB.
+func (tdb *TracingDB) SetSync(key []byte, value []byte) error {
+	start := time.Now()
+	err := tdb.db.SetSync(key, value)
+	trace(tdb.logger, "SetSync", key, len(value), start, err)
+	return err
+}
+
+// Delete implements DB.
+func (tdb *TracingDB) Delete(key []byte) error {
+	start := time.Now()
+	err := tdb.db.Delete(key)
+	trace(tdb.logger, "Delete", key, -1, start, err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (tdb *TracingDB) DeleteSync(key []byte) error {
+	start := time.Now()
+	err := tdb.db.DeleteSync(key)
+	trace(tdb.logger, "DeleteSync", key, -1, start, err)
+	return err
+}
+
+// CompareAndSet implements DB.
+func (tdb *TracingDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	start := time.Now()
+	swapped, err := tdb.db.CompareAndSet(key, expected, newVal)
+	trace(tdb.logger, "CompareAndSet", key, len(newVal), start, err)
+	return swapped, err
+}
+
+// DeleteRange implements DB. The start key is logged as the key.
+func (tdb *TracingDB) DeleteRange(start, end []byte) error {
+	now := time.Now()
+	err := tdb.db.DeleteRange(start, end)
+	trace(tdb.logger, "DeleteRange", start, -1, now, err)
+	return err
+}
+
+// Iterator implements DB. The start key is logged as the key.
+func (tdb *TracingDB) Iterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := tdb.db.Iterator(start, end)
+	trace(tdb.logger, "Iterator", start, -1, now, err)
+	return itr, err
+}
+
+// ReverseIterator implements DB. The start key is logged as the key.
+func (tdb *TracingDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := tdb.db.ReverseIterator(start, end)
+	trace(tdb.logger, "ReverseIterator", start, -1, now, err)
+	return itr, err
+}
+
+// IteratorWithContext implements DB. The start key is logged as the key.
+func (tdb *TracingDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := tdb.db.IteratorWithContext(ctx, start, end)
+	trace(tdb.logger, "IteratorWithContext", start, -1, now, err)
+	return itr, err
+}
+
+// Compact implements DB. The start key is logged as the key.
+func (tdb *TracingDB) Compact(start, end []byte) error {
+	now := time.Now()
+	err := tdb.db.Compact(start, end)
+	trace(tdb.logger, "Compact", start, -1, now, err)
+	return err
+}
+
+// WriteBatch implements DB. The number of operations is logged as the value length.
+func (tdb *TracingDB) WriteBatch(ops []BatchOp) error {
+	start := time.Now()
+	err := tdb.db.WriteBatch(ops)
+	trace(tdb.logger, "WriteBatch", nil, len(ops), start, err)
+	return err
+}
+
+// WriteBatchSync implements DB. The number of operations is logged as the value length.
+func (tdb *TracingDB) WriteBatchSync(ops []BatchOp) error {
+	start := time.Now()
+	err := tdb.db.WriteBatchSync(ops)
+	trace(tdb.logger, "WriteBatchSync", nil, len(ops), start, err)
+	return err
+}
+
+// ApplyLog implements DB. The number of operations is logged as the value length.
+func (tdb *TracingDB) ApplyLog(ops BatchOpList) error {
+	start := time.Now()
+	err := tdb.db.ApplyLog(ops)
+	trace(tdb.logger, "ApplyLog", nil, len(ops), start, err)
+	return err
+}
+
+// ForEach implements DB.
+func (tdb *TracingDB) ForEach(fn func(key, value []byte) error) error {
+	start := time.Now()
+	err := tdb.db.ForEach(fn)
+	trace(tdb.logger, "ForEach", nil, -1, start, err)
+	return err
+}
+
+// Close implements DB.
+func (tdb *TracingDB) Close() error {
+	start := time.Now()
+	err := tdb.db.Close()
+	trace(tdb.logger, "Close", nil, -1, start, err)
+	return err
+}
+
+// NewBatch implements DB.
+func (tdb *TracingDB) NewBatch() Batch {
+	trace(tdb.logger, "NewBatch", nil, -1, time.Now(), nil)
+	return newTracingBatch(tdb.db.NewBatch(), tdb.logger)
+}
+
+// NewBatchWithSize implements DB.
+func (tdb *TracingDB) NewBatchWithSize(expectedOps int) Batch {
+	trace(tdb.logger, "NewBatchWithSize", nil, -1, time.Now(), nil)
+	return newTracingBatch(tdb.db.NewBatchWithSize(expectedOps), tdb.logger)
+}
+
+// Print implements DB.
+func (tdb *TracingDB) Print() error {
+	start := time.Now()
+	err := tdb.db.Print()
+	trace(tdb.logger, "Print", nil, -1, start, err)
+	return err
+}
+
+// Stats implements DB.
+func (tdb *TracingDB) Stats() map[string]string {
+	start := time.Now()
+	stats := tdb.db.Stats()
+	trace(tdb.logger, "Stats", nil, -1, start, nil)
+	return stats
+}
+
+// tracingBatch wraps a batch, and logs every operation.
+type tracingBatch struct {
+	batch  Batch
+	logger Logger
+}
+
+var _ Batch = (*tracingBatch)(nil)
+
+func newTracingBatch(batch Batch, logger Logger) *tracingBatch {
+	return &tracingBatch{
+		batch:  batch,
+		logger: logger,
+	}
+}
+
+// Set implements Batch.
+func (b *tracingBatch) Set(key, value []byte) error {
+	start := time.Now()
+	err := b.batch.Set(key, value)
+	trace(b.logger, "Batch.Set", key, len(value), start, err)
+	return err
+}
+
+// Delete implements Batch.
+func (b *tracingBatch) Delete(key []byte) error {
+	start := time.Now()
+	err := b.batch.Delete(key)
+	trace(b.logger, "Batch.Delete", key, -1, start, err)
+	return err
+}
+
+// Len implements Batch.
+func (b *tracingBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *tracingBatch) Write() error {
+	start := time.Now()
+	err := b.batch.Write()
+	trace(b.logger, "Batch.Write", nil, -1, start, err)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *tracingBatch) WriteSync() error {
+	start := time.Now()
+	err := b.batch.WriteSync()
+	trace(b.logger, "Batch.WriteSync", nil, -1, start, err)
+	return err
+}
+
+// Close implements Batch.
+func (b *tracingBatch) Close() error {
+	start := time.Now()
+	err := b.batch.Close()
+	trace(b.logger, "Batch.Close", nil, -1, start, err)
+	return err
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bufferLogger is a Logger writing one line per message to a buffer. Backends log from their own
+// goroutines, so the buffer is guarded by a mutex.
+type bufferLogger struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+var _ Logger = (*bufferLogger)(nil)
+
+func (l *bufferLogger) log(level, msg string, keyvals ...interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	fmt.Fprintf(&l.buf, "%s %s", level, msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&l.buf, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	l.buf.WriteString("\n")
+}
+
+func (l *bufferLogger) Debug(msg string, keyvals ...interface{}) { l.log("D", msg, keyvals...) }
+func (l *bufferLogger) Info(msg string, keyvals ...interface{})  { l.log("I", msg, keyvals...) }
+func (l *bufferLogger) Error(msg string, keyvals ...interface{}) { l.log("E", msg, keyvals...) }
+
+// String returns the logged lines as a single string.
+func (l *bufferLogger) String() string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.buf.String()
+}
+
+// lines returns the logged lines, and resets the buffer.
+func (l *bufferLogger) lines() []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	lines := strings.Split(strings.TrimSpace(l.buf.String()), "\n")
+	l.buf.Reset()
+	return lines
+}
+
+func TestTracingDB(t *testing.T) {
+	logger := &bufferLogger{}
+	db := NewTracingDB(NewMemDB(), logger)
+
+	require.NoError(t, db.Set([]byte{0x01, 0x02}, []byte("value")))
+	_, err := db.Get([]byte{0x01, 0x02})
+	require.NoError(t, err)
+	_, err = db.Has([]byte{0x01})
+	require.NoError(t, err)
+	require.NoError(t, db.Delete([]byte{0x01, 0x02}))
+
+	lines := logger.lines()
+	require.Len(t, lines, 4)
+	require.Regexp(t, `^D db operation op=Set key=0102 valueLen=5 duration=\S+$`, lines[0])
+	require.Regexp(t, `^D db operation op=Get key=0102 valueLen=5 duration=\S+$`, lines[1])
+	require.Regexp(t, `^D db operation op=Has key=01 duration=\S+$`, lines[2])
+	require.Regexp(t, `^D db operation op=Delete key=0102 duration=\S+$`, lines[3])
+
+	// Long keys are truncated, and errors are logged.
+	_ = db.Set(bytes.Repeat([]byte{0xff}, 40), nil)
+	lines = logger.lines()
+	require.Len(t, lines, 1)
+	require.Regexp(t, fmt.Sprintf(`^D db operation op=Set key=%s\.\.\. valueLen=0 duration=\S+ err=%s$`,
+		strings.Repeat("ff", 32), errValueNil), lines[0])
+}
+
+func TestTracingDBBatch(t *testing.T) {
+	logger := &bufferLogger{}
+	db := NewTracingDB(NewMemDB(), logger)
+
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	batch = db.NewBatchWithSize(1)
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+
+	ops := []string{}
+	for _, line := range logger.lines() {
+		ops = append(ops, strings.Fields(line)[3])
+	}
+	require.Equal(t, []string{
+		"op=NewBatch", "op=Batch.Set", "op=Batch.Delete", "op=Batch.Write", "op=Batch.Close",
+		"op=NewBatchWithSize", "op=Batch.WriteSync", "op=Batch.Close",
+	}, ops)
+}
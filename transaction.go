@@ -0,0 +1,29 @@
+package db
+
+import "errors"
+
+// errTxDone is returned when using a transaction which has been committed or rolled back.
+var errTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Transaction is a database transaction, which sees a consistent view of the database and whose
+// writes are applied atomically when committed. Read-only transactions fail all writes with
+// ErrReadOnly. Close rolls the transaction back if it has not been committed.
+//
+// Unlike a DB, a Transaction must only be used by one goroutine at a time.
+type Transaction interface {
+	DB
+
+	// Commit applies the writes made in the transaction, and ends it.
+	Commit() error
+
+	// Rollback discards the writes made in the transaction, and ends it.
+	Rollback() error
+}
+
+// Transactional is implemented by databases supporting transactions with read isolation.
+type Transactional interface {
+	// Begin starts a transaction. A read-only transaction can not write. Depending on the
+	// backend, a long-lived read-only transaction may block a read-write transaction from
+	// committing until it ends, so read-only transactions should be kept short.
+	Begin(readOnly bool) (Transaction, error)
+}
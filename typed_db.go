@@ -0,0 +1,121 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// KeyEncoder encodes values of type T to bytes and decodes them back, for storing typed keys, or
+// values, in a database. For keys, the encoding should preserve the natural order of T, so that
+// iteration order is meaningful.
+type KeyEncoder[T any] interface {
+	Encode(T) []byte
+	Decode([]byte) (T, error)
+}
+
+// StringEncoder is a KeyEncoder for strings, stored as their bytes.
+type StringEncoder struct{}
+
+var _ KeyEncoder[string] = StringEncoder{}
+
+// Encode implements KeyEncoder.
+func (StringEncoder) Encode(s string) []byte { return []byte(s) }
+
+// Decode implements KeyEncoder.
+func (StringEncoder) Decode(bz []byte) (string, error) { return string(bz), nil }
+
+// Uint64Encoder is a KeyEncoder for uint64s, stored as 8 big-endian bytes so that they sort in
+// numerical order.
+type Uint64Encoder struct{}
+
+var _ KeyEncoder[uint64] = Uint64Encoder{}
+
+// Encode implements KeyEncoder.
+func (Uint64Encoder) Encode(i uint64) []byte {
+	bz := make([]byte, 8)
+	binary.BigEndian.PutUint64(bz, i)
+	return bz
+}
+
+// Decode implements KeyEncoder.
+func (Uint64Encoder) Decode(bz []byte) (uint64, error) {
+	if len(bz) != 8 {
+		return 0, fmt.Errorf("uint64 must be 8 bytes, got %v", len(bz))
+	}
+	return binary.BigEndian.Uint64(bz), nil
+}
+
+// BytesEncoder is a KeyEncoder for byte slices, stored as is. Decoded slices are copies.
+type BytesEncoder struct{}
+
+var _ KeyEncoder[[]byte] = BytesEncoder{}
+
+// Encode implements KeyEncoder.
+func (BytesEncoder) Encode(bz []byte) []byte { return bz }
+
+// Decode implements KeyEncoder.
+func (BytesEncoder) Decode(bz []byte) ([]byte, error) { return cp(bz), nil }
+
+// TypedDB wraps a database with typed keys and values, which are encoded and decoded with the
+// given KeyEncoders.
+type TypedDB[K, V any] struct {
+	db     DB
+	keys   KeyEncoder[K]
+	values KeyEncoder[V]
+}
+
+// NewTypedDB creates a TypedDB wrapping the given database.
+func NewTypedDB[K, V any](db DB, keys KeyEncoder[K], values KeyEncoder[V]) *TypedDB[K, V] {
+	return &TypedDB[K, V]{
+		db:     db,
+		keys:   keys,
+		values: values,
+	}
+}
+
+// DB returns the wrapped database.
+func (tdb *TypedDB[K, V]) DB() DB {
+	return tdb.db
+}
+
+// Get fetches the value of the given key, or the zero value if it does not exist. Use Has to
+// tell a missing key from a zero value.
+func (tdb *TypedDB[K, V]) Get(key K) (V, error) {
+	var value V
+	bz, err := tdb.db.Get(tdb.keys.Encode(key))
+	if err != nil || bz == nil {
+		return value, err
+	}
+	return tdb.values.Decode(bz)
+}
+
+// Has checks if a key exists.
+func (tdb *TypedDB[K, V]) Has(key K) (bool, error) {
+	return tdb.db.Has(tdb.keys.Encode(key))
+}
+
+// Set sets the value for the given key.
+func (tdb *TypedDB[K, V]) Set(key K, value V) error {
+	return tdb.db.Set(tdb.keys.Encode(key), tdb.values.Encode(value))
+}
+
+// Delete deletes the key.
+func (tdb *TypedDB[K, V]) Delete(key K) error {
+	return tdb.db.Delete(tdb.keys.Encode(key))
+}
+
+// ForEach calls fn for every decoded item in the database, in key order, like DB.ForEach. Items
+// which can not be decoded stop the iteration with an error.
+func (tdb *TypedDB[K, V]) ForEach(fn func(key K, value V) error) error {
+	return tdb.db.ForEach(func(kbz, vbz []byte) error {
+		key, err := tdb.keys.Decode(kbz)
+		if err != nil {
+			return fmt.Errorf("failed to decode key %X: %w", kbz, err)
+		}
+		value, err := tdb.values.Decode(vbz)
+		if err != nil {
+			return fmt.Errorf("failed to decode value of key %X: %w", kbz, err)
+		}
+		return fn(key, value)
+	})
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyEncoders(t *testing.T) {
+	for _, s := range []string{"a", "hello world", "\x00\xff"} {
+		decoded, err := StringEncoder{}.Decode(StringEncoder{}.Encode(s))
+		require.NoError(t, err)
+		assert.Equal(t, s, decoded)
+	}
+
+	var prev []byte
+	for _, i := range []uint64{0, 1, 255, 256, 1 << 32, math.MaxUint64} {
+		encoded := Uint64Encoder{}.Encode(i)
+		require.Len(t, encoded, 8)
+		if prev != nil {
+			assert.Less(t, string(prev), string(encoded), "encoding must preserve order")
+		}
+		prev = encoded
+		decoded, err := Uint64Encoder{}.Decode(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, i, decoded)
+	}
+	_, err := Uint64Encoder{}.Decode([]byte{1, 2, 3})
+	require.Error(t, err)
+
+	for _, bz := range [][]byte{{1}, {0, 1, 2}, []byte("key")} {
+		decoded, err := BytesEncoder{}.Decode(BytesEncoder{}.Encode(bz))
+		require.NoError(t, err)
+		assert.Equal(t, bz, decoded)
+	}
+}
+
+func TestTypedDB(t *testing.T) {
+	tdb := NewTypedDB[uint64, string](NewMemDB(), Uint64Encoder{}, StringEncoder{})
+
+	require.NoError(t, tdb.Set(256, "b"))
+	require.NoError(t, tdb.Set(1, "a"))
+	require.NoError(t, tdb.Set(math.MaxUint64, "c"))
+
+	value, err := tdb.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+	value, err = tdb.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "", value)
+	has, err := tdb.Has(2)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, tdb.Delete(math.MaxUint64))
+	var keys []uint64
+	var values []string
+	require.NoError(t, tdb.ForEach(func(key uint64, value string) error {
+		keys = append(keys, key)
+		values = append(values, value)
+		return nil
+	}))
+	assert.Equal(t, []uint64{1, 256}, keys)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	// Undecodable items are errors.
+	require.NoError(t, tdb.DB().Set([]byte("bad"), []byte("x")))
+	require.Error(t, tdb.ForEach(func(uint64, string) error { return nil }))
+}
+
+func TestTypedDBBytes(t *testing.T) {
+	tdb := NewTypedDB[string, []byte](NewMemDB(), StringEncoder{}, BytesEncoder{})
+	require.NoError(t, tdb.Set("key", []byte{1, 2}))
+	value, err := tdb.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, value)
+
+	require.Equal(t, errKeyEmpty, tdb.Set("", []byte{1}))
+}
@@ -1,8 +1,14 @@
 package db
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 var (
+	// ErrReadOnly is returned when attempting to write to a database opened in read-only mode.
+	ErrReadOnly = errors.New("database is read-only")
+
 	// errBatchClosed is returned when a closed or written batch is used.
 	errBatchClosed = errors.New("batch has been written or closed")
 
@@ -27,6 +33,11 @@ type DB interface {
 	// CONTRACT: key, value readonly []byte
 	Has(key []byte) (bool, error)
 
+	// MultiGet fetches the values of the given keys. The returned values are parallel to the keys,
+	// with nil for keys which do not exist. Backends may fetch the keys in a single round-trip.
+	// CONTRACT: key, value readonly []byte
+	MultiGet(keys [][]byte) ([][]byte, error)
+
 	// Set sets the value for the given key, replacing it if it already exists.
 	// CONTRACT: key, value readonly []byte
 	Set([]byte, []byte) error
@@ -41,6 +52,19 @@ type DB interface {
 	// DeleteSync deletes the key, and flushes the delete to storage before returning.
 	DeleteSync([]byte) error
 
+	// CompareAndSet atomically sets the value of the given key to newVal, but only if its current
+	// value equals expected, and returns whether the value was set. A nil expected value means
+	// that the key must not exist, while an empty one matches an existing empty value.
+	// CONTRACT: key, expected, newVal readonly []byte
+	CompareAndSet(key, expected, newVal []byte) (swapped bool, err error)
+
+	// DeleteRange deletes all keys in the given domain. End is exclusive, and a nil start or end
+	// deletes from the first key or to the last key (inclusive) respectively, just like Iterator.
+	// Deleting an empty domain, or one where start is not less than end, is a no-op. Empty keys
+	// are not valid. Some backends do not delete the whole domain atomically.
+	// CONTRACT: start, end readonly []byte
+	DeleteRange(start, end []byte) error
+
 	// Iterator returns an iterator over a domain of keys, in ascending order. The caller must call
 	// Close when done. End is exclusive, and start must be less than end. A nil start iterates
 	// from the first key, and a nil end iterates to the last key (inclusive). Empty keys are not
@@ -57,16 +81,52 @@ type DB interface {
 	// CONTRACT: start, end readonly []byte
 	ReverseIterator(start, end []byte) (Iterator, error)
 
+	// IteratorWithContext is like Iterator, but the returned iterator becomes invalid once the
+	// context is done, and then returns the context's error from Error. The context is checked
+	// before every step, so cancellation takes effect at the next call to Next at the latest.
+	// CONTRACT: No writes may happen within a domain while an iterator exists over it.
+	// CONTRACT: start, end readonly []byte
+	IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error)
+
+	// ForEach calls fn for every key/value pair in the database, in ascending key order. If fn
+	// returns an error, iteration stops and the error is returned. The iterator used internally
+	// is always closed. fn must not write to the database.
+	// CONTRACT: key, value readonly []byte, and only valid until fn returns
+	ForEach(fn func(key, value []byte) error) error
+
+	// Compact compacts the given domain of keys, reclaiming space used by deleted or overwritten
+	// data. A nil start or end compacts from the first key or to the last key respectively, so
+	// Compact(nil, nil) compacts the whole database. Backends without manual compaction do nothing.
+	// CONTRACT: start, end readonly []byte
+	Compact(start, end []byte) error
+
 	// Close closes the database connection.
 	Close() error
 
 	// NewBatch creates a batch for atomic updates. The caller must call Batch.Close.
 	NewBatch() Batch
 
+	// NewBatchWithSize creates a batch for atomic updates like NewBatch, using expectedOps as a
+	// hint for the number of operations that will be added to it. Backends may use the hint to
+	// preallocate buffers, or ignore it. The caller must call Batch.Close.
+	NewBatchWithSize(expectedOps int) Batch
+
+	// WriteBatch atomically applies the given operations in order, as if they were added to a
+	// batch which is then written, but without the NewBatch/Write/Close ceremony.
+	WriteBatch(ops []BatchOp) error
+
+	// WriteBatchSync is like WriteBatch, but flushes the operations to storage before returning.
+	WriteBatchSync(ops []BatchOp) error
+
+	// ApplyLog atomically applies a log of operations, e.g. when replaying a write-ahead log. It
+	// is equivalent to WriteBatchSync.
+	ApplyLog(ops BatchOpList) error
+
 	// Print is used for debugging.
 	Print() error
 
-	// Stats returns a map of property values for all keys and the size of the cache.
+	// Stats returns a map of property values for all keys and the size of the cache. The
+	// available properties are backend-specific, but the map is never nil.
 	Stats() map[string]string
 }
 
@@ -84,6 +144,10 @@ type Batch interface {
 	// CONTRACT: key readonly []byte
 	Delete(key []byte) error
 
+	// Len returns the number of pending Set and Delete operations. It is 0 once the batch has
+	// been written or closed.
+	Len() int
+
 	// Write writes the batch, possibly without flushing to disk. Only Close() can be called after,
 	// other methods will error.
 	Write() error
@@ -92,10 +156,26 @@ type Batch interface {
 	// methods will error.
 	WriteSync() error
 
-	// Close closes the batch. It is idempotent, but calls to other methods afterwards will error.
+	// Close closes the batch, releasing any resources held by it. It is idempotent, but calls to
+	// other methods afterwards will error. Write and WriteSync do not relieve the caller from
+	// calling Close, and a batch that is abandoned without being written must be closed as well.
 	Close() error
 }
 
+// BatchOp is a single operation for DB.WriteBatch: it sets Key to Value, or deletes Key if Delete
+// is true, in which case Value is ignored.
+type BatchOp struct {
+	Key    []byte
+	Value  []byte
+	Delete bool
+}
+
+// KeyValue is a key and its value.
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
 // Iterator represents an iterator over a domain of keys. Callers must call Close when done.
 // No writes can happen to a domain while there exists an iterator over it, some backends may take
 // out database locks to ensure this will not happen.
@@ -124,9 +204,16 @@ type Iterator interface {
 	Domain() (start []byte, end []byte)
 
 	// Valid returns whether the current iterator is valid. Once invalid, the Iterator remains
-	// invalid forever.
+	// invalid forever, unless it is repositioned with Seek.
 	Valid() bool
 
+	// Seek moves the iterator to the first key at or after the given key within its domain, and
+	// returns whether there is such a key, i.e. whether the iterator is now valid. Reverse
+	// iterators move to the last key at or before the given key instead. Seek may also be used on
+	// an exhausted iterator, but not on one that has failed or been closed.
+	// CONTRACT: key readonly []byte
+	Seek(key []byte) bool
+
 	// Next moves the iterator to the next key in the database, as defined by order of iteration.
 	// If Valid returns false, this method will panic.
 	Next()
@@ -139,7 +226,14 @@ type Iterator interface {
 	// CONTRACT: value readonly []byte
 	Value() (value []byte)
 
-	// Error returns the last error encountered by the iterator, if any.
+	// Position returns a copy of the key at the current position, which remains valid after the
+	// iterator is closed, so that an interrupted scan can be resumed from the same item with
+	// IteratorFrom. It returns nil if the iterator is invalid.
+	Position() []byte
+
+	// Error returns the last error encountered by the iterator, if any. An iterator that
+	// encounters an error becomes invalid, so callers should check Error once Valid returns
+	// false to distinguish failures from reaching the end of the domain.
 	Error() error
 
 	// Close closes the iterator, relasing any allocated resources.
@@ -2,6 +2,7 @@ package db
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 )
 
@@ -34,6 +35,226 @@ func cpIncr(bz []byte) (ret []byte) {
 	return nil
 }
 
+// multiGet implements DB.MultiGet on top of DB.Get, for backends without native multi-gets.
+func multiGet(db DB, keys [][]byte) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := db.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// iteratorPosition implements Iterator.Position on top of Iterator.Key.
+func iteratorPosition(itr Iterator) []byte {
+	if !itr.Valid() {
+		return nil
+	}
+	return cp(itr.Key())
+}
+
+// IteratorFrom opens an iterator over [pos, end), resuming a scan from a position saved with
+// Iterator.Position. The item at the saved position is the first one visited again.
+func IteratorFrom(db DB, pos, end []byte) (Iterator, error) {
+	return db.Iterator(pos, end)
+}
+
+// forEach implements DB.ForEach on top of DB.Iterator.
+func forEach(db DB, fn func(key, value []byte) error) error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	for ; itr.Valid(); itr.Next() {
+		if err := fn(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+// ForEachReverse calls fn for every key/value pair in the database like DB.ForEach, but in
+// descending key order, e.g. to visit the most recent entries of a log first. If fn returns an
+// error, iteration stops and the error is returned. fn must not write to the database.
+// CONTRACT: key, value readonly []byte, and only valid until fn returns
+func ForEachReverse(db DB, fn func(key, value []byte) error) error {
+	itr, err := db.ReverseIterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	for ; itr.Valid(); itr.Next() {
+		if err := fn(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+	}
+	return itr.Error()
+}
+
+// deleteRangeBatchSize is the maximum number of keys deleted per batch by deleteRange.
+const deleteRangeBatchSize = 1000
+
+// deleteRange implements DB.DeleteRange on top of DB.Iterator. Keys are deleted in batches of
+// at most deleteRangeBatchSize keys, so the domain is not deleted atomically.
+func deleteRange(db DB, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if isEmptyDomain(start, end) {
+		return nil
+	}
+	for {
+		// Writes are not allowed while iterating, so collect a chunk of keys first. Deleted keys
+		// are gone when reopening the iterator, so start does not need to be advanced.
+		keys := make([][]byte, 0, deleteRangeBatchSize)
+		itr, err := db.Iterator(start, end)
+		if err != nil {
+			return err
+		}
+		for ; itr.Valid() && len(keys) < deleteRangeBatchSize; itr.Next() {
+			keys = append(keys, cp(itr.Key()))
+		}
+		err = itr.Error()
+		itr.Close()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		batch := db.NewBatchWithSize(len(keys))
+		for _, key := range keys {
+			if err := batch.Delete(key); err != nil {
+				batch.Close()
+				return err
+			}
+		}
+		err = batch.Write()
+		batch.Close()
+		if err != nil {
+			return err
+		}
+		if len(keys) < deleteRangeBatchSize {
+			return nil
+		}
+	}
+}
+
+// writeBatch implements DB.WriteBatch and DB.WriteBatchSync on top of DB.NewBatch. The batch is
+// always closed, so nothing is written if applying an operation fails or panics.
+func writeBatch(db DB, ops []BatchOp, sync bool) error {
+	batch := db.NewBatchWithSize(len(ops))
+	defer batch.Close()
+
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = batch.Delete(op.Key)
+		} else {
+			err = batch.Set(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// Merge returns the minimal set of operations equivalent to applying ops in order: only the last
+// operation for each key is kept, so the last set wins, a delete cancels the sets before it, and a
+// set after a delete replaces it. The kept operations are returned in the order of their position
+// in ops. An error is returned for empty keys and nil set values, as they would fail to be written.
+func Merge(ops []BatchOp) ([]BatchOp, error) {
+	last := make(map[string]int, len(ops))
+	for i, op := range ops {
+		if len(op.Key) == 0 {
+			return nil, errKeyEmpty
+		}
+		if !op.Delete && op.Value == nil {
+			return nil, errValueNil
+		}
+		last[string(op.Key)] = i
+	}
+	merged := make([]BatchOp, 0, len(last))
+	for i, op := range ops {
+		if last[string(op.Key)] == i {
+			merged = append(merged, op)
+		}
+	}
+	return merged, nil
+}
+
+// isEmptyDomain returns whether the domain of start and end can not contain any keys, because
+// start is not less than end.
+func isEmptyDomain(start, end []byte) bool {
+	return start != nil && end != nil && bytes.Compare(start, end) >= 0
+}
+
+// readOnlyBatch is returned by NewBatch on read-only databases. All writes fail with ErrReadOnly.
+type readOnlyBatch struct{}
+
+var _ Batch = readOnlyBatch{}
+
+// Set implements Batch.
+func (readOnlyBatch) Set(key, value []byte) error { return ErrReadOnly }
+
+// Delete implements Batch.
+func (readOnlyBatch) Delete(key []byte) error { return ErrReadOnly }
+
+// Len implements Batch.
+func (readOnlyBatch) Len() int { return 0 }
+
+// Write implements Batch.
+func (readOnlyBatch) Write() error { return ErrReadOnly }
+
+// WriteSync implements Batch.
+func (readOnlyBatch) WriteSync() error { return ErrReadOnly }
+
+// Close implements Batch.
+func (readOnlyBatch) Close() error { return nil }
+
+// compareAndSet implements DB.CompareAndSet on top of Get and the given set function. It is only
+// atomic if the caller excludes all other writes, e.g. by holding a mutex, so set must not take
+// that mutex itself.
+func compareAndSet(db DB, key, expected, newVal []byte, set func(key, value []byte) error) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	current, err := db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	if err := set(key, newVal); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// valueMatches returns whether a current value, nil if the key does not exist, matches an
+// expected value as specified by DB.CompareAndSet.
+func valueMatches(current, expected []byte) bool {
+	if expected == nil || current == nil {
+		return expected == nil && current == nil
+	}
+	return bytes.Equal(current, expected)
+}
+
 // See DB interface documentation for more information.
 func IsKeyInDomain(key, start, end []byte) bool {
 	if bytes.Compare(key, start) < 0 {
@@ -49,3 +270,179 @@ func FileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
 }
+
+// defaultCopyBatchSize is the number of writes per batch used by CopyTo by default.
+const defaultCopyBatchSize = 1000
+
+// CopyOptions configures CopyTo.
+type CopyOptions struct {
+	// BatchSize is the number of key/value pairs written per batch. Defaults to 1000 if not
+	// positive.
+	BatchSize int
+}
+
+// CopyTo copies all key/value pairs from src to dst, using a forward iterator on src and
+// writing to dst in batches of opts.BatchSize pairs. Existing keys in dst are overwritten, and
+// keys only present in dst are left as is. It returns the first error encountered, in which case
+// dst may contain a partial copy.
+func CopyTo(src, dst DB, opts CopyOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCopyBatchSize
+	}
+
+	itr, err := src.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	return copyIterator(itr, dst, batchSize, nil)
+}
+
+// copyIterator writes all remaining key/value pairs of itr to dst, in batches of batchSize pairs.
+// If transform is given, pairs are written as transformed by it, and skipped if it returns false.
+func copyIterator(itr Iterator, dst DB, batchSize int, transform func(k, v []byte) ([]byte, []byte, bool)) error {
+	batch := dst.NewBatchWithSize(batchSize)
+	defer func() {
+		batch.Close()
+	}()
+	pending := 0
+	for ; itr.Valid(); itr.Next() {
+		key, value := itr.Key(), itr.Value()
+		if transform != nil {
+			var ok bool
+			if key, value, ok = transform(key, value); !ok {
+				continue
+			}
+		}
+		if err := batch.Set(key, value); err != nil {
+			return err
+		}
+		pending++
+		if pending < batchSize {
+			continue
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+		batch = dst.NewBatchWithSize(batchSize)
+		pending = 0
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return batch.Write()
+	}
+	return nil
+}
+
+// Countable is implemented by databases which can count the keys in a domain more cheaply than
+// by iterating over them.
+type Countable interface {
+	// Count returns the exact number of keys in the domain [start, end).
+	Count(start, end []byte) (int64, error)
+}
+
+// Count returns the number of keys in the domain [start, end). Databases implementing Countable
+// count them themselves, otherwise the domain is iterated over.
+func Count(db DB, start, end []byte) (int64, error) {
+	if c, ok := db.(Countable); ok {
+		return c.Count(start, end)
+	}
+	itr, err := db.Iterator(start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer itr.Close()
+
+	var count int64
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	return count, itr.Error()
+}
+
+// KeysOnlyIterator is implemented by databases which can iterate over keys without fetching their
+// values.
+type KeysOnlyIterator interface {
+	// KeysOnlyIterator returns an iterator over the domain [start, end) like DB.Iterator, which
+	// avoids fetching values. Value may still be called, but can be expensive.
+	KeysOnlyIterator(start, end []byte) (Iterator, error)
+}
+
+// KeysOnly returns the keys in the domain [start, end), in ascending order. Databases implementing
+// KeysOnlyIterator are iterated over without fetching values.
+func KeysOnly(db DB, start, end []byte) ([][]byte, error) {
+	var (
+		itr Iterator
+		err error
+	)
+	if k, ok := db.(KeysOnlyIterator); ok {
+		itr, err = k.KeysOnlyIterator(start, end)
+	} else {
+		itr, err = db.Iterator(start, end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	var keys [][]byte
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, cp(itr.Key()))
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Values returns the values in the domain [start, end), in ascending key order.
+func Values(db DB, start, end []byte) ([][]byte, error) {
+	itr, err := db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	var values [][]byte
+	for ; itr.Valid(); itr.Next() {
+		values = append(values, cp(itr.Value()))
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Page returns up to pageSize entries in the domain [cursor, end), for paginating through a
+// database, e.g. over an API. A nil cursor starts at the beginning of the database. If there are
+// further entries, nextCursor is the smallest key after the last returned entry, to be passed as
+// the cursor for the next page; otherwise it is nil. Since the cursor does not depend on existing
+// keys, keys inserted between pages after the previous page are included in the next one.
+func Page(db DB, cursor []byte, pageSize int, end []byte) (entries []KeyValue, nextCursor []byte, err error) {
+	if pageSize <= 0 {
+		return nil, nil, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+	itr, err := db.Iterator(cursor, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer itr.Close()
+
+	for ; itr.Valid() && len(entries) < pageSize; itr.Next() {
+		entries = append(entries, KeyValue{Key: cp(itr.Key()), Value: cp(itr.Value())})
+	}
+	if err := itr.Error(); err != nil {
+		return nil, nil, err
+	}
+	if itr.Valid() {
+		last := entries[len(entries)-1].Key
+		nextCursor = append(cp(last), 0x00)
+	}
+	return entries, nextCursor, nil
+}
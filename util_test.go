@@ -118,3 +118,276 @@ func TestPrefixIteratorMatches1N(t *testing.T) {
 		})
 	}
 }
+
+// Reverse iterator with prefix iterates over everything with same prefix, in descending order.
+func TestReversePrefixIteratorMatches1N(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			// prefixed
+			err := db.SetSync(bz("a/1"), bz("value_1"))
+			require.NoError(t, err)
+			err = db.SetSync(bz("a/3"), bz("value_3"))
+			require.NoError(t, err)
+
+			// not
+			err = db.SetSync(bz("b/3"), bz("value_3"))
+			require.NoError(t, err)
+			err = db.SetSync(bz("a-3"), bz("value_3"))
+			require.NoError(t, err)
+			err = db.SetSync(bz("a.3"), bz("value_3"))
+			require.NoError(t, err)
+			err = db.SetSync(bz("abcdefg"), bz("value_3"))
+			require.NoError(t, err)
+			itr, err := ReverseIteratePrefix(db, bz("a/"))
+			require.NoError(t, err)
+
+			checkValid(t, itr, true)
+			checkItem(t, itr, bz("a/3"), bz("value_3"))
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("a/1"), bz("value_1"))
+
+			// Bad!
+			checkNext(t, itr, false)
+
+			// Once invalid...
+			checkInvalid(t, itr)
+		})
+	}
+}
+
+// Empty reverse iterator for prefix without matches.
+func TestReversePrefixIteratorNoMatch(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+			err := db.SetSync(bz("3"), bz("value_3"))
+			require.NoError(t, err)
+			err = db.SetSync(bz("5"), bz("value_5"))
+			require.NoError(t, err)
+			itr, err := ReverseIteratePrefix(db, []byte("4"))
+			require.NoError(t, err)
+
+			checkInvalid(t, itr)
+		})
+	}
+}
+
+// Prefixes consisting only of 0xFF bytes have no upper bound, and must iterate to the last key.
+func TestPrefixIteratorAllFF(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			for _, key := range [][]byte{
+				{0xfe}, {0xfe, 0xff}, {0xff}, {0xff, 0x00}, {0xff, 0xff}, {0xff, 0xff, 0x01},
+			} {
+				err := db.SetSync(key, key)
+				require.NoError(t, err)
+			}
+
+			itr, err := IteratePrefix(db, []byte{0xff})
+			require.NoError(t, err)
+			checkValid(t, itr, true)
+			checkItem(t, itr, []byte{0xff}, []byte{0xff})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0xff, 0x00}, []byte{0xff, 0x00})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0xff, 0xff}, []byte{0xff, 0xff})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0xff, 0xff, 0x01}, []byte{0xff, 0xff, 0x01})
+			checkNext(t, itr, false)
+			checkInvalid(t, itr)
+			itr.Close()
+
+			itr, err = ReverseIteratePrefix(db, []byte{0xff, 0xff})
+			require.NoError(t, err)
+			checkValid(t, itr, true)
+			checkItem(t, itr, []byte{0xff, 0xff, 0x01}, []byte{0xff, 0xff, 0x01})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0xff, 0xff}, []byte{0xff, 0xff})
+			checkNext(t, itr, false)
+			checkInvalid(t, itr)
+			itr.Close()
+		})
+	}
+}
+
+func TestCopyTo(t *testing.T) {
+	src := NewMemDB()
+	expect := map[string][]byte{}
+	for i := 0; i < 500; i++ {
+		key, value := fmt.Sprintf("key%03d", i), []byte(fmt.Sprintf("value%d", i))
+		require.NoError(t, src.Set([]byte(key), value))
+		expect[key] = value
+	}
+
+	for _, batchSize := range []int{0, 1, 7, 500, 1000} {
+		t.Run(fmt.Sprintf("BatchSize %v", batchSize), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			dst, err := NewGoLevelDB(name, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer dst.Close()
+
+			require.NoError(t, CopyTo(src, dst, CopyOptions{BatchSize: batchSize}))
+			assertKeyValues(t, dst, expect)
+		})
+	}
+}
+
+func TestCopyToEmpty(t *testing.T) {
+	dst := NewMemDB()
+	require.NoError(t, dst.Set([]byte("a"), []byte{1}))
+
+	require.NoError(t, CopyTo(NewMemDB(), dst, CopyOptions{}))
+	assertKeyValues(t, dst, map[string][]byte{"a": {1}})
+}
+
+func TestDeleteRangeMultipleBatches(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewGoLevelDB(name, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	for i := 0; i < 2*deleteRangeBatchSize+10; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key%05d", i)), []byte{1}))
+	}
+	require.NoError(t, db.Set([]byte("other"), []byte{1}))
+
+	require.NoError(t, deleteRange(db, []byte("key"), []byte("kez")))
+	assertKeyValues(t, db, map[string][]byte{"other": {1}})
+}
+
+func TestPage(t *testing.T) {
+	db := NewMemDB()
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, db.Set([]byte(k), []byte("v"+k)))
+	}
+	keys := func(entries []KeyValue) (keys []string) {
+		for _, e := range entries {
+			require.Equal(t, "v"+string(e.Key), string(e.Value))
+			keys = append(keys, string(e.Key))
+		}
+		return keys
+	}
+
+	// A single page holding everything has no next cursor.
+	entries, next, err := Page(db, nil, 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, keys(entries))
+	require.Nil(t, next)
+
+	// So does a page ending exactly at the last entry, of the database or of the domain.
+	entries, next, err = Page(db, []byte("c"), 3, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "d", "e"}, keys(entries))
+	require.Nil(t, next)
+	entries, next, err = Page(db, nil, 2, []byte("c"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, keys(entries))
+	require.Nil(t, next)
+
+	// Multiple pages, with keys inserted between them before and after the cursor.
+	entries, next, err = Page(db, nil, 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, keys(entries))
+	require.NotNil(t, next)
+	require.NoError(t, db.Set([]byte("aa"), []byte("vaa")))
+	require.NoError(t, db.Set([]byte("b\x00"), []byte("vb\x00")))
+	require.NoError(t, db.Set([]byte("ba"), []byte("vba")))
+
+	var all []string
+	for next != nil {
+		entries, next, err = Page(db, next, 2, nil)
+		require.NoError(t, err)
+		all = append(all, keys(entries)...)
+	}
+	require.Equal(t, []string{"b\x00", "ba", "c", "d", "e"}, all)
+
+	// An empty domain returns no entries.
+	entries, next, err = Page(db, []byte("x"), 2, nil)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.Nil(t, next)
+
+	_, _, err = Page(db, nil, 0, nil)
+	require.Error(t, err)
+}
+
+func TestWalkPrefix(t *testing.T) {
+	db := NewMemDB()
+	for _, key := range []string{"a", "key", "key/1", "key/2", "key/3", "keya", "z"} {
+		require.NoError(t, db.Set([]byte(key), []byte("v"+key)))
+	}
+
+	visited := map[string]string{}
+	err := WalkPrefix(db, []byte("key/"), func(key, value []byte) error {
+		visited[string(key)] = string(value)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"1": "vkey/1", "2": "vkey/2", "3": "vkey/3"}, visited)
+
+	var keys []string
+	err = WalkPrefixWithOptions(db, []byte("key"), WalkPrefixOptions{KeepPrefix: true},
+		func(key, value []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, []string{"key", "key/1", "key/2", "key/3", "keya"}, keys)
+
+	// Returning an error stops the walk and forwards the error.
+	errStop := fmt.Errorf("stop")
+	calls := 0
+	err = WalkPrefix(db, []byte("key/"), func(key, value []byte) error {
+		calls++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestMerge(t *testing.T) {
+	set := func(key, value string) BatchOp { return BatchOp{Key: []byte(key), Value: []byte(value)} }
+	del := func(key string) BatchOp { return BatchOp{Key: []byte(key), Delete: true} }
+
+	merged, err := Merge([]BatchOp{
+		set("a", "1"),
+		set("b", "1"),
+		set("a", "2"), // last set wins
+		del("b"),      // delete cancels the prior set
+		del("c"),
+		set("c", "1"), // set after a delete keeps only the set
+		set("d", "1"),
+		del("e"),
+		set("a", "3"),
+		del("d"),
+		set("d", "2"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []BatchOp{
+		del("b"),
+		set("c", "1"),
+		del("e"),
+		set("a", "3"),
+		set("d", "2"),
+	}, merged)
+
+	merged, err = Merge(nil)
+	require.NoError(t, err)
+	require.Empty(t, merged)
+
+	_, err = Merge([]BatchOp{set("a", "1"), set("", "1")})
+	require.Equal(t, errKeyEmpty, err)
+	_, err = Merge([]BatchOp{{Key: []byte("a")}})
+	require.Equal(t, errValueNil, err)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// VerifyError is returned by VerifyDB when a database is inconsistent. It lists every
+// inconsistency found.
+type VerifyError struct {
+	Inconsistencies []string
+}
+
+// Error implements error.
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("database verification found %d inconsistencies: %s",
+		len(e.Inconsistencies), strings.Join(e.Inconsistencies, "; "))
+}
+
+// VerifyDB checks the integrity of a database by iterating over all of its items, and checking
+// that keys are in strictly ascending order and that Get returns the same values as the
+// iterator. Inconsistencies are returned as a *VerifyError, while failures to read the database
+// are returned as is.
+//
+// The database must not be written to while it is being verified.
+func VerifyDB(db DB) error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	var (
+		inconsistencies []string
+		prev            []byte
+	)
+	for ; itr.Valid(); itr.Next() {
+		key, value := cp(itr.Key()), itr.Value()
+		if prev != nil {
+			switch bytes.Compare(prev, key) {
+			case 0:
+				inconsistencies = append(inconsistencies, fmt.Sprintf("duplicate key %X", key))
+			case 1:
+				inconsistencies = append(inconsistencies,
+					fmt.Sprintf("key %X out of order after key %X", key, prev))
+			}
+		}
+		prev = key
+
+		got, err := db.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get key %X: %w", key, err)
+		}
+		switch {
+		case got == nil:
+			inconsistencies = append(inconsistencies,
+				fmt.Sprintf("key %X returned by iterator not found by Get", key))
+		case !bytes.Equal(got, value):
+			inconsistencies = append(inconsistencies,
+				fmt.Sprintf("key %X has value %X from iterator but %X from Get", key, value, got))
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	if len(inconsistencies) > 0 {
+		return &VerifyError{Inconsistencies: inconsistencies}
+	}
+	return nil
+}
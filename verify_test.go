@@ -0,0 +1,67 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptDB is a database whose iterators yield the given items instead of its own.
+type corruptDB struct {
+	DB
+	items []BatchOp
+}
+
+func (db *corruptDB) Iterator(start, end []byte) (Iterator, error) {
+	return &fixedIterator{items: db.items}, nil
+}
+
+// fixedIterator iterates over a fixed list of items, in the given order.
+type fixedIterator struct {
+	items []BatchOp
+}
+
+func (itr *fixedIterator) Domain() ([]byte, []byte) { return nil, nil }
+func (itr *fixedIterator) Valid() bool              { return len(itr.items) > 0 }
+func (itr *fixedIterator) Seek(key []byte) bool     { panic("not implemented") }
+func (itr *fixedIterator) Next()                    { itr.items = itr.items[1:] }
+func (itr *fixedIterator) Key() []byte              { return itr.items[0].Key }
+func (itr *fixedIterator) Value() []byte            { return itr.items[0].Value }
+func (itr *fixedIterator) Position() []byte         { return iteratorPosition(itr) }
+func (itr *fixedIterator) Error() error             { return nil }
+func (itr *fixedIterator) Close() error             { return nil }
+
+func TestVerifyDB(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+	require.NoError(t, VerifyDB(db))
+	require.NoError(t, VerifyDB(NewMemDB()))
+
+	err := VerifyDB(&corruptDB{DB: db, items: []BatchOp{
+		{Key: []byte("a"), Value: []byte{1}},
+		{Key: []byte("b"), Value: []byte{9}}, // value mismatch
+		{Key: []byte("b"), Value: []byte{2}}, // duplicate
+		{Key: []byte("d"), Value: []byte{4}}, // missing from Get
+		{Key: []byte("c"), Value: []byte{3}}, // out of order
+	}})
+	var verifyErr *VerifyError
+	require.True(t, errors.As(err, &verifyErr), "unexpected error %v", err)
+	assert.Equal(t, []string{
+		"key 62 has value 09 from iterator but 02 from Get",
+		"duplicate key 62",
+		"key 64 returned by iterator not found by Get",
+		"key 63 out of order after key 64",
+	}, verifyErr.Inconsistencies)
+	assert.Contains(t, err.Error(), "4 inconsistencies")
+
+	// Read failures are returned as is.
+	mdb := NewMockDBWrapping(db)
+	mdb.SetError("Get", errors.New("boom"))
+	err = VerifyDB(mdb)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &verifyErr))
+}
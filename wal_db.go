@@ -0,0 +1,537 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The WAL file of a WALDB starts with a header, followed by a sequence of records:
+//
+//	header:  magic "TWAL" (4 bytes) | version (1 byte, currently 1)
+//	record:  length (4 bytes) | crc (4 bytes) | payload (length bytes)
+//	payload: type (1 byte) | body
+//
+// Integers are big-endian, and crc is the CRC-32 (Castagnoli) of the payload. The body of a
+// walRecordBatch is a BatchOpList in its binary encoding. The body of a walRecordDeleteRange is a
+// flags byte, where bit 0 is set if start is non-nil and bit 1 if end is non-nil, followed by
+// start and end, each prefixed by its length as a uvarint.
+//
+// A record which is truncated, or fails its checksum, is treated as the end of the log: it was
+// being written when the process crashed, so it was never applied.
+const (
+	walFileName = "wal.log"
+	walMagic    = "TWAL"
+	walVersion  = 1
+
+	walHeaderSize       = len(walMagic) + 1
+	walRecordHeaderSize = 8
+
+	walRecordBatch       byte = 1
+	walRecordDeleteRange byte = 2
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WALDB wraps a database, and journals every write to a write-ahead log (WAL) before applying
+// it, so that writes interrupted by a crash are applied when the database is reopened. Sync
+// writes, such as SetSync or Batch.WriteSync, sync the WAL to disk before returning; other writes
+// survive a process crash, but not necessarily an operating system crash.
+//
+// Recovery replays the whole WAL, which is correct since every write overwrites the keys it
+// touches, regardless of their previous state. Writes which are journaled but fail to apply are
+// applied again on recovery.
+//
+// The WAL is reset when the database is closed cleanly. If the wrapped database is a goleveldb,
+// cleveldb, rocksdb or pebble database, whose sync writes also persist all earlier writes, the WAL
+// is also reset after every successful sync write and after recovery, unless a journaled write
+// failed to apply. Otherwise, e.g. for a MemDB, the WAL is the only copy of the writes, and grows
+// with every write until the database is closed.
+type WALDB struct {
+	db      DB
+	durable bool // whether sync writes to db persist all earlier writes, see syncWriteDurable
+
+	// mtx serializes writes, so that they are journaled in the order they are applied.
+	mtx         sync.Mutex
+	file        *os.File
+	size        int64 // the size of the valid part of the WAL
+	applyFailed bool  // whether a journaled write failed to apply since the WAL was last reset
+}
+
+// syncWriteDurable is implemented by backends which write everything to a single sequential log,
+// so that a successful sync write also persists all earlier writes.
+type syncWriteDurable interface {
+	syncWriteDurable()
+}
+
+var _ DB = (*WALDB)(nil)
+
+// NewWALDB creates a WALDB wrapping the given database, with its WAL in the given directory,
+// which is created if necessary. Any writes journaled in an existing WAL are applied to the
+// database first. Unless inner is a goleveldb, cleveldb, rocksdb or pebble database, the WAL
+// grows with every write until the database is closed, and is replayed in full on recovery.
+func NewWALDB(inner DB, dir string) (*WALDB, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	_, durable := inner.(syncWriteDurable)
+	wdb := &WALDB{
+		db:      inner,
+		durable: durable,
+		file:    file,
+	}
+	if err := wdb.recover(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to recover WAL: %w", err)
+	}
+	return wdb, nil
+}
+
+// recover applies all valid records in the WAL, and truncates it after the last one, so that new
+// records are not appended after a partially written one. If the wrapped database is durable and
+// the last record was a batch, which is applied with a sync write, every record has been
+// persisted, so the WAL is reset instead.
+func (wdb *WALDB) recover() error {
+	data, err := io.ReadAll(wdb.file)
+	if err != nil {
+		return err
+	}
+	if len(data) < walHeaderSize {
+		// New, or crashed while writing the header.
+		return wdb.reset()
+	}
+	if string(data[:len(walMagic)]) != walMagic {
+		return errors.New("invalid WAL magic bytes")
+	}
+	if version := data[len(walMagic)]; version != walVersion {
+		return fmt.Errorf("unsupported WAL version %d", version)
+	}
+
+	offset := walHeaderSize
+	synced := true
+	for len(data)-offset >= walRecordHeaderSize {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		checksum := binary.BigEndian.Uint32(data[offset+4:])
+		start := offset + walRecordHeaderSize
+		if length > len(data)-start {
+			break
+		}
+		payload := data[start : start+length]
+		if crc32.Checksum(payload, walCRCTable) != checksum {
+			break
+		}
+		if _, err := applyWALRecord(wdb.db, payload); err != nil {
+			return fmt.Errorf("record at offset %d: %w", offset, err)
+		}
+		synced = payload[0] == walRecordBatch
+		offset = start + length
+	}
+	if wdb.durable && synced {
+		return wdb.reset()
+	}
+
+	if err := wdb.file.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	if _, err := wdb.file.Seek(int64(offset), io.SeekStart); err != nil {
+		return err
+	}
+	wdb.size = int64(offset)
+	return nil
+}
+
+// reset truncates the WAL to just its header.
+func (wdb *WALDB) reset() error {
+	wdb.applyFailed = false
+	if err := wdb.file.Truncate(0); err != nil {
+		return err
+	}
+	header := append([]byte(walMagic), walVersion)
+	if _, err := wdb.file.WriteAt(header, 0); err != nil {
+		return err
+	}
+	if _, err := wdb.file.Seek(int64(len(header)), io.SeekStart); err != nil {
+		return err
+	}
+	wdb.size = int64(len(header))
+	return wdb.file.Sync()
+}
+
+// applyWALRecord applies a WAL record payload to a database, returning the number of operations
+// applied: the number of operations in a batch, or 1 for a DeleteRange.
+func applyWALRecord(db DB, payload []byte) (int, error) {
+	if len(payload) == 0 {
+		return 0, errors.New("empty record")
+	}
+	switch payload[0] {
+	case walRecordBatch:
+		var ops BatchOpList
+		if err := ops.UnmarshalBinary(payload[1:]); err != nil {
+			return 0, err
+		}
+		if err := db.WriteBatchSync(ops); err != nil {
+			return 0, err
+		}
+		return len(ops), nil
+	case walRecordDeleteRange:
+		start, end, err := decodeWALDeleteRange(payload[1:])
+		if err != nil {
+			return 0, err
+		}
+		if err := db.DeleteRange(start, end); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("unknown record type %d", payload[0])
+	}
+}
+
+// append appends a record to the WAL, syncing it if requested. It requires holding mtx. If the
+// write fails, the WAL is truncated to remove the partial record.
+func (wdb *WALDB) append(payload []byte, sync bool) error {
+	record := make([]byte, walRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(payload, walCRCTable))
+	copy(record[walRecordHeaderSize:], payload)
+
+	if _, err := wdb.file.Write(record); err != nil {
+		if terr := wdb.file.Truncate(wdb.size); terr == nil {
+			_, _ = wdb.file.Seek(wdb.size, io.SeekStart)
+		}
+		return fmt.Errorf("failed to write WAL: %w", err)
+	}
+	wdb.size += int64(len(record))
+	if sync {
+		if err := wdb.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync WAL: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeOps journals and applies a list of operations.
+func (wdb *WALDB) writeOps(ops []BatchOp, sync bool, applyFn func() error) error {
+	for _, op := range ops {
+		if len(op.Key) == 0 {
+			return errKeyEmpty
+		}
+		if !op.Delete && op.Value == nil {
+			return errValueNil
+		}
+	}
+	body, err := BatchOpList(ops).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	if len(ops) > 0 {
+		if err := wdb.append(append([]byte{walRecordBatch}, body...), sync); err != nil {
+			return err
+		}
+	}
+	if err := wdb.applied(applyFn()); err != nil {
+		return err
+	}
+	if sync && wdb.durable && !wdb.applyFailed {
+		// The sync write persisted all journaled writes to the wrapped database.
+		if err := wdb.reset(); err != nil {
+			return fmt.Errorf("failed to reset WAL: %w", err)
+		}
+	}
+	return nil
+}
+
+// applied records the result of applying a journaled write to the wrapped database, and returns
+// it. It requires holding mtx.
+func (wdb *WALDB) applied(err error) error {
+	if err != nil {
+		wdb.applyFailed = true
+	}
+	return err
+}
+
+// encodeWALDeleteRange encodes the body of a walRecordDeleteRange.
+func encodeWALDeleteRange(start, end []byte) []byte {
+	var flags byte
+	if start != nil {
+		flags |= 0x01
+	}
+	if end != nil {
+		flags |= 0x02
+	}
+	buf := bytes.NewBuffer([]byte{walRecordDeleteRange, flags})
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, key := range [][]byte{start, end} {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(key)))
+		buf.Write(lenBuf[:n])
+		buf.Write(key)
+	}
+	return buf.Bytes()
+}
+
+// decodeWALDeleteRange decodes the body of a walRecordDeleteRange.
+func decodeWALDeleteRange(body []byte) (start, end []byte, err error) {
+	if len(body) == 0 {
+		return nil, nil, errors.New("invalid delete range record")
+	}
+	flags, body := body[0], body[1:]
+	keys := make([][]byte, 2)
+	for i := range keys {
+		length, n := binary.Uvarint(body)
+		if n <= 0 || length > uint64(len(body)-n) {
+			return nil, nil, errors.New("invalid delete range record")
+		}
+		body = body[n:]
+		if flags&(1<<i) != 0 {
+			keys[i] = body[:length:length]
+		}
+		body = body[length:]
+	}
+	return keys[0], keys[1], nil
+}
+
+// Get implements DB.
+func (wdb *WALDB) Get(key []byte) ([]byte, error) {
+	return wdb.db.Get(key)
+}
+
+// Has implements DB.
+func (wdb *WALDB) Has(key []byte) (bool, error) {
+	return wdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (wdb *WALDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return wdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (wdb *WALDB) Set(key []byte, value []byte) error {
+	return wdb.writeOps([]BatchOp{{Key: key, Value: value}}, false, func() error {
+		return wdb.db.Set(key, value)
+	})
+}
+
+// SetSync implements DB.
+func (wdb *WALDB) SetSync(key []byte, value []byte) error {
+	return wdb.writeOps([]BatchOp{{Key: key, Value: value}}, true, func() error {
+		return wdb.db.SetSync(key, value)
+	})
+}
+
+// Delete implements DB.
+func (wdb *WALDB) Delete(key []byte) error {
+	return wdb.writeOps([]BatchOp{{Key: key, Delete: true}}, false, func() error {
+		return wdb.db.Delete(key)
+	})
+}
+
+// DeleteSync implements DB.
+func (wdb *WALDB) DeleteSync(key []byte) error {
+	return wdb.writeOps([]BatchOp{{Key: key, Delete: true}}, true, func() error {
+		return wdb.db.DeleteSync(key)
+	})
+}
+
+// CompareAndSet implements DB. The comparison is made under the write lock, and only a
+// successful swap is journaled.
+func (wdb *WALDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if newVal == nil {
+		return false, errValueNil
+	}
+	body, err := BatchOpList{{Key: key, Value: newVal}}.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	current, err := wdb.db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if !valueMatches(current, expected) {
+		return false, nil
+	}
+	if err := wdb.append(append([]byte{walRecordBatch}, body...), false); err != nil {
+		return false, err
+	}
+	return true, wdb.applied(wdb.db.Set(key, newVal))
+}
+
+// DeleteRange implements DB.
+func (wdb *WALDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	if err := wdb.append(encodeWALDeleteRange(start, end), false); err != nil {
+		return err
+	}
+	return wdb.applied(wdb.db.DeleteRange(start, end))
+}
+
+// Iterator implements DB.
+func (wdb *WALDB) Iterator(start, end []byte) (Iterator, error) {
+	return wdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (wdb *WALDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return wdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (wdb *WALDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return wdb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (wdb *WALDB) Compact(start, end []byte) error {
+	return wdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (wdb *WALDB) WriteBatch(ops []BatchOp) error {
+	return wdb.writeOps(ops, false, func() error {
+		return wdb.db.WriteBatch(ops)
+	})
+}
+
+// WriteBatchSync implements DB.
+func (wdb *WALDB) WriteBatchSync(ops []BatchOp) error {
+	return wdb.writeOps(ops, true, func() error {
+		return wdb.db.WriteBatchSync(ops)
+	})
+}
+
+// ApplyLog implements DB.
+func (wdb *WALDB) ApplyLog(ops BatchOpList) error {
+	return wdb.WriteBatchSync(ops)
+}
+
+// ForEach implements DB.
+func (wdb *WALDB) ForEach(fn func(key, value []byte) error) error {
+	return wdb.db.ForEach(fn)
+}
+
+// Close implements DB. If the wrapped database closes successfully, all journaled writes have
+// been persisted, so the WAL is reset.
+func (wdb *WALDB) Close() error {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	err := wdb.db.Close()
+	if err == nil {
+		err = wdb.reset()
+	}
+	if cerr := wdb.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (wdb *WALDB) NewBatch() Batch {
+	return newWALDBBatch(wdb, wdb.db.NewBatch())
+}
+
+// NewBatchWithSize implements DB.
+func (wdb *WALDB) NewBatchWithSize(expectedOps int) Batch {
+	return newWALDBBatch(wdb, wdb.db.NewBatchWithSize(expectedOps))
+}
+
+// Print implements DB.
+func (wdb *WALDB) Print() error {
+	return wdb.db.Print()
+}
+
+// Stats implements DB. The size of the WAL is included as "wal.size".
+func (wdb *WALDB) Stats() map[string]string {
+	stats := wdb.db.Stats()
+	wdb.mtx.Lock()
+	stats["wal.size"] = fmt.Sprintf("%d", wdb.size)
+	wdb.mtx.Unlock()
+	return stats
+}
+
+// walDBBatch wraps a batch, recording its operations so that they can be journaled when written.
+type walDBBatch struct {
+	db    *WALDB
+	batch Batch
+	ops   []BatchOp
+}
+
+var _ Batch = (*walDBBatch)(nil)
+
+func newWALDBBatch(db *WALDB, batch Batch) *walDBBatch {
+	return &walDBBatch{
+		db:    db,
+		batch: batch,
+		ops:   []BatchOp{},
+	}
+}
+
+// Set implements Batch.
+func (b *walDBBatch) Set(key, value []byte) error {
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Value: cp(value)})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *walDBBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, BatchOp{Key: cp(key), Delete: true})
+	return nil
+}
+
+// Len implements Batch.
+func (b *walDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Write implements Batch.
+func (b *walDBBatch) Write() error {
+	return b.write(false, b.batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *walDBBatch) WriteSync() error {
+	return b.write(true, b.batch.WriteSync)
+}
+
+func (b *walDBBatch) write(sync bool, writeFn func() error) error {
+	// Check before journaling, since the journaled operations would be applied on recovery.
+	if b.ops == nil {
+		return errBatchClosed
+	}
+	ops := b.ops
+	b.ops = nil
+	return b.db.writeOps(ops, sync, writeFn)
+}
+
+// Close implements Batch.
+func (b *walDBBatch) Close() error {
+	b.ops = nil
+	return b.batch.Close()
+}
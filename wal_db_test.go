@@ -0,0 +1,204 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeWALEntries writes a mix of operations through a WALDB.
+func writeWALEntries(t *testing.T, wdb *WALDB) {
+	require.NoError(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.SetSync([]byte("b"), []byte{2}))
+	require.NoError(t, wdb.Set([]byte("c"), []byte{}))
+	require.NoError(t, wdb.Delete([]byte("a")))
+	batch := wdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("d"), []byte{4}))
+	require.NoError(t, batch.Set([]byte("e"), []byte{5}))
+	require.NoError(t, batch.Set([]byte("f"), []byte{6}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.NoError(t, batch.WriteSync())
+	require.Error(t, batch.Write())
+	require.NoError(t, batch.Close())
+	swapped, err := wdb.CompareAndSet([]byte("d"), []byte{4}, []byte{7})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	require.NoError(t, wdb.DeleteRange([]byte("e"), []byte("f")))
+}
+
+func TestWALDBRecovery(t *testing.T) {
+	dir := t.TempDir()
+	wdb, err := NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	writeWALEntries(t, wdb)
+	expect := map[string][]byte{"c": {}, "d": {7}, "f": {6}}
+	assertKeyValues(t, wdb, expect)
+
+	// Simulate a crash losing all unpersisted writes of the wrapped database, which the WAL
+	// recovers when reopened.
+	require.NoError(t, wdb.file.Close())
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, expect)
+
+	// Replaying on top of a database which already has some of the writes has the same result.
+	inner := NewMemDB()
+	require.NoError(t, inner.Set([]byte("e"), []byte{5}))
+	require.NoError(t, inner.Set([]byte("g"), []byte{8}))
+	require.NoError(t, wdb.file.Close())
+	wdb, err = NewWALDB(inner, dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{"c": {}, "d": {7}, "f": {6}, "g": {8}})
+
+	// A clean close resets the WAL.
+	require.NoError(t, wdb.Close())
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.EqualValues(t, walHeaderSize, info.Size())
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{})
+	require.NoError(t, wdb.Close())
+}
+
+func TestWALDBTruncated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, walFileName)
+	wdb, err := NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	require.NoError(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.Set([]byte("b"), []byte{2}))
+	require.NoError(t, wdb.file.Close())
+
+	// Crash in the middle of writing the last record, which is discarded.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{"a": {1}})
+
+	// The partial record was removed, so new records are recovered.
+	require.NoError(t, wdb.Set([]byte("c"), []byte{3}))
+	require.NoError(t, wdb.file.Close())
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{"a": {1}, "c": {3}})
+	require.NoError(t, wdb.file.Close())
+
+	// A corrupted record is also treated as the end of the log.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0x01
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{"a": {1}})
+	require.NoError(t, wdb.file.Close())
+
+	// Crashing while writing the header leaves an empty log.
+	require.NoError(t, os.Truncate(path, 2))
+	wdb, err = NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	assertKeyValues(t, wdb, map[string][]byte{})
+	require.NoError(t, wdb.Close())
+
+	// Files which are not a WAL are rejected.
+	require.NoError(t, os.WriteFile(path, []byte("not a WAL file"), 0644))
+	_, err = NewWALDB(NewMemDB(), dir)
+	require.Error(t, err)
+	require.NoError(t, os.WriteFile(path, []byte{'T', 'W', 'A', 'L', 9}, 0644))
+	_, err = NewWALDB(NewMemDB(), dir)
+	require.Error(t, err)
+}
+
+func TestWALDBInvalidWrites(t *testing.T) {
+	dir := t.TempDir()
+	wdb, err := NewWALDB(NewMemDB(), dir)
+	require.NoError(t, err)
+	defer wdb.Close()
+
+	// Invalid writes are rejected before being journaled, so they do not break recovery.
+	require.Equal(t, errKeyEmpty, wdb.Set(nil, []byte{1}))
+	require.Equal(t, errValueNil, wdb.Set([]byte("a"), nil))
+	require.Equal(t, errKeyEmpty, wdb.DeleteRange([]byte{}, nil))
+	require.Equal(t, errValueNil, wdb.WriteBatch([]BatchOp{{Key: []byte("a")}}))
+	assert.Equal(t, "5", wdb.Stats()["wal.size"])
+}
+
+// failingSetLevelDB is a GoLevelDB whose Set fails.
+type failingSetLevelDB struct {
+	*GoLevelDB
+}
+
+func (db failingSetLevelDB) Set(key []byte, value []byte) error {
+	return errors.New("set failed")
+}
+
+func TestWALDBReset(t *testing.T) {
+	walSize := func(wdb *WALDB) string { return wdb.Stats()["wal.size"] }
+	header := fmt.Sprintf("%d", walHeaderSize)
+
+	// Without a durable wrapped database, the WAL keeps every write.
+	wdb, err := NewWALDB(NewMemDB(), t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, wdb.SetSync([]byte("a"), []byte{1}))
+	assert.NotEqual(t, header, walSize(wdb))
+	require.NoError(t, wdb.Close())
+
+	// With goleveldb, a sync write persists all earlier writes, so it resets the WAL.
+	dir := t.TempDir()
+	open := func() (*GoLevelDB, *WALDB) {
+		ldb, err := NewGoLevelDB("inner", dir)
+		require.NoError(t, err)
+		wdb, err := NewWALDB(ldb, filepath.Join(dir, "wal"))
+		require.NoError(t, err)
+		return ldb, wdb
+	}
+	crash := func(ldb *GoLevelDB, wdb *WALDB) {
+		require.NoError(t, wdb.file.Close())
+		require.NoError(t, ldb.Close())
+	}
+	ldb, wdb := open()
+	require.NoError(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.Delete([]byte("b")))
+	assert.NotEqual(t, header, walSize(wdb))
+	require.NoError(t, wdb.SetSync([]byte("c"), []byte{3}))
+	assert.Equal(t, header, walSize(wdb))
+	batch := wdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("d"), []byte{4}))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	assert.Equal(t, header, walSize(wdb))
+
+	// Recovery applies batches with sync writes, so it resets the WAL once they are replayed.
+	require.NoError(t, wdb.Set([]byte("e"), []byte{5}))
+	crash(ldb, wdb)
+	ldb, wdb = open()
+	assert.Equal(t, header, walSize(wdb))
+	assertKeyValues(t, wdb, map[string][]byte{"a": {1}, "c": {3}, "d": {4}, "e": {5}})
+
+	// A DeleteRange is not replayed with a sync write, so a WAL ending with one is kept.
+	require.NoError(t, wdb.DeleteRange([]byte("a"), []byte("b")))
+	crash(ldb, wdb)
+	ldb, wdb = open()
+	assert.NotEqual(t, header, walSize(wdb))
+	assertKeyValues(t, wdb, map[string][]byte{"c": {3}, "d": {4}, "e": {5}})
+	require.NoError(t, wdb.Close())
+
+	// A WAL holding a write which failed to apply is kept, so that recovery retries it.
+	ldb, err = NewGoLevelDB("failing", dir)
+	require.NoError(t, err)
+	defer ldb.Close()
+	wdb, err = NewWALDB(failingSetLevelDB{ldb}, filepath.Join(dir, "failing"))
+	require.NoError(t, err)
+	require.Error(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.SetSync([]byte("b"), []byte{2}))
+	assert.NotEqual(t, header, walSize(wdb))
+	require.NoError(t, wdb.file.Close())
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// MutationEvent is a change made to a WatchDB, numbered by a sequence number. Value is nil for
+// deletes.
+type MutationEvent struct {
+	Seq   uint64
+	Op    ChangeOpType
+	Key   []byte
+	Value []byte
+}
+
+// WatchDB wraps a database, and records every change made through it in a ring buffer of
+// MutationEvents, numbered with sequence numbers increasing by one from 1. Subscribers receive
+// the events from a given sequence number onward, including past events still in the buffer, so
+// a subscriber which falls behind or reconnects can catch up.
+//
+// Unlike ObservableDB, writes never wait for subscribers: a subscriber which falls behind by more
+// than the buffer size misses the overwritten events, which it can detect by a gap in the
+// sequence numbers. Changes are recorded like ObservableDB does, which it is built on.
+type WatchDB struct {
+	db *ObservableDB
+
+	mtx     sync.Mutex
+	cond    *sync.Cond // signalled when an event is added, or the database is closed
+	events  []MutationEvent
+	lastSeq uint64
+	closed  bool
+}
+
+var _ DB = (*WatchDB)(nil)
+
+// NewWatchDB creates a WatchDB wrapping the given database, keeping the last bufferSize events.
+// A bufferSize below 1 is treated as 1.
+func NewWatchDB(inner DB, bufferSize int) *WatchDB {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	wdb := &WatchDB{events: make([]MutationEvent, bufferSize)}
+	wdb.cond = sync.NewCond(&wdb.mtx)
+	wdb.db = NewObservableDB(inner, ObservableOptions{OnChange: wdb.record})
+	return wdb
+}
+
+// record adds a change to the ring buffer. ObservableDB calls it for each change, in order.
+func (wdb *WatchDB) record(change ChangeOp) {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	wdb.lastSeq++
+	wdb.events[(wdb.lastSeq-1)%uint64(len(wdb.events))] = MutationEvent{
+		Seq:   wdb.lastSeq,
+		Op:    change.Op,
+		Key:   change.Key,
+		Value: change.Value,
+	}
+	wdb.cond.Broadcast()
+}
+
+// LastSeq returns the sequence number of the last event, or 0 if there have been none.
+func (wdb *WatchDB) LastSeq() uint64 {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	return wdb.lastSeq
+}
+
+// oldestSeq returns the sequence number of the oldest event in the buffer. It requires holding
+// mtx.
+func (wdb *WatchDB) oldestSeq() uint64 {
+	if wdb.lastSeq < uint64(len(wdb.events)) {
+		return 1
+	}
+	return wdb.lastSeq - uint64(len(wdb.events)) + 1
+}
+
+// Subscribe returns a channel receiving the events from sequence number fromSeq onward, in
+// order, and a function cancelling the subscription. If fromSeq is older than the oldest event in
+// the buffer, delivery starts from the oldest one instead. The channel is closed once the
+// subscription is cancelled, or once the database is closed and all recorded events have been
+// delivered. The cancel function must be called to release the subscription.
+func (wdb *WatchDB) Subscribe(fromSeq uint64) (<-chan MutationEvent, func()) {
+	out := make(chan MutationEvent)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(done)
+			// Take the lock, so the broadcast is not missed by a subscriber about to wait.
+			wdb.mtx.Lock()
+			wdb.cond.Broadcast()
+			wdb.mtx.Unlock()
+		})
+	}
+
+	go func() {
+		defer close(out)
+		next := fromSeq
+		if next == 0 {
+			next = 1
+		}
+		for {
+			events, ok := wdb.waitEvents(next, done)
+			if !ok {
+				return
+			}
+			for _, event := range events {
+				select {
+				case out <- event:
+				case <-done:
+					return
+				}
+			}
+			next = events[len(events)-1].Seq + 1
+		}
+	}()
+	return out, cancel
+}
+
+// waitEvents waits for events from sequence number next onward, and returns them. It returns false
+// once done is closed, or the database is closed and there are no further events.
+func (wdb *WatchDB) waitEvents(next uint64, done <-chan struct{}) ([]MutationEvent, bool) {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	for {
+		select {
+		case <-done:
+			return nil, false
+		default:
+		}
+		if next <= wdb.lastSeq {
+			break
+		}
+		if wdb.closed {
+			return nil, false
+		}
+		wdb.cond.Wait()
+	}
+
+	if oldest := wdb.oldestSeq(); next < oldest {
+		next = oldest
+	}
+	events := make([]MutationEvent, 0, wdb.lastSeq-next+1)
+	for seq := next; seq <= wdb.lastSeq; seq++ {
+		events = append(events, wdb.events[(seq-1)%uint64(len(wdb.events))])
+	}
+	return events, true
+}
+
+// Get implements DB.
+func (wdb *WatchDB) Get(key []byte) ([]byte, error) {
+	return wdb.db.Get(key)
+}
+
+// Has implements DB.
+func (wdb *WatchDB) Has(key []byte) (bool, error) {
+	return wdb.db.Has(key)
+}
+
+// MultiGet implements DB.
+func (wdb *WatchDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return wdb.db.MultiGet(keys)
+}
+
+// Set implements DB.
+func (wdb *WatchDB) Set(key []byte, value []byte) error {
+	return wdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (wdb *WatchDB) SetSync(key []byte, value []byte) error {
+	return wdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (wdb *WatchDB) Delete(key []byte) error {
+	return wdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (wdb *WatchDB) DeleteSync(key []byte) error {
+	return wdb.db.DeleteSync(key)
+}
+
+// CompareAndSet implements DB.
+func (wdb *WatchDB) CompareAndSet(key, expected, newVal []byte) (bool, error) {
+	return wdb.db.CompareAndSet(key, expected, newVal)
+}
+
+// DeleteRange implements DB.
+func (wdb *WatchDB) DeleteRange(start, end []byte) error {
+	return wdb.db.DeleteRange(start, end)
+}
+
+// Iterator implements DB.
+func (wdb *WatchDB) Iterator(start, end []byte) (Iterator, error) {
+	return wdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (wdb *WatchDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return wdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithContext implements DB.
+func (wdb *WatchDB) IteratorWithContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	return wdb.db.IteratorWithContext(ctx, start, end)
+}
+
+// Compact implements DB.
+func (wdb *WatchDB) Compact(start, end []byte) error {
+	return wdb.db.Compact(start, end)
+}
+
+// WriteBatch implements DB.
+func (wdb *WatchDB) WriteBatch(ops []BatchOp) error {
+	return wdb.db.WriteBatch(ops)
+}
+
+// WriteBatchSync implements DB.
+func (wdb *WatchDB) WriteBatchSync(ops []BatchOp) error {
+	return wdb.db.WriteBatchSync(ops)
+}
+
+// ApplyLog implements DB.
+func (wdb *WatchDB) ApplyLog(ops BatchOpList) error {
+	return wdb.db.ApplyLog(ops)
+}
+
+// ForEach implements DB.
+func (wdb *WatchDB) ForEach(fn func(key, value []byte) error) error {
+	return wdb.db.ForEach(fn)
+}
+
+// Close implements DB. Subscription channels are closed once the recorded events have been
+// delivered.
+func (wdb *WatchDB) Close() error {
+	err := wdb.db.Close()
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	wdb.closed = true
+	wdb.cond.Broadcast()
+	return err
+}
+
+// NewBatch implements DB.
+func (wdb *WatchDB) NewBatch() Batch {
+	return wdb.db.NewBatch()
+}
+
+// NewBatchWithSize implements DB.
+func (wdb *WatchDB) NewBatchWithSize(expectedOps int) Batch {
+	return wdb.db.NewBatchWithSize(expectedOps)
+}
+
+// Print implements DB.
+func (wdb *WatchDB) Print() error {
+	return wdb.db.Print()
+}
+
+// Stats implements DB.
+func (wdb *WatchDB) Stats() map[string]string {
+	return wdb.db.Stats()
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveEvents receives n events from the channel, failing the test if they do not arrive.
+func receiveEvents(t *testing.T, ch <-chan MutationEvent, n int) []MutationEvent {
+	t.Helper()
+	events := make([]MutationEvent, 0, n)
+	for len(events) < n {
+		select {
+		case event, ok := <-ch:
+			require.True(t, ok, "channel closed after %d events", len(events))
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d events", len(events))
+		}
+	}
+	return events
+}
+
+func TestWatchDB(t *testing.T) {
+	wdb := NewWatchDB(NewMemDB(), 100)
+	live, cancelLive := wdb.Subscribe(0)
+	defer cancelLive()
+
+	require.NoError(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.Set([]byte("b"), []byte{2}))
+	require.NoError(t, wdb.Delete([]byte("a")))
+	batch := wdb.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assert.EqualValues(t, 5, wdb.LastSeq())
+
+	expect := []MutationEvent{
+		{Seq: 1, Op: ChangeOpSet, Key: []byte("a"), Value: []byte{1}},
+		{Seq: 2, Op: ChangeOpSet, Key: []byte("b"), Value: []byte{2}},
+		{Seq: 3, Op: ChangeOpDelete, Key: []byte("a")},
+		{Seq: 4, Op: ChangeOpSet, Key: []byte("c"), Value: []byte{3}},
+		{Seq: 5, Op: ChangeOpDelete, Key: []byte("b")},
+	}
+	assert.Equal(t, expect, receiveEvents(t, live, 5))
+
+	// A late subscriber receives the missed events from the buffer, then new ones.
+	late, cancelLate := wdb.Subscribe(2)
+	defer cancelLate()
+	assert.Equal(t, expect[1:], receiveEvents(t, late, 4))
+	require.NoError(t, wdb.Set([]byte("d"), []byte{4}))
+	event := MutationEvent{Seq: 6, Op: ChangeOpSet, Key: []byte("d"), Value: []byte{4}}
+	assert.Equal(t, []MutationEvent{event}, receiveEvents(t, late, 1))
+	assert.Equal(t, []MutationEvent{event}, receiveEvents(t, live, 1))
+
+	// Cancelling closes the channel.
+	cancelLate()
+	cancelLate()
+	_, ok := <-late
+	assert.False(t, ok)
+
+	// Closing the database closes the channels once the events have been delivered.
+	require.NoError(t, wdb.Set([]byte("e"), []byte{5}))
+	require.NoError(t, wdb.Close())
+	assert.EqualValues(t, 7, receiveEvents(t, live, 1)[0].Seq)
+	_, ok = <-live
+	assert.False(t, ok)
+}
+
+func TestWatchDBRingBuffer(t *testing.T) {
+	wdb := NewWatchDB(NewMemDB(), 3)
+	defer wdb.Close()
+	for i := int64(1); i <= 10; i++ {
+		require.NoError(t, wdb.Set(int642Bytes(i), []byte{1}))
+	}
+
+	// Events which have been overwritten are skipped, starting from the oldest one kept.
+	sub, cancel := wdb.Subscribe(1)
+	defer cancel()
+	events := receiveEvents(t, sub, 3)
+	for i, event := range events {
+		assert.EqualValues(t, 8+i, event.Seq)
+		assert.Equal(t, int642Bytes(int64(8+i)), event.Key)
+	}
+
+	// Subscribing from the future waits for that event.
+	future, cancelFuture := wdb.Subscribe(12)
+	defer cancelFuture()
+	require.NoError(t, wdb.Set([]byte("a"), []byte{1}))
+	require.NoError(t, wdb.Set([]byte("b"), []byte{2}))
+	assert.EqualValues(t, 12, receiveEvents(t, future, 1)[0].Seq)
+	assert.EqualValues(t, 11, receiveEvents(t, sub, 1)[0].Seq)
+}